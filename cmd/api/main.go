@@ -1,36 +1,120 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"maps"
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/compress"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"github.com/gofiber/fiber/v3/middleware/logger"
+	"github.com/gofiber/fiber/v3/middleware/pprof"
 	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/redis/go-redis/v9"
 
+	"fingerprint-converter/internal/admission"
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/breaker"
 	"fingerprint-converter/internal/cache"
+	"fingerprint-converter/internal/cdn"
 	"fingerprint-converter/internal/config"
+	"fingerprint-converter/internal/consumer"
+	"fingerprint-converter/internal/devices"
+	"fingerprint-converter/internal/experiments"
 	"fingerprint-converter/internal/handlers"
+	"fingerprint-converter/internal/history"
+	"fingerprint-converter/internal/jobs"
+	"fingerprint-converter/internal/models"
 	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/resolver"
+	"fingerprint-converter/internal/rpcconv"
+	"fingerprint-converter/internal/scanner"
 	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/slowlog"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/usage"
+	"fingerprint-converter/internal/validation"
+	"fingerprint-converter/internal/warmup"
+	"fingerprint-converter/internal/watcher"
 )
 
 func main() {
+	started := time.Now()
+
 	// Set up logging
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.SetPrefix("[FingerprintConverter] ")
 	log.Println("🚀 Starting Fingerprint Converter API...")
 
+	configPath := flag.String("config", "", "YAML config file (default: CONFIG_FILE; environment variables override it)")
+	flag.Parse()
+
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			log.Printf("❌ Invalid configuration: %s", line)
+		}
+		log.Fatalf("❌ Fix the settings above and restart")
+	}
+
+	if err := services.SetDefaultAFLevel(cfg.DefaultAFLevel); err != nil {
+		log.Fatalf("❌ DEFAULT_AF_LEVEL: %v", err)
+	}
+	if err := services.SetProfiles(cfg.Profiles); err != nil {
+		log.Fatalf("❌ Invalid AF profiles: %v", err)
+	}
+	services.SetAFRanges(cfg.AFRanges)
+	if len(cfg.Profiles) > 0 {
+		log.Printf("🎛️  AF profiles: %s", strings.Join(services.ProfileNames(), ", "))
+	}
+	if err := services.SetExperiments(cfg.Experiments); err != nil {
+		log.Fatalf("❌ Invalid experiments: %v", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Experiments)) {
+		log.Printf("🧪 Experiment %s: %d variants", name, len(cfg.Experiments[name].Variants))
+	}
+
+	// Every ffmpeg/ffprobe run goes through one executor
+	var ffmpegExecutor services.Executor = services.LocalExecutor{LogCommands: cfg.FFmpegLogCommands}
+	if cfg.FFmpegDryRun {
+		ffmpegExecutor = services.DryRunExecutor{Next: ffmpegExecutor}
+		log.Printf("🧪 FFmpeg dry run: commands are logged instead of run, outputs are copies of the inputs")
+	}
+	if cfg.FFmpegChaosRate > 0 {
+		ffmpegExecutor = services.ChaosExecutor{Next: ffmpegExecutor, FailureRate: cfg.FFmpegChaosRate}
+		log.Printf("🐒 FFmpeg chaos: failing %.0f%% of runs on purpose (do not use in production)", cfg.FFmpegChaosRate*100)
+	}
+	services.SetExecutor(ffmpegExecutor)
+	if cfg.FFmpegReapGrace > 0 {
+		services.StartReaper(cfg.FFmpegReapGrace)
+	}
+	services.SetProbeCacheSize(cfg.ProbeCacheSize)
+	log.Printf("🔬 Probe cache: up to %d results by input content (0 = off)", cfg.ProbeCacheSize)
+
+	// Mark outputs so they are recognized if they loop back as inputs
+	services.SetOutputMarker(services.NewOutputMarker(cfg.OutputMarkerSecret))
+	if cfg.OutputMarkerSecret != "" {
+		log.Printf("♻️  Output marking enabled: marked inputs are handled with mode=%s", cfg.ReprocessMode)
+	}
 
 	// Set runtime optimizations
 	runtime.GOMAXPROCS(runtime.NumCPU())
+	applyGCTuning(cfg)
 	log.Printf("⚙️  GOMAXPROCS=%d, GOGC=%d, GOMEMLIMIT=%s", 
 		runtime.NumCPU(), cfg.GOGC, cfg.GoMemLimit)
 
@@ -45,40 +129,473 @@ func main() {
 	if err := workerPool.Start(); err != nil {
 		log.Fatalf("❌ Failed to start worker pool: %v", err)
 	}
+	services.SetFFmpegThreads(ffmpegThreads(cfg))
+	log.Printf("🧵 FFmpeg threads per job: %d", services.FFmpegThreads())
 
 	// Initialize device cache
 	var deviceCache *cache.DeviceCache
 	if cfg.EnableCache {
-		log.Printf("💾 Initializing device cache: dir=%s, cacheTTL=%v, fileTTL=%v",
-			cfg.CacheDir, cfg.CacheTTL, cfg.FileTTL)
-		deviceCache = cache.NewDeviceCache(cfg.CacheDir, cfg.CacheTTL, cfg.FileTTL)
+		log.Printf("💾 Initializing device cache: dir=%s, cacheTTL=%v, fileTTL=%v, failureTTL=%v",
+			cfg.CacheDir, cfg.CacheTTL, cfg.FileTTL, cfg.FailureTTL)
+		deviceCache = cache.NewDeviceCache(cfg.CacheDir, cfg.CacheTTL, cfg.FileTTL, cfg.FailureTTL)
 	} else {
 		log.Println("⚠️  Cache disabled")
 		// Create dummy cache with 0 TTL
-		deviceCache = cache.NewDeviceCache(cfg.CacheDir, 0, 0)
+		deviceCache = cache.NewDeviceCache(cfg.CacheDir, 0, 0, 0)
+	}
+	deviceCache.SetMinFreeDisk(cfg.MinFreeDisk)
+	deviceCache.SetMaxPinned(cfg.CacheMaxPinned)
+	if cfg.EnableCache {
+		deviceCache.SetRetention(cfg.FileRetention)
+	}
+	deviceCache.SetProfileVersion(services.ProfileVersion())
+	deviceCache.SetDropStale(cfg.EnableCache && cfg.ReencodeStale != "off")
+	if cfg.DedupOutputs {
+		if err := deviceCache.EnableDedup(); err != nil {
+			log.Fatalf("❌ Failed to enable output dedup: %v", err)
+		}
+		log.Println("🔗 Output dedup enabled: identical outputs share one file")
 	}
 
 	// Initialize downloader
-	downloader := services.NewDownloader(bufferPool, cfg.MaxDownloadSize, cfg.DownloadTimeout)
+	var hostBreaker *breaker.Settings
+	if cfg.DownloadBreaker {
+		hostBreaker = &breaker.Settings{
+			FailureRate: cfg.DownloadBreakerFailureRate,
+			MinRequests: cfg.DownloadBreakerMinRequests,
+			Window:      cfg.DownloadBreakerWindow,
+			Cooldown:    cfg.DownloadBreakerCooldown,
+		}
+	}
+
+	// Optional platform URL resolution (yt-dlp) so callers don't have to pre-download
+	var urlResolver resolver.Resolver
+	switch cfg.ResolverMode {
+	case "":
+	case "ytdlp":
+		ytdlp, err := resolver.NewYtDlp(cfg.YtDlpPath, cfg.YtDlpFormat, cfg.ResolverHosts, cfg.ResolverTimeout)
+		if err != nil {
+			log.Fatalf("❌ Invalid resolver configuration: %v", err)
+		}
+		log.Printf("🔗 Platform URL resolution enabled: yt-dlp, format=%s", cfg.YtDlpFormat)
+		urlResolver = ytdlp
+	default:
+		log.Fatalf("❌ Invalid RESOLVER_MODE %q (supported: ytdlp)", cfg.ResolverMode)
+	}
+	// FTP/SFTP sources and upload targets (partner file drops)
+	remoteCredentials, err := remote.ParseCredentials(cfg.RemoteCredentials)
+	if err != nil {
+		log.Fatalf("❌ Invalid REMOTE_CREDENTIALS: %v", err)
+	}
+	remoteClient, err := remote.New(remote.Config{
+		Credentials:     remoteCredentials,
+		KeyFile:         cfg.SFTPKeyFile,
+		KnownHosts:      cfg.SFTPKnownHosts,
+		InsecureHostKey: cfg.SFTPInsecureHostKey,
+		Timeout:         cfg.DownloadTimeout,
+	})
+	if err != nil {
+		log.Fatalf("❌ Invalid SFTP configuration: %v", err)
+	}
+	if cfg.SFTPInsecureHostKey {
+		log.Printf("⚠️  SFTP host keys are not verified (SFTP_INSECURE_HOST_KEY=true)")
+	}
+	if len(cfg.LocalInputRoots) > 0 {
+		log.Printf("📂 Local file inputs enabled: roots=%s", strings.Join(cfg.LocalInputRoots, ", "))
+	}
+
+	downloader := services.NewDownloader(bufferPool, cfg.MaxDownloadSize, cfg.DownloadTimeout, cfg.StreamMaxDuration, urlResolver, remoteClient, cfg.LocalInputRoots, hostBreaker, cfg.DownloadPrecheck)
 
 	// Initialize converters
-	audioConverter := services.NewAudioConverter(workerPool, bufferPool)
-	imageConverter := services.NewImageConverter(workerPool, bufferPool)
-	videoConverter := services.NewVideoConverter(workerPool, bufferPool)
+	// One random source shared by every converter
+	rng := services.NewRNG(cfg.AFSeed)
+	if cfg.AFSeed != 0 {
+		log.Printf("🎲 AF randomness seeded with AF_SEED=%d (output is reproducible, do not use in production)", cfg.AFSeed)
+	}
+	audioConverter := services.NewAudioConverter(workerPool, bufferPool, rng)
+	imageConverter := services.NewImageConverter(workerPool, bufferPool, rng)
+	videoConverter := services.NewVideoConverter(workerPool, bufferPool, rng)
+	slideshowBuilder := services.NewSlideshowBuilder(workerPool, bufferPool, rng)
+	concatenator := services.NewConcatenator(workerPool, bufferPool)
+
+	// HLS/DASH packages follow the renditions they segment, so they expire with FILE_TTL
+	packager := services.NewPackager(cfg.CacheDir, cfg.PackageSegmentDuration, cfg.FileTTL,
+		cfg.PackageUploadURL, cfg.PackageUploadAuth, cfg.RequestTimeout, remoteClient)
+	if packager.CanUpload() {
+		log.Printf("☁️  Package uploads enabled: segments=%v", cfg.PackageSegmentDuration)
+	}
+
+	// Uploaded outputs (destination_url, packages) are purged from the CDN in front of them when replaced
+	var cdnPurger cdn.Purger
+	switch cfg.CDNProvider {
+	case "":
+	case "cloudflare":
+		cdnPurger, err = cdn.NewCloudflare(cfg.CDNZoneID, cfg.CDNAPIToken)
+	case "fastly":
+		cdnPurger, err = cdn.NewFastly(cfg.CDNAPIToken)
+	case "cloudfront":
+		cdnPurger, err = cdn.NewCloudFront(cfg.CDNZoneID, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+	default:
+		log.Fatalf("❌ Invalid CDN_PROVIDER %q (supported: cloudflare, fastly, cloudfront)", cfg.CDNProvider)
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to set up CDN purging: %v", err)
+	}
+	var cdnInvalidator *cdn.Invalidator
+	if cdnPurger != nil {
+		cdnInvalidator, err = cdn.NewInvalidator(cdnPurger, cfg.CDNPublicBaseURL, 30*time.Second)
+		if err != nil {
+			log.Fatalf("❌ Failed to set up CDN purging: %v", err)
+		}
+		log.Printf("🧹 CDN purging enabled: cdn=%s, public_base=%q", cdnPurger.Name(), cfg.CDNPublicBaseURL)
+	}
+
+	// Load tenants (API keys, quotas, isolated cache namespaces)
+	tenants, err := tenant.LoadRegistry(cfg.TenantsFile)
+	if err != nil {
+		log.Fatalf("❌ Failed to load tenants: %v", err)
+	}
+	if tenants.Enabled() {
+		log.Printf("🏢 Multi-tenancy enabled: tenants=%d, file=%s", len(tenants.Tenants()), cfg.TenantsFile)
+	}
+
+	// Post-processors (upload, notify, tagging) chained after each conversion
+	postProcessors, err := buildPipeline(cfg, tenants)
+	if err != nil {
+		log.Fatalf("❌ Invalid post-processors: %v", err)
+	}
+
+	// Initialize usage accounting
+	var usageStore *usage.Store
+	if cfg.EnableUsage {
+		log.Printf("🧾 Initializing usage store: path=%s", cfg.UsageDBPath)
+		usageStore, err = usage.OpenStore(cfg.UsageDBPath)
+		if err != nil {
+			log.Fatalf("❌ Failed to open usage store: %v", err)
+		}
+	}
+
+	// Initialize device registry
+	var deviceStore *devices.Store
+	if cfg.EnableDevices {
+		log.Printf("📱 Initializing device registry: path=%s", cfg.DeviceDBPath)
+		deviceStore, err = devices.OpenStore(cfg.DeviceDBPath)
+		if err != nil {
+			log.Fatalf("❌ Failed to open device registry: %v", err)
+		}
+	}
+
+	// Initialize audit log
+	var auditLogger *audit.Logger
+	var auditFile *audit.FileSink
+	var sinks []audit.Sink
+	if cfg.AuditEnabled {
+		log.Printf("📜 Initializing audit log: dir=%s", cfg.AuditDir)
+		auditFile, err = audit.NewFileSink(cfg.AuditDir)
+		if err != nil {
+			log.Fatalf("❌ Failed to open audit log: %v", err)
+		}
+		sinks = append(sinks, auditFile)
+		if cfg.AuditWebhookURL != "" {
+			log.Printf("📜 Mirroring audit log to webhook")
+			sinks = append(sinks, audit.NewWebhookSink(cfg.AuditWebhookURL, 10*time.Second))
+		}
+	}
+
+	// Per-device history is fed by the same entries, even with the audit log off
+	var deviceHistory *history.Store
+	if cfg.HistorySize > 0 {
+		log.Printf("🕘 Keeping the last %d conversions per device (max %d devices)", cfg.HistorySize, cfg.HistoryMaxDevices)
+		deviceHistory = history.NewStore(cfg.HistorySize, cfg.HistoryMaxDevices)
+		sinks = append(sinks, deviceHistory)
+	}
+
+	// Experiment outcomes are counted from the same entries
+	experimentTracker := experiments.NewTracker()
+	sinks = append(sinks, experimentTracker)
+	auditLogger = audit.NewLogger(sinks...)
+
+	// Conversions past the per-media thresholds are logged (and POSTed to the alert webhook)
+	slowConversions := slowlog.New(slowThresholds(cfg), cfg.SlowConversionWebhookURL, 10*time.Second)
+	if cfg.SlowConversionWebhookURL != "" {
+		log.Printf("🐢 Sending slow-conversion alerts to webhook")
+	}
+
+	// Hold back new conversions while the host is short of CPU or memory
+	var admissionControl *admission.Controller
+	if cfg.AdmissionMaxLoad > 0 || cfg.AdmissionMaxMemory > 0 {
+		admissionControl = admission.New(admissionThresholds(cfg), cfg.AdmissionSampleInterval, cfg.AdmissionMaxWait)
+		log.Printf("🚦 Admission control: max load %.2f per core, max memory %.0f%%, audio/image wait up to %s",
+			cfg.AdmissionMaxLoad, cfg.AdmissionMaxMemory*100, cfg.AdmissionMaxWait)
+	}
+
+	// Initialize malware scanner
+	var malwareScanner scanner.Scanner
+	switch cfg.ScanMode {
+	case "":
+	case "clamav":
+		clamav, err := scanner.NewClamAV(cfg.ClamdAddress, cfg.ScanTimeout)
+		if err != nil {
+			log.Fatalf("❌ Invalid clamd configuration: %v", err)
+		}
+		pingCtx, cancel := context.WithTimeout(context.Background(), cfg.ScanTimeout)
+		if err := clamav.Ping(pingCtx); err != nil {
+			log.Printf("⚠️  clamd not reachable yet: %v", err)
+		}
+		cancel()
+
+		log.Printf("🦠 Malware scanning enabled: clamd=%s, fail-open=%v", cfg.ClamdAddress, cfg.ScanFailOpen)
+		malwareScanner = clamav
+		if cfg.ScanFailOpen {
+			malwareScanner = scanner.FailOpen(clamav)
+		}
+	default:
+		log.Fatalf("❌ Invalid SCAN_MODE %q (supported: clamav)", cfg.ScanMode)
+	}
+
+	// Input limits (ffprobe runs before encoding when any are set)
+	maxLongEdge, maxShortEdge, err := services.ParseMaxResolution(cfg.MaxVideoResolution)
+	if err != nil {
+		log.Fatalf("❌ Invalid MAX_VIDEO_RESOLUTION: %v", err)
+	}
+	inputLimits := services.InputLimits{
+		MaxImageMegapixels: cfg.MaxImageMegapixels,
+		MaxVideoLongEdge:   maxLongEdge,
+		MaxVideoShortEdge:  maxShortEdge,
+		MaxVideoDuration:   cfg.MaxVideoDuration,
+		MaxAudioDuration:   cfg.MaxAudioDuration,
+		MaxImageSize:       cfg.MaxImageSize,
+		MaxAudioSize:       cfg.MaxAudioSize,
+		MaxVideoSize:       cfg.MaxVideoSize,
+	}
+
+	// Zip archive inputs: each media file inside counts against MAX_DOWNLOAD_SIZE on its own
+	archiveLimits := services.ArchiveLimits{
+		MaxEntries:   cfg.ArchiveMaxEntries,
+		MaxSize:      cfg.ArchiveMaxSize,
+		MaxEntrySize: cfg.MaxDownloadSize,
+	}
+
+	// Output quality scoring (ffmpeg ssim/psnr/libvmaf) after encoding
+	qualityCheck := services.QualityCheck{
+		Always:  cfg.QualityMetrics,
+		VMAF:    cfg.QualityVMAF,
+		MinSSIM: cfg.QualityMinSSIM,
+		MinPSNR: cfg.QualityMinPSNR,
+		MinVMAF: cfg.QualityMinVMAF,
+	}
+	if qualityCheck.Always || qualityCheck.Enforced() {
+		log.Printf("📐 Quality metrics enabled: min SSIM=%v, min PSNR=%v, min VMAF=%v",
+			cfg.QualityMinSSIM, cfg.QualityMinPSNR, cfg.QualityMinVMAF)
+	}
+
+	// Output verification (ffprobe + decode of both ends) before caching
+	outputCheck := services.OutputCheck{
+		Enabled:           cfg.VerifyOutput,
+		DurationTolerance: cfg.VerifyDurationTolerance,
+	}
+	if outputCheck.Enabled {
+		log.Printf("🔎 Output verification enabled: duration tolerance=%s", cfg.VerifyDurationTolerance)
+	}
+
+	// Remote converter node: delegated media types skip local ffmpeg
+	var remoteConverter *rpcconv.Client
+	if cfg.RemoteConverterAddr != "" {
+		remoteConverter, err = rpcconv.NewClient(rpcconv.ClientConfig{
+			Address:    cfg.RemoteConverterAddr,
+			MediaTypes: cfg.RemoteConverterMedia,
+			TLS:        cfg.RemoteConverterTLS,
+			CAFile:     cfg.RemoteConverterCAFile,
+			Token:      cfg.RemoteConverterToken,
+		})
+		if err != nil {
+			log.Fatalf("❌ Failed to set up remote converter: %v", err)
+		}
+		log.Printf("🛰️  Remote converter: %s for %s", remoteConverter.Address(), strings.Join(cfg.RemoteConverterMedia, ", "))
+	}
+
+	// FFmpeg circuit breakers: fail fast while conversions of a media type keep failing
+	// Delegated media types are probed with the node's health check instead of a local self-test
+	var ffmpegBreakers map[string]*breaker.Breaker
+	if cfg.FFmpegBreaker {
+		settings := breaker.Settings{
+			FailureRate: cfg.FFmpegBreakerFailureRate,
+			MinRequests: cfg.FFmpegBreakerMinRequests,
+			Window:      cfg.FFmpegBreakerWindow,
+			Cooldown:    cfg.FFmpegBreakerCooldown,
+		}
+		ffmpegBreakers = make(map[string]*breaker.Breaker)
+		for _, mediaType := range []string{"audio", "image", "video"} {
+			ffmpegBreakers[mediaType] = breaker.New("ffmpeg "+mediaType, settings, func(ctx context.Context) error {
+				if remoteConverter.Handles(mediaType) {
+					return remoteConverter.Check(ctx)
+				}
+				return services.SelfTest(ctx, mediaType)
+			})
+		}
+		log.Printf("🔌 FFmpeg circuit breakers: open at %.0f%% failures of %d+ requests per %s, probe every %s",
+			settings.FailureRate*100, settings.MinRequests, settings.Window, settings.Cooldown)
+	}
 
 	// Initialize handler
 	converterHandler := handlers.NewConverterHandler(
 		audioConverter,
 		imageConverter,
 		videoConverter,
+		remoteConverter,
+		slideshowBuilder,
+		concatenator,
+		packager,
+		postProcessors,
 		downloader,
 		deviceCache,
 		workerPool,
 		bufferPool,
 		cfg.RequestTimeout,
 		cfg.CacheDir,
+		tenants,
+		deviceStore,
+		usageStore,
+		auditLogger,
+		slowConversions,
+		admissionControl,
+		malwareScanner,
+		inputLimits,
+		archiveLimits,
+		qualityCheck,
+		outputCheck,
+		cfg.EncodeFallback,
+		cfg.ReprocessMode,
+		cdnInvalidator,
+		ffmpegBreakers,
+		cfg.Debug,
 	)
 
+	// Initialize async job manager (persisted, recovers jobs after restarts)
+	var jobStore jobs.Store
+	var jobManager *jobs.Manager
+	var redisClient *redis.Client
+	if cfg.EnableJobs {
+		var jobQueue jobs.Queue
+		switch cfg.JobBackend {
+		case "local":
+			log.Printf("🗂️  Initializing job store: path=%s, workers=%d", cfg.JobDBPath, cfg.JobWorkers)
+			boltStore, err := jobs.OpenBoltStore(cfg.JobDBPath)
+			if err != nil {
+				log.Fatalf("❌ Failed to open job store: %v", err)
+			}
+			jobStore, jobQueue = boltStore, jobs.NewLocalQueue(cfg.JobFairScheduling)
+		case "redis":
+			log.Printf("🗂️  Initializing Redis job queue: prefix=%s, workers=%d, visibility=%v",
+				cfg.RedisKeyPrefix, cfg.JobWorkers, cfg.JobVisibilityTimeout)
+			var err error
+			redisClient, err = jobs.ConnectRedis(cfg.RedisURL)
+			if err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			jobStore = jobs.NewRedisStore(redisClient, cfg.RedisKeyPrefix)
+			jobQueue = jobs.NewRedisQueue(redisClient, cfg.RedisKeyPrefix, cfg.JobVisibilityTimeout)
+		default:
+			log.Fatalf("❌ Invalid JOB_BACKEND %q (supported: local, redis)", cfg.JobBackend)
+		}
+
+		jobManager = jobs.NewManager(jobStore, jobQueue, converterHandler.Process,
+			cfg.JobWorkers, cfg.JobQueueSize, cfg.RequestTimeout, cfg.JobRetention, cfg.JobVisibilityTimeout/3,
+			models.RetryPolicy{
+				MaxAttempts:    cfg.JobMaxAttempts,
+				BackoffSeconds: int(cfg.JobRetryBackoff.Seconds()),
+			})
+		if err := jobManager.Start(); err != nil {
+			log.Fatalf("❌ Failed to start job manager: %v", err)
+		}
+	}
+
+	// Optional worker mode: consume ConvertRequest messages from Kafka or RabbitMQ
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	if cfg.ConsumerMode != "" {
+		var broker consumer.Broker
+		var err error
+		switch cfg.ConsumerMode {
+		case "kafka":
+			log.Printf("📨 Consuming from Kafka: brokers=%v, topic=%s → %s",
+				cfg.KafkaBrokers, cfg.KafkaRequestTopic, cfg.KafkaResponseTopic)
+			broker, err = consumer.NewKafkaBroker(cfg.KafkaBrokers, cfg.KafkaGroupID,
+				cfg.KafkaRequestTopic, cfg.KafkaResponseTopic)
+		case "rabbitmq":
+			log.Printf("📨 Consuming from RabbitMQ: queue=%s → %s",
+				cfg.RabbitMQRequestQueue, cfg.RabbitMQResponseQueue)
+			broker, err = consumer.NewRabbitMQBroker(cfg.RabbitMQURL, cfg.RabbitMQRequestQueue,
+				cfg.RabbitMQResponseQueue, cfg.ConsumerConcurrency)
+		default:
+			log.Fatalf("❌ Invalid CONSUMER_MODE %q (supported: kafka, rabbitmq)", cfg.ConsumerMode)
+		}
+		if err != nil {
+			log.Fatalf("❌ Failed to start consumer: %v", err)
+		}
+
+		messageConsumer := consumer.New(broker, converterHandler.Process, cfg.ConsumerConcurrency, cfg.RequestTimeout)
+		go func() {
+			messageConsumer.Run(consumerCtx)
+			close(consumerDone)
+		}()
+	} else {
+		close(consumerDone)
+	}
+
+	// Cache warm-up: conversions queued ahead of campaigns run only while live traffic leaves room
+	warmupCtx, stopWarmup := context.WithCancel(context.Background())
+	warmupDone := make(chan struct{})
+	var warmer *warmup.Warmer
+	if cfg.EnableWarmup {
+		busy := func() bool {
+			maxWorkers := workerPool.GetStats().MaxWorkers
+			return float64(converterHandler.ActiveConversions()) >= cfg.WarmupBusyRatio*float64(maxWorkers)
+		}
+		warmer = warmup.New(converterHandler.Process, busy, cfg.WarmupConcurrency, cfg.WarmupQueueSize, cfg.RequestTimeout)
+		log.Printf("🔥 Cache warm-up enabled: concurrency=%d, queue=%d, busy above %.0f%% of workers",
+			cfg.WarmupConcurrency, cfg.WarmupQueueSize, cfg.WarmupBusyRatio*100)
+		go func() {
+			warmer.Run(warmupCtx)
+			close(warmupDone)
+		}()
+
+		// Pre-convert a manifest of assets so a fresh deployment doesn't start cold
+		if cfg.WarmupManifest != "" {
+			go preloadManifest(cfg.WarmupManifest, warmer, converterHandler.ValidateRequest)
+		}
+	} else {
+		close(warmupDone)
+	}
+
+	// Optional watch-folder mode for systems that only speak "shared folder"
+	watcherCtx, stopWatcher := context.WithCancel(context.Background())
+	watcherDone := make(chan struct{})
+	if cfg.WatchEnabled {
+		folderWatcher, err := watcher.New(watcher.Config{
+			InputDir:    cfg.WatchInputDir,
+			OutputDir:   cfg.WatchOutputDir,
+			ArchiveDir:  cfg.WatchArchiveDir,
+			FailedDir:   cfg.WatchFailedDir,
+			Interval:    cfg.WatchInterval,
+			Level:       cfg.WatchLevel,
+			Concurrency: cfg.WatchConcurrency,
+			Timeout:     cfg.RequestTimeout,
+		}, audioConverter, imageConverter, videoConverter)
+		if err != nil {
+			log.Fatalf("❌ Failed to start watcher: %v", err)
+		}
+
+		log.Printf("👀 Watching %s → %s (archive: %s, every %v)",
+			cfg.WatchInputDir, cfg.WatchOutputDir, cfg.WatchArchiveDir, cfg.WatchInterval)
+		go func() {
+			folderWatcher.Run(watcherCtx)
+			close(watcherDone)
+		}()
+	} else {
+		close(watcherDone)
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		ServerHeader:     "FingerprintConverter",
@@ -86,101 +603,318 @@ func main() {
 		BodyLimit:        cfg.BodyLimit,
 		ReadTimeout:      cfg.ReadTimeout,
 		WriteTimeout:     cfg.WriteTimeout,
+		IdleTimeout:      cfg.IdleTimeout,
+		ReadBufferSize:   cfg.ReadBufferSize,
 		DisableKeepalive: false,
-		ErrorHandler: func(c fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			message := "Internal Server Error"
-
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-				message = e.Message
-			}
-
-			return c.Status(code).JSON(fiber.Map{
-				"success": false,
-				"error":   message,
-				"timestamp": time.Now().Unix(),
-			})
-		},
+		ErrorHandler:     handlers.ErrorHandler,
+		StructValidator:  validation.New(),
+		// Lets /convert/raw stream large uploads; LimitBody keeps BodyLimit for everything else
+		StreamRequestBody: true,
 	})
 
 	// Middleware
 	app.Use(recover.New())
+	app.Use(reqid.Middleware())
+	app.Use(handlers.LimitBody(cfg.BodyLimit, "/api/convert/raw", "/api/v1/convert/raw"))
+	app.Use(handlers.DecompressBody(cfg.BodyLimit, "/api/convert/raw", "/api/v1/convert/raw"))
+
+	if cfg.EnableCompression {
+		app.Use(compress.New(compress.Config{
+			Next:  handlers.SkipCompression,
+			Level: handlers.CompressionLevel(cfg.CompressionLevel),
+		}))
+		log.Printf("🗜️  Response compression enabled: level=%s", cfg.CompressionLevel)
+	}
 	
 	if cfg.EnableCORS {
 		app.Use(cors.New(cors.Config{
-			AllowOrigins: []string{"*"},
-			AllowMethods: []string{"GET", "POST", "HEAD", "OPTIONS"},
-			AllowHeaders: []string{"Origin", "Content-Type", "Accept"},
+			AllowOrigins:     cfg.CORSAllowedOrigins,
+			AllowMethods:     cfg.CORSAllowedMethods,
+			AllowHeaders:     cfg.CORSAllowedHeaders,
+			ExposeHeaders:    cfg.CORSExposedHeaders,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           int(cfg.CORSMaxAge.Seconds()),
 		}))
+		log.Printf("🌐 CORS enabled: origins=%s, credentials=%v",
+			strings.Join(cfg.CORSAllowedOrigins, ","), cfg.CORSAllowCredentials)
 	}
 
 	if cfg.EnablePerformanceLogs {
 		app.Use(logger.New(logger.Config{
-			Format: "[${time}] ${status} - ${latency} ${method} ${path}\n",
+			Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${respHeader:X-Request-ID}\n",
 		}))
 	}
 
-	// Routes
-	api := app.Group("/api")
+	// Runtime tunables are reloaded on SIGHUP or POST /admin/reload
+	applied := *cfg
+	tunables := &reloader{current: &applied, cache: deviceCache, workers: workerPool, tenants: tenants, slow: slowConversions, admission: admissionControl, warmer: warmer}
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			log.Println("🔄 SIGHUP received, reloading configuration...")
+			if _, err := tunables.Reload(); err != nil {
+				log.Printf("❌ Reload failed: %v", err)
+			}
+		}
+	}()
+
+	// Admin endpoints (shared token, cross-tenant)
+	var dashboardHandler *handlers.DashboardHandler
+	if cfg.AdminToken != "" {
+		adminHandler := handlers.NewAdminHandler(auditFile, tunables.Reload, tunables.Effective)
+		admin := app.Group("/admin", handlers.RequireAdminToken(cfg.AdminToken))
+		admin.Get("/audit", adminHandler.Audit)
+		admin.Post("/reload", adminHandler.Reload)
 
-	// Conversion endpoint
-	api.Post("/convert", converterHandler.Convert)
+		// Registered before /api so tenant auth doesn't apply
+		apiAdmin := app.Group("/api/admin", handlers.RequireAdminToken(cfg.AdminToken))
+		apiAdmin.Get("/config", adminHandler.Config)
 
-	// Cache stats
-	api.Get("/cache/stats", converterHandler.GetCacheStats)
-	api.Get("/cache/stats/:deviceID", converterHandler.GetCacheStats)
+		// Operational snapshot with rolling rates, for triage without Prometheus
+		dashboardHandler = handlers.NewDashboardHandler(converterHandler, jobManager, started)
+		apiAdmin.Get("/dashboard", dashboardHandler.Dashboard)
+		apiAdmin.Get("/experiments", handlers.NewExperimentHandler(experimentTracker).Stats)
+	} else {
+		log.Println("⚠️  ADMIN_TOKEN not set, admin endpoints disabled")
+	}
 
-	// Health check
-	if cfg.EnableHealthCheck {
-		api.Get("/health", converterHandler.Health)
+	// Diagnostics (admin token, cross-tenant); registered before /api so tenant auth doesn't apply
+	if cfg.EnableDebugEndpoints {
+		if cfg.AdminToken == "" {
+			log.Println("⚠️  ENABLE_DEBUG_ENDPOINTS requires ADMIN_TOKEN, debug endpoints disabled")
+		} else {
+			debugHandler := handlers.NewDebugHandler(started)
+			debug := app.Group("/api/debug", handlers.RequireAdminToken(cfg.AdminToken))
+			debug.Get("/runtime", debugHandler.Runtime)
+			debug.Use(pprof.New(pprof.Config{Prefix: "/api"}))
+			log.Println("🩺 Debug endpoints enabled: /api/debug/runtime, /api/debug/pprof/")
+		}
 	}
 
+	// Prometheus metrics (unauthenticated like the health check; counters only)
+	if cfg.EnableMetrics {
+		app.Get("/metrics", converterHandler.Metrics)
+	}
+
+	// Routes (tenant API keys are required on everything but the health check)
+	// /api/v1 is the versioned API; the unversioned /api routes are a deprecated alias of v1
+	api := app.Group("/api", tenants.Middleware("/api/health", "/api/v1/health"), handlers.APIVersion("/api"))
+	wsHandler := handlers.NewWebSocketHandler(converterHandler, cfg.MaxDownloadSize, cfg.RequestTimeout)
+
+	registerRoutes := func(r fiber.Router) {
+		// Conversion endpoint
+		r.Post("/convert", converterHandler.Convert)
+
+		// Raw upload conversion (media as the request body, streamed to disk)
+		r.Post("/convert/raw", converterHandler.ConvertRaw)
+
+		// Slideshow builder (images + optional audio -> MP4)
+		r.Post("/slideshow", converterHandler.Slideshow)
+
+		// Concatenate clips (intro/outro splicing)
+		r.Post("/concat", converterHandler.Concat)
+
+		// Zip archive input (every media file inside is converted)
+		r.Post("/convert/archive", converterHandler.ConvertArchive)
+
+		// Several files of mixed media types (e.g. one message's attachments), converted concurrently
+		r.Post("/convert/files", converterHandler.ConvertFiles)
+
+		// Media details (container, streams, codecs) without converting
+		r.Post("/probe", converterHandler.Probe)
+
+		// Realtime conversion over WebSocket (upload bytes, stream result back)
+		r.Get("/ws", wsHandler.Handle)
+
+		// Async jobs
+		if jobManager != nil {
+			jobHandler := handlers.NewJobHandler(jobManager, converterHandler, cfg.JobMaxScheduleAhead)
+			r.Post("/jobs", jobHandler.Submit)
+			r.Get("/jobs", jobHandler.List)
+			r.Get("/jobs/dead-letter", jobHandler.DeadLetter)
+			r.Get("/jobs/:id", jobHandler.Get)
+			r.Post("/jobs/:id/requeue", jobHandler.Requeue)
+		}
+
+		// Usage rollups for chargeback
+		if usageStore != nil {
+			usageHandler := handlers.NewUsageHandler(usageStore)
+			r.Get("/usage", usageHandler.Get)
+		}
+
+		// Device registry (per-device defaults)
+		if deviceStore != nil {
+			deviceHandler := handlers.NewDeviceHandler(deviceStore)
+			r.Get("/devices", deviceHandler.List)
+			r.Get("/devices/:deviceID", deviceHandler.Get)
+			r.Put("/devices/:deviceID", deviceHandler.Put)
+			r.Delete("/devices/:deviceID", deviceHandler.Delete)
+		}
+
+		// Recent conversions per device (support investigations)
+		if deviceHistory != nil {
+			historyHandler := handlers.NewHistoryHandler(deviceHistory)
+			r.Get("/devices/:deviceID/history", historyHandler.Get)
+		}
+
+		// Cache stats
+		r.Get("/cache/stats", converterHandler.GetCacheStats)
+		r.Get("/cache/stats/:deviceID", converterHandler.GetCacheStats)
+		r.Get("/cache/check", converterHandler.CheckCache)
+		r.Head("/cache/check", converterHandler.CheckCache)
+		r.Delete("/cache/:deviceID", converterHandler.InvalidateCache)
+		r.Post("/cache/:deviceID/pin", converterHandler.PinCache)
+		r.Delete("/cache/:deviceID/pin", converterHandler.UnpinCache)
+
+		// Cache warm-up (convert scheduled media before the burst of requests)
+		if warmer != nil {
+			warmHandler := handlers.NewWarmHandler(warmer, converterHandler)
+			r.Post("/cache/warm", warmHandler.Warm)
+			r.Get("/cache/warm", warmHandler.Stats)
+		}
+
+		// Health check
+		if cfg.EnableHealthCheck {
+			r.Get("/health", converterHandler.Health)
+		}
+	}
+	registerRoutes(api.Group("/" + handlers.CurrentAPIVersion))
+	registerRoutes(api)
+
 	// Root endpoint
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"service":  "Fingerprint Media Converter API",
 			"version":  "1.0.0",
 			"status":   "running",
+			"api_version": handlers.CurrentAPIVersion,
 			"endpoints": []string{
-				"POST /api/convert",
-				"GET  /api/cache/stats",
-				"GET  /api/cache/stats/:deviceID",
-				"GET  /api/health",
+				"POST /api/v1/convert",
+				"POST /api/v1/convert/raw",
+				"POST /api/v1/convert/files",
+				"POST /api/v1/slideshow",
+				"POST /api/v1/concat",
+				"POST /api/v1/probe",
+				"GET  /api/v1/ws (WebSocket)",
+				"POST /api/v1/jobs",
+				"GET  /api/v1/jobs",
+				"GET  /api/v1/jobs/dead-letter",
+				"GET  /api/v1/jobs/:id",
+				"POST /api/v1/jobs/:id/requeue",
+				"GET  /api/v1/usage",
+				"GET  /api/v1/devices",
+				"GET  /api/v1/devices/:deviceID",
+				"PUT  /api/v1/devices/:deviceID",
+				"DELETE /api/v1/devices/:deviceID",
+				"GET  /api/v1/devices/:deviceID/history",
+				"GET  /api/v1/cache/stats",
+				"GET  /api/v1/cache/stats/:deviceID",
+				"GET  /api/v1/cache/check",
+				"DELETE /api/v1/cache/:deviceID",
+				"GET  /api/v1/health",
+				"GET  /metrics",
+				"GET  /admin/audit",
+				"POST /admin/reload",
+				"GET  /api/admin/config",
+				"GET  /api/admin/dashboard",
+				"GET  /api/admin/experiments",
 			},
 		})
 	})
 
-	// Graceful shutdown
+	// Graceful shutdown: stop intake, drain HTTP, drain workers and ffmpeg, then stop the cache
+	// Everything shares one grace period; ffmpeg processes still running after it are killed
+	shutdownDone := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
+		defer close(shutdownDone)
 
-		log.Println("🛑 Shutting down gracefully...")
+		log.Printf("🛑 Shutting down gracefully (grace period %s)...", cfg.ShutdownGracePeriod)
+		grace, cancelGrace := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+		defer cancelGrace()
 
-		// Stop worker pool
-		workerPool.Stop()
+		drained := within(grace, func() {
+			// Stop watching (running conversions finish first)
+			stopWatcher()
+			<-watcherDone
 
-		// Stop cache cleanup
-		deviceCache.Stop()
+			// Stop warming (queued warm-ups are dropped; real requests convert them on demand)
+			stopWarmup()
+			<-warmupDone
+
+			// Stop consuming messages (unacknowledged ones are redelivered)
+			stopConsumer()
+			<-consumerDone
+		})
 
-		// Shutdown Fiber
-		if err := app.Shutdown(); err != nil {
-			log.Printf("⚠️  Error during shutdown: %v", err)
+		// Stop accepting connections and wait for in-flight requests; connections still open at the deadline are closed
+		if err := app.ShutdownWithContext(grace); err != nil {
+			log.Printf("⚠️  HTTP requests still running at the end of the grace period: %v", err)
+			drained = false
 		}
 
+		// Let job workers finish their current job (queued jobs stay persisted for the next start)
+		if !within(grace, func() {
+			if jobManager != nil {
+				jobManager.Stop()
+			}
+			workerPool.Stop()
+		}) {
+			drained = false
+		}
+
+		// Wait for ffmpeg and ffprobe to exit
+		exited := services.WaitProcesses(grace) == nil
+
+		// The job store is closed before anything is killed, so cut-off jobs stay "processing"
+		// and are re-queued on the next start instead of being recorded as failed
+		if jobManager != nil {
+			jobStore.Close()
+		}
+		if redisClient != nil {
+			redisClient.Close()
+		}
+		if !exited {
+			log.Printf("⚠️  Grace period over, killed %d ffmpeg/ffprobe processes", services.KillProcesses())
+		}
+		if !drained || !exited {
+			log.Println("⚠️  Some conversions were cut off by the shutdown")
+		}
+		remoteConverter.Close()
+
+		// Flush usage and audit records
+		usageStore.Close()
+		deviceStore.Close()
+		auditLogger.Close()
+		slowConversions.Close()
+		admissionControl.Close()
+
+		// Stop cache cleanup and dashboard sampling
+		deviceCache.Stop()
+		if dashboardHandler != nil {
+			dashboardHandler.Stop()
+		}
+		packager.Stop()
+
 		log.Println("👋 Goodbye!")
-		os.Exit(0)
 	}()
 
 	// Start server
 	log.Printf("🌐 Server starting on port %s", cfg.Port)
 	log.Printf("🎯 Environment: %s", cfg.AppEnv)
-	log.Printf("📊 Anti-Fingerprint Default Level: %s", cfg.DefaultAFLevel)
+	if cfg.DefaultAFLevel != "" {
+		log.Printf("📊 Anti-Fingerprint Default Level: %s", cfg.DefaultAFLevel)
+	} else {
+		log.Printf("📊 Anti-Fingerprint Default Level: per media type")
+	}
 	log.Println("✅ Ready to process media!")
 
 	if err := app.Listen(":" + cfg.Port); err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
+	// Listen returns as soon as shutdown begins; the rest of it runs in the background
+	<-shutdownDone
 }
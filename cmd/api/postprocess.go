@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"fingerprint-converter/internal/config"
+	"fingerprint-converter/internal/hooks"
+	"fingerprint-converter/internal/tenant"
+)
+
+// buildPipeline registers the post-processors and checks every chain that names them
+// Go integrations compiled into the binary are registered here next to the command hooks, e.g.
+//
+//	pipeline.Register(notify.New(cfg.NotifyURL), false)
+//
+// Returns nil when nothing is registered
+func buildPipeline(cfg *config.Config, tenants *tenant.Registry) (*hooks.Pipeline, error) {
+	pipeline := hooks.NewPipeline(cfg.PostProcessors)
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Hooks)) {
+		command, err := hooks.NewCommand(name, cfg.Hooks[name])
+		if err != nil {
+			return nil, fmt.Errorf("hooks.%s: %w", name, err)
+		}
+		if err := pipeline.Register(command, cfg.Hooks[name].Required); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := pipeline.Check(cfg.PostProcessors); err != nil {
+		return nil, fmt.Errorf("POST_PROCESSORS: %w", err)
+	}
+	for _, t := range tenants.Tenants() {
+		if err := pipeline.Check(t.PostProcessors); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+	}
+
+	// Chains can only name registered processors, so without any there is nothing to run
+	names := pipeline.Names()
+	if len(names) == 0 {
+		return nil, nil
+	}
+	log.Printf("🪝 Post-processors: registered=%s, default chain=%s",
+		strings.Join(names, ","), strings.Join(cfg.PostProcessors, ","))
+	return pipeline, nil
+}
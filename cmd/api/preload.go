@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/validation"
+	"fingerprint-converter/internal/warmup"
+)
+
+// preloadManifest queues the conversions listed in a warm-up manifest
+// A missing or broken manifest only means a cold start, so errors are logged and skipped
+func preloadManifest(source string, warmer *warmup.Warmer, validate func(context.Context, *models.ConvertRequest) error) {
+	manifest, err := warmup.LoadManifest(context.Background(), source)
+	if err != nil {
+		log.Printf("⚠️  Warm-up manifest skipped: %v", err)
+		return
+	}
+
+	ctx := tenant.WithID(context.Background(), manifest.TenantID)
+	reqs := manifest.Requests()
+	valid := make([]models.ConvertRequest, 0, len(reqs))
+	for i := range reqs {
+		req := &reqs[i]
+		err := validation.Struct(req)
+		if err == nil && (req.Data != "" || req.IsBase64 || services.IsDataURI(req.URL)) {
+			err = errors.New("manifest entries take URLs only")
+		}
+		if err == nil {
+			err = validate(ctx, req)
+		}
+		if err != nil {
+			log.Printf("⚠️  Warm-up manifest entry skipped: device=%s, url=%s, error=%v", req.DeviceID, remote.Redact(req.URL), err)
+			continue
+		}
+		valid = append(valid, *req)
+	}
+
+	queued, err := warmer.Enqueue(manifest.TenantID, valid)
+	if err != nil {
+		log.Printf("⚠️  Warm-up manifest %s not queued: %v (%d conversions, raise WARMUP_QUEUE_SIZE)", source, err, len(valid))
+		return
+	}
+	log.Printf("🔥 Warm-up manifest %s: %d conversions queued (%d assets, %d skipped)",
+		source, queued, len(manifest.Assets), len(reqs)-queued)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"sync"
+
+	"fingerprint-converter/internal/admission"
+	"fingerprint-converter/internal/cache"
+	"fingerprint-converter/internal/config"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/slowlog"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/warmup"
+)
+
+// reloader applies runtime tunables without a restart (SIGHUP or POST /admin/reload)
+// Only AF defaults, profiles, experiments and ranges, cache TTLs and re-encoding, slow-conversion thresholds, admission thresholds, GC tuning, worker count and the tenants file are reloaded;
+// anything else (ports, paths, backends) still needs a restart.
+// In-flight conversions are never interrupted: each change only affects new work.
+type reloader struct {
+	mu      sync.Mutex
+	current *config.Config // Settings in effect; only the reloadable fields change
+	cache   *cache.DeviceCache
+	workers *pool.WorkerPool
+	tenants *tenant.Registry
+	slow    *slowlog.Reporter
+
+	admission *admission.Controller // nil when admission control was off at startup
+	warmer    *warmup.Warmer        // nil when warm-up was off at startup
+}
+
+// reencodeStale queues the cached outputs of older AF ranges and profiles for conversion with the new ones
+// Outputs that don't fit in the warm-up queue are converted again on their next request instead
+func (r *reloader) reencodeStale() string {
+	byTenant := make(map[string][]models.ConvertRequest)
+	for _, source := range r.cache.StaleSources() {
+		byTenant[source.TenantID] = append(byTenant[source.TenantID], source.Request)
+	}
+
+	queued, left := 0, 0
+	for tenantID, reqs := range byTenant {
+		n, err := r.warmer.Enqueue(tenantID, reqs)
+		if err != nil {
+			log.Printf("⚠️  Re-encode of %d stale outputs not queued (tenant=%s): %v", len(reqs), tenantID, err)
+			left += len(reqs)
+			continue
+		}
+		queued += n
+	}
+	return fmt.Sprintf("stale outputs: %d queued for re-encoding, %d left to their next request", queued, left)
+}
+
+// Reload re-reads the configuration and returns a description of each applied change
+func (r *reloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Reload()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	prev := r.current
+	var changes []string
+
+	if next.DefaultAFLevel != prev.DefaultAFLevel {
+		if err := services.SetDefaultAFLevel(next.DefaultAFLevel); err != nil {
+			return nil, fmt.Errorf("DEFAULT_AF_LEVEL: %w", err)
+		}
+		changes = append(changes, fmt.Sprintf("DEFAULT_AF_LEVEL: %q → %q", prev.DefaultAFLevel, next.DefaultAFLevel))
+		prev.DefaultAFLevel = next.DefaultAFLevel
+	}
+
+	profilesChanged := !reflect.DeepEqual(next.Profiles, prev.Profiles)
+	if profilesChanged {
+		if err := services.SetProfiles(next.Profiles); err != nil {
+			return changes, err
+		}
+		changes = append(changes, fmt.Sprintf("profiles: %d defined", len(next.Profiles)))
+		prev.Profiles = next.Profiles
+	}
+
+	// Re-checked when profiles change too, since variants refer to them
+	if profilesChanged || !reflect.DeepEqual(next.Experiments, prev.Experiments) {
+		if err := services.SetExperiments(next.Experiments); err != nil {
+			return changes, err
+		}
+		if !reflect.DeepEqual(next.Experiments, prev.Experiments) {
+			changes = append(changes, fmt.Sprintf("experiments: %d running", len(next.Experiments)))
+			prev.Experiments = next.Experiments
+		}
+	}
+
+	rangesChanged := next.AFRanges != prev.AFRanges
+	if rangesChanged {
+		services.SetAFRanges(next.AFRanges)
+		changes = append(changes, "AF ranges updated")
+		prev.AFRanges = next.AFRanges
+	}
+
+	if prev.EnableCache && next.ReencodeStale != prev.ReencodeStale {
+		r.cache.SetDropStale(next.ReencodeStale != "off")
+		changes = append(changes, fmt.Sprintf("REENCODE_ON_PROFILE_CHANGE: %s → %s", prev.ReencodeStale, next.ReencodeStale))
+		prev.ReencodeStale = next.ReencodeStale
+	}
+
+	// Outputs encoded with the previous ranges and profiles are stale from now on
+	if profilesChanged || rangesChanged {
+		r.cache.SetProfileVersion(services.ProfileVersion())
+		if prev.EnableCache && prev.ReencodeStale == "background" && r.warmer != nil {
+			changes = append(changes, r.reencodeStale())
+		}
+	}
+
+	if prev.EnableCache && (next.CacheTTL != prev.CacheTTL || next.FileTTL != prev.FileTTL) {
+		r.cache.SetTTL(next.CacheTTL, next.FileTTL)
+		changes = append(changes, fmt.Sprintf("CACHE_TTL/FILE_TTL: %v/%v → %v/%v",
+			prev.CacheTTL, prev.FileTTL, next.CacheTTL, next.FileTTL))
+		prev.CacheTTL, prev.FileTTL = next.CacheTTL, next.FileTTL
+	}
+
+	if prev.EnableCache && !slices.Equal(next.FileRetention, prev.FileRetention) {
+		r.cache.SetRetention(next.FileRetention)
+		changes = append(changes, fmt.Sprintf("FILE_RETENTION: %v → %v", prev.FileRetention, next.FileRetention))
+		prev.FileRetention = next.FileRetention
+	}
+
+	if prev.EnableCache && next.FailureTTL != prev.FailureTTL {
+		r.cache.SetFailureTTL(next.FailureTTL)
+		changes = append(changes, fmt.Sprintf("FAILURE_CACHE_TTL: %v → %v", prev.FailureTTL, next.FailureTTL))
+		prev.FailureTTL = next.FailureTTL
+	}
+
+	if next.MinFreeDisk != prev.MinFreeDisk {
+		r.cache.SetMinFreeDisk(next.MinFreeDisk)
+		changes = append(changes, fmt.Sprintf("MIN_FREE_DISK: %d → %d", prev.MinFreeDisk, next.MinFreeDisk))
+		prev.MinFreeDisk = next.MinFreeDisk
+	}
+
+	if next.CacheMaxPinned != prev.CacheMaxPinned {
+		r.cache.SetMaxPinned(next.CacheMaxPinned)
+		changes = append(changes, fmt.Sprintf("CACHE_MAX_PINNED: %d → %d", prev.CacheMaxPinned, next.CacheMaxPinned))
+		prev.CacheMaxPinned = next.CacheMaxPinned
+	}
+
+	if slowThresholds(next) != slowThresholds(prev) {
+		r.slow.SetThresholds(slowThresholds(next))
+		changes = append(changes, fmt.Sprintf("SLOW_CONVERSION_AUDIO/IMAGE/VIDEO: %v/%v/%v → %v/%v/%v",
+			prev.SlowConversionAudio, prev.SlowConversionImage, prev.SlowConversionVideo,
+			next.SlowConversionAudio, next.SlowConversionImage, next.SlowConversionVideo))
+		prev.SlowConversionAudio, prev.SlowConversionImage, prev.SlowConversionVideo =
+			next.SlowConversionAudio, next.SlowConversionImage, next.SlowConversionVideo
+	}
+
+	// Admission control can't be switched on without a restart, only retuned
+	if r.admission != nil && admissionThresholds(next) != admissionThresholds(prev) {
+		r.admission.SetThresholds(admissionThresholds(next))
+		changes = append(changes, fmt.Sprintf("ADMISSION_MAX_LOAD/MEMORY: %v/%v → %v/%v",
+			prev.AdmissionMaxLoad, prev.AdmissionMaxMemory, next.AdmissionMaxLoad, next.AdmissionMaxMemory))
+		prev.AdmissionMaxLoad, prev.AdmissionMaxMemory = next.AdmissionMaxLoad, next.AdmissionMaxMemory
+	}
+
+	if next.GOGC != prev.GOGC || next.GoMemLimit != prev.GoMemLimit {
+		applyGCTuning(next)
+		changes = append(changes, fmt.Sprintf("GOGC/GOMEMLIMIT: %d/%s → %d/%s",
+			prev.GOGC, prev.GoMemLimit, next.GOGC, next.GoMemLimit))
+		prev.GOGC, prev.GoMemLimit = next.GOGC, next.GoMemLimit
+	}
+
+	if next.MaxWorkers != prev.MaxWorkers {
+		r.workers.Resize(next.MaxWorkers)
+		changes = append(changes, fmt.Sprintf("MAX_WORKERS: %d → %d", prev.MaxWorkers, next.MaxWorkers))
+	}
+
+	// The thread allowance follows MAX_WORKERS unless FFMPEG_THREADS fixes it
+	if ffmpegThreads(next) != ffmpegThreads(prev) {
+		services.SetFFmpegThreads(ffmpegThreads(next))
+		changes = append(changes, fmt.Sprintf("FFmpeg threads per job: %d → %d", ffmpegThreads(prev), ffmpegThreads(next)))
+	}
+	prev.MaxWorkers, prev.FFmpegThreads = next.MaxWorkers, next.FFmpegThreads
+
+	// The tenants file is re-read even when its path is unchanged (quotas, keys, AF defaults)
+	err = r.tenants.Reload(next.TenantsFile)
+	if err == nil && r.tenants.Enabled() {
+		changes = append(changes, fmt.Sprintf("TENANTS_FILE: reloaded %d tenants from %s",
+			len(r.tenants.Tenants()), next.TenantsFile))
+		prev.TenantsFile = next.TenantsFile
+	}
+
+	for _, change := range changes {
+		log.Printf("🔄 Reloaded %s", change)
+	}
+	if err != nil {
+		return changes, fmt.Errorf("TENANTS_FILE: %w", err)
+	}
+	return changes, nil
+}
+
+// Effective returns the configuration in effect, secrets redacted (GET /api/admin/config)
+func (r *reloader) Effective() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.Effective()
+}
+
+// applyGCTuning sets GOGC and GOMEMLIMIT from the configuration
+// The runtime only reads them from the process environment, so values from .env
+// or the config file would otherwise be ignored. cfg must already be validated.
+func applyGCTuning(cfg *config.Config) {
+	limit, _ := config.ParseMemLimit(cfg.GoMemLimit)
+	debug.SetGCPercent(cfg.GOGC)
+	debug.SetMemoryLimit(limit)
+}
+
+// ffmpegThreads returns the threads each ffmpeg run may use under cfg
+func ffmpegThreads(cfg *config.Config) int {
+	if cfg.FFmpegThreads > 0 {
+		return cfg.FFmpegThreads
+	}
+	return services.ThreadsPerJob(cfg.MaxWorkers)
+}
+
+// admissionThresholds returns the admission control thresholds of cfg
+func admissionThresholds(cfg *config.Config) admission.Thresholds {
+	return admission.Thresholds{
+		Load:   cfg.AdmissionMaxLoad,
+		Memory: cfg.AdmissionMaxMemory,
+	}
+}
+
+// slowThresholds returns the slow-conversion thresholds of cfg
+func slowThresholds(cfg *config.Config) slowlog.Thresholds {
+	return slowlog.Thresholds{
+		Audio: cfg.SlowConversionAudio,
+		Image: cfg.SlowConversionImage,
+		Video: cfg.SlowConversionVideo,
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "context"
+
+// within runs f and waits for it until ctx ends; reports whether f finished in time
+// f keeps running in the background when ctx ends first
+func within(ctx context.Context, f func()) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
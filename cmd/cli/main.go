@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/services"
+)
+
+// converters bundles the media converters used by the CLI
+type converters struct {
+	audio *services.AudioConverter
+	image *services.ImageConverter
+	video *services.VideoConverter
+}
+
+// task is one input file and where its output goes
+type task struct {
+	input     string
+	output    string
+	mediaType string
+	level     string
+}
+
+func main() {
+	var (
+		mediaType     = flag.String("type", "", "Media type: audio, image or video (default: detect from extension)")
+		level         = flag.String("level", "", "AF level: none, basic, moderate or paranoid (default: per media type)")
+		outputDir     = flag.String("out", "converted", "Output directory")
+		concurrency   = flag.Int("concurrency", runtime.NumCPU(), "Files converted in parallel")
+		timeout       = flag.Duration("timeout", 5*time.Minute, "Timeout per file")
+		recursive     = flag.Bool("recursive", false, "Walk subdirectories")
+		overwrite     = flag.Bool("overwrite", false, "Re-convert files whose output already exists")
+		maxResolution = flag.String("max-resolution", "", "Video: max resolution (sd, hd, fhd or WxH)")
+		frameRate     = flag.String("frame-rate", "", "Video: output frame rate (e.g. 30 or 30000/1001)")
+		dropAudio     = flag.Bool("drop-audio", false, "Video: strip the audio track")
+		audioFormat   = flag.String("audio-format", "", "Audio: opus or mp3 (default: opus)")
+		seed          = flag.Uint64("seed", 0, "Seed for AF randomness; with -concurrency 1 the same seed reproduces the same output (default: random)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <file or directory>...\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Converts local media with the same AF pipeline as the API, without running the server.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *mediaType != "" && *mediaType != "audio" && *mediaType != "image" && *mediaType != "video" {
+		log.Fatalf("❌ Invalid -type %q (supported: audio, image, video)", *mediaType)
+	}
+	if *concurrency <= 0 {
+		*concurrency = 1
+	}
+	services.SetFFmpegThreads(services.ThreadsPerJob(*concurrency))
+
+	// Options are validated per media type, like the API does
+	opts := services.ConvertOptions{DropAudio: *dropAudio}
+	var err error
+	if *maxResolution != "" {
+		if opts.MaxLongEdge, opts.MaxShortEdge, err = services.ParseMaxResolution(*maxResolution); err != nil {
+			log.Fatalf("❌ Invalid -max-resolution: %v", err)
+		}
+	}
+	if opts.FrameRate, err = services.ParseFrameRate(*frameRate); err != nil {
+		log.Fatalf("❌ Invalid -frame-rate: %v", err)
+	}
+	if opts.AudioFormat, err = services.ParseAudioFormat(*audioFormat); err != nil {
+		log.Fatalf("❌ Invalid -audio-format: %v", err)
+	}
+
+	// Converters don't need running pools, but share the constructors with the server
+	bufferPool := pool.NewBufferPool(1, 1024)
+	workerPool := pool.NewWorkerPool(1)
+	rng := services.NewRNG(*seed)
+	conv := &converters{
+		audio: services.NewAudioConverter(workerPool, bufferPool, rng),
+		image: services.NewImageConverter(workerPool, bufferPool, rng),
+		video: services.NewVideoConverter(workerPool, bufferPool, rng),
+	}
+
+	tasks, err := collectTasks(conv, flag.Args(), *recursive, *mediaType, *level, *outputDir, opts.AudioFormat)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(tasks) == 0 {
+		log.Fatalf("❌ No supported media files found")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Printf("🚀 Converting %d file(s) with concurrency %d → %s", len(tasks), *concurrency, *outputDir)
+	start := time.Now()
+
+	var converted, skipped, failed int64
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, *concurrency)
+
+	for _, t := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if !*overwrite {
+			if _, err := os.Stat(t.output); err == nil {
+				atomic.AddInt64(&skipped, 1)
+				continue
+			}
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t task) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fileStart := time.Now()
+			if err := conv.convert(ctx, t, opts, *timeout); err != nil {
+				atomic.AddInt64(&failed, 1)
+				log.Printf("❌ %s: %v", t.input, err)
+				return
+			}
+			atomic.AddInt64(&converted, 1)
+			log.Printf("✅ %s → %s (%s, %dms)", t.input, t.output, t.level, time.Since(fileStart).Milliseconds())
+		}(t)
+	}
+	wg.Wait()
+
+	log.Printf("📊 Done in %v: converted=%d, skipped=%d, failed=%d",
+		time.Since(start).Round(time.Millisecond), converted, skipped, failed)
+	if failed > 0 || ctx.Err() != nil {
+		os.Exit(1)
+	}
+}
+
+// collectTasks expands files and directories into conversion tasks
+func collectTasks(conv *converters, paths []string, recursive bool, mediaType, level, outputDir, audioFormat string) ([]task, error) {
+	tasks := []task{}
+
+	add := func(root, path string) {
+		fileType := mediaType
+		if fileType == "" {
+			fileType = services.DetectMediaType(path)
+		}
+		if fileType == "" {
+			return // Not a media file
+		}
+
+		fileLevel := level
+		if fileLevel == "" {
+			fileLevel = services.DefaultAFLevel(fileType)
+		}
+
+		// Keep the input layout below the output directory
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(path)
+		}
+		base := strings.TrimSuffix(rel, filepath.Ext(rel))
+
+		tasks = append(tasks, task{
+			input:     path,
+			output:    filepath.Join(outputDir, base+conv.extension(fileType, path, audioFormat)),
+			mediaType: fileType,
+			level:     fileLevel,
+		})
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			add(filepath.Dir(path), path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if file != path && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			add(path, file)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tasks, nil
+}
+
+// convert runs one task through the matching converter
+func (c *converters) convert(ctx context.Context, t task, opts services.ConvertOptions, timeout time.Duration) error {
+	inputData, err := os.ReadFile(t.input)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.output), 0755); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch t.mediaType {
+	case "audio":
+		_, err = c.audio.Convert(ctx, inputData, t.level, t.output, opts)
+	case "image":
+		_, err = c.image.Convert(ctx, inputData, t.level, t.output, opts)
+	default:
+		_, err = c.video.Convert(ctx, inputData, t.level, t.output, opts)
+	}
+	return err
+}
+
+// extension returns the output file extension for an input of a media type
+// Images keep PNG/WebP content, so their extension depends on the input's leading bytes
+func (c *converters) extension(mediaType, inputPath, audioFormat string) string {
+	switch mediaType {
+	case "audio":
+		return c.audio.GetOutputExtension(audioFormat)
+	case "image":
+		return c.image.OutputExtensionOf(inputPath, services.ConvertOptions{})
+	default:
+		return c.video.GetOutputExtension()
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/rpcconv"
+	"fingerprint-converter/internal/rpcconv/convpb"
+	"fingerprint-converter/internal/services"
+)
+
+func main() {
+	var (
+		listen      = flag.String("listen", ":50051", "Address to serve gRPC on")
+		concurrency = flag.Int("concurrency", runtime.NumCPU(), "Conversions run in parallel; further calls wait")
+		maxSize     = flag.Int64("max-size", 500*1024*1024, "Largest input accepted, in bytes")
+		tlsCert     = flag.String("tls-cert", "", "TLS certificate file (default: plaintext)")
+		tlsKey      = flag.String("tls-key", "", "TLS key file")
+		seed        = flag.Uint64("seed", 0, "Seed for AF randomness, for debugging only (default: random)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Serves conversions to API instances that delegate media types with REMOTE_CONVERTER_ADDR.")
+		fmt.Fprintln(os.Stderr, "Callers must send REMOTE_CONVERTER_TOKEN when it is set.")
+		fmt.Fprintln(os.Stderr, "Set OUTPUT_MARKER_SECRET to the API's value so node outputs are marked too.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *concurrency <= 0 {
+		*concurrency = 1
+	}
+	services.SetFFmpegThreads(services.ThreadsPerJob(*concurrency))
+
+	serverOpts := []grpc.ServerOption{grpc.MaxConcurrentStreams(uint32(*concurrency))}
+	if *tlsCert != "" || *tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("❌ Failed to load TLS certificate: %v", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})))
+	}
+
+	// Converters don't need running pools, but share the constructors with the API
+	bufferPool := pool.NewBufferPool(1, 1024)
+	workerPool := pool.NewWorkerPool(1)
+	rng := services.NewRNG(*seed)
+	token := os.Getenv("REMOTE_CONVERTER_TOKEN")
+	services.SetOutputMarker(services.NewOutputMarker(os.Getenv("OUTPUT_MARKER_SECRET")))
+	node := rpcconv.NewServer(
+		services.NewAudioConverter(workerPool, bufferPool, rng),
+		services.NewImageConverter(workerPool, bufferPool, rng),
+		services.NewVideoConverter(workerPool, bufferPool, rng),
+		*maxSize, token)
+
+	server := grpc.NewServer(serverOpts...)
+	convpb.RegisterConverterServer(server, node)
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("❌ Failed to listen on %s: %v", *listen, err)
+	}
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		log.Println("🛑 Shutting down gracefully...")
+		healthServer.Shutdown()
+		server.GracefulStop()
+	}()
+
+	if token == "" {
+		log.Println("⚠️  REMOTE_CONVERTER_TOKEN is not set, any caller can convert")
+	}
+	log.Printf("🚀 Converter node listening on %s (concurrency %d)", listener.Addr(), *concurrency)
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("❌ Server error: %v", err)
+	}
+}
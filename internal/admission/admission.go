@@ -0,0 +1,203 @@
+// Package admission holds back new conversions while the host is short of CPU or memory,
+// so an overloaded instance sheds work itself instead of letting the OOM killer pick a victim
+package admission
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// Thresholds are the utilization levels above which new conversions are held back (0 = not checked)
+type Thresholds struct {
+	Load   float64 // 1-minute load average per CPU core
+	Memory float64 // Share of memory in use (0-1)
+}
+
+// Usage is the last sample of the host's utilization
+type Usage struct {
+	Load   float64 // 1-minute load average per CPU core
+	Memory float64 // Share of memory in use (0-1)
+}
+
+// OverloadError is returned while the host is above a threshold
+type OverloadError struct {
+	Resource string // "load" or "memory"
+	Used     float64
+	Limit    float64
+}
+
+func (e *OverloadError) Error() string {
+	if e.Resource == "memory" {
+		return fmt.Sprintf("memory use %.0f%% is above the limit of %.0f%%", e.Used*100, e.Limit*100)
+	}
+	return fmt.Sprintf("load %.2f per core is above the limit of %.2f", e.Used, e.Limit)
+}
+
+// Controller samples the host's load and memory and decides whether new conversions may start
+// Video, the heaviest work, is turned away at once while the host is overloaded; audio and image
+// conversions wait for it to recover, up to maxWait. A nil Controller admits everything
+type Controller struct {
+	thresholds atomic.Pointer[Thresholds]
+	usage      atomic.Pointer[Usage]
+	interval   time.Duration
+	maxWait    time.Duration
+
+	deferred atomic.Int64 // Conversions that had to wait
+	rejected atomic.Int64 // Conversions turned away
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New samples the host right away and then every interval until Close
+func New(thresholds Thresholds, interval, maxWait time.Duration) *Controller {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Controller{
+		interval: interval,
+		maxWait:  maxWait,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	c.thresholds.Store(&thresholds)
+	c.sample()
+	go c.run(ctx)
+	return c
+}
+
+// SetThresholds replaces the thresholds; conversions already waiting are judged by the new ones
+func (c *Controller) SetThresholds(thresholds Thresholds) {
+	c.thresholds.Store(&thresholds)
+}
+
+// Admit returns nil when a conversion of mediaType may start
+// While the host is overloaded, video gets an *OverloadError at once, and other media wait until
+// it recovers, maxWait passes (*OverloadError) or ctx ends
+func (c *Controller) Admit(ctx context.Context, mediaType string) error {
+	if c == nil {
+		return nil
+	}
+	err := c.check()
+	if err == nil {
+		return nil
+	}
+	if mediaType == "video" || c.maxWait <= 0 {
+		c.rejected.Add(1)
+		return err
+	}
+
+	c.deferred.Add(1)
+	deadline := time.NewTimer(c.maxWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err = c.check(); err == nil {
+				return nil
+			}
+		case <-deadline.C:
+			c.rejected.Add(1)
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Overloaded reports whether the last sample is above a threshold
+func (c *Controller) Overloaded() bool {
+	return c != nil && c.check() != nil
+}
+
+// Stats reports the last sample, the thresholds and how many conversions were held back
+func (c *Controller) Stats() map[string]interface{} {
+	if c == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	usage, thresholds := c.usage.Load(), c.thresholds.Load()
+	return map[string]interface{}{
+		"enabled":         true,
+		"overloaded":      c.check() != nil,
+		"load_per_core":   round(usage.Load),
+		"memory_used":     round(usage.Memory),
+		"max_load":        thresholds.Load,
+		"max_memory":      thresholds.Memory,
+		"deferred_total":  c.deferred.Load(),
+		"rejected_total":  c.rejected.Load(),
+		"sample_interval": c.interval.String(),
+		"max_wait":        c.maxWait.String(),
+	}
+}
+
+// Close stops sampling
+func (c *Controller) Close() {
+	if c == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
+
+// check compares the last sample with the thresholds
+func (c *Controller) check() *OverloadError {
+	usage, thresholds := c.usage.Load(), c.thresholds.Load()
+	if thresholds.Memory > 0 && usage.Memory > thresholds.Memory {
+		return &OverloadError{Resource: "memory", Used: usage.Memory, Limit: thresholds.Memory}
+	}
+	if thresholds.Load > 0 && usage.Load > thresholds.Load {
+		return &OverloadError{Resource: "load", Used: usage.Load, Limit: thresholds.Load}
+	}
+	return nil
+}
+
+func (c *Controller) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	overloaded := c.Overloaded()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.sample()
+
+		// Log transitions only, not every sample
+		if err := c.check(); (err != nil) != overloaded {
+			overloaded = err != nil
+			if overloaded {
+				log.Printf("🥵 Host overloaded (%v): pausing video intake, deferring audio and image", err)
+			} else {
+				log.Printf("😌 Host load back to normal: admitting all conversions")
+			}
+		}
+	}
+}
+
+// sample reads the load average and memory use; a value that can't be read counts as idle
+func (c *Controller) sample() {
+	var usage Usage
+	if avg, err := load.Avg(); err == nil {
+		usage.Load = avg.Load1 / float64(runtime.NumCPU())
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		usage.Memory = vm.UsedPercent / 100
+	}
+	c.usage.Store(&usage)
+}
+
+// round keeps two decimals for reporting
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
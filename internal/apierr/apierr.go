@@ -0,0 +1,119 @@
+// Package apierr defines the stable error codes returned by the API and
+// writes them as RFC 7807 problem details
+package apierr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+)
+
+// Error codes; clients branch on these, so never rename one
+const (
+	InvalidRequest      = "INVALID_REQUEST"
+	UnsupportedFormat   = "UNSUPPORTED_FORMAT"
+	DownloadFailed      = "DOWNLOAD_FAILED"
+	SourceUnavailable   = "SOURCE_UNAVAILABLE"
+	FileTooLarge        = "FILE_TOO_LARGE"
+	InputLimitExceeded  = "INPUT_LIMIT_EXCEEDED"
+	ContentMismatch     = "CONTENT_MISMATCH"
+	InvalidMedia        = "INVALID_MEDIA"
+	InvalidArchive      = "INVALID_ARCHIVE"
+	MalwareDetected     = "MALWARE_DETECTED"
+	ScannerUnavailable  = "SCANNER_UNAVAILABLE"
+	ConversionFailed    = "CONVERSION_FAILED"
+	QualityTooLow       = "QUALITY_TOO_LOW"
+	OutputInvalid       = "OUTPUT_INVALID"
+	FFmpegTimeout       = "FFMPEG_TIMEOUT"
+	CircuitOpen         = "CIRCUIT_OPEN"
+	QueueFull           = "QUEUE_FULL"
+	InsufficientStorage = "INSUFFICIENT_STORAGE"
+	Overloaded          = "OVERLOADED"
+	JobNotFound         = "JOB_NOT_FOUND"
+	JobNotFailed        = "JOB_NOT_FAILED"
+	JobInterrupted      = "JOB_INTERRUPTED"
+	DeviceNotFound      = "DEVICE_NOT_FOUND"
+	NotCached           = "NOT_CACHED"
+	PinQuotaExceeded    = "PIN_QUOTA_EXCEEDED"
+	Unauthorized        = "UNAUTHORIZED"
+	UnknownTenant       = "UNKNOWN_TENANT"
+	MediaTypeNotAllowed = "MEDIA_TYPE_NOT_ALLOWED"
+	RateLimited         = "RATE_LIMITED"
+	ConcurrencyLimited  = "CONCURRENCY_LIMITED"
+	NotFound            = "NOT_FOUND"
+	UpgradeRequired     = "UPGRADE_REQUIRED"
+	FeatureDisabled     = "FEATURE_DISABLED"
+	UploadFailed        = "UPLOAD_FAILED"
+	PostProcessFailed   = "POST_PROCESS_FAILED"
+	InternalError       = "INTERNAL_ERROR"
+)
+
+// ContentType is the media type of problem detail responses
+const ContentType = "application/problem+json"
+
+// TypeURI returns the problem type URI for code
+func TypeURI(code string) string {
+	return "urn:fingerprint-converter:error:" + code
+}
+
+// Coded is implemented by errors that carry an API error code
+type Coded interface {
+	ErrorCode() string
+}
+
+// CodeOf returns the code carried by err, or INTERNAL_ERROR
+func CodeOf(err error) string {
+	var coded Coded
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return InternalError
+}
+
+// CodeForStatus picks a generic code for responses produced outside the handlers
+func CodeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest, fiber.StatusUnprocessableEntity:
+		return InvalidRequest
+	case fiber.StatusUnauthorized:
+		return Unauthorized
+	case fiber.StatusNotFound, fiber.StatusMethodNotAllowed:
+		return NotFound
+	case fiber.StatusRequestEntityTooLarge:
+		return FileTooLarge
+	case fiber.StatusTooManyRequests:
+		return RateLimited
+	default:
+		return InternalError
+	}
+}
+
+// Problem builds the problem details body; the legacy success/error/details fields stay populated
+func Problem(c fiber.Ctx, status int, code, message, details string) models.ErrorResponse {
+	return models.ErrorResponse{
+		Type:      TypeURI(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    message,
+		Instance:  c.Path(),
+		Code:      code,
+		Success:   false,
+		Error:     message,
+		Details:   details,
+		RequestID: reqid.FromFiber(c),
+	}
+}
+
+// Write sends a problem details response
+func Write(c fiber.Ctx, status int, code, message, details string) error {
+	return WriteProblem(c, Problem(c, status, code, message, details))
+}
+
+// WriteProblem sends a prepared problem details body
+func WriteProblem(c fiber.Ctx, problem models.ErrorResponse) error {
+	return c.Status(problem.Status).JSON(problem, ContentType)
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"log"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Sink receives audit entries
+type Sink interface {
+	Write(entry *models.AuditEntry) error
+	Close() error
+}
+
+// Logger fans audit entries out to its sinks
+// A nil Logger records nothing
+type Logger struct {
+	sinks []Sink
+}
+
+// NewLogger creates a logger writing to every sink
+func NewLogger(sinks ...Sink) *Logger {
+	return &Logger{sinks: sinks}
+}
+
+// Record writes entry to all sinks; failures are logged so auditing never fails a request
+func (l *Logger) Record(entry *models.AuditEntry) {
+	if l == nil {
+		return
+	}
+	for _, sink := range l.sinks {
+		if err := sink.Write(entry); err != nil {
+			log.Printf("⚠️  Failed to write audit entry: %v", err)
+		}
+	}
+}
+
+// Close flushes and closes all sinks
+func (l *Logger) Close() {
+	if l == nil {
+		return
+	}
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			log.Printf("⚠️  Failed to close audit sink: %v", err)
+		}
+	}
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the caller identity recorded in audit entries
+func WithCaller(ctx context.Context, caller string) context.Context {
+	if caller == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller carried by ctx ("" if none)
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
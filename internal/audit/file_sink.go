@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// DateLayout is the format of audit file dates
+const DateLayout = "2006-01-02"
+
+// FileSink appends entries as JSON lines to one file per UTC day (audit-YYYY-MM-DD.jsonl)
+type FileSink struct {
+	dir  string
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// Query selects audit entries of one day (empty fields match everything)
+type Query struct {
+	Date     time.Time
+	TenantID string
+	DeviceID string
+	Limit    int
+}
+
+// NewFileSink creates a file sink writing into dir
+func NewFileSink(dir string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	return &FileSink{dir: dir}, nil
+}
+
+func (s *FileSink) path(day string) string {
+	return filepath.Join(s.dir, "audit-"+day+".jsonl")
+}
+
+// Write appends entry to the file of the entry's day
+func (s *FileSink) Write(entry *models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := entry.Time.UTC().Format(DateLayout)
+	if s.file == nil || day != s.day {
+		if s.file != nil {
+			s.file.Close()
+		}
+		s.file, err = os.OpenFile(s.path(day), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			s.file = nil
+			return fmt.Errorf("failed to open audit file: %w", err)
+		}
+		s.day = day
+	}
+
+	_, err = s.file.Write(data)
+	return err
+}
+
+// Query returns matching entries of the query's day, newest first
+func (s *FileSink) Query(query Query) ([]*models.AuditEntry, error) {
+	entries := []*models.AuditEntry{}
+
+	file, err := os.Open(s.path(query.Date.UTC().Format(DateLayout)))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := &models.AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			continue // Torn write from a crash
+		}
+		if query.TenantID != "" && entry.TenantID != query.TenantID {
+			continue
+		}
+		if query.DeviceID != "" && entry.DeviceID != query.DeviceID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	// Files are in write order; reverse for newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	return entries, nil
+}
+
+// Close closes the current file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+)
+
+const webhookBuffer = 1024
+
+// WebhookSink POSTs each entry as JSON to an external collector (SIEM, log pipeline)
+// Entries are sent in the background; when the collector falls behind they are dropped and counted
+type WebhookSink struct {
+	url     string
+	client  *http.Client
+	entries chan *models.AuditEntry
+	wg      sync.WaitGroup
+	dropped int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookSink creates a webhook sink and starts its sender
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	s := &WebhookSink{
+		url:     url,
+		client:  &http.Client{Timeout: timeout},
+		entries: make(chan *models.AuditEntry, webhookBuffer),
+	}
+
+	s.wg.Add(1)
+	go s.send()
+	return s
+}
+
+// Write queues entry for delivery
+func (s *WebhookSink) Write(entry *models.AuditEntry) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("audit webhook sink is closed")
+	}
+
+	select {
+	case s.entries <- entry:
+		return nil
+	default:
+		dropped := atomic.AddInt64(&s.dropped, 1)
+		return fmt.Errorf("audit webhook buffer full, %d entries dropped", dropped)
+	}
+}
+
+func (s *WebhookSink) send() {
+	defer s.wg.Done()
+
+	for entry := range s.entries {
+		if err := s.post(entry); err != nil {
+			log.Printf("⚠️  Audit webhook delivery failed: %v", err)
+		}
+	}
+}
+
+func (s *WebhookSink) post(entry *models.AuditEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if entry.RequestID != "" {
+		req.Header.Set(reqid.Header, entry.RequestID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close delivers queued entries and stops the sender
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.entries)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
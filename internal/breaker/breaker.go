@@ -0,0 +1,223 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// States reported by Breaker.State
+const (
+	Closed   = "closed"    // Requests flow normally
+	Open     = "open"      // Requests fail fast until the cooldown ends
+	HalfOpen = "half_open" // One trial request decides whether to close again
+)
+
+// Settings tune when a breaker opens and how long it stays open
+type Settings struct {
+	FailureRate float64       // Share of failed requests in a window that opens the breaker (0-1)
+	MinRequests int           // Requests a window needs before its failure rate counts
+	Window      time.Duration // Length of the counting window
+	Cooldown    time.Duration // Time open before probing or letting a trial request through
+}
+
+// OpenError is returned while a breaker rejects requests
+type OpenError struct {
+	Name       string
+	Reason     string        // Last failure seen before opening (or by the probe)
+	RetryAfter time.Duration // Until the next probe or trial request
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s circuit is open after repeated failures (last: %s), retry in %s",
+		e.Name, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// Snapshot is a breaker's state for health checks and metrics
+type Snapshot struct {
+	State    string     `json:"state"`
+	Requests int        `json:"window_requests"`
+	Failures int        `json:"window_failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// Breaker fails requests fast once a dependency keeps failing
+// With a probe it stays open until the probe succeeds; without one, a single trial request
+// is let through after each cooldown. A nil Breaker allows everything
+type Breaker struct {
+	name     string
+	settings Settings
+	probe    func(ctx context.Context) error
+
+	mu          sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	reason      string
+	trial       bool // A half-open trial request is in flight
+}
+
+// New creates a closed breaker; probe may be nil
+func New(name string, settings Settings, probe func(ctx context.Context) error) *Breaker {
+	return &Breaker{
+		name:        name,
+		settings:    settings,
+		probe:       probe,
+		state:       Closed,
+		windowStart: time.Now(),
+	}
+}
+
+// Check reports whether a request would be let through, without claiming the half-open trial
+// Used to reject work (e.g. job submissions) before it is queued
+func (b *Breaker) Check() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.check(time.Now())
+}
+
+// Allow claims permission for one request; callers must Record its outcome
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if err := b.check(now); err != nil {
+		return err
+	}
+	if b.state == Open {
+		b.state, b.trial = HalfOpen, true
+		log.Printf("🔌 %s circuit half-open: letting a trial request through", b.name)
+	}
+	return nil
+}
+
+// check decides with b.mu held
+func (b *Breaker) check(now time.Time) error {
+	switch b.state {
+	case Open:
+		retryAfter := b.openedAt.Add(b.settings.Cooldown).Sub(now)
+		if retryAfter > 0 || b.probe != nil {
+			return &OpenError{Name: b.name, Reason: b.reason, RetryAfter: max(retryAfter, 0)}
+		}
+	case HalfOpen:
+		if b.trial {
+			return &OpenError{Name: b.name, Reason: b.reason}
+		}
+	}
+	return nil
+}
+
+// Record counts the outcome of an allowed request (nil = success)
+func (b *Breaker) Record(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case HalfOpen:
+		b.trial = false
+		if err == nil {
+			b.close(now)
+		} else {
+			b.open(now, err.Error())
+		}
+		return
+	case Open:
+		return // Requests allowed before opening finish late; they don't count
+	}
+
+	if now.Sub(b.windowStart) > b.settings.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if err == nil {
+		return
+	}
+	b.failures++
+	if b.requests >= b.settings.MinRequests && float64(b.failures)/float64(b.requests) >= b.settings.FailureRate {
+		b.open(now, err.Error())
+	}
+}
+
+// Skip releases an allowed request without counting it (canceled, or failed for reasons
+// unrelated to the protected dependency)
+func (b *Breaker) Skip() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// open trips the breaker and starts the probe loop; callers hold b.mu
+func (b *Breaker) open(now time.Time, reason string) {
+	log.Printf("🔌 %s circuit OPEN: %d/%d requests failed, last: %s", b.name, b.failures, b.requests, reason)
+	b.state, b.openedAt, b.reason = Open, now, reason
+	if b.probe != nil {
+		go b.probeLoop()
+	}
+}
+
+// close resets the breaker; callers hold b.mu
+func (b *Breaker) close(now time.Time) {
+	log.Printf("🔌 %s circuit closed", b.name)
+	b.state, b.reason, b.openedAt = Closed, "", time.Time{}
+	b.windowStart, b.requests, b.failures = now, 0, 0
+}
+
+// probeLoop runs the probe every cooldown until it succeeds
+func (b *Breaker) probeLoop() {
+	for {
+		time.Sleep(b.settings.Cooldown)
+
+		ctx, cancel := context.WithTimeout(context.Background(), max(b.settings.Cooldown, 10*time.Second))
+		err := b.probe(ctx)
+		cancel()
+
+		b.mu.Lock()
+		if err == nil {
+			b.close(time.Now())
+			b.mu.Unlock()
+			return
+		}
+		log.Printf("🔌 %s circuit probe failed: %v", b.name, err)
+		b.openedAt, b.reason = time.Now(), err.Error()
+		b.mu.Unlock()
+	}
+}
+
+// State returns the breaker's current state ("closed" for a nil Breaker)
+func (b *Breaker) State() Snapshot {
+	if b == nil {
+		return Snapshot{State: Closed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot := Snapshot{State: b.state, Requests: b.requests, Failures: b.failures, Reason: b.reason}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		snapshot.OpenedAt = &openedAt
+	}
+	return snapshot
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// dedupDir is the content-addressed store under the cache dir
+const dedupDir = ".dedup"
+
+// Deduper keeps one copy on disk of identical outputs
+// Each output is hashed and hard-linked to a copy named by its SHA-256 under the store, so
+// instances sharing the cache dir share the copies. Deleting an output only drops one link;
+// the data goes away with its last link. Store names are only kept for FILE_TTL after their
+// last use, since no output linked before then is still cached.
+type Deduper struct {
+	dir      string
+	disabled atomic.Bool  // The filesystem refused a hard link
+	linked   atomic.Int64 // Outputs replaced by a link to an identical copy
+	saved    atomic.Int64 // Bytes not stored twice
+	swept    atomic.Int64 // Store names removed after their TTL
+}
+
+// NewDeduper creates the store under cacheDir
+func NewDeduper(cacheDir string) (*Deduper, error) {
+	dir := filepath.Join(cacheDir, dedupDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dedup store: %w", err)
+	}
+	return &Deduper{dir: dir}, nil
+}
+
+// Dedup replaces the file at path with a hard link to an identical stored copy, or stores it
+// Returns the bytes saved (0 when path is the first copy). Nil-safe.
+func (d *Deduper) Dedup(path string) (int64, error) {
+	if d == nil || d.disabled.Load() {
+		return 0, nil
+	}
+
+	sum, size, err := hashFile(path)
+	if err != nil {
+		return 0, err
+	}
+	stored := filepath.Join(d.dir, sum[:2], sum)
+	if err := os.MkdirAll(filepath.Dir(stored), 0755); err != nil {
+		return 0, err
+	}
+
+	// A copy swept between the two links is stored again on the second try
+	for attempt := 0; attempt < 2; attempt++ {
+		err = os.Link(path, stored)
+		if err == nil {
+			return 0, nil // First copy; the output itself is now the stored copy
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return 0, d.unsupported(err)
+		}
+
+		if same, err := sameContent(stored, path, size); err != nil || same {
+			return 0, err
+		}
+
+		// Link the copy next to the output and swap it in, so the output never disappears
+		tmp := path + ".dedup"
+		os.Remove(tmp)
+		if err = os.Link(stored, tmp); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return 0, d.unsupported(err)
+		}
+		if err = os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return 0, err
+		}
+
+		// Touching the copy keeps it in the store while this output is cached
+		now := time.Now()
+		os.Chtimes(stored, now, now)
+		d.linked.Add(1)
+		d.saved.Add(size)
+		return size, nil
+	}
+	return 0, err
+}
+
+// unsupported disables deduplication when the filesystem can't hard-link (EXDEV, EPERM, ...)
+func (d *Deduper) unsupported(err error) error {
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrExist) {
+		if d.disabled.CompareAndSwap(false, true) {
+			log.Printf("⚠️  Output dedup disabled, the cache dir doesn't support hard links: %v", err)
+		}
+	}
+	return err
+}
+
+// Sweep removes store names unused for ttl; outputs still linked to them keep their data
+func (d *Deduper) Sweep(ttl time.Duration) {
+	if d == nil {
+		return
+	}
+	cutoff := time.Now().Add(-ttl)
+	removed := int64(0)
+	filepath.WalkDir(d.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	if removed > 0 {
+		d.swept.Add(removed)
+		log.Printf("🧹 Dedup sweep: removed %d unused copies", removed)
+	}
+}
+
+// Stats reports deduplication counters (nil when dedup is off)
+func (d *Deduper) Stats() map[string]interface{} {
+	if d == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"enabled":  !d.disabled.Load(),
+		"linked":   d.linked.Load(),
+		"saved_mb": d.saved.Load() / (1024 * 1024),
+		"swept":    d.swept.Load(),
+	}
+}
+
+// hashFile returns the hex SHA-256 and size of the file at path
+func hashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// sameContent reports whether path is already linked to stored, and fails when their sizes differ
+func sameContent(stored, path string, size int64) (bool, error) {
+	storedInfo, err := os.Stat(stored)
+	if err != nil {
+		return false, nil // Swept meanwhile; linking reports it
+	}
+	if storedInfo.Size() != size {
+		return false, fmt.Errorf("dedup copy %s has a different size", filepath.Base(stored))
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(storedInfo, info), nil
+}
@@ -7,8 +7,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"fingerprint-converter/internal/remote"
 )
 
 // CacheEntry represents a cached file with metadata
@@ -21,24 +25,52 @@ type CacheEntry struct {
 	Size          int64     // File size in bytes
 	MediaType     string    // audio/image/video
 	URL           string    // Original URL
+	Fallback      bool      // Produced by a safe-mode retry (no AF applied)
+	Uploads       []string  // URLs the output was uploaded to (purged from the CDN on invalidation)
+	Pinned        bool      // Kept past CacheExpires and FileExpires until unpinned
+
+	ProfileVersion string  // AF ranges and profiles the output was encoded with ("" = not tracked)
+	Source         *Source // Request that produced the output (nil = input can't be fetched again)
+}
+
+// FailureEntry remembers a conversion that failed for reasons that would recur
+type FailureEntry struct {
+	Err     error     // Returned again instead of reprocessing
+	Expires time.Time // When the input may be tried again
+	Hits    int64     // Requests answered from this entry
+	URL     string    // Original URL
 }
 
 // DeviceCache manages per-device file caching with fixed TTL
 type DeviceCache struct {
 	cache         map[string]map[string]*CacheEntry // deviceID -> urlHash -> entry
+	failures      map[string]map[string]*FailureEntry // deviceID -> urlHash -> failed conversion
 	mu            sync.RWMutex
-	cacheTTL      time.Duration // 28 minutes
+	cacheTTL      time.Duration // 28 minutes (guarded by mu, see SetTTL)
 	fileTTL       time.Duration // 30 minutes
+	failureTTL    time.Duration // 0 = failures are not cached
+	retention     []RetentionRule // Size and type tiers that replace fileTTL (guarded by mu)
 	cleanupTicker *time.Ticker
 	stopCleanup   chan struct{}
 	cacheDir      string
-	stats         CacheStats
+	dedup         *Deduper // nil = identical outputs are stored separately
+	minFreeDisk   atomic.Int64 // Free bytes kept on the cache volume (0 = no admission control)
+	lowDisk       atomic.Bool  // Last CheckSpace found less than minFreeDisk
+	evicting      atomic.Bool  // A low-disk eviction is running
+	maxPinned     atomic.Int64 // Pinned entries allowed per device (0 = pinning off)
+
+	profileVersion atomic.Pointer[string] // Version of the AF ranges and profiles in effect
+	dropStale      atomic.Bool            // Entries of other versions are misses
+	staleMisses    atomic.Int64           // Stale entries converted again
+
+	stats CacheStats
 }
 
 // CacheStats tracks cache performance metrics
 type CacheStats struct {
 	Hits          int64
 	Misses        int64
+	FailureHits   int64
 	Evictions     int64
 	TotalDevices  int
 	TotalEntries  int
@@ -49,7 +81,8 @@ type CacheStats struct {
 }
 
 // NewDeviceCache creates a new device-specific cache manager
-func NewDeviceCache(cacheDir string, cacheTTL, fileTTL time.Duration) *DeviceCache {
+// failureTTL is how long failed conversions are remembered (0 = not at all)
+func NewDeviceCache(cacheDir string, cacheTTL, fileTTL, failureTTL time.Duration) *DeviceCache {
 	if cacheTTL <= 0 {
 		cacheTTL = 28 * time.Minute
 	}
@@ -64,8 +97,10 @@ func NewDeviceCache(cacheDir string, cacheTTL, fileTTL time.Duration) *DeviceCac
 
 	dc := &DeviceCache{
 		cache:       make(map[string]map[string]*CacheEntry),
+		failures:    make(map[string]map[string]*FailureEntry),
 		cacheTTL:    cacheTTL,
 		fileTTL:     fileTTL,
+		failureTTL:  max(failureTTL, 0),
 		stopCleanup: make(chan struct{}),
 		cacheDir:    cacheDir,
 	}
@@ -74,11 +109,45 @@ func NewDeviceCache(cacheDir string, cacheTTL, fileTTL time.Duration) *DeviceCac
 	dc.cleanupTicker = time.NewTicker(1 * time.Minute)
 	go dc.cleanupLoop()
 
-	log.Printf("✅ Device cache initialized: TTL=%v, FileTTL=%v, Dir=%s", cacheTTL, fileTTL, cacheDir)
+	log.Printf("✅ Device cache initialized: TTL=%v, FileTTL=%v, FailureTTL=%v, Dir=%s", cacheTTL, fileTTL, dc.failureTTL, cacheDir)
 
 	return dc
 }
 
+// SetTTL changes the TTLs applied to new entries; existing entries keep their expiry
+func (dc *DeviceCache) SetTTL(cacheTTL, fileTTL time.Duration) {
+	if cacheTTL <= 0 || fileTTL <= 0 {
+		return
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.cacheTTL = cacheTTL
+	dc.fileTTL = fileTTL
+	log.Printf("🔄 Device cache TTL updated: TTL=%v, FileTTL=%v", cacheTTL, fileTTL)
+}
+
+// SetFailureTTL changes how long new failures are remembered; 0 stops caching them
+func (dc *DeviceCache) SetFailureTTL(ttl time.Duration) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.failureTTL = max(ttl, 0)
+	if dc.failureTTL == 0 {
+		clear(dc.failures)
+	}
+	log.Printf("🔄 Failure cache TTL updated: %v", dc.failureTTL)
+}
+
+// EnableDedup hard-links identical outputs to one copy in the cache dir (call before serving)
+func (dc *DeviceCache) EnableDedup() error {
+	dedup, err := NewDeduper(dc.cacheDir)
+	if err != nil {
+		return err
+	}
+	dc.dedup = dedup
+	return nil
+}
+
 // Get retrieves a cached file if still valid
 // Returns nil if cache expired or not found
 func (dc *DeviceCache) Get(deviceID, url string) *CacheEntry {
@@ -98,9 +167,17 @@ func (dc *DeviceCache) Get(deviceID, url string) *CacheEntry {
 		return nil
 	}
 
-	// Check if cache expired (28 minutes)
-	if time.Now().After(entry.CacheExpires) {
+	// Check if cache expired (28 minutes); pinned entries don't
+	if dc.expired(entry) {
+		dc.recordMiss()
+		return nil
+	}
+
+	// Outputs of older AF profiles are converted again when re-encoding is on
+	if dc.stale(entry) {
 		dc.recordMiss()
+		dc.staleMisses.Add(1)
+		log.Printf("♻️  Cache STALE: device=%s, url=%s, profile version %s", deviceID, truncateURL(url), entry.ProfileVersion)
 		return nil
 	}
 
@@ -111,8 +188,28 @@ func (dc *DeviceCache) Get(deviceID, url string) *CacheEntry {
 	return entry
 }
 
+// Peek returns a copy of the valid entry for url without counting a hit or a use
+// Used by the cache check, which must not skew the hit rate
+func (dc *DeviceCache) Peek(deviceID, url string) (CacheEntry, bool) {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	entry, exists := dc.cache[deviceID][hashURL(url)]
+	if !exists || dc.expired(entry) || dc.stale(entry) {
+		return CacheEntry{}, false
+	}
+	return *entry, true
+}
+
 // Set stores a processed file in cache
 func (dc *DeviceCache) Set(deviceID, url, processedPath, mediaType string, fileSize int64) error {
+	// Hashing happens outside the lock; a failed dedup keeps the output as is
+	if saved, err := dc.dedup.Dedup(processedPath); err != nil {
+		log.Printf("⚠️  Dedup failed for %s: %v", processedPath, err)
+	} else if saved > 0 {
+		log.Printf("🔗 Dedup: %s linked to an identical output (%d bytes saved)", processedPath, saved)
+	}
+
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 
@@ -124,10 +221,11 @@ func (dc *DeviceCache) Set(deviceID, url, processedPath, mediaType string, fileS
 		dc.cache[deviceID] = make(map[string]*CacheEntry)
 	}
 
+	cacheTTL, fileTTL := dc.ttlsFor(mediaType, fileSize)
 	entry := &CacheEntry{
 		ProcessedPath: processedPath,
-		CacheExpires:  now.Add(dc.cacheTTL), // 28 minutes
-		FileExpires:   now.Add(dc.fileTTL),  // 30 minutes
+		CacheExpires:  now.Add(cacheTTL), // 28 minutes unless a retention rule matches
+		FileExpires:   now.Add(fileTTL),  // 30 minutes unless a retention rule matches
 		Created:       now,
 		Uses:          0,
 		Size:          fileSize,
@@ -135,10 +233,16 @@ func (dc *DeviceCache) Set(deviceID, url, processedPath, mediaType string, fileS
 		URL:           url,
 	}
 
+	// A pin belongs to the URL and options, so it carries over to a new output for them
+	if old, exists := dc.cache[deviceID][urlHash]; exists && old.Pinned {
+		entry.Pinned = true
+	}
+
 	dc.cache[deviceID][urlHash] = entry
+	delete(dc.failures[deviceID], urlHash)
 
 	// Schedule file deletion after fileTTL (30 minutes)
-	go dc.scheduleFileDeletion(deviceID, urlHash, processedPath, dc.fileTTL)
+	go dc.scheduleFileDeletion(deviceID, urlHash, processedPath, fileTTL)
 
 	log.Printf("📦 Cache SET: device=%s, url=%s, path=%s, expires=%v",
 		deviceID, truncateURL(url), processedPath, entry.CacheExpires.Format("15:04:05"))
@@ -146,15 +250,112 @@ func (dc *DeviceCache) Set(deviceID, url, processedPath, mediaType string, fileS
 	return nil
 }
 
+// GetFailure returns the error of a recent failed conversion of url, or nil
+func (dc *DeviceCache) GetFailure(deviceID, url string) error {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	entry, exists := dc.failures[deviceID][hashURL(url)]
+	if !exists || time.Now().After(entry.Expires) {
+		return nil
+	}
+
+	dc.stats.mu.Lock()
+	entry.Hits++
+	dc.stats.FailureHits++
+	dc.stats.mu.Unlock()
+	return entry.Err
+}
+
+// SetFailure remembers a failed conversion of url for the failure TTL
+// A later successful Set for the same url replaces it
+func (dc *DeviceCache) SetFailure(deviceID, url string, err error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.failureTTL == 0 {
+		return
+	}
+	if dc.failures[deviceID] == nil {
+		dc.failures[deviceID] = make(map[string]*FailureEntry)
+	}
+
+	entry := &FailureEntry{Err: err, Expires: time.Now().Add(dc.failureTTL), URL: url}
+	dc.failures[deviceID][hashURL(url)] = entry
+
+	log.Printf("🚫 Failure cached: device=%s, url=%s, until=%v, error=%v",
+		deviceID, truncateURL(url), entry.Expires.Format("15:04:05"), err)
+}
+
+// MarkFallback flags a cached output as produced by a safe-mode retry
+func (dc *DeviceCache) MarkFallback(deviceID, url string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if entry, exists := dc.cache[deviceID][hashURL(url)]; exists {
+		entry.Fallback = true
+	}
+}
+
+// RecordUpload notes that the device's entry holding processedPath was uploaded to uploadURL
+func (dc *DeviceCache) RecordUpload(deviceID, processedPath, uploadURL string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	for _, entry := range dc.cache[deviceID] {
+		if entry.ProcessedPath == processedPath && !slices.Contains(entry.Uploads, uploadURL) {
+			entry.Uploads = append(entry.Uploads, uploadURL)
+		}
+	}
+}
+
+// Invalidate drops the device's entries and cached failures for url, whatever options they were
+// converted with, or everything cached for the device when url is empty
+// Their files are deleted at once; the removed entries are returned
+func (dc *DeviceCache) Invalidate(deviceID, url string) []*CacheEntry {
+	dc.mu.Lock()
+	var removed []*CacheEntry
+	for urlHash, entry := range dc.cache[deviceID] {
+		if matchesURL(entry.URL, url) {
+			removed = append(removed, entry)
+			delete(dc.cache[deviceID], urlHash)
+		}
+	}
+	if len(dc.cache[deviceID]) == 0 {
+		delete(dc.cache, deviceID)
+	}
+	for urlHash, failure := range dc.failures[deviceID] {
+		if matchesURL(failure.URL, url) {
+			delete(dc.failures[deviceID], urlHash)
+		}
+	}
+	dc.mu.Unlock()
+
+	// Delete physical files outside lock
+	for _, entry := range removed {
+		if err := os.Remove(entry.ProcessedPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Failed to delete invalidated file %s: %v", entry.ProcessedPath, err)
+		}
+	}
+	if len(removed) > 0 {
+		log.Printf("🗑️  Cache INVALIDATE: device=%s, url=%s, entries=%d", deviceID, truncateURL(url), len(removed))
+	}
+	return removed
+}
+
 // scheduleFileDeletion deletes the file after the specified TTL
 func (dc *DeviceCache) scheduleFileDeletion(deviceID, urlHash, filePath string, ttl time.Duration) {
 	time.Sleep(ttl)
 
-	// Remove from cache
+	// Remove from cache; pinned entries, and entries unpinned since, are deleted on their own schedule
 	dc.mu.Lock()
-	if deviceCache, exists := dc.cache[deviceID]; exists {
-		delete(deviceCache, urlHash)
-		if len(deviceCache) == 0 {
+	if entry, exists := dc.cache[deviceID][urlHash]; exists && entry.ProcessedPath == filePath {
+		if entry.Pinned || time.Now().Before(entry.FileExpires) {
+			dc.mu.Unlock()
+			return
+		}
+		delete(dc.cache[deviceID], urlHash)
+		if len(dc.cache[deviceID]) == 0 {
 			delete(dc.cache, deviceID)
 		}
 	}
@@ -196,8 +397,8 @@ func (dc *DeviceCache) cleanup() {
 
 	for deviceID, deviceCache := range dc.cache {
 		for urlHash, entry := range deviceCache {
-			// Remove entries where file already expired (30 minutes)
-			if now.After(entry.FileExpires) {
+			// Remove entries where file already expired (30 minutes), unless pinned
+			if !entry.Pinned && now.After(entry.FileExpires) {
 				expiredFiles = append(expiredFiles, entry.ProcessedPath)
 				delete(deviceCache, urlHash)
 			}
@@ -209,6 +410,18 @@ func (dc *DeviceCache) cleanup() {
 		}
 	}
 
+	// Failures hold no files; expired ones are just dropped
+	for deviceID, deviceFailures := range dc.failures {
+		for urlHash, entry := range deviceFailures {
+			if now.After(entry.Expires) {
+				delete(deviceFailures, urlHash)
+			}
+		}
+		if len(deviceFailures) == 0 {
+			delete(dc.failures, deviceID)
+		}
+	}
+
 	// Delete physical files outside lock
 	if len(expiredFiles) > 0 {
 		go func() {
@@ -222,6 +435,11 @@ func (dc *DeviceCache) cleanup() {
 			log.Printf("🧹 Cleanup: removed %d expired files", len(expiredFiles))
 		}()
 	}
+
+	// Copies unused for a file TTL are no longer linked from any cached output
+	if dc.dedup != nil {
+		go dc.dedup.Sweep(dc.fileTTL)
+	}
 }
 
 // GetDeviceStats returns cache statistics for a specific device
@@ -240,13 +458,18 @@ func (dc *DeviceCache) GetDeviceStats(deviceID string) map[string]interface{} {
 
 	totalSize := int64(0)
 	entryCount := 0
+	pinned := 0
 	for _, entry := range deviceCache {
 		totalSize += entry.Size
 		entryCount++
+		if entry.Pinned {
+			pinned++
+		}
 	}
 
 	return map[string]interface{}{
 		"entries":   entryCount,
+		"pinned":    pinned,
 		"total_kb":  totalSize / 1024,
 		"cache_ttl": dc.cacheTTL.Minutes(),
 		"file_ttl":  dc.fileTTL.Minutes(),
@@ -263,6 +486,7 @@ func (dc *DeviceCache) GetGlobalStats() map[string]interface{} {
 
 	totalEntries := 0
 	totalSize := int64(0)
+	totalFailures := 0
 
 	for _, deviceCache := range dc.cache {
 		totalEntries += len(deviceCache)
@@ -271,6 +495,10 @@ func (dc *DeviceCache) GetGlobalStats() map[string]interface{} {
 		}
 	}
 
+	for _, deviceFailures := range dc.failures {
+		totalFailures += len(deviceFailures)
+	}
+
 	hitRate := 0.0
 	if total := dc.stats.Hits + dc.stats.Misses; total > 0 {
 		hitRate = float64(dc.stats.Hits) / float64(total) * 100
@@ -283,9 +511,16 @@ func (dc *DeviceCache) GetGlobalStats() map[string]interface{} {
 		"hits":         dc.stats.Hits,
 		"misses":       dc.stats.Misses,
 		"evictions":    dc.stats.Evictions,
+		"failures":     totalFailures,
+		"pinned":       dc.pinnedCount(),
+		"failure_hits": dc.stats.FailureHits,
+		"stale_misses": dc.staleMisses.Load(),
 		"hit_rate":     fmt.Sprintf("%.2f%%", hitRate),
 		"cache_ttl_min": dc.cacheTTL.Minutes(),
 		"file_ttl_min":  dc.fileTTL.Minutes(),
+		"failure_ttl_sec": dc.failureTTL.Seconds(),
+		"dedup":         dc.dedup.Stats(),
+		"disk":          dc.diskStats(),
 	}
 }
 
@@ -303,12 +538,20 @@ func hashURL(url string) string {
 }
 
 func truncateURL(url string) string {
+	url = remote.Redact(url)
 	if len(url) > 60 {
 		return url[:57] + "..."
 	}
 	return url
 }
 
+// HitCounts returns the cache hits and misses since startup
+func (dc *DeviceCache) HitCounts() (hits, misses int64) {
+	dc.stats.mu.RLock()
+	defer dc.stats.mu.RUnlock()
+	return dc.stats.Hits, dc.stats.Misses
+}
+
 func (dc *DeviceCache) recordHit() {
 	dc.stats.mu.Lock()
 	dc.stats.Hits++
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+// LowDiskError rejects new work while the cache volume is short of free space
+type LowDiskError struct {
+	Free    uint64 // Bytes available on the cache volume
+	MinFree uint64 // MIN_FREE_DISK
+}
+
+func (e *LowDiskError) Error() string {
+	return fmt.Sprintf("cache volume has %d MB free, below the %d MB minimum", e.Free/(1024*1024), e.MinFree/(1024*1024))
+}
+
+// SetMinFreeDisk sets the free space kept on the cache volume; 0 turns admission control off
+func (dc *DeviceCache) SetMinFreeDisk(bytes int64) {
+	dc.minFreeDisk.Store(max(bytes, 0))
+	if bytes <= 0 {
+		dc.lowDisk.Store(false)
+	}
+	log.Printf("💽 Disk admission: conversions need %d MB free on the cache volume (0 = off)", max(bytes, 0)/(1024*1024))
+}
+
+// CheckSpace admits new outputs while the cache volume has MIN_FREE_DISK free
+// Below it, cached outputs are evicted oldest first; if that doesn't free enough, a
+// *LowDiskError is returned. A volume whose space can't be read is always admitted.
+func (dc *DeviceCache) CheckSpace() error {
+	minFree := uint64(dc.minFreeDisk.Load())
+	if minFree == 0 {
+		return nil
+	}
+	free, _, err := diskSpace(dc.cacheDir)
+	if err != nil || free >= minFree {
+		dc.lowDisk.Store(false)
+		return nil
+	}
+
+	// One request evicts at a time; the others are turned away meanwhile
+	if dc.evicting.CompareAndSwap(false, true) {
+		free = dc.evictForSpace(minFree + minFree/4)
+		dc.evicting.Store(false)
+		if free >= minFree {
+			dc.lowDisk.Store(false)
+			return nil
+		}
+	}
+
+	if !dc.lowDisk.Swap(true) {
+		log.Printf("💽 Cache volume low on space: %d MB free, minimum %d MB; rejecting new conversions",
+			free/(1024*1024), minFree/(1024*1024))
+	}
+	return &LowDiskError{Free: free, MinFree: minFree}
+}
+
+// LowDisk reports whether the last CheckSpace found the cache volume short of space
+func (dc *DeviceCache) LowDisk() bool {
+	return dc.lowDisk.Load()
+}
+
+// evictForSpace deletes cached outputs, oldest first, until target bytes are free
+// Returns the free space left. Pinned outputs, uploaded copies and packages are not touched.
+func (dc *DeviceCache) evictForSpace(target uint64) uint64 {
+	// Dedup copies keep the data of evicted outputs alive; drop their names first
+	if dc.dedup != nil {
+		dc.dedup.Sweep(0)
+	}
+
+	type victim struct {
+		deviceID, urlHash string
+		entry             *CacheEntry
+	}
+	dc.mu.RLock()
+	var victims []victim
+	for deviceID, deviceCache := range dc.cache {
+		for urlHash, entry := range deviceCache {
+			if !entry.Pinned {
+				victims = append(victims, victim{deviceID, urlHash, entry})
+			}
+		}
+	}
+	dc.mu.RUnlock()
+	slices.SortFunc(victims, func(a, b victim) int {
+		return a.entry.Created.Compare(b.entry.Created)
+	})
+
+	start := time.Now()
+	free, _, _ := diskSpace(dc.cacheDir)
+	evicted := 0
+	for _, v := range victims {
+		if free >= target {
+			break
+		}
+
+		// Skip entries replaced since the snapshot
+		dc.mu.Lock()
+		current := dc.cache[v.deviceID][v.urlHash] == v.entry
+		if current {
+			delete(dc.cache[v.deviceID], v.urlHash)
+			if len(dc.cache[v.deviceID]) == 0 {
+				delete(dc.cache, v.deviceID)
+			}
+		}
+		dc.mu.Unlock()
+		if !current {
+			continue
+		}
+
+		if err := os.Remove(v.entry.ProcessedPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Failed to evict %s: %v", v.entry.ProcessedPath, err)
+			continue
+		}
+		evicted++
+		free, _, _ = diskSpace(dc.cacheDir)
+	}
+
+	if evicted > 0 {
+		dc.stats.mu.Lock()
+		dc.stats.Evictions += int64(evicted)
+		dc.stats.mu.Unlock()
+		log.Printf("🧹 Low disk eviction: removed %d cached outputs in %dms, %d MB free",
+			evicted, time.Since(start).Milliseconds(), free/(1024*1024))
+	}
+	return free
+}
+
+// diskStats reports the cache volume's space for the health endpoint (nil if unknown)
+func (dc *DeviceCache) diskStats() map[string]interface{} {
+	free, total, err := diskSpace(dc.cacheDir)
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"free_mb":     free / (1024 * 1024),
+		"total_mb":    total / (1024 * 1024),
+		"min_free_mb": dc.minFreeDisk.Load() / (1024 * 1024),
+		"low":         dc.lowDisk.Load(),
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cache
+
+import "errors"
+
+// diskSpace is not implemented on this platform; MIN_FREE_DISK is then ignored
+func diskSpace(dir string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk space is not available on this platform")
+}
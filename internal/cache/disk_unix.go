@@ -0,0 +1,14 @@
+//go:build unix
+
+package cache
+
+import "syscall"
+
+// diskSpace returns the bytes available to this process and the size of the volume holding dir
+func diskSpace(dir string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
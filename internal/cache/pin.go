@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrNotCached is returned when there is no cached output to pin
+var ErrNotCached = errors.New("no cached output for this URL")
+
+// PinQuotaError is returned when pinning would take a device past its pinned-entry quota
+type PinQuotaError struct {
+	Pinned int // Entries the device already has pinned
+	Max    int
+}
+
+func (e *PinQuotaError) Error() string {
+	return fmt.Sprintf("device has %d of %d pinned outputs", e.Pinned, e.Max)
+}
+
+// SetMaxPinned sets how many entries one device may have pinned; 0 turns pinning off
+// Entries pinned already stay pinned
+func (dc *DeviceCache) SetMaxPinned(n int) {
+	dc.maxPinned.Store(int64(max(n, 0)))
+	log.Printf("📌 Cache pinning: up to %d pinned outputs per device (0 = off)", max(n, 0))
+}
+
+// Pin exempts the device's entries for url, whatever options they were converted with, from the
+// TTLs and low-disk eviction until they are unpinned. Returns every entry now pinned for url.
+// Fails with ErrNotCached when nothing valid is cached for url, and *PinQuotaError past the quota.
+func (dc *DeviceCache) Pin(deviceID, url string) ([]*CacheEntry, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	now := time.Now()
+	pinned := 0
+	var matched, added []*CacheEntry
+	for _, entry := range dc.cache[deviceID] {
+		if entry.Pinned {
+			pinned++
+		}
+		if matchesURL(entry.URL, url) && (entry.Pinned || now.Before(entry.CacheExpires)) {
+			matched = append(matched, entry)
+			if !entry.Pinned {
+				added = append(added, entry)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrNotCached
+	}
+	if limit := int(dc.maxPinned.Load()); pinned+len(added) > limit {
+		return nil, &PinQuotaError{Pinned: pinned, Max: limit}
+	}
+
+	for _, entry := range added {
+		entry.Pinned = true
+	}
+	if len(added) > 0 {
+		log.Printf("📌 Cache PIN: device=%s, url=%s, entries=%d", deviceID, truncateURL(url), len(added))
+	}
+	return matched, nil
+}
+
+// Unpin returns the device's entries for url to the TTLs, counted from now, and reports how many
+// were pinned. An empty url unpins everything the device has pinned.
+func (dc *DeviceCache) Unpin(deviceID, url string) int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	now := time.Now()
+	unpinned := 0
+	for urlHash, entry := range dc.cache[deviceID] {
+		if !entry.Pinned || (url != "" && !matchesURL(entry.URL, url)) {
+			continue
+		}
+		entry.Pinned = false
+		cacheTTL, fileTTL := dc.ttlsFor(entry.MediaType, entry.Size)
+		entry.CacheExpires = now.Add(cacheTTL)
+		entry.FileExpires = now.Add(fileTTL)
+		go dc.scheduleFileDeletion(deviceID, urlHash, entry.ProcessedPath, fileTTL)
+		unpinned++
+	}
+	if unpinned > 0 {
+		log.Printf("📌 Cache UNPIN: device=%s, url=%s, entries=%d", deviceID, truncateURL(url), unpinned)
+	}
+	return unpinned
+}
+
+// matchesURL reports whether the cache key of an entry belongs to url, with any options
+func matchesURL(key, url string) bool {
+	return url == "" || key == url || strings.HasPrefix(key, url+"#")
+}
+
+// pinnedCount returns how many entries are pinned across all devices; callers hold dc.mu
+func (dc *DeviceCache) pinnedCount() int {
+	count := 0
+	for _, deviceCache := range dc.cache {
+		for _, entry := range deviceCache {
+			if entry.Pinned {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionRule keeps the outputs it matches for TTL instead of the file TTL
+type RetentionRule struct {
+	MediaType string        // audio/image/video ("" = any)
+	MaxSize   int64         // Largest output matched, in bytes (0 = any size)
+	TTL       time.Duration // How long the file is kept
+}
+
+// ParseRetentionRule parses a "media:max_bytes:ttl" rule, e.g. audio:2097152:6h or video:0:10m
+// media may be * for any media type, and max_bytes 0 for any size
+func ParseRetentionRule(s string) (RetentionRule, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return RetentionRule{}, fmt.Errorf("%q is not media:max_bytes:ttl", s)
+	}
+
+	var rule RetentionRule
+	switch parts[0] {
+	case "*":
+	case "audio", "image", "video":
+		rule.MediaType = parts[0]
+	default:
+		return RetentionRule{}, fmt.Errorf("%q: media must be audio, image, video or *", s)
+	}
+
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || size < 0 {
+		return RetentionRule{}, fmt.Errorf("%q: max_bytes must be a number of bytes (0 = any size)", s)
+	}
+	rule.MaxSize = size
+
+	ttl, err := time.ParseDuration(parts[2])
+	if err != nil || ttl <= 0 {
+		return RetentionRule{}, fmt.Errorf("%q: ttl must be a positive duration", s)
+	}
+	rule.TTL = ttl
+	return rule, nil
+}
+
+// String formats the rule the way ParseRetentionRule reads it
+func (r RetentionRule) String() string {
+	media := r.MediaType
+	if media == "" {
+		media = "*"
+	}
+	return fmt.Sprintf("%s:%d:%v", media, r.MaxSize, r.TTL)
+}
+
+// matches reports whether an output of mediaType and size falls under the rule
+func (r RetentionRule) matches(mediaType string, size int64) bool {
+	return (r.MediaType == "" || r.MediaType == mediaType) && (r.MaxSize == 0 || size <= r.MaxSize)
+}
+
+// SetRetention replaces the retention rules applied to new entries; the first matching rule wins
+// Outputs no rule matches keep the cache and file TTLs
+func (dc *DeviceCache) SetRetention(rules []RetentionRule) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.retention = rules
+	if len(rules) > 0 {
+		log.Printf("🗄️  Retention tiers: %v (others: FileTTL=%v)", rules, dc.fileTTL)
+	}
+}
+
+// ttlsFor returns the cache and file TTLs of an output; callers hold dc.mu
+// Under a rule, the cache stops answering with the file as long before its deletion as it
+// does with the default TTLs
+func (dc *DeviceCache) ttlsFor(mediaType string, size int64) (cacheTTL, fileTTL time.Duration) {
+	for _, rule := range dc.retention {
+		if rule.matches(mediaType, size) {
+			return rule.TTL - (dc.fileTTL - dc.cacheTTL), rule.TTL
+		}
+	}
+	return dc.cacheTTL, dc.fileTTL
+}
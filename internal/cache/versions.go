@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Source is the request an output was converted from, so it can be converted again
+type Source struct {
+	TenantID string
+	Request  models.ConvertRequest
+}
+
+// SetProfileVersion sets the version of the AF ranges and profiles in effect
+// Entries stamped with another version are stale; see SetDropStale
+func (dc *DeviceCache) SetProfileVersion(version string) {
+	dc.profileVersion.Store(&version)
+}
+
+// SetDropStale makes stale entries cache misses, so they are converted again on their next request
+// When off, stale entries are served until they expire
+func (dc *DeviceCache) SetDropStale(drop bool) {
+	dc.dropStale.Store(drop)
+}
+
+// Stamp records the profile version an entry was encoded with, and the request that produced it
+// source may be nil when the input can't be fetched again (uploads, inline data)
+func (dc *DeviceCache) Stamp(deviceID, url, version string, source *Source) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if entry, exists := dc.cache[deviceID][hashURL(url)]; exists {
+		entry.ProfileVersion = version
+		entry.Source = source
+	}
+}
+
+// StaleSources returns the sources of the valid entries encoded with an older profile version
+func (dc *DeviceCache) StaleSources() []Source {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	var sources []Source
+	for _, deviceCache := range dc.cache {
+		for _, entry := range deviceCache {
+			if entry.Source != nil && dc.outdated(entry) && !dc.expired(entry) {
+				sources = append(sources, *entry.Source)
+			}
+		}
+	}
+	return sources
+}
+
+// stale reports whether entry must be converted again instead of served; callers hold dc.mu
+func (dc *DeviceCache) stale(entry *CacheEntry) bool {
+	return dc.dropStale.Load() && dc.outdated(entry)
+}
+
+// expired reports whether entry is past its cache TTL; callers hold dc.mu
+func (dc *DeviceCache) expired(entry *CacheEntry) bool {
+	return !entry.Pinned && time.Now().After(entry.CacheExpires)
+}
+
+// outdated reports whether entry was encoded with another profile version; callers hold dc.mu
+// Entries without a version (concatenations, slideshows) never go stale
+func (dc *DeviceCache) outdated(entry *CacheEntry) bool {
+	current := dc.profileVersion.Load()
+	return current != nil && entry.ProfileVersion != "" && entry.ProfileVersion != *current
+}
@@ -0,0 +1,127 @@
+// Package cdn purges edge-cached copies of uploaded outputs, so a CDN in front of the upload
+// target doesn't keep serving a file the converter has replaced or invalidated
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Purger removes URLs from a CDN's edge caches
+type Purger interface {
+	// Purge invalidates every URL; it fails if the CDN rejected any of them
+	Purge(ctx context.Context, urls []string) error
+	// Name identifies the CDN in logs
+	Name() string
+}
+
+// Invalidator purges the public URLs of uploaded outputs in the background
+// A nil Invalidator does nothing
+type Invalidator struct {
+	purger     Purger
+	publicBase *url.URL      // Replaces the scheme and host of uploaded URLs (nil = purge them as uploaded)
+	timeout    time.Duration // Limit per purge call
+	purged     atomic.Int64
+	failed     atomic.Int64
+}
+
+// Stats counts purge calls since startup
+type Stats struct {
+	Provider string `json:"provider"`
+	Purged   int64  `json:"purged"` // URLs the CDN accepted
+	Failed   int64  `json:"failed"` // URLs whose purge failed (logged, not retried)
+}
+
+// NewInvalidator creates an invalidator that purges through purger
+// publicBase is the CDN origin outputs are served from, e.g. https://cdn.example.com; uploaded URLs keep
+// their path under it. Empty means the CDN serves them under the upload URL itself.
+func NewInvalidator(purger Purger, publicBase string, timeout time.Duration) (*Invalidator, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	inv := &Invalidator{purger: purger, timeout: timeout}
+	if publicBase != "" {
+		base, err := url.Parse(strings.TrimSuffix(publicBase, "/"))
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			return nil, fmt.Errorf("public base URL must be an http(s) URL (got %q)", publicBase)
+		}
+		inv.publicBase = base
+	}
+	return inv, nil
+}
+
+// PublicURL maps an uploaded URL to the URL the CDN serves it under
+func (inv *Invalidator) PublicURL(uploaded string) string {
+	if inv == nil || inv.publicBase == nil {
+		return uploaded
+	}
+	u, err := url.Parse(uploaded)
+	if err != nil {
+		return uploaded
+	}
+	public := *inv.publicBase
+	public.Path = inv.publicBase.Path + u.Path
+	public.RawPath = ""
+	return public.String()
+}
+
+// Invalidate purges the public URLs of the uploaded files in the background
+// reason is logged; failures are logged and counted, since the upload itself succeeded
+func (inv *Invalidator) Invalidate(reason string, uploaded ...string) {
+	if inv == nil || len(uploaded) == 0 {
+		return
+	}
+	urls := make([]string, len(uploaded))
+	for i, u := range uploaded {
+		urls[i] = inv.PublicURL(u)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), inv.timeout)
+		defer cancel()
+
+		if err := inv.purger.Purge(ctx, urls); err != nil {
+			inv.failed.Add(int64(len(urls)))
+			log.Printf("⚠️  CDN purge failed: cdn=%s, reason=%s, urls=%d, error=%v", inv.purger.Name(), reason, len(urls), err)
+			return
+		}
+		inv.purged.Add(int64(len(urls)))
+		log.Printf("🧹 CDN purged: cdn=%s, reason=%s, urls=%d, first=%s", inv.purger.Name(), reason, len(urls), urls[0])
+	}()
+}
+
+// Stats returns the purge counters (nil-safe: nil when purging is disabled)
+func (inv *Invalidator) Stats() *Stats {
+	if inv == nil {
+		return nil
+	}
+	return &Stats{Provider: inv.purger.Name(), Purged: inv.purged.Load(), Failed: inv.failed.Load()}
+}
+
+// client sends purge requests; each call is bounded by the invalidator's context
+var client = &http.Client{}
+
+// do sends req and fails on any status outside 2xx, quoting the start of the CDN's answer
+func do(req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 200)])))
+	}
+	return body, nil
+}
@@ -0,0 +1,80 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cloudflareBatch is the most files one purge_cache call takes
+const cloudflareBatch = 30
+
+// cloudflareAPI is the Cloudflare API base URL
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare purges files from a Cloudflare zone by URL
+type Cloudflare struct {
+	zoneID string
+	token  string // API token with the Cache Purge permission
+}
+
+// NewCloudflare creates a purger for zoneID, authenticated with an API token
+func NewCloudflare(zoneID, token string) (*Cloudflare, error) {
+	if zoneID == "" || token == "" {
+		return nil, fmt.Errorf("cloudflare purging needs a zone ID and an API token")
+	}
+	return &Cloudflare{zoneID: zoneID, token: token}, nil
+}
+
+// Name identifies the CDN in logs
+func (c *Cloudflare) Name() string {
+	return "cloudflare"
+}
+
+// Purge purges urls in batches of cloudflareBatch
+func (c *Cloudflare) Purge(ctx context.Context, urls []string) error {
+	for start := 0; start < len(urls); start += cloudflareBatch {
+		if err := c.purge(ctx, urls[start:min(start+cloudflareBatch, len(urls))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cloudflare) purge(ctx context.Context, files []string) error {
+	body, err := json.Marshal(map[string][]string{"files": files})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cloudflareAPI+"/zones/"+c.zoneID+"/purge_cache", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	answer, err := do(req)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(answer, &result); err != nil {
+		return fmt.Errorf("unexpected answer: %w", err)
+	}
+	if !result.Success {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("purge rejected: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
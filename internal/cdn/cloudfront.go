@@ -0,0 +1,141 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cloudFrontAPI is the CloudFront API endpoint (global, signed for us-east-1)
+const cloudFrontAPI = "https://cloudfront.amazonaws.com/2020-05-31"
+
+// CloudFront creates invalidations on a CloudFront distribution
+type CloudFront struct {
+	distributionID string
+	accessKeyID    string
+	secretKey      string
+	sessionToken   string // Temporary credentials only
+}
+
+// NewCloudFront creates a purger for distributionID with AWS credentials
+// The credentials need cloudfront:CreateInvalidation on the distribution
+func NewCloudFront(distributionID, accessKeyID, secretKey, sessionToken string) (*CloudFront, error) {
+	if distributionID == "" || accessKeyID == "" || secretKey == "" {
+		return nil, fmt.Errorf("cloudfront purging needs a distribution ID and AWS credentials")
+	}
+	return &CloudFront{distributionID: distributionID, accessKeyID: accessKeyID, secretKey: secretKey, sessionToken: sessionToken}, nil
+}
+
+// Name identifies the CDN in logs
+func (c *CloudFront) Name() string {
+	return "cloudfront"
+}
+
+// invalidationBatch is the CreateInvalidation request body
+type invalidationBatch struct {
+	XMLName         xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Paths           []string `xml:"Paths>Items>Path"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// Purge creates one invalidation for the paths of urls
+// CloudFront invalidates by path, so the URLs' hosts must be served by the distribution
+func (c *CloudFront) Purge(ctx context.Context, urls []string) error {
+	batch := invalidationBatch{CallerReference: "fingerprint-converter-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+		}
+		batch.Paths = append(batch.Paths, u.EscapedPath())
+	}
+	batch.Quantity = len(batch.Paths)
+
+	body, err := xml.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cloudFrontAPI+"/distribution/"+url.PathEscape(c.distributionID)+"/invalidation", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	c.sign(req, body, time.Now())
+
+	_, err = do(req)
+	return err
+}
+
+// sign adds an AWS Signature Version 4 to req for the cloudfront service
+func (c *CloudFront) sign(req *http.Request, body []byte, now time.Time) {
+	const region, service = "us-east-1", "cloudfront"
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	// Canonical headers: host plus every header set above, lower-cased and sorted
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,49 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// fastlyAPI is the Fastly API base URL
+const fastlyAPI = "https://api.fastly.com"
+
+// Fastly purges single URLs from every Fastly service that caches them
+type Fastly struct {
+	apiKey string // API token with the purge_select scope
+}
+
+// NewFastly creates a purger authenticated with a Fastly API token
+func NewFastly(apiKey string) (*Fastly, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("fastly purging needs an API token")
+	}
+	return &Fastly{apiKey: apiKey}, nil
+}
+
+// Name identifies the CDN in logs
+func (f *Fastly) Name() string {
+	return "fastly"
+}
+
+// Purge purges urls one at a time (Fastly has no multi-URL purge)
+func (f *Fastly) Purge(ctx context.Context, urls []string) error {
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, fastlyAPI+"/purge/"+u.Host+u.EscapedPath(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Fastly-Key", f.apiKey)
+		req.Header.Set("Accept", "application/json")
+		if _, err := do(req); err != nil {
+			return fmt.Errorf("%s: %w", u.Host+u.EscapedPath(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"fingerprint-converter/internal/hooks"
+	"fingerprint-converter/internal/services"
+)
+
+// configFile is the YAML file given to Load; Reload reads it again
+var configFile string
+
+// fileKeys holds the variables defined in the config file at the last read
+var fileKeys map[string]bool
+
+// fileProfiles holds the profiles section of the config file
+var fileProfiles map[string]services.AFProfile
+
+// fileExperiments holds the experiments section of the config file
+var fileExperiments map[string]services.Experiment
+
+// fileHooks holds the hooks section of the config file
+var fileHooks map[string]hooks.CommandConfig
+
+// knownKeys collects every variable build reads, so typos in the config file are reported
+var knownKeys = make(map[string]bool)
+
+// fileConfig is the on-disk format of the config file
+// Sections (server, cache, download, ...) only group settings; each setting is named
+// after its environment variable in lower case, e.g. cache: {cache_ttl: 28m}
+type fileConfig struct {
+	Profiles    map[string]services.AFProfile  `yaml:"profiles"`
+	Experiments map[string]services.Experiment `yaml:"experiments"`
+	Hooks       map[string]hooks.CommandConfig `yaml:"hooks"`
+	Sections    map[string]map[string]any      `yaml:",inline"`
+}
+
+// lookup reads an environment variable and records it as a known setting
+func lookup(key string) string {
+	knownKeys[key] = true
+	return os.Getenv(key)
+}
+
+// readFile parses a config file into environment-style values, AF profiles, experiments and hooks
+func readFile(path string) (map[string]string, *fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file fileConfig
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	origin := make(map[string]string)
+	for section, settings := range file.Sections {
+		for name, value := range settings {
+			key := strings.ToUpper(name)
+			if other, exists := origin[key]; exists {
+				return nil, nil, fmt.Errorf("config file %s: %s is set in both %s and %s", path, name, other, section)
+			}
+			str, err := settingValue(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("config file %s: %s.%s: %w", path, section, name, err)
+			}
+			values[key] = str
+			origin[key] = section
+		}
+	}
+
+	for name, profile := range file.Profiles {
+		if err := profile.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("config file %s: profiles.%s: %w", path, name, err)
+		}
+	}
+	if err := services.ValidateExperiments(file.Experiments, file.Profiles); err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	for name, hook := range file.Hooks {
+		if err := hook.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("config file %s: hooks.%s: %w", path, name, err)
+		}
+	}
+	return values, &file, nil
+}
+
+// settingValue formats a YAML value the way it would be written in the environment
+// Lists become comma-separated strings
+func settingValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := settingValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf("nested sections are not supported")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// applyFile copies config file values into the environment
+// The process environment and .env both take precedence over the file
+func applyFile() error {
+	if configFile == "" {
+		return nil
+	}
+
+	values, file, err := readFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	for key := range fileKeys {
+		if _, ok := values[key]; !ok && !processEnv[key] && !dotenvKeys[key] {
+			os.Unsetenv(key)
+		}
+	}
+	fileKeys = make(map[string]bool)
+	for key, value := range values {
+		fileKeys[key] = true
+		if processEnv[key] || dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+	}
+	fileProfiles = file.Profiles
+	fileExperiments = file.Experiments
+	fileHooks = file.Hooks
+	return nil
+}
+
+// warnUnknownKeys reports config file settings that build never reads
+func warnUnknownKeys() {
+	var unknown []string
+	for key := range fileKeys {
+		if !knownKeys[key] {
+			unknown = append(unknown, strings.ToLower(key))
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		log.Printf("⚠️  Unknown setting in config file %s: %s", configFile, name)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+
+	"fingerprint-converter/internal/services"
+)
+
+// boundedRange is a range or deviation that can be checked against its field's bounds tag
+type boundedRange interface {
+	CheckBounds(bounds string) error
+}
+
+// getAFRanges applies AF_<MEDIA>_<LEVEL>_<PARAM> overrides to the built-in ranges
+// e.g. AF_VIDEO_PARANOID_CRF=21-25 or AF_AUDIO_MODERATE_PITCH_SHIFT=0.001
+// Overrides outside what the encoder accepts (the field's bounds tag) are rejected
+func getAFRanges() (services.AFRanges, []error) {
+	ranges := services.DefaultAFRanges()
+	var errs []error
+	walkRanges(reflect.ValueOf(&ranges).Elem(), "AF", func(key, bounds string, field reflect.Value) {
+		value := lookup(key)
+		if value == "" {
+			return
+		}
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+		if bounds == "" {
+			return
+		}
+		if err := field.Interface().(boundedRange).CheckBounds(bounds); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	})
+	return ranges, errs
+}
+
+// walkRanges calls fn for every range or deviation in v, keyed by the joined af tags, with its bounds tag
+func walkRanges(v reflect.Value, prefix string, fn func(key, bounds string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		tag := v.Type().Field(i).Tag
+		key := prefix + "_" + tag.Get("af")
+		field := v.Field(i)
+		if _, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			fn(key, tag.Get("bounds"), field)
+			continue
+		}
+		walkRanges(field, key, fn)
+	}
+}
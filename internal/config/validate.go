@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/services"
+)
+
+// Validate checks for settings that would fail or misbehave at runtime
+// Every problem is reported at once, each naming the variable to fix
+func (c *Config) Validate() error {
+	errs := slices.Clone(c.invalid)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a number between 1 and 65535 (got %q)", c.Port))
+	}
+	check(c.ReadTimeout > 0, "READ_TIMEOUT must be positive (got %v)", c.ReadTimeout)
+	check(c.WriteTimeout > 0, "WRITE_TIMEOUT must be positive (got %v)", c.WriteTimeout)
+	check(c.IdleTimeout > 0, "IDLE_TIMEOUT must be positive (got %v)", c.IdleTimeout)
+	check(c.ReadBufferSize >= 4096, "READ_BUFFER_SIZE must be at least 4096 bytes (got %d)", c.ReadBufferSize)
+	check(c.RequestTimeout > 0, "REQUEST_TIMEOUT must be positive (got %v)", c.RequestTimeout)
+	check(c.FFmpegThreads >= 0, "FFMPEG_THREADS must not be negative (got %d)", c.FFmpegThreads)
+	check(c.ShutdownGracePeriod > 0, "SHUTDOWN_GRACE_PERIOD must be positive (got %v)", c.ShutdownGracePeriod)
+	check(c.DownloadTimeout > 0, "DOWNLOAD_TIMEOUT must be positive (got %v)", c.DownloadTimeout)
+
+	check(c.BodyLimit > 0, "BODY_LIMIT must be a positive number of bytes (got %d)", c.BodyLimit)
+	check(c.CacheMaxPinned >= 0, "CACHE_MAX_PINNED must not be negative (got %d)", c.CacheMaxPinned)
+	check(c.MinFreeDisk >= 0, "MIN_FREE_DISK must be 0 or a number of bytes (got %d)", c.MinFreeDisk)
+	check(c.AdmissionMaxLoad >= 0, "ADMISSION_MAX_LOAD must not be negative (got %v)", c.AdmissionMaxLoad)
+	check(c.AdmissionMaxMemory >= 0 && c.AdmissionMaxMemory <= 1, "ADMISSION_MAX_MEMORY must be between 0 and 1 (got %v)", c.AdmissionMaxMemory)
+	check(c.AdmissionSampleInterval > 0, "ADMISSION_SAMPLE_INTERVAL must be positive (got %v)", c.AdmissionSampleInterval)
+	check(c.AdmissionMaxWait >= 0, "ADMISSION_MAX_WAIT must not be negative (got %v)", c.AdmissionMaxWait)
+	check(c.MaxDownloadSize > 0, "MAX_DOWNLOAD_SIZE must be a positive number of bytes (got %d)", c.MaxDownloadSize)
+	check(c.StreamMaxDuration > 0, "STREAM_MAX_DURATION must be positive (got %v)", c.StreamMaxDuration)
+	check(c.ResolverMode == "" || c.ResolverMode == "ytdlp", "RESOLVER_MODE must be ytdlp or empty (got %q)", c.ResolverMode)
+	check(c.ResolverMode == "" || c.ResolverTimeout > 0, "RESOLVER_TIMEOUT must be positive (got %v)", c.ResolverTimeout)
+	if _, err := remote.ParseCredentials(c.RemoteCredentials); err != nil {
+		errs = append(errs, fmt.Errorf("REMOTE_CREDENTIALS: %w", err))
+	}
+	for _, root := range c.LocalInputRoots {
+		if !filepath.IsAbs(root) {
+			errs = append(errs, fmt.Errorf("LOCAL_INPUT_ROOTS: %q must be an absolute path", root))
+		} else if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("LOCAL_INPUT_ROOTS: %q is not a directory", root))
+		}
+	}
+	check(services.IsValidReprocessMode(c.ReprocessMode), "REPROCESS_MODE must be process, skip or remux (got %q)", c.ReprocessMode)
+	check(c.ReprocessMode == services.ReprocessConvert || c.OutputMarkerSecret != "",
+		"REPROCESS_MODE=%s requires OUTPUT_MARKER_SECRET", c.ReprocessMode)
+	check(c.FFmpegChaosRate >= 0 && c.FFmpegChaosRate <= 1, "FFMPEG_CHAOS_RATE must be between 0 and 1 (got %v)", c.FFmpegChaosRate)
+	check(c.FFmpegReapGrace >= 0, "FFMPEG_REAP_GRACE must not be negative (got %v)", c.FFmpegReapGrace)
+	check(c.ProbeCacheSize >= 0, "PROBE_CACHE_SIZE must not be negative (got %d)", c.ProbeCacheSize)
+	if c.RemoteConverterAddr != "" {
+		for _, mediaType := range c.RemoteConverterMedia {
+			check(mediaType == "audio" || mediaType == "image" || mediaType == "video",
+				"REMOTE_CONVERTER_MEDIA: unknown media type %q (supported: audio, image, video)", mediaType)
+		}
+		check(len(c.RemoteConverterMedia) > 0, "REMOTE_CONVERTER_MEDIA must name at least one media type")
+	}
+	check(c.ArchiveMaxEntries > 0, "ARCHIVE_MAX_ENTRIES must be positive (got %d)", c.ArchiveMaxEntries)
+	check(c.ArchiveMaxSize > 0, "ARCHIVE_MAX_SIZE must be a positive number of bytes (got %d)", c.ArchiveMaxSize)
+	if _, err := ParseMemLimit(c.GoMemLimit); err != nil {
+		errs = append(errs, fmt.Errorf("GOMEMLIMIT: %w", err))
+	}
+	check(c.BufferPoolSize > 0, "BUFFER_POOL_SIZE must be positive (got %d)", c.BufferPoolSize)
+	check(c.BufferSize > 0, "BUFFER_SIZE must be a positive number of bytes (got %d)", c.BufferSize)
+
+	if c.EnableCache {
+		check(c.CacheTTL > 0, "CACHE_TTL must be positive (got %v)", c.CacheTTL)
+		check(c.FileTTL > c.CacheTTL,
+			"FILE_TTL (%v) must be longer than CACHE_TTL (%v), or files are deleted while the cache still returns them",
+			c.FileTTL, c.CacheTTL)
+		check(c.FailureTTL >= 0, "FAILURE_CACHE_TTL must not be negative (got %v)", c.FailureTTL)
+		check(slices.Contains([]string{"off", "lazy", "background"}, c.ReencodeStale),
+			"REENCODE_ON_PROFILE_CHANGE must be off, lazy or background (got %q)", c.ReencodeStale)
+		check(c.ReencodeStale != "background" || c.EnableWarmup,
+			"REENCODE_ON_PROFILE_CHANGE=background requires ENABLE_WARMUP")
+		for _, rule := range c.FileRetention {
+			check(rule.TTL > c.FileTTL-c.CacheTTL,
+				"FILE_RETENTION rule %s must keep files longer than FILE_TTL - CACHE_TTL (%v), the time the cache stops answering before deletion",
+				rule, c.FileTTL-c.CacheTTL)
+		}
+	}
+
+	checkLevel := func(name, level string) {
+		check(level == "" || services.IsValidLevel(level),
+			"%s must be none, basic, moderate or paranoid (got %q)", name, level)
+	}
+	checkLevel("DEFAULT_AF_LEVEL", c.DefaultAFLevel)
+	checkLevel("WATCH_LEVEL", c.WatchLevel)
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		if err := c.Profiles[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("profiles.%s: %w", name, err))
+		}
+	}
+	if err := services.ValidateExperiments(c.Experiments, c.Profiles); err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Hooks)) {
+		if err := c.Hooks[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("hooks.%s: %w", name, err))
+		}
+	}
+
+	if c.EnableJobs {
+		check(c.JobWorkers > 0, "JOB_WORKERS must be positive (got %d)", c.JobWorkers)
+		check(c.JobQueueSize > 0, "JOB_QUEUE_SIZE must be positive (got %d)", c.JobQueueSize)
+		check(c.JobMaxAttempts > 0, "JOB_MAX_ATTEMPTS must be at least 1 (got %d)", c.JobMaxAttempts)
+		check(c.JobMaxScheduleAhead > 0, "JOB_MAX_SCHEDULE_AHEAD must be positive (got %v)", c.JobMaxScheduleAhead)
+		check(c.JobBackend == "local" || c.JobBackend == "redis",
+			"JOB_BACKEND must be local or redis (got %q)", c.JobBackend)
+		check(c.JobBackend != "redis" || c.RedisURL != "", "JOB_BACKEND=redis requires REDIS_URL")
+	}
+
+	if c.EnableWarmup {
+		check(c.WarmupConcurrency > 0, "WARMUP_CONCURRENCY must be positive (got %d)", c.WarmupConcurrency)
+		check(c.WarmupQueueSize > 0, "WARMUP_QUEUE_SIZE must be positive (got %d)", c.WarmupQueueSize)
+		check(c.WarmupBusyRatio > 0 && c.WarmupBusyRatio <= 1,
+			"WARMUP_BUSY_RATIO must be between 0 and 1 (got %v)", c.WarmupBusyRatio)
+	}
+	check(c.WarmupManifest == "" || c.EnableWarmup, "WARMUP_MANIFEST requires ENABLE_WARMUP=true")
+
+	check(c.PackageSegmentDuration >= time.Second, "PACKAGE_SEGMENT_DURATION must be at least 1s (got %v)", c.PackageSegmentDuration)
+	if c.PackageUploadURL != "" {
+		if u, err := url.Parse(c.PackageUploadURL); err != nil || !slices.Contains([]string{"http", "https", "ftp", "sftp"}, u.Scheme) || u.Host == "" {
+			errs = append(errs, fmt.Errorf("PACKAGE_UPLOAD_URL must be an http(s), ftp or sftp URL (got %q)", redactURL(c.PackageUploadURL)))
+		}
+	}
+
+	switch c.CDNProvider {
+	case "":
+	case "cloudflare":
+		check(c.CDNZoneID != "" && c.CDNAPIToken != "", "CDN_PROVIDER=cloudflare requires CDN_ZONE_ID and CDN_API_TOKEN")
+	case "fastly":
+		check(c.CDNAPIToken != "", "CDN_PROVIDER=fastly requires CDN_API_TOKEN")
+	case "cloudfront":
+		check(c.CDNZoneID != "" && c.AWSAccessKeyID != "" && c.AWSSecretAccessKey != "",
+			"CDN_PROVIDER=cloudfront requires CDN_ZONE_ID (distribution ID), AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	default:
+		errs = append(errs, fmt.Errorf("CDN_PROVIDER must be cloudflare, fastly, cloudfront or empty (got %q)", c.CDNProvider))
+	}
+	if c.CDNPublicBaseURL != "" {
+		if u, err := url.Parse(c.CDNPublicBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("CDN_PUBLIC_BASE_URL must be an http(s) URL (got %q)", redactURL(c.CDNPublicBaseURL)))
+		}
+	}
+
+	check(c.ConsumerMode == "" || c.ConsumerMode == "kafka" || c.ConsumerMode == "rabbitmq",
+		"CONSUMER_MODE must be kafka, rabbitmq or empty (got %q)", c.ConsumerMode)
+	check(c.ConsumerMode == "" || c.ConsumerConcurrency > 0,
+		"CONSUMER_CONCURRENCY must be positive (got %d)", c.ConsumerConcurrency)
+	check(c.ScanMode == "" || c.ScanMode == "clamav", "SCAN_MODE must be clamav or empty (got %q)", c.ScanMode)
+
+	check(c.SlowConversionAudio >= 0, "SLOW_CONVERSION_AUDIO must not be negative (got %v)", c.SlowConversionAudio)
+	check(c.SlowConversionImage >= 0, "SLOW_CONVERSION_IMAGE must not be negative (got %v)", c.SlowConversionImage)
+	check(c.SlowConversionVideo >= 0, "SLOW_CONVERSION_VIDEO must not be negative (got %v)", c.SlowConversionVideo)
+	check(c.HistorySize >= 0, "HISTORY_SIZE must not be negative (got %d)", c.HistorySize)
+	check(c.HistorySize == 0 || c.HistoryMaxDevices > 0,
+		"HISTORY_MAX_DEVICES must be positive (got %d)", c.HistoryMaxDevices)
+
+	check(c.MaxImageSize >= 0, "MAX_IMAGE_SIZE must not be negative (got %d)", c.MaxImageSize)
+	check(c.MaxAudioSize >= 0, "MAX_AUDIO_SIZE must not be negative (got %d)", c.MaxAudioSize)
+	check(c.MaxVideoSize >= 0, "MAX_VIDEO_SIZE must not be negative (got %d)", c.MaxVideoSize)
+	check(c.MaxImageMegapixels >= 0, "MAX_IMAGE_MEGAPIXELS must not be negative (got %v)", c.MaxImageMegapixels)
+	if _, _, err := services.ParseMaxResolution(c.MaxVideoResolution); err != nil {
+		errs = append(errs, fmt.Errorf("MAX_VIDEO_RESOLUTION: %w", err))
+	}
+
+	check(c.QualityMinSSIM >= 0 && c.QualityMinSSIM <= 1, "QUALITY_MIN_SSIM must be between 0 and 1 (got %v)", c.QualityMinSSIM)
+	check(c.QualityMinPSNR >= 0, "QUALITY_MIN_PSNR must not be negative (got %v)", c.QualityMinPSNR)
+	if c.DownloadBreaker {
+		check(c.DownloadBreakerFailureRate > 0 && c.DownloadBreakerFailureRate <= 1,
+			"DOWNLOAD_BREAKER_FAILURE_RATE must be between 0 and 1 (got %v)", c.DownloadBreakerFailureRate)
+		check(c.DownloadBreakerMinRequests > 0, "DOWNLOAD_BREAKER_MIN_REQUESTS must be positive (got %d)", c.DownloadBreakerMinRequests)
+		check(c.DownloadBreakerWindow > 0, "DOWNLOAD_BREAKER_WINDOW must be positive (got %s)", c.DownloadBreakerWindow)
+		check(c.DownloadBreakerCooldown > 0, "DOWNLOAD_BREAKER_COOLDOWN must be positive (got %s)", c.DownloadBreakerCooldown)
+	}
+	if c.FFmpegBreaker {
+		check(c.FFmpegBreakerFailureRate > 0 && c.FFmpegBreakerFailureRate <= 1,
+			"FFMPEG_BREAKER_FAILURE_RATE must be between 0 and 1 (got %v)", c.FFmpegBreakerFailureRate)
+		check(c.FFmpegBreakerMinRequests > 0, "FFMPEG_BREAKER_MIN_REQUESTS must be positive (got %d)", c.FFmpegBreakerMinRequests)
+		check(c.FFmpegBreakerWindow > 0, "FFMPEG_BREAKER_WINDOW must be positive (got %s)", c.FFmpegBreakerWindow)
+		check(c.FFmpegBreakerCooldown > 0, "FFMPEG_BREAKER_COOLDOWN must be positive (got %s)", c.FFmpegBreakerCooldown)
+	}
+	check(c.VerifyDurationTolerance >= 0, "VERIFY_DURATION_TOLERANCE must not be negative (got %s)", c.VerifyDurationTolerance)
+	check(c.QualityMinVMAF >= 0 && c.QualityMinVMAF <= 100, "QUALITY_MIN_VMAF must be between 0 and 100 (got %v)", c.QualityMinVMAF)
+
+	check(!c.EnableCORS || !c.CORSAllowCredentials || !slices.Contains(c.CORSAllowedOrigins, "*"),
+		"CORS_ALLOW_CREDENTIALS requires an explicit CORS_ALLOWED_ORIGINS list (not *)")
+	check(!c.EnableCompression || slices.Contains([]string{"speed", "default", "best"}, strings.ToLower(c.CompressionLevel)),
+		"COMPRESSION_LEVEL must be speed, default or best (got %q)", c.CompressionLevel)
+
+	return errors.Join(errs...)
+}
+
+// secretFields are never shown by Effective
+var secretFields = map[string]bool{
+	"AdminToken":           true,
+	"PackageUploadAuth":    true,
+	"RemoteCredentials":    true,
+	"RemoteConverterToken": true,
+	"OutputMarkerSecret":   true,
+	"CDNAPIToken":          true,
+	"AWSSecretAccessKey":   true,
+	"AWSSessionToken":      true,
+}
+
+// Effective returns the configuration as snake_case keys for display
+// Secrets are redacted, and so are credentials and query strings in URLs
+func (c *Config) Effective() map[string]any {
+	effective := make(map[string]any)
+	value := reflect.ValueOf(*c)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		v := value.Field(i).Interface()
+
+		switch typed := v.(type) {
+		case time.Duration:
+			v = typed.String()
+		case string:
+			switch {
+			case secretFields[field.Name] && typed != "":
+				v = "REDACTED"
+			case strings.HasSuffix(field.Name, "URL"):
+				v = redactURL(typed)
+			}
+		}
+		effective[snakeCase(field.Name)] = v
+	}
+	return effective
+}
+
+// redactURL hides the password and query string of a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.Redacted()
+}
+
+// snakeCase converts a Go field name to snake_case, keeping acronyms together
+// (CORSMaxAge -> cors_max_age, GoMemLimit -> go_mem_limit)
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// ParseMemLimit parses GOMEMLIMIT in the runtime's format: bytes with an optional
+// B, KiB, MiB, GiB or TiB suffix, or "off" for no limit
+func ParseMemLimit(raw string) (int64, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" || strings.EqualFold(value, "off") {
+		return math.MaxInt64, nil
+	}
+
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1},
+	}
+	scale := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, scale = strings.TrimSuffix(value, unit.suffix), unit.scale
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("invalid memory limit %q (e.g. 2GiB, 512MiB or off)", raw)
+	}
+	return n * scale, nil
+}
@@ -0,0 +1,175 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/tenant"
+)
+
+// Processor runs a single conversion for a consumed message
+type Processor func(ctx context.Context, req *models.ConvertRequest) (*models.ConvertResponse, error)
+
+// Delivery is a message fetched from a broker
+type Delivery struct {
+	Body          []byte
+	CorrelationID string // Kafka key or AMQP correlation ID, echoed on the response
+	ReplyTo       string // Optional reply destination (AMQP reply_to)
+	ack           func() error
+}
+
+// Broker is the message system requests are consumed from and results published to
+type Broker interface {
+	// Fetch blocks until a message is available or ctx is done
+	Fetch(ctx context.Context) (*Delivery, error)
+	// Publish sends a result; replyTo overrides the configured response destination
+	Publish(ctx context.Context, correlationID, replyTo string, body []byte) error
+	Close() error
+}
+
+// Consumer reads ConvertRequest messages from a broker, converts them and publishes the results
+type Consumer struct {
+	broker      Broker
+	process     Processor
+	concurrency int
+	timeout     time.Duration
+	processed   int64
+	failed      int64
+}
+
+// Stats reports consumer activity
+type Stats struct {
+	Processed int64
+	Failed    int64
+}
+
+// New creates a consumer; call Run to start consuming
+func New(broker Broker, process Processor, concurrency int, timeout time.Duration) *Consumer {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	return &Consumer{
+		broker:      broker,
+		process:     process,
+		concurrency: concurrency,
+		timeout:     timeout,
+	}
+}
+
+// Run consumes messages until ctx is cancelled, then closes the broker
+func (c *Consumer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if err := c.broker.Close(); err != nil {
+		log.Printf("⚠️  Failed to close broker: %v", err)
+	}
+	log.Println("🛑 Consumer stopped")
+}
+
+func (c *Consumer) loop(ctx context.Context) {
+	for {
+		delivery, err := c.broker.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("⚠️  Consumer fetch failed: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		c.handle(ctx, delivery)
+	}
+}
+
+// handle converts one message, publishes the result and acknowledges it
+// Failed conversions are published as error results so one bad message can't block the queue
+func (c *Consumer) handle(ctx context.Context, delivery *Delivery) {
+	var msg models.QueueRequest
+	result := models.QueueResult{}
+
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		result.Error = "Invalid message"
+		result.Code = apierr.InvalidRequest
+		result.Details = err.Error()
+	} else {
+		result.RequestID = msg.RequestID
+
+		// The message's request_id doubles as the request ID logged and forwarded downstream
+		procCtx := audit.WithCaller(tenant.WithID(ctx, msg.TenantID), "queue:"+msg.RequestID)
+		procCtx = reqid.WithID(procCtx, msg.RequestID)
+		procCtx, cancel := context.WithTimeout(procCtx, c.timeout)
+		resp, err := c.process(procCtx, &msg.ConvertRequest)
+		cancel()
+
+		if err != nil {
+			result.Error = err.Error()
+			result.Code = apierr.CodeOf(err)
+		} else {
+			result.Success = true
+			result.Result = resp
+		}
+	}
+
+	if result.Success {
+		atomic.AddInt64(&c.processed, 1)
+		log.Printf("✅ Consumed message: request_id=%s", result.RequestID)
+	} else {
+		atomic.AddInt64(&c.failed, 1)
+		log.Printf("❌ Consumed message failed: request_id=%s, error=%s", result.RequestID, result.Error)
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("⚠️  Failed to encode result: %v", err)
+		return
+	}
+
+	correlationID := delivery.CorrelationID
+	if correlationID == "" {
+		correlationID = result.RequestID
+	}
+	if err := c.broker.Publish(ctx, correlationID, delivery.ReplyTo, body); err != nil {
+		// Leave the message unacknowledged so it is redelivered
+		log.Printf("⚠️  Failed to publish result: %v", err)
+		return
+	}
+
+	if delivery.ack != nil {
+		if err := delivery.ack(); err != nil {
+			log.Printf("⚠️  Failed to acknowledge message: %v", err)
+		}
+	}
+}
+
+// GetStats returns current statistics
+func (c *Consumer) GetStats() Stats {
+	return Stats{
+		Processed: atomic.LoadInt64(&c.processed),
+		Failed:    atomic.LoadInt64(&c.failed),
+	}
+}
@@ -0,0 +1,76 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBroker consumes from a request topic and publishes to a response topic
+type KafkaBroker struct {
+	reader *kafka.Reader
+	writer *kafka.Writer
+}
+
+// NewKafkaBroker creates a Kafka broker in the given consumer group
+func NewKafkaBroker(brokers []string, groupID, requestTopic, responseTopic string) (*KafkaBroker, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers configured")
+	}
+	if requestTopic == "" || responseTopic == "" {
+		return nil, fmt.Errorf("Kafka request and response topics are required")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  brokers,
+		GroupID:  groupID,
+		Topic:    requestTopic,
+		MinBytes: 1,
+		MaxBytes: 10 * 1024 * 1024, // Requests are small; media is referenced by URL
+		MaxWait:  time.Second,
+	})
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        responseTopic,
+		Balancer:     &kafka.Hash{}, // Same key, same partition
+		RequiredAcks: kafka.RequireAll,
+	}
+
+	return &KafkaBroker{reader: reader, writer: writer}, nil
+}
+
+// Fetch returns the next message; it is committed once the result is published
+func (b *KafkaBroker) Fetch(ctx context.Context) (*Delivery, error) {
+	msg, err := b.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{
+		Body:          msg.Value,
+		CorrelationID: string(msg.Key),
+		ack: func() error {
+			return b.reader.CommitMessages(context.Background(), msg)
+		},
+	}, nil
+}
+
+// Publish writes a result to the response topic (replyTo is ignored)
+func (b *KafkaBroker) Publish(ctx context.Context, correlationID, _ string, body []byte) error {
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(correlationID),
+		Value: body,
+	})
+}
+
+// Close closes the reader and writer
+func (b *KafkaBroker) Close() error {
+	readerErr := b.reader.Close()
+	if err := b.writer.Close(); err != nil {
+		return err
+	}
+	return readerErr
+}
@@ -0,0 +1,101 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQBroker consumes from a request queue and publishes to a response queue
+type RabbitMQBroker struct {
+	conn          *amqp.Connection
+	channel       *amqp.Channel
+	deliveries    <-chan amqp.Delivery
+	responseQueue string
+}
+
+// NewRabbitMQBroker connects to RabbitMQ and declares both queues (durable)
+// prefetch bounds how many unacknowledged messages this instance holds
+func NewRabbitMQBroker(url, requestQueue, responseQueue string, prefetch int) (*RabbitMQBroker, error) {
+	if requestQueue == "" || responseQueue == "" {
+		return nil, fmt.Errorf("RabbitMQ request and response queues are required")
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	for _, queue := range []string{requestQueue, responseQueue} {
+		if _, err := channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to declare queue %s: %w", queue, err)
+		}
+	}
+
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	deliveries, err := channel.Consume(requestQueue, "", false, false, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to consume %s: %w", requestQueue, err)
+	}
+
+	return &RabbitMQBroker{
+		conn:          conn,
+		channel:       channel,
+		deliveries:    deliveries,
+		responseQueue: responseQueue,
+	}, nil
+}
+
+// Fetch returns the next delivery; it is acknowledged once the result is published
+func (b *RabbitMQBroker) Fetch(ctx context.Context) (*Delivery, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-b.deliveries:
+		if !ok {
+			return nil, fmt.Errorf("RabbitMQ delivery channel closed")
+		}
+		return &Delivery{
+			Body:          msg.Body,
+			CorrelationID: msg.CorrelationId,
+			ReplyTo:       msg.ReplyTo,
+			ack: func() error {
+				return msg.Ack(false)
+			},
+		}, nil
+	}
+}
+
+// Publish sends a result to replyTo, or to the response queue if unset
+func (b *RabbitMQBroker) Publish(ctx context.Context, correlationID, replyTo string, body []byte) error {
+	queue := b.responseQueue
+	if replyTo != "" {
+		queue = replyTo
+	}
+
+	return b.channel.PublishWithContext(ctx, "", queue, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: correlationID,
+		Body:          body,
+	})
+}
+
+// Close closes the channel and connection
+func (b *RabbitMQBroker) Close() error {
+	b.channel.Close()
+	return b.conn.Close()
+}
@@ -0,0 +1,176 @@
+package devices
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+var devicesBucket = []byte("devices")
+
+// Store keeps registered devices per tenant in an embedded bbolt database
+// A nil Store has no devices
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens (or creates) the device database at path
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create device store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open device store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(devicesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize device store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// tenantPrefix groups a tenant's devices so listing is a prefix scan
+func tenantPrefix(tenantID string) []byte {
+	return []byte(tenantID + "\x00")
+}
+
+func deviceKey(tenantID, deviceID string) []byte {
+	return append(tenantPrefix(tenantID), deviceID...)
+}
+
+// Get returns a registered device, or nil when it isn't registered
+func (s *Store) Get(tenantID, deviceID string) (*models.Device, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	var device *models.Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(devicesBucket).Get(deviceKey(tenantID, deviceID))
+		if data == nil {
+			return nil
+		}
+		device = &models.Device{}
+		return json.Unmarshal(data, device)
+	})
+	return device, err
+}
+
+// Put registers a device or replaces its settings, keeping the original registration time
+func (s *Store) Put(tenantID string, device models.Device) (models.Device, error) {
+	if s == nil {
+		return device, fmt.Errorf("device registry is disabled")
+	}
+
+	key := deviceKey(tenantID, device.DeviceID)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(devicesBucket)
+
+		now := time.Now().UTC()
+		device.CreatedAt = now
+		device.UpdatedAt = now
+		if data := bucket.Get(key); data != nil {
+			var existing models.Device
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return err
+			}
+			device.CreatedAt = existing.CreatedAt
+		}
+
+		data, err := json.Marshal(device)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+	return device, err
+}
+
+// List returns a tenant's devices ordered by device ID
+func (s *Store) List(tenantID string) ([]models.Device, error) {
+	devices := []models.Device{}
+	if s == nil {
+		return devices, nil
+	}
+
+	prefix := tenantPrefix(tenantID)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(devicesBucket).Cursor()
+		for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			var device models.Device
+			if err := json.Unmarshal(data, &device); err != nil {
+				return err
+			}
+			devices = append(devices, device)
+		}
+		return nil
+	})
+	return devices, err
+}
+
+// Delete unregisters a device; false when it wasn't registered
+func (s *Store) Delete(tenantID, deviceID string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+
+	found := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(devicesBucket)
+		key := deviceKey(tenantID, deviceID)
+		if bucket.Get(key) == nil {
+			return nil
+		}
+		found = true
+		return bucket.Delete(key)
+	})
+	return found, err
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// AllowsMediaType reports whether a device may convert mediaType (nil or no list = all)
+func AllowsMediaType(device *models.Device, mediaType string) bool {
+	return device == nil || len(device.AllowedMediaTypes) == 0 || slices.Contains(device.AllowedMediaTypes, mediaType)
+}
+
+// DefaultLevel returns a device's AF level for mediaType ("" = fall back to the tenant and media defaults)
+// A bound profile wins over the device's default_level; a profile removed from the config is skipped
+func DefaultLevel(device *models.Device, mediaType string) string {
+	if device == nil {
+		return ""
+	}
+	if device.Profile != "" {
+		profile, ok := services.Profile(device.Profile)
+		if !ok {
+			log.Printf("⚠️  Device %s is bound to unknown profile %q, ignoring it", device.DeviceID, device.Profile)
+		} else if level := profile.LevelFor(mediaType); level != "" {
+			return level
+		}
+	}
+	return device.DefaultLevel
+}
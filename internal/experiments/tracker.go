@@ -0,0 +1,135 @@
+package experiments
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// variantKey identifies one variant of one experiment
+type variantKey struct {
+	experiment string
+	variant    string
+}
+
+// counters are the outcomes of one variant
+type counters struct {
+	requests       int64
+	failures       int64
+	cacheHits      int64
+	conversions    int64
+	originalBytes  int64
+	processedBytes int64
+	durationMs     int64
+}
+
+// Tracker aggregates per-variant outcomes of A/B experiments in memory
+// It is an audit sink: entries tagged with an experiment are counted
+type Tracker struct {
+	mu       sync.Mutex
+	variants map[variantKey]*counters
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{variants: make(map[variantKey]*counters)}
+}
+
+// Write counts an audit entry (audit.Sink)
+func (t *Tracker) Write(entry *models.AuditEntry) error {
+	if t == nil || entry.Experiment == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := variantKey{entry.Experiment, entry.Variant}
+	c := t.variants[key]
+	if c == nil {
+		c = &counters{}
+		t.variants[key] = c
+	}
+
+	c.requests++
+	switch {
+	case !entry.Success:
+		c.failures++
+	case entry.CacheHit:
+		c.cacheHits++
+	default:
+		c.conversions++
+		c.originalBytes += entry.OriginalSize
+		c.processedBytes += entry.ProcessedSize
+		c.durationMs += entry.DurationMs
+	}
+	return nil
+}
+
+// Close is a no-op; stats live only in memory (audit.Sink)
+func (t *Tracker) Close() error {
+	return nil
+}
+
+// Report lists the defined experiments with their variant stats, followed by
+// experiments that were removed from the config but still have stats
+func (t *Tracker) Report(defined map[string]services.Experiment) []models.ExperimentReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reports := []models.ExperimentReport{}
+	for _, name := range slices.Sorted(maps.Keys(defined)) {
+		experiment := defined[name]
+		report := models.ExperimentReport{Name: name, MediaTypes: experiment.MediaTypes, Active: true}
+		for _, variant := range experiment.Variants {
+			stats := t.variantReport(variantKey{name, variant.Name})
+			stats.Profile = variant.Profile
+			stats.Weight = max(variant.Weight, 1)
+			report.Variants = append(report.Variants, stats)
+		}
+		reports = append(reports, report)
+	}
+
+	removed := make(map[string][]string)
+	for key := range t.variants {
+		if _, ok := defined[key.experiment]; !ok {
+			removed[key.experiment] = append(removed[key.experiment], key.variant)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(removed)) {
+		report := models.ExperimentReport{Name: name}
+		for _, variant := range slices.Sorted(slices.Values(removed[name])) {
+			report.Variants = append(report.Variants, t.variantReport(variantKey{name, variant}))
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// variantReport computes rates and averages; callers hold t.mu
+func (t *Tracker) variantReport(key variantKey) models.VariantReport {
+	report := models.VariantReport{Name: key.variant}
+	c := t.variants[key]
+	if c == nil {
+		return report
+	}
+
+	report.Requests = c.requests
+	report.Failures = c.failures
+	report.CacheHits = c.cacheHits
+	report.Conversions = c.conversions
+	if c.requests > 0 {
+		report.SuccessRate = float64(c.requests-c.failures) / float64(c.requests)
+	}
+	if c.conversions > 0 {
+		report.AvgProcessedSize = c.processedBytes / c.conversions
+		report.AvgDurationMs = c.durationMs / c.conversions
+	}
+	if c.originalBytes > 0 {
+		report.AvgSizeIncreasePercent = float64(c.processedBytes-c.originalBytes) / float64(c.originalBytes) * 100
+	}
+	return report
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/models"
+)
+
+// RequireAdminToken guards admin endpoints with a shared token
+// (X-Admin-Token or Authorization: Bearer)
+func RequireAdminToken(token string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		provided := c.Get("X-Admin-Token")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return apierr.Write(c, fiber.StatusUnauthorized, apierr.Unauthorized,
+				"Invalid or missing admin token", "")
+		}
+		return c.Next()
+	}
+}
+
+// AdminHandler serves operator endpoints
+type AdminHandler struct {
+	auditLog  *audit.FileSink
+	reload    func() ([]string, error)
+	effective func() map[string]any
+}
+
+// NewAdminHandler creates a new admin handler; auditLog may be nil when auditing is disabled
+// reload applies runtime tunables and describes what changed (nil disables POST /admin/reload)
+// effective returns the redacted configuration in effect (nil disables GET /api/admin/config)
+func NewAdminHandler(auditLog *audit.FileSink, reload func() ([]string, error), effective func() map[string]any) *AdminHandler {
+	return &AdminHandler{auditLog: auditLog, reload: reload, effective: effective}
+}
+
+// Config handles GET /api/admin/config
+// Returns the effective configuration (after env, .env, file and reloads) with secrets redacted
+func (h *AdminHandler) Config(c fiber.Ctx) error {
+	if h.effective == nil {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.FeatureDisabled, "Config endpoint is disabled", "")
+	}
+	return c.JSON(h.effective())
+}
+
+// Reload handles POST /admin/reload
+// Same as SIGHUP: re-reads .env/environment and the tenants file without dropping conversions
+func (h *AdminHandler) Reload(c fiber.Ctx) error {
+	if h.reload == nil {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.FeatureDisabled, "Reload is disabled", "")
+	}
+
+	changes, err := h.reload()
+	if err != nil {
+		return apierr.Write(c, fiber.StatusUnprocessableEntity, apierr.InvalidRequest,
+			"Reload failed", err.Error())
+	}
+	if changes == nil {
+		changes = []string{}
+	}
+
+	return c.JSON(models.ReloadResponse{
+		Success: true,
+		Changes: changes,
+	})
+}
+
+// Audit handles GET /admin/audit?date=&device_id=&tenant_id=&limit=
+// date is YYYY-MM-DD (UTC, default today); entries are newest first
+func (h *AdminHandler) Audit(c fiber.Ctx) error {
+	if h.auditLog == nil {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.FeatureDisabled, "Audit log is disabled", "")
+	}
+
+	date := time.Now().UTC()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse(audit.DateLayout, raw)
+		if err != nil {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"Invalid date", "Expected format: YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"limit must be between 1 and 1000", "")
+		}
+		limit = parsed
+	}
+
+	entries, err := h.auditLog.Query(audit.Query{
+		Date:     date,
+		TenantID: c.Query("tenant_id"),
+		DeviceID: c.Query("device_id"),
+		Limit:    limit,
+	})
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to query audit log", err.Error())
+	}
+
+	return c.JSON(models.AuditListResponse{
+		Date:    date.Format(audit.DateLayout),
+		Entries: entries,
+		Count:   len(entries),
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+)
+
+// ConvertArchive handles POST /api/convert/archive
+// Every media file of the zip goes through the regular pipeline (cached by content, audited on its own);
+// one failing entry doesn't fail the others. With ?download=true (or zip) the outputs come back as a zip with a manifest.
+func (h *ConverterHandler) ConvertArchive(c fiber.Ctx) error {
+	start := time.Now()
+
+	var req models.ArchiveRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+	downloadMode := c.Query("download") == "true" || c.Query("download") == "zip"
+
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+
+	entries, skipped, err := h.loadArchive(ctx, &req)
+	if err != nil {
+		return respondError(c, err)
+	}
+	reqid.Printf(ctx, "🗜️ ARCHIVE: device=%s, entries=%d, skipped=%d", req.DeviceID, len(entries), len(skipped))
+
+	resp := models.ArchiveResponse{Entries: make([]models.ArchiveEntryResult, len(entries))}
+	for _, skip := range skipped {
+		resp.Skipped = append(resp.Skipped, models.ArchiveSkipped{Name: skip.Name, Reason: skip.Reason})
+	}
+
+	var firstErr error
+	bundle := newBundleBuilder()
+	for i, entry := range entries {
+		result := models.ArchiveEntryResult{Name: entry.Name, MediaType: entry.MediaType}
+		entryReq := models.ConvertRequest{
+			DeviceID:             req.DeviceID,
+			MediaType:            entry.MediaType,
+			AntiFingerprintLevel: req.AntiFingerprintLevel,
+			Profile:              req.Profile,
+			MaxResolution:        req.MaxResolution,
+			FrameRate:            req.FrameRate,
+			AudioFormat:          req.AudioFormat,
+			ImageFormat:          req.ImageFormat,
+		}
+
+		converted, err := h.ProcessData(ctx, &entryReq, entry.Name, entry.Data)
+		entries[i].Data = nil // Release the input as soon as it is converted
+		if err != nil {
+			reqErr := asRequestError(err)
+			result.Error, result.Code = reqErr.Error(), reqErr.Code
+			resp.Failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			bundle.addFailure(entry.Name, entry.MediaType, err)
+			reqid.Printf(ctx, "❌ Archive entry failed: device=%s, entry=%s, error=%v", req.DeviceID, entry.Name, err)
+		} else {
+			result.Success = true
+			result.Result = converted
+			resp.Converted++
+			bundle.add(entry.Name, strings.TrimSuffix(entry.Name, path.Ext(entry.Name)), converted)
+		}
+		resp.Entries[i] = result
+	}
+
+	resp.Success = resp.Failed == 0
+	resp.ProcessingTime = fmt.Sprintf("%d", time.Since(start).Milliseconds())
+	reqid.Printf(ctx, "✅ ARCHIVE DONE: device=%s, converted=%d, failed=%d, time=%sms",
+		req.DeviceID, resp.Converted, resp.Failed, resp.ProcessingTime)
+
+	if downloadMode {
+		if resp.Converted == 0 {
+			return respondError(c, firstErr)
+		}
+		return bundle.send(c, "converted.zip")
+	}
+	return c.JSON(resp)
+}
+
+// loadArchive downloads or decodes (base64 or data: URI) the request's zip and extracts its media files
+func (h *ConverterHandler) loadArchive(ctx context.Context, req *models.ArchiveRequest) ([]services.ArchiveEntry, []services.SkippedEntry, error) {
+	var data []byte
+	var err error
+	if req.Data != "" || services.IsDataURI(req.URL) {
+		data, err = decodeData(&models.ConvertRequest{URL: req.URL, Data: req.Data})
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		data, err = h.downloader.Download(ctx, req.URL)
+		if err != nil {
+			return nil, nil, downloadError("Failed to download archive", err)
+		}
+	}
+
+	if !services.IsZipArchive(data) {
+		return nil, nil, newRequestError(fiber.StatusUnprocessableEntity, apierr.InvalidArchive,
+			"Invalid archive", services.ErrInvalidArchive.Error())
+	}
+	entries, skipped, err := services.ExtractArchive(data, h.archiveLimits)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidArchive) {
+			return nil, nil, wrapRequestError(fiber.StatusUnprocessableEntity, apierr.InvalidArchive, "Invalid archive", err)
+		}
+		return nil, nil, inspectionError(err)
+	}
+	return entries, skipped, nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/models"
+)
+
+// bundleManifestName is the manifest file at the root of every bundle
+const bundleManifestName = "manifest.json"
+
+// sendResponseBundle sends every output of resp (one for single-output requests) as a zip with a manifest
+func sendResponseBundle(c fiber.Ctx, resp *models.ConvertResponse) error {
+	bundle := newBundleBuilder()
+	if len(resp.Outputs) == 0 {
+		name := filepath.Base(resp.ProcessedPath)
+		bundle.add(name, strings.TrimSuffix(name, filepath.Ext(name)), resp)
+	}
+	// Output names come from the caller, so they can't be allowed to form paths inside the zip
+	flatten := strings.NewReplacer("/", "_", "\\", "_")
+	for i := range resp.Outputs {
+		bundle.add(resp.Outputs[i].Name, flatten.Replace(resp.Outputs[i].Name), &resp.Outputs[i].ConvertResponse)
+	}
+	return bundle.send(c, "outputs.zip")
+}
+
+// bundleFile is a processed output placed in a bundle
+type bundleFile struct {
+	name string // Path inside the zip
+	path string // Processed file on disk
+}
+
+// bundleBuilder collects outputs and their manifest entries for a zip download
+type bundleBuilder struct {
+	files    []bundleFile
+	manifest models.BundleManifest
+	used     map[string]bool
+}
+
+func newBundleBuilder() *bundleBuilder {
+	return &bundleBuilder{used: map[string]bool{bundleManifestName: true}}
+}
+
+// add places resp's output in the bundle as base plus the output's extension
+func (b *bundleBuilder) add(source, base string, resp *models.ConvertResponse) {
+	name := b.uniqueName(base, resp.ProcessedPath)
+	b.files = append(b.files, bundleFile{name: name, path: resp.ProcessedPath})
+	b.manifest.Converted++
+	b.manifest.Files = append(b.manifest.Files, models.BundleEntry{
+		File:           name,
+		Source:         source,
+		MediaType:      resp.MediaType,
+		Success:        true,
+		CacheHit:       resp.CacheHit,
+		Fallback:       resp.Fallback,
+		OriginalSize:   resp.OriginalSize,
+		ProcessedSize:  resp.ProcessedSize,
+		ProcessingTime: resp.ProcessingTime,
+		Quality:        resp.Quality,
+	})
+}
+
+// addFailure lists source in the manifest without a file
+func (b *bundleBuilder) addFailure(source, mediaType string, err error) {
+	reqErr := asRequestError(err)
+	b.manifest.Failed++
+	b.manifest.Files = append(b.manifest.Files, models.BundleEntry{
+		Source:    source,
+		MediaType: mediaType,
+		Error:     reqErr.Error(),
+		Code:      reqErr.Code,
+	})
+}
+
+// uniqueName appends the output's extension to base, suffixing a counter when two outputs collide
+func (b *bundleBuilder) uniqueName(base, outputPath string) string {
+	ext := filepath.Ext(outputPath)
+	name := base + ext
+	for i := 2; b.used[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	b.used[name] = true
+	return name
+}
+
+// send streams the bundle as filename; the manifest comes first so it can be read before the media
+// Outputs are already compressed media, so they are stored rather than deflated
+func (b *bundleBuilder) send(c fiber.Ctx, filename string) error {
+	b.manifest.Created = time.Now().UTC().Format(time.RFC3339)
+
+	c.Set("X-Bundle-Converted", strconv.Itoa(b.manifest.Converted))
+	c.Set("X-Bundle-Failed", strconv.Itoa(b.manifest.Failed))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Attachment(filename)
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(b.write(writer))
+	}()
+	return c.SendStream(reader)
+}
+
+// write writes the manifest and the outputs to w as a zip
+func (b *bundleBuilder) write(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	manifest, err := archive.Create(bundleManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(b.manifest); err != nil {
+		return err
+	}
+
+	for _, file := range b.files {
+		if err := addBundleFile(archive, file); err != nil {
+			log.Printf("❌ Failed to add %s to bundle: %v", file.name, err)
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addBundleFile copies file into archive
+func addBundleFile(archive *zip.Writer, file bundleFile) error {
+	src, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/compress"
+	"github.com/klauspost/compress/zstd"
+
+	"fingerprint-converter/internal/apierr"
+)
+
+// CompressionLevel maps COMPRESSION_LEVEL (speed, default, best) to a compress level
+func CompressionLevel(name string) compress.Level {
+	switch strings.ToLower(name) {
+	case "default":
+		return compress.LevelDefault
+	case "best":
+		return compress.LevelBestCompression
+	default:
+		return compress.LevelBestSpeed
+	}
+}
+
+// SkipCompression excludes WebSocket upgrades and downloads from response compression
+// Media responses are skipped by content type (audio/*, video/*, image/* are never compressed), but zip
+// bundles are application/zip, so downloads are skipped by their query; the media inside won't shrink
+func SkipCompression(c fiber.Ctx) bool {
+	if download := c.Query("download"); download == "true" || download == "zip" {
+		return true
+	}
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket")
+}
+
+// DecompressBody inflates gzip/zstd request bodies (Content-Encoding) so large base64
+// payloads can be sent compressed. The inflated size is capped at limit to defuse
+// compression bombs. Paths in skip decode the body stream themselves.
+func DecompressBody(limit int, skip ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		encoding := strings.ToLower(strings.TrimSpace(c.Get(fiber.HeaderContentEncoding)))
+		if encoding == "" || encoding == "identity" || slices.Contains(skip, c.Path()) {
+			return c.Next()
+		}
+
+		// Request().Body() is the raw payload; c.Body() would inflate it without a size cap
+		reader, err := decodingReader(encoding, bytes.NewReader(c.Request().Body()))
+		if err != nil {
+			return respondError(c, err)
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+		if err != nil {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"Failed to decompress request body", err.Error())
+		}
+		if len(data) > limit {
+			return apierr.Write(c, fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge,
+				"Decompressed request body too large", fmt.Sprintf("max: %d bytes", limit))
+		}
+
+		c.Request().SetBody(data)
+		c.Request().Header.Del(fiber.HeaderContentEncoding)
+		return c.Next()
+	}
+}
+
+// decodingReader returns a reader that decodes r according to a Content-Encoding value
+// Failures are RequestErrors: 415 for unknown encodings, 400 for corrupt data
+func decodingReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(r)
+	case "zstd":
+		var decoder *zstd.Decoder
+		if decoder, err = zstd.NewReader(r); err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	default:
+		return nil, newRequestError(fiber.StatusUnsupportedMediaType, apierr.InvalidRequest,
+			"Unsupported Content-Encoding", fmt.Sprintf("%q (supported: gzip, zstd)", encoding))
+	}
+	if err != nil {
+		return nil, wrapRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Failed to decompress request body", err)
+	}
+	return reader, nil
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+)
+
+// Concat handles POST /api/concat
+func (h *ConverterHandler) Concat(c fiber.Ctx) error {
+	start := time.Now()
+	auditEntry := newAuditEntry(h.requestContext(c), "concat", "", start)
+	defer h.finishAudit(c, auditEntry)
+
+	var req models.ConcatRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+
+	downloadMode := c.Query("download") == "true"
+	auditEntry.DeviceID = req.DeviceID
+
+	if req.MediaType == "" {
+		req.MediaType = services.DetectMediaType(req.URLs[0])
+	}
+	if req.MediaType != "video" && req.MediaType != "audio" {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.UnsupportedFormat,
+			"Could not determine concat media type. Please provide media_type (audio/video)", "")
+	}
+
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+
+	t, err := h.tenantFor(ctx)
+	if err == nil {
+		err = checkMediaAllowed(t, req.MediaType)
+	}
+	var device *models.Device
+	if err == nil {
+		device, err = h.deviceFor(t, req.DeviceID, req.MediaType)
+	}
+	if err != nil {
+		return respondError(c, err)
+	}
+	deviceKey := t.DeviceKey(req.DeviceID)
+
+	if req.AntiFingerprintLevel == "" {
+		req.AntiFingerprintLevel = defaultLevel(t, device, req.MediaType)
+	}
+
+	opts, err := parseConvertOptions(&models.ConvertRequest{
+		MediaType:     req.MediaType,
+		MaxResolution: req.MaxResolution,
+		FrameRate:     req.FrameRate,
+		AudioFormat:   req.AudioFormat,
+	})
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	cacheKey := "concat:" + strings.Join(req.URLs, "|")
+	if sig := opts.Signature(); sig != "" {
+		cacheKey += "#" + sig
+	}
+
+	auditEntry.MediaType = req.MediaType
+	auditEntry.Level = req.AntiFingerprintLevel
+	auditEntry.SourceHash = hashSource(cacheKey)
+
+	if cachedEntry := h.cache.Get(deviceKey, cacheKey); cachedEntry != nil {
+		fileInfo, err := os.Stat(cachedEntry.ProcessedPath)
+		if err == nil {
+			reqid.Printf(ctx, "✅ CACHE HIT: device=%s, concat of %d clips, path=%s",
+				req.DeviceID, len(req.URLs), cachedEntry.ProcessedPath)
+			h.recordUsage(t, req.DeviceID, true, 0, fileInfo.Size(), 0)
+			auditEntry.CacheHit = true
+			auditEntry.ProcessedSize = fileInfo.Size()
+
+			if downloadMode {
+				return h.sendFile(c, cachedEntry.ProcessedPath, cachedEntry.MediaType)
+			}
+
+			return c.JSON(models.ConvertResponse{
+				Success:        true,
+				ProcessedPath:  cachedEntry.ProcessedPath,
+				CacheHit:       true,
+				MediaType:      cachedEntry.MediaType,
+				ProcessedSize:  fileInfo.Size(),
+				CacheExpires:   cachedEntry.CacheExpires.Format(time.RFC3339),
+				FileExpires:    cachedEntry.FileExpires.Format(time.RFC3339),
+				ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+			})
+		}
+	}
+
+	if err := h.checkSpace(); err != nil {
+		return respondError(c, err)
+	}
+	if err := h.admit(ctx, req.MediaType); err != nil {
+		return respondError(c, err)
+	}
+	if err := acquireSlot(t); err != nil {
+		return respondError(c, err)
+	}
+	defer t.Release()
+	h.active.Add(1)
+	defer h.active.Add(-1)
+
+	clips, err := h.downloadAll(ctx, t, req.MediaType, req.URLs)
+	if err != nil {
+		return respondError(c, h.inputDownloadError(t, req.MediaType, "Failed to download clips", err))
+	}
+
+	originalSize := int64(0)
+	for _, clip := range clips {
+		originalSize += int64(len(clip))
+	}
+
+	if err := h.scanInput(ctx, clips...); err != nil {
+		return respondError(c, err)
+	}
+
+	// Each clip must fit the limits, and so must the joined duration
+	var totalDuration time.Duration
+	for _, clip := range clips {
+		info, err := h.inspectInput(ctx, req.MediaType, clip)
+		if err != nil {
+			return respondError(c, err)
+		}
+		if info != nil {
+			totalDuration += info.Duration
+		}
+	}
+	if err := h.limits.CheckInfo(req.MediaType, &services.MediaInfo{Duration: totalDuration}); err != nil {
+		return respondError(c, inspectionError(err))
+	}
+
+	// Join into a normalized intermediate, then run the regular AF conversion on it
+	processingStart := time.Now()
+	joined, err := h.concatenator.Concat(ctx, clips, req.MediaType)
+	if err != nil {
+		return respondError(c, h.conversionError(ctx, "Concat failed", err))
+	}
+
+	result, err := h.runConverter(ctx, t, req.DeviceID, hashURL(cacheKey), req.MediaType, req.AntiFingerprintLevel, joined, opts)
+	if err != nil {
+		return respondError(c, h.conversionError(ctx, fmt.Sprintf("Conversion failed: %s", req.MediaType), err))
+	}
+	outputPath := result.Path
+
+	fileInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to stat output file", err.Error())
+	}
+	processedSize := fileInfo.Size()
+	auditEntry.OriginalSize = originalSize
+	auditEntry.ProcessedSize = processedSize
+	h.recordUsage(t, req.DeviceID, false, originalSize, processedSize, time.Since(processingStart))
+
+	if err := h.cache.Set(deviceKey, cacheKey, outputPath, req.MediaType, processedSize); err != nil {
+		reqid.Printf(ctx, "⚠️  Failed to cache file: %v", err)
+	}
+
+	cacheExpires := ""
+	fileExpires := ""
+	if cacheEntry := h.cache.Get(deviceKey, cacheKey); cacheEntry != nil {
+		cacheExpires = cacheEntry.CacheExpires.Format(time.RFC3339)
+		fileExpires = cacheEntry.FileExpires.Format(time.RFC3339)
+	}
+
+	reqid.Printf(ctx, "✅ CONCAT: device=%s, type=%s, clips=%d, level=%s, size=%d→%d, time=%dms",
+		req.DeviceID, req.MediaType, len(clips), req.AntiFingerprintLevel,
+		originalSize, processedSize, time.Since(processingStart).Milliseconds())
+
+	if downloadMode {
+		return h.sendFile(c, outputPath, req.MediaType)
+	}
+
+	sizeIncrease := float64(processedSize-originalSize) / float64(originalSize) * 100
+
+	return c.JSON(models.ConvertResponse{
+		Success:        true,
+		ProcessedPath:  outputPath,
+		CacheHit:       false,
+		MediaType:      req.MediaType,
+		OriginalSize:   originalSize,
+		ProcessedSize:  processedSize,
+		SizeIncrease:   fmt.Sprintf("%.2f%%", sizeIncrease),
+		ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		CacheExpires:   cacheExpires,
+		FileExpires:    fileExpires,
+	})
+}
@@ -1,37 +1,83 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"runtime/metrics"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 
+	"fingerprint-converter/internal/admission"
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/breaker"
 	"fingerprint-converter/internal/cache"
+	"fingerprint-converter/internal/cdn"
+	"fingerprint-converter/internal/devices"
+	"fingerprint-converter/internal/hooks"
 	"fingerprint-converter/internal/models"
 	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/rpcconv"
+	"fingerprint-converter/internal/scanner"
 	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/slowlog"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/usage"
+	"fingerprint-converter/internal/validation"
 )
 
 // ConverterHandler handles media conversion requests with caching
 type ConverterHandler struct {
-	audioConverter *services.AudioConverter
-	imageConverter *services.ImageConverter
-	videoConverter *services.VideoConverter
-	downloader     *services.Downloader
-	cache          *cache.DeviceCache
-	workerPool     *pool.WorkerPool
-	bufferPool     *pool.BufferPool
-	requestTimeout time.Duration
-	cacheDir       string
+	audioConverter   *services.AudioConverter
+	imageConverter   *services.ImageConverter
+	videoConverter   *services.VideoConverter
+	remoteConverter  *rpcconv.Client // nil = every media type converts locally
+	slideshowBuilder *services.SlideshowBuilder
+	concatenator     *services.Concatenator
+	packager         *services.Packager
+	postProcessors   *hooks.Pipeline // nil = no post-processing
+	downloader       *services.Downloader
+	cache            *cache.DeviceCache
+	workerPool       *pool.WorkerPool
+	bufferPool       *pool.BufferPool
+	requestTimeout   time.Duration
+	cacheDir         string
+	tenants          *tenant.Registry
+	devices          *devices.Store
+	usage            *usage.Store
+	audit            *audit.Logger
+	slow             *slowlog.Reporter     // Logs and alerts on conversions past the per-media thresholds
+	admission        *admission.Controller // Holds back conversions while the host is overloaded (nil = off)
+	scanner          scanner.Scanner
+	limits           services.InputLimits
+	archiveLimits    services.ArchiveLimits
+	quality          services.QualityCheck
+	verify           services.OutputCheck
+	fallback         bool                        // Retry failed encodes in safe mode
+	reprocess        string                      // What happens to inputs that are our own outputs (services.Reprocess*)
+	cdn              *cdn.Invalidator            // Purges replaced uploads from the CDN (nil = disabled)
+	breakers         map[string]*breaker.Breaker // FFmpeg circuit per media type (nil = none)
+	debug            bool                        // Expose raw ffmpeg stderr in error details
+	active           atomic.Int64                // Conversions holding a slot (downloading, encoding or checking)
+	fastLane         fastLane                    // Cache hits answered before the pipeline
 }
 
 // NewConverterHandler creates a new converter handler
@@ -39,219 +85,763 @@ func NewConverterHandler(
 	audioConverter *services.AudioConverter,
 	imageConverter *services.ImageConverter,
 	videoConverter *services.VideoConverter,
+	remoteConverter *rpcconv.Client,
+	slideshowBuilder *services.SlideshowBuilder,
+	concatenator *services.Concatenator,
+	packager *services.Packager,
+	postProcessors *hooks.Pipeline,
 	downloader *services.Downloader,
 	deviceCache *cache.DeviceCache,
 	workerPool *pool.WorkerPool,
 	bufferPool *pool.BufferPool,
 	requestTimeout time.Duration,
 	cacheDir string,
+	tenants *tenant.Registry,
+	deviceStore *devices.Store,
+	usageStore *usage.Store,
+	auditLogger *audit.Logger,
+	slowConversions *slowlog.Reporter,
+	admissionControl *admission.Controller,
+	malwareScanner scanner.Scanner,
+	limits services.InputLimits,
+	archiveLimits services.ArchiveLimits,
+	quality services.QualityCheck,
+	verify services.OutputCheck,
+	fallback bool,
+	reprocessMode string,
+	cdnInvalidator *cdn.Invalidator,
+	ffmpegBreakers map[string]*breaker.Breaker,
+	debug bool,
 ) *ConverterHandler {
 	if requestTimeout <= 0 {
 		requestTimeout = 5 * time.Minute
 	}
 
 	return &ConverterHandler{
-		audioConverter: audioConverter,
-		imageConverter: imageConverter,
-		videoConverter: videoConverter,
-		downloader:     downloader,
-		cache:          deviceCache,
-		workerPool:     workerPool,
-		bufferPool:     bufferPool,
-		requestTimeout: requestTimeout,
-		cacheDir:       cacheDir,
+		audioConverter:   audioConverter,
+		imageConverter:   imageConverter,
+		videoConverter:   videoConverter,
+		remoteConverter:  remoteConverter,
+		slideshowBuilder: slideshowBuilder,
+		concatenator:     concatenator,
+		packager:         packager,
+		postProcessors:   postProcessors,
+		downloader:       downloader,
+		cache:            deviceCache,
+		workerPool:       workerPool,
+		bufferPool:       bufferPool,
+		requestTimeout:   requestTimeout,
+		cacheDir:         cacheDir,
+		tenants:          tenants,
+		devices:          deviceStore,
+		usage:            usageStore,
+		audit:            auditLogger,
+		slow:             slowConversions,
+		admission:        admissionControl,
+		scanner:          malwareScanner,
+		limits:           limits,
+		archiveLimits:    archiveLimits,
+		quality:          quality,
+		verify:           verify,
+		fallback:         fallback,
+		reprocess:        reprocessMode,
+		cdn:              cdnInvalidator,
+		breakers:         ffmpegBreakers,
+		debug:            debug,
 	}
 }
 
 // Convert handles POST /api/convert
 func (h *ConverterHandler) Convert(c fiber.Ctx) error {
-	start := time.Now()
-
 	// Parse request
 	var req models.ConvertRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid request body",
-			Details: err.Error(),
-		})
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
 	}
 
-	// Check if download mode is enabled (query param ?download=true)
-	downloadMode := c.Query("download") == "true"
+	// Check if download mode is enabled (?download=true sends the file, ?download=zip bundles every output)
+	download := c.Query("download")
 
-	// Validate required fields
-	if req.DeviceID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   "device_id is required",
-		})
+	// Cache hits are answered right away, however busy the encoders are
+	resp, err := h.serveCached(h.requestContext(c), &req)
+	if err != nil {
+		return respondError(c, err)
+	}
+	if resp != nil {
+		return h.sendConvertResponse(c, download, resp)
 	}
 
-	if req.URL == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   "url is required",
+	// ?stream=true sends the file while it is encoded; several outputs can't share one stream
+	if download == "true" && c.Query("stream") == "true" && req.DestinationURL == "" && len(req.Outputs) <= 1 {
+		return h.streamDownload(c, req.KeepProcessing, func(ctx context.Context) (*models.ConvertResponse, error) {
+			return h.Process(ctx, &req)
 		})
 	}
 
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+
+	resp, err = h.Process(ctx, &req)
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	// If download mode, return file stream (outputs sent to destination_url are only described)
+	if req.DestinationURL != "" {
+		download = ""
+	}
+	return h.sendConvertResponse(c, download, resp)
+}
+
+// sendConvertResponse sends the output file (download=true), a bundle of every output (download=zip) or the JSON response
+func (h *ConverterHandler) sendConvertResponse(c fiber.Ctx, download string, resp *models.ConvertResponse) error {
+	switch download {
+	case "true":
+		return h.sendFile(c, resp.ProcessedPath, resp.MediaType)
+	case "zip":
+		return sendResponseBundle(c, resp)
+	}
+
+	return c.JSON(resp)
+}
+
+// ValidateRequest checks required fields and fills defaults (media type, AF level)
+// Safe to call more than once on the same request
+func (h *ConverterHandler) ValidateRequest(ctx context.Context, req *models.ConvertRequest) error {
+	if err := h.validateRequest(ctx, req); err != nil {
+		return err
+	}
+	// Jobs that could only fail are turned away before they are queued
+	if err := h.checkCircuit(req.MediaType); err != nil {
+		return err
+	}
+	return h.checkSpace()
+}
+
+// validateRequest is ValidateRequest without the circuit check, for work that runs later
+func (h *ConverterHandler) validateRequest(ctx context.Context, req *models.ConvertRequest) error {
+	t, err := h.tenantFor(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := decodeData(req)
+	if err != nil {
+		return err
+	}
+	if data != nil && req.MediaType == "" {
+		req.MediaType = services.DetectMediaTypeFromContent("", data)
+	}
+	if data == nil && h.typedByContent(req) {
+		// The rest is checked once the download tells the media type
+		if err := validation.Struct(req); err != nil {
+			return requestBodyError(err)
+		}
+		return nil
+	}
+	if len(req.Outputs) > 0 {
+		_, _, err = h.prepareOutputs(req, t)
+		return err
+	}
+	_, err = h.prepareRequest(req, t)
+	return err
+}
+
+// requestContext returns a background context carrying the caller's tenant, address and request ID
+func (h *ConverterHandler) requestContext(c fiber.Ctx) context.Context {
+	ctx := tenant.WithID(context.Background(), tenant.IDFromFiber(c))
+	ctx = reqid.WithID(ctx, reqid.FromFiber(c))
+	return audit.WithCaller(ctx, "ip:"+c.IP())
+}
+
+// tenantFor resolves the tenant carried by ctx (nil when tenancy is disabled)
+func (h *ConverterHandler) tenantFor(ctx context.Context) (*tenant.Tenant, error) {
+	if !h.tenants.Enabled() {
+		return nil, nil
+	}
+	id := tenant.IDFromContext(ctx)
+	t := h.tenants.Get(id)
+	if t == nil {
+		return nil, newRequestError(fiber.StatusForbidden, apierr.UnknownTenant, "Unknown tenant", id)
+	}
+	return t, nil
+}
+
+// checkMediaAllowed rejects media types outside the tenant's allow-list
+func checkMediaAllowed(t *tenant.Tenant, mediaType string) error {
+	if !t.AllowsMediaType(mediaType) {
+		return newRequestError(fiber.StatusForbidden, apierr.MediaTypeNotAllowed,
+			fmt.Sprintf("media_type %s is not allowed for this tenant", mediaType), "")
+	}
+	return nil
+}
+
+// deviceFor loads the registered device and enforces its media allow-list
+// Returns nil for unregistered devices (tenant and global defaults apply)
+func (h *ConverterHandler) deviceFor(t *tenant.Tenant, deviceID, mediaType string) (*models.Device, error) {
+	tenantID := ""
+	if t != nil {
+		tenantID = t.ID
+	}
+	device, err := h.devices.Get(tenantID, deviceID)
+	if err != nil {
+		return nil, wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError, "Failed to load device", err)
+	}
+	if !devices.AllowsMediaType(device, mediaType) {
+		return nil, newRequestError(fiber.StatusForbidden, apierr.MediaTypeNotAllowed,
+			fmt.Sprintf("media_type %s is not allowed for this device", mediaType), "")
+	}
+	return device, nil
+}
+
+// defaultLevel picks the AF level for requests without one: device (profile, then level), then tenant, then the media default
+func defaultLevel(t *tenant.Tenant, device *models.Device, mediaType string) string {
+	if level := devices.DefaultLevel(device, mediaType); level != "" {
+		return level
+	}
+	if level := t.DefaultLevel(); level != "" {
+		return level
+	}
+	return services.DefaultAFLevel(mediaType)
+}
+
+// acquireSlot enforces the tenant's concurrency quota; transient so async jobs retry later
+// Callers must t.Release() on success
+func acquireSlot(t *tenant.Tenant) error {
+	if !t.Acquire() {
+		return wrapRequestError(fiber.StatusTooManyRequests, apierr.ConcurrencyLimited, "Tenant concurrency limit reached",
+			&services.TransientError{Err: fmt.Errorf("max %d concurrent conversions", t.Quota.MaxConcurrent)})
+	}
+	return nil
+}
+
+// checkFileSize enforces the size limit for mediaType, then the tenant's per-file size quota
+func (h *ConverterHandler) checkFileSize(t *tenant.Tenant, mediaType string, size int64) error {
+	if err := h.limits.CheckSize(mediaType, size); err != nil {
+		return wrapRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge, sizeLimitMessage(mediaType), err)
+	}
+	if t.FileTooLarge(size) {
+		return newRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge, "File exceeds tenant size limit",
+			fmt.Sprintf("max: %d bytes", t.Quota.MaxFileSize))
+	}
+	return nil
+}
+
+// sizeLimitMessage is the error message for an input over the size limit for mediaType
+func sizeLimitMessage(mediaType string) string {
+	return fmt.Sprintf("File exceeds %s size limit", mediaType)
+}
+
+// mediaSizeLimit returns the size limit for mediaType when it is the tightest cap on the input (0 = it isn't)
+// Neither MAX_DOWNLOAD_SIZE nor the tenant's quota can be raised by it
+func (h *ConverterHandler) mediaSizeLimit(t *tenant.Tenant, mediaType string) int64 {
+	limit := h.limits.MaxSize(mediaType)
+	if limit <= 0 || limit >= h.downloader.MaxSize() {
+		return 0
+	}
+	if t != nil && t.Quota.MaxFileSize > 0 && t.Quota.MaxFileSize <= limit {
+		return 0
+	}
+	return limit
+}
+
+// downloadCheck is what a source for mediaType is checked against before it is downloaded
+func (h *ConverterHandler) downloadCheck(t *tenant.Tenant, mediaType string) services.DownloadCheck {
+	check := services.DownloadCheck{MediaType: mediaType}
+	if t != nil {
+		check.MaxSize = t.Quota.MaxFileSize
+	}
+	if limit := h.mediaSizeLimit(t, mediaType); limit > 0 {
+		check.MaxSize = limit
+	}
+	return check
+}
+
+// inputDownloadError is downloadError for a source of mediaType
+// Sources cut off by the size limit for mediaType get an error naming it
+func (h *ConverterHandler) inputDownloadError(t *tenant.Tenant, mediaType, message string, err error) *RequestError {
+	if errors.Is(err, services.ErrFileTooLarge) && h.mediaSizeLimit(t, mediaType) > 0 {
+		return wrapRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge, sizeLimitMessage(mediaType), err)
+	}
+	return downloadError(message, err)
+}
+
+// scanInput rejects inputs flagged by the malware scanner (no-op when scanning is disabled)
+// Scanner outages are transient so async jobs retry once the scanner is back
+func (h *ConverterHandler) scanInput(ctx context.Context, inputs ...[]byte) error {
+	if h.scanner == nil {
+		return nil
+	}
+
+	for _, data := range inputs {
+		err := h.scanner.Scan(ctx, data)
+		if err == nil {
+			continue
+		}
+
+		var infected *scanner.InfectedError
+		if errors.As(err, &infected) {
+			reqid.Printf(ctx, "🦠 Rejected infected input: signature=%s, size=%d", infected.Signature, len(data))
+			return wrapRequestError(fiber.StatusUnprocessableEntity, apierr.MalwareDetected, "File rejected by malware scan", err)
+		}
+		return wrapRequestError(fiber.StatusServiceUnavailable, apierr.ScannerUnavailable, "Malware scan unavailable",
+			&services.TransientError{Err: err})
+	}
+	return nil
+}
+
+// inspectInput rejects inputs whose content doesn't match mediaType (disguised files, polyglots)
+// or that exceed the configured limits. Probing only runs when limits are configured;
+// it then also checks the decoded stream types. Returns nil info when nothing was probed.
+func (h *ConverterHandler) inspectInput(ctx context.Context, mediaType string, data []byte) (*services.MediaInfo, error) {
+	if err := services.CheckContent(mediaType, data); err != nil {
+		reqid.Printf(ctx, "🚫 Content mismatch: %v", err)
+		return nil, inspectionError(err)
+	}
+
+	if !h.limits.Enabled() {
+		return nil, nil
+	}
+	info, err := h.limits.Check(ctx, mediaType, data)
+	if err == nil {
+		err = services.CheckStreams(mediaType, info)
+		if err != nil {
+			reqid.Printf(ctx, "🚫 Stream mismatch: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, inspectionError(err)
+	}
+	return info, nil
+}
+
+// inspectionError maps content, probe and limit failures to 413/422
+func inspectionError(err error) error {
+	var limitErr *services.LimitError
+	var mismatchErr *services.MismatchError
+	switch {
+	case errors.As(err, &mismatchErr):
+		return wrapRequestError(fiber.StatusUnprocessableEntity, apierr.ContentMismatch, "File content does not match media_type", err)
+	case errors.As(err, &limitErr):
+		return wrapRequestError(fiber.StatusRequestEntityTooLarge, apierr.InputLimitExceeded, "Input exceeds limits", err)
+	case errors.Is(err, services.ErrInvalidMedia):
+		return wrapRequestError(fiber.StatusUnprocessableEntity, apierr.InvalidMedia, "Invalid media file", err)
+	default:
+		return wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError, "Failed to inspect input", err)
+	}
+}
+
+// prepareRequest validates the request, fills defaults and resolves processing options
+func (h *ConverterHandler) prepareRequest(req *models.ConvertRequest, t *tenant.Tenant) (services.ConvertOptions, error) {
+	// Requests from jobs, queues and WebSocket never went through Bind
+	if err := validation.Struct(req); err != nil {
+		return services.ConvertOptions{}, requestBodyError(err)
+	}
+
+	// Audio extraction runs video inputs through the audio pipeline
+	if req.ExtractAudio {
+		req.MediaType = "audio"
+	}
+
 	// Auto-detect media type if not provided
 	if req.MediaType == "" {
-		req.MediaType = detectMediaType(req.URL)
+		req.MediaType = services.DetectMediaType(req.URL)
+		if req.MediaType == "" && h.downloader.Resolves(req.URL) {
+			req.MediaType = "video" // Platform pages serve video; extract_audio takes their soundtrack
+		}
 		if req.MediaType == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Success: false,
-				Error:   "Could not detect media type from URL. Please provide media_type (audio/image/video)",
-				Details: "Supported extensions: audio (.mp3,.opus,.ogg,.m4a,.wav,.aac), image (.jpg,.jpeg,.png,.webp,.gif), video (.mp4,.avi,.mov,.mkv,.webm,.flv)",
-			})
+			return services.ConvertOptions{}, newRequestError(fiber.StatusBadRequest, apierr.UnsupportedFormat,
+				"Could not detect media type from URL. Please provide media_type (audio/image/video)",
+				"Supported extensions: audio (.mp3,.opus,.ogg,.m4a,.wav,.aac), image (.jpg,.jpeg,.png,.webp,.gif), video (.mp4,.avi,.mov,.mkv,.webm,.flv)")
 		}
 		log.Printf("🔍 Auto-detected media type: %s from URL: %s", req.MediaType, truncateURL(req.URL))
 	}
 
+	if !isSupportedMediaType(req.MediaType) {
+		return services.ConvertOptions{}, newRequestError(fiber.StatusBadRequest, apierr.UnsupportedFormat,
+			fmt.Sprintf("Unsupported media_type: %s", req.MediaType),
+			"Supported types: audio, image, video")
+	}
+
+	if err := checkMediaAllowed(t, req.MediaType); err != nil {
+		return services.ConvertOptions{}, err
+	}
+	if err := h.checkPackaging(req); err != nil {
+		return services.ConvertOptions{}, err
+	}
+	if err := checkDestination(req); err != nil {
+		return services.ConvertOptions{}, err
+	}
+	device, err := h.deviceFor(t, req.DeviceID, req.MediaType)
+	if err != nil {
+		return services.ConvertOptions{}, err
+	}
+
+	// A named profile picks the level when the request doesn't set one; its techniques always apply
+	var techniques []string
+	var audioBitrate services.IntRange
+	if req.Profile != "" {
+		profile, ok := services.Profile(req.Profile)
+		if !ok {
+			return services.ConvertOptions{}, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+				fmt.Sprintf("Unknown profile: %s", req.Profile),
+				"Defined profiles: "+strings.Join(services.ProfileNames(), ", "))
+		}
+		if req.AntiFingerprintLevel == "" {
+			req.AntiFingerprintLevel = profile.LevelFor(req.MediaType)
+		}
+		techniques = profile.TechniquesFor(req.MediaType)
+		audioBitrate = profile.AudioBitrateBounds()
+	}
+
+	// Devices without settings of their own take part in A/B experiments
+	// Tags are re-checked because jobs prepare the same request twice, and clients can't claim a variant
+	assignment, inExperiment := services.AssignExperiment(req.MediaType, t.DeviceKey(req.DeviceID))
+	if req.AntiFingerprintLevel == "" && req.Profile == "" && inExperiment && devices.DefaultLevel(device, req.MediaType) == "" {
+		req.AntiFingerprintLevel = assignment.Level
+		req.Experiment, req.Variant = assignment.Experiment, assignment.Variant
+		log.Printf("🧪 Experiment %s: device=%s, variant=%s, level=%s", req.Experiment, req.DeviceID, req.Variant, req.AntiFingerprintLevel)
+	}
+	if !inExperiment || req.Experiment != assignment.Experiment || req.Variant != assignment.Variant ||
+		req.AntiFingerprintLevel != assignment.Level {
+		req.Experiment, req.Variant = "", ""
+	}
+	if req.Experiment != "" {
+		techniques, audioBitrate = assignment.Techniques, assignment.AudioBitrateKbps
+	}
+
 	// Set default anti-fingerprint level if not provided
 	if req.AntiFingerprintLevel == "" {
-		req.AntiFingerprintLevel = getDefaultAFLevel(req.MediaType)
+		req.AntiFingerprintLevel = defaultLevel(t, device, req.MediaType)
 		log.Printf("🎯 Using default AF level: %s for media type: %s", req.AntiFingerprintLevel, req.MediaType)
 	}
 
-	// Check cache first
-	urlHash := hashURL(req.URL)
-	if cachedEntry := h.cache.Get(req.DeviceID, req.URL); cachedEntry != nil {
-		// Cache hit - return cached file
-		fileInfo, err := os.Stat(cachedEntry.ProcessedPath)
-		if err == nil {
-			log.Printf("✅ CACHE HIT: device=%s, url=%s, path=%s",
-				req.DeviceID, truncateURL(req.URL), cachedEntry.ProcessedPath)
+	// Resolve per-request processing options
+	opts, err := parseConvertOptions(req)
+	opts.Techniques = techniques
+	opts.AudioBitrateKbps = audioBitrate
+	return opts, err
+}
 
-			// If download mode, return file stream
-			if downloadMode {
-				return h.sendFile(c, cachedEntry.ProcessedPath, cachedEntry.MediaType)
-			}
+// Process runs the conversion pipeline: cache lookup, download, convert, cache store
+// Used by the HTTP handler and by async jobs
+func (h *ConverterHandler) Process(ctx context.Context, req *models.ConvertRequest) (resp *models.ConvertResponse, err error) {
+	defer func(start time.Time) {
+		if resp != nil {
+			resp.RequestID = reqid.FromContext(ctx)
+		}
+		h.auditConvert(ctx, req, start, resp, err)
+	}(time.Now())
+
+	t, err := h.tenantFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeData(req)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		return h.executeData(ctx, t, req, "", data)
+	}
+
+	if h.typedByContent(req) {
+		return h.executeSniffed(ctx, t, req)
+	}
 
-			// Otherwise return JSON
-			return c.JSON(models.ConvertResponse{
-				Success:        true,
-				ProcessedPath:  cachedEntry.ProcessedPath,
-				CacheHit:       true,
-				MediaType:      cachedEntry.MediaType,
-				ProcessedSize:  fileInfo.Size(),
-				CacheExpires:   cachedEntry.CacheExpires.Format(time.RFC3339),
-				FileExpires:    cachedEntry.FileExpires.Format(time.RFC3339),
-				ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
-			})
+	return h.executeRequest(ctx, t, req, func() ([]byte, error) {
+		// Download from URL
+		data, err := h.downloader.DownloadChecked(ctx, req.URL, h.downloadCheck(t, req.MediaType))
+		if err != nil {
+			return nil, h.inputDownloadError(t, req.MediaType, "Failed to download file", err)
 		}
-		// File was deleted, cache entry will be cleaned up
+		return data, nil
+	})
+}
+
+// typedByContent reports whether req's media type can only be told from the downloaded file
+// (no media_type, and no known extension in the URL's path)
+func (h *ConverterHandler) typedByContent(req *models.ConvertRequest) bool {
+	return req.MediaType == "" && !req.ExtractAudio && req.URL != "" &&
+		services.DetectMediaType(req.URL) == "" && !h.downloader.Resolves(req.URL)
+}
+
+// executeSniffed downloads req's input first and takes its media type from the leading bytes
+// The input is downloaded even when its output is cached, since the cache can't be looked up without a type
+func (h *ConverterHandler) executeSniffed(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest) (*models.ConvertResponse, error) {
+	data, err := h.downloader.DownloadChecked(ctx, req.URL, h.downloadCheck(t, ""))
+	if err != nil {
+		return nil, h.inputDownloadError(t, "", "Failed to download file", err)
+	}
+	req.MediaType = services.DetectMediaTypeFromContent("", data)
+	if req.MediaType == "" {
+		return nil, newRequestError(fiber.StatusBadRequest, apierr.UnsupportedFormat,
+			"Could not detect media type from the URL or the file's content. Please provide media_type (audio/image/video)", "")
 	}
+	reqid.Printf(ctx, "🔍 Detected media type: %s from content of: %s", req.MediaType, truncateURL(req.URL))
 
-	// Cache miss - process file
-	log.Printf("⚡ CACHE MISS: device=%s, url=%s, processing...",
-		req.DeviceID, truncateURL(req.URL))
+	return h.executeRequest(ctx, t, req, func() ([]byte, error) {
+		return data, nil
+	})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
-	defer cancel()
+// ProcessData runs the pipeline on media bytes supplied by the caller instead of a URL
+// The cache key is derived from the content (and filename, used for type detection)
+func (h *ConverterHandler) ProcessData(ctx context.Context, req *models.ConvertRequest, filename string, data []byte) (resp *models.ConvertResponse, err error) {
+	defer func(start time.Time) {
+		if resp != nil {
+			resp.RequestID = reqid.FromContext(ctx)
+		}
+		h.auditConvert(ctx, req, start, resp, err)
+	}(time.Now())
 
-	// Download or decode input data
-	var inputData []byte
-	var err error
+	t, err := h.tenantFor(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if req.IsBase64 {
-		// Decode base64 data
-		inputData, err = base64.StdEncoding.DecodeString(req.URL)
+	return h.executeData(ctx, t, req, filename, data)
+}
+
+// decodeData decodes the request's base64 payload; nil when the input is a URL
+// Legacy requests carrying base64 in url with is_base64, and data: URIs in url, are moved to data first
+func decodeData(req *models.ConvertRequest) ([]byte, error) {
+	if req.IsBase64 && req.Data == "" {
+		req.Data, req.URL = req.URL, ""
+	}
+	if req.Data == "" && services.IsDataURI(req.URL) {
+		mimeType, data, err := services.ParseDataURI(req.URL)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Success: false,
-				Error:   "Failed to decode base64 data",
-				Details: err.Error(),
-			})
+			return nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Invalid data URI", err.Error())
 		}
+		if len(data) == 0 {
+			return nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "No media data received", "")
+		}
+		// The declared type wins; content sniffing covers generic ones like application/octet-stream
+		if req.MediaType == "" {
+			req.MediaType = services.DetectMediaTypeFromContent(mimeType, data)
+		}
+		// Re-encoded so the request can be validated again (jobs) like any other inline request
+		req.Data, req.URL = base64.StdEncoding.EncodeToString(data), ""
+	}
+	req.IsBase64 = false
+	if req.Data == "" {
+		return nil, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		return nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Failed to decode base64 data", err.Error())
+	}
+	if len(data) == 0 {
+		return nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "No media data received", "")
+	}
+	return data, nil
+}
+
+// executeData runs the pipeline on in-memory input
+// The URL is replaced by a content hash so cache keys and logs stay small
+func (h *ConverterHandler) executeData(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, filename string, data []byte) (*models.ConvertResponse, error) {
+	if len(data) == 0 {
+		return nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "No media data received", "")
+	}
+
+	req.URL = uploadKey(filename, data)
+	if req.MediaType == "" && services.DetectMediaType(req.URL) == "" {
+		req.MediaType = services.DetectMediaTypeFromContent("", data)
+	}
+
+	return h.executeRequest(ctx, t, req, func() ([]byte, error) {
+		return data, nil
+	})
+}
+
+// executeRequest prepares and runs a single- or multi-output request, then packages and post-processes it
+func (h *ConverterHandler) executeRequest(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, load func() ([]byte, error)) (*models.ConvertResponse, error) {
+	var resp *models.ConvertResponse
+	var err error
+	if len(req.Outputs) > 0 {
+		resp, err = h.executeOutputs(ctx, t, req, load)
 	} else {
-		// Download from URL
-		inputData, err = h.downloader.Download(ctx, req.URL)
+		var opts services.ConvertOptions
+		opts, err = h.prepareRequest(req, t)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Success: false,
-				Error:   "Failed to download file",
-				Details: err.Error(),
-			})
+			return nil, err
 		}
+		resp, err = h.execute(ctx, t, req, opts, load)
+	}
+	if err != nil {
+		return nil, err
 	}
+	resp, err = h.packageResponse(ctx, t, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = h.deliver(ctx, t, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	return h.postProcess(ctx, t, req, resp)
+}
 
-	originalSize := int64(len(inputData))
+// uploadKey identifies caller-supplied content by its hash (plus filename, used for type detection)
+func uploadKey(filename string, data []byte) string {
+	sum := sha256.Sum256(data)
+	key := "upload:" + hex.EncodeToString(sum[:])
+	if filename != "" {
+		key += "/" + path.Base(filename)
+	}
+	return key
+}
 
-	// Create media-specific subdirectory
-	mediaSubdir := getMediaSubdir(req.MediaType)
-	mediaCacheDir := filepath.Join(h.cacheDir, mediaSubdir)
+// execute runs cache lookup, input loading, conversion and cache store for a prepared request
+// Cache entries and outputs are namespaced by tenant
+func (h *ConverterHandler) execute(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, opts services.ConvertOptions, load func() ([]byte, error)) (resp *models.ConvertResponse, err error) {
+	start := time.Now()
+	deviceKey := t.DeviceKey(req.DeviceID)
 
-	log.Printf("📁 Creating directory: %s", mediaCacheDir)
+	// Check cache first
+	cacheKey := cacheKeyFor(req, opts)
+	urlHash := hashURL(cacheKey)
+	if resp := h.cachedResponse(ctx, t, req, cacheKey, start); resp != nil {
+		return resp, nil
+	}
 
-	// Ensure media subdirectory exists
-	if err := os.MkdirAll(mediaCacheDir, 0755); err != nil {
-		log.Printf("❌ Failed to create directory %s: %v", mediaCacheDir, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to create media cache directory",
-			Details: err.Error(),
-		})
+	// The same broken input fails the same way; answer from the failure cache until it expires
+	if cachedErr := h.cache.GetFailure(deviceKey, cacheKey); cachedErr != nil {
+		reqid.Printf(ctx, "🚫 CACHED FAILURE: device=%s, url=%s, error=%v",
+			req.DeviceID, truncateURL(req.URL), cachedErr)
+		return nil, cachedErr
 	}
+	defer func() {
+		if err != nil && cacheableFailure(ctx, err) {
+			h.cache.SetFailure(deviceKey, cacheKey, err)
+		}
+	}()
 
-	log.Printf("✅ Directory ready: %s", mediaCacheDir)
+	// Cache miss - process file
+	reqid.Printf(ctx, "⚡ CACHE MISS: device=%s, url=%s, processing...",
+		req.DeviceID, truncateURL(req.URL))
+	profileVersion := services.ProfileVersion()
 
-	// Generate output path in media-specific subdirectory
-	var outputPath string
-	switch req.MediaType {
-	case "audio":
-		outputPath = h.audioConverter.GenerateOutputPath(mediaCacheDir, req.DeviceID, urlHash)
-	case "image":
-		outputPath = h.imageConverter.GenerateOutputPath(mediaCacheDir, req.DeviceID, urlHash)
-	case "video":
-		outputPath = h.videoConverter.GenerateOutputPath(mediaCacheDir, req.DeviceID, urlHash)
-	default:
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Unsupported media_type: %s", req.MediaType),
-			Details: "Supported types: audio, image, video",
-		})
+	// Time each stage so conversions past the slow threshold are reported with a breakdown
+	ctx, stages := services.WithStages(ctx)
+	defer func() { h.reportSlow(ctx, t, req, start, stages, err) }()
+
+	// Fail fast while ffmpeg is broken for this media type, before downloading anything
+	if err := h.checkCircuit(req.MediaType); err != nil {
+		return nil, err
+	}
+	if err := h.checkSpace(); err != nil {
+		return nil, err
+	}
+	admitStart := time.Now()
+	err = h.admit(ctx, req.MediaType)
+	stages.Since("queue_wait", admitStart)
+	if err != nil {
+		return nil, err
 	}
 
-	// Process file with appropriate converter
+	if err := acquireSlot(t); err != nil {
+		return nil, err
+	}
+	defer t.Release()
+	h.active.Add(1)
+	defer h.active.Add(-1)
+
+	// Download, decode or take the input data
+	downloadStart := time.Now()
+	inputData, err := load()
+	stages.Since("download", downloadStart)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.checkFileSize(t, req.MediaType, int64(len(inputData))); err != nil {
+		return nil, err
+	}
+
+	originalSize := int64(len(inputData))
+
+	// Fetch the watermark logo alongside the media
+	if opts.Watermark != nil && opts.Watermark.LogoURL != "" {
+		logoStart := time.Now()
+		opts.Watermark.Logo, err = h.downloader.Download(ctx, opts.Watermark.LogoURL)
+		stages.Since("download", logoStart)
+		if err != nil {
+			return nil, downloadError("Failed to download watermark image", err)
+		}
+	}
+
+	// Untrusted bytes are scanned before ffmpeg ever parses them
+	probeStart := time.Now()
+	inputs := [][]byte{inputData}
+	if opts.Watermark != nil && opts.Watermark.Logo != nil {
+		inputs = append(inputs, opts.Watermark.Logo)
+	}
+	if err := h.scanInput(ctx, inputs...); err != nil {
+		return nil, err
+	}
+
+	// Reject disguised files, decompression bombs and overlong media before encoding
+	inputInfo, err := h.inspectInput(ctx, req.MediaType, inputData)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Watermark != nil && opts.Watermark.Logo != nil {
+		if _, err := h.inspectInput(ctx, "image", opts.Watermark.Logo); err != nil {
+			return nil, err
+		}
+	}
+	stages.Since("probe", probeStart)
+
+	// Process file with appropriate converter, unless it is one of our outputs that needs no encode
 	processingStart := time.Now()
-	switch req.MediaType {
-	case "audio":
-		err = h.audioConverter.Convert(ctx, inputData, req.AntiFingerprintLevel, outputPath)
-	case "image":
-		err = h.imageConverter.Convert(ctx, inputData, req.AntiFingerprintLevel, outputPath)
-	case "video":
-		err = h.videoConverter.Convert(ctx, inputData, req.AntiFingerprintLevel, outputPath)
+	outputPath, reprocess := h.passthrough(ctx, t, req, urlHash, inputData, inputInfo, opts)
+	fallback := false
+	var result *services.ConversionResult
+	if outputPath == "" {
+		result, fallback, err = h.convert(ctx, t, req, urlHash, inputData, opts)
+		if err != nil {
+			return nil, err
+		}
+		outputPath = result.Path
 	}
 
+	// Corrupted outputs are dropped before anything is cached or returned
+	verifyStart := time.Now()
+	if err := h.verifyOutput(ctx, req, inputData, inputInfo, outputPath); err != nil {
+		return nil, err
+	}
+
+	// Compare the output with its source before it is cached
+	quality, err := h.measureQuality(ctx, req, inputData, outputPath)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Conversion failed: %s", req.MediaType),
-			Details: err.Error(),
-		})
+		return nil, err
 	}
+	stages.Since("verify", verifyStart)
 
 	// Get processed file size
 	fileInfo, err := os.Stat(outputPath)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to stat output file",
-			Details: err.Error(),
-		})
+		return nil, newRequestError(fiber.StatusInternalServerError, apierr.InternalError, "Failed to stat output file", err.Error())
 	}
 
 	processedSize := fileInfo.Size()
 	sizeIncrease := float64(processedSize-originalSize) / float64(originalSize) * 100
+	h.recordUsage(t, req.DeviceID, false, originalSize, processedSize, time.Since(processingStart))
 
 	// Store in cache
-	if err := h.cache.Set(req.DeviceID, req.URL, outputPath, req.MediaType, processedSize); err != nil {
-		log.Printf("⚠️  Failed to cache file: %v", err)
+	if err := h.cache.Set(deviceKey, cacheKey, outputPath, req.MediaType, processedSize); err != nil {
+		reqid.Printf(ctx, "⚠️  Failed to cache file: %v", err)
 	}
+	if fallback {
+		h.cache.MarkFallback(deviceKey, cacheKey)
+	}
+	h.cache.Stamp(deviceKey, cacheKey, profileVersion, cacheSource(t, req))
 
 	// Get cache entry for expiration times
-	cacheEntry := h.cache.Get(req.DeviceID, req.URL)
+	cacheEntry := h.cache.Get(deviceKey, cacheKey)
 	cacheExpires := ""
 	fileExpires := ""
 	if cacheEntry != nil {
@@ -259,17 +849,12 @@ func (h *ConverterHandler) Convert(c fiber.Ctx) error {
 		fileExpires = cacheEntry.FileExpires.Format(time.RFC3339)
 	}
 
-	log.Printf("✅ PROCESSED: device=%s, type=%s, level=%s, size=%d→%d (+%.1f%%), time=%dms",
+	reqid.Printf(ctx, "✅ PROCESSED: device=%s, type=%s, level=%s, size=%d→%d (+%.1f%%), time=%dms",
 		req.DeviceID, req.MediaType, req.AntiFingerprintLevel,
 		originalSize, processedSize, sizeIncrease, time.Since(processingStart).Milliseconds())
+	services.RecordConversionTime(req.MediaType, time.Since(downloadStart)) // Feeds queue estimates
 
-	// If download mode, return file stream
-	if downloadMode {
-		return h.sendFile(c, outputPath, req.MediaType)
-	}
-
-	// Otherwise return JSON
-	return c.JSON(models.ConvertResponse{
+	return &models.ConvertResponse{
 		Success:        true,
 		ProcessedPath:  outputPath,
 		CacheHit:       false,
@@ -278,9 +863,502 @@ func (h *ConverterHandler) Convert(c fiber.Ctx) error {
 		ProcessedSize:  processedSize,
 		SizeIncrease:   fmt.Sprintf("%.2f%%", sizeIncrease),
 		ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		Timings:        timingsOf(stages),
 		CacheExpires:   cacheExpires,
 		FileExpires:    fileExpires,
+		Experiment:     req.Experiment,
+		Variant:        req.Variant,
+		Quality:        quality,
+		Fallback:       fallback,
+		Reprocess:      reprocess,
+		Encode:         encodeDetails(result),
+	}, nil
+}
+
+// encodeDetails reports result in the response; nil when the input was passed through
+func encodeDetails(result *services.ConversionResult) *models.EncodeDetails {
+	if result == nil {
+		return nil
+	}
+	return &models.EncodeDetails{
+		Format:   result.Format,
+		Width:    result.Width,
+		Height:   result.Height,
+		FFmpegMs: result.FFmpegTime.Milliseconds(),
+		Params:   result.Params,
+	}
+}
+
+// reportSlow hands a finished cache-miss conversion to the slow-conversion reporter
+func (h *ConverterHandler) reportSlow(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, start time.Time, stages *services.Stages, err error) {
+	if h.slow.Threshold(req.MediaType) <= 0 {
+		return
+	}
+	conv := &models.SlowConversion{
+		Time:       start,
+		RequestID:  reqid.FromContext(ctx),
+		DeviceID:   req.DeviceID,
+		MediaType:  req.MediaType,
+		Level:      req.AntiFingerprintLevel,
+		SourceHash: hashSource(req.URL),
+		Success:    err == nil,
+		DurationMs: time.Since(start).Milliseconds(),
+		StagesMs:   stages.Milliseconds(),
+	}
+	if t != nil {
+		conv.TenantID = t.ID
+	}
+	if err != nil {
+		conv.Error = err.Error()
+	}
+	h.slow.Observe(conv)
+}
+
+// convert runs the converter behind the media type's circuit breaker, retrying once in safe mode
+// Also returns whether the output came from the safe-mode retry
+func (h *ConverterHandler) convert(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, urlHash string, inputData []byte, opts services.ConvertOptions) (*services.ConversionResult, bool, error) {
+	circuit := h.breakers[req.MediaType]
+	if err := circuit.Allow(); err != nil {
+		return nil, false, circuitError(req.MediaType, err)
+	}
+	result, err := h.runConverter(ctx, t, req.DeviceID, urlHash, req.MediaType, req.AntiFingerprintLevel, inputData, opts)
+	fallback := false
+	if err != nil && h.fallback && services.CanFallback(err) {
+		// Filter and encoder failures get one more try without filters; the original error is kept if it fails too
+		reqid.Printf(ctx, "🛟 Retrying in safe mode: device=%s, type=%s, reason=%v", req.DeviceID, req.MediaType, err)
+		var fallbackErr error
+		result, fallbackErr = h.runConverter(ctx, t, req.DeviceID, urlHash, req.MediaType, services.FallbackLevel, inputData, opts.Fallback())
+		if fallbackErr == nil {
+			err, fallback = nil, true
+		} else {
+			reqid.Printf(ctx, "❌ Safe mode failed too: device=%s, reason=%v", req.DeviceID, fallbackErr)
+		}
+	}
+	if ctx.Err() != nil || services.IsInputFault(err) {
+		circuit.Skip()
+	} else {
+		circuit.Record(err)
+	}
+	if err != nil {
+		return nil, false, h.conversionError(ctx, fmt.Sprintf("Conversion failed: %s", req.MediaType), err)
+	}
+	return result, fallback, nil
+}
+
+// cacheKeyFor keys outputs by source URL; outputs produced with different options are cached separately
+func cacheKeyFor(req *models.ConvertRequest, opts services.ConvertOptions) string {
+	if sig := opts.Signature(); sig != "" {
+		return req.URL + "#" + sig
+	}
+	return req.URL
+}
+
+// cacheSource returns what an output can be converted again from when AF profiles change
+// nil for uploads and inline data, which aren't kept
+func cacheSource(t *tenant.Tenant, req *models.ConvertRequest) *cache.Source {
+	if req.Data != "" || strings.HasPrefix(req.URL, "upload:") {
+		return nil
+	}
+	source := &cache.Source{Request: *req}
+	if t != nil {
+		source.TenantID = t.ID
+	}
+	// Only the cached rendition is converted again: nothing is packaged, uploaded or measured
+	source.Request.Outputs = nil
+	source.Request.Packaging, source.Request.Upload = "", false
+	source.Request.DestinationURL = ""
+	source.Request.QualityMetrics, source.Request.KeepProcessing = false, false
+	return source
+}
+
+// cachedResponse answers from the device's cache; nil on a miss or when the cached file is gone
+func (h *ConverterHandler) cachedResponse(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, cacheKey string, start time.Time) *models.ConvertResponse {
+	cachedEntry := h.cache.Get(t.DeviceKey(req.DeviceID), cacheKey)
+	if cachedEntry == nil {
+		return nil
+	}
+	fileInfo, err := os.Stat(cachedEntry.ProcessedPath)
+	if err != nil {
+		// File was deleted, cache entry will be cleaned up
+		return nil
+	}
+
+	reqid.Printf(ctx, "✅ CACHE HIT: device=%s, url=%s, path=%s",
+		req.DeviceID, truncateURL(req.URL), cachedEntry.ProcessedPath)
+	h.recordUsage(t, req.DeviceID, true, 0, fileInfo.Size(), 0)
+
+	return &models.ConvertResponse{
+		Success:        true,
+		ProcessedPath:  cachedEntry.ProcessedPath,
+		CacheHit:       true,
+		MediaType:      cachedEntry.MediaType,
+		ProcessedSize:  fileInfo.Size(),
+		CacheExpires:   cachedEntry.CacheExpires.Format(time.RFC3339),
+		FileExpires:    cachedEntry.FileExpires.Format(time.RFC3339),
+		ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		Timings:        &models.Timings{},
+		Experiment:     req.Experiment,
+		Variant:        req.Variant,
+		Fallback:       cachedEntry.Fallback,
+	}
+}
+
+// cacheableFailure reports whether err would recur for the same input and options
+// Transient failures, timeouts, cancellations and server-side errors are retried instead
+func cacheableFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || services.IsTransient(err) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return errorStatus(err) < fiber.StatusInternalServerError || services.IsInputFault(err)
+}
+
+// checkCircuit rejects requests while the media type's ffmpeg circuit is open
+func (h *ConverterHandler) checkCircuit(mediaType string) error {
+	if err := h.breakers[mediaType].Check(); err != nil {
+		return circuitError(mediaType, err)
+	}
+	return nil
+}
+
+// circuitError maps an open circuit to 503; transient so jobs retry once it closes
+func circuitError(mediaType string, err error) error {
+	return wrapRequestError(fiber.StatusServiceUnavailable, apierr.CircuitOpen,
+		fmt.Sprintf("%s conversion is temporarily unavailable", mediaType), &services.TransientError{Err: err})
+}
+
+// checkSpace rejects new conversions while the cache volume is below MIN_FREE_DISK
+func (h *ConverterHandler) checkSpace() error {
+	if err := h.cache.CheckSpace(); err != nil {
+		return storageError(err)
+	}
+	return nil
+}
+
+// admit waits for or rejects conversions while the host is short of CPU or memory
+func (h *ConverterHandler) admit(ctx context.Context, mediaType string) error {
+	err := h.admission.Admit(ctx, mediaType)
+	var overload *admission.OverloadError
+	if errors.As(err, &overload) {
+		return wrapRequestError(fiber.StatusServiceUnavailable, apierr.Overloaded,
+			fmt.Sprintf("Server is too busy to convert %s right now", mediaType), &services.TransientError{Err: err})
+	}
+	return err
+}
+
+// storageError maps a full cache volume to 507; transient so jobs retry once space is freed
+func storageError(err error) *RequestError {
+	return wrapRequestError(fiber.StatusInsufficientStorage, apierr.InsufficientStorage,
+		"Not enough disk space to convert right now", &services.TransientError{Err: err})
+}
+
+// verifyOutput checks that the output plays when verification is enabled and deletes it otherwise
+// Failures are transient: a fresh encode usually succeeds, so jobs retry
+func (h *ConverterHandler) verifyOutput(ctx context.Context, req *models.ConvertRequest, source []byte, sourceInfo *services.MediaInfo, outputPath string) error {
+	if !h.verify.Enabled {
+		return nil
+	}
+
+	// The source is only probed up front when input limits are set
+	if sourceInfo == nil && req.MediaType != "image" && h.verify.DurationTolerance > 0 {
+		info, err := services.ProbeMedia(ctx, source)
+		if err != nil {
+			reqid.Printf(ctx, "⚠️  Could not probe source for output verification, skipping duration check: %v", err)
+		}
+		sourceInfo = info
+	}
+
+	err := h.verify.Verify(ctx, req.MediaType, outputPath, sourceInfo)
+	if err == nil {
+		return nil
+	}
+	os.Remove(outputPath)
+
+	var outputErr *services.OutputError
+	if !errors.As(err, &outputErr) {
+		return h.conversionError(ctx, "Output verification failed", err)
+	}
+	reqid.Printf(ctx, "💔 Output failed verification: device=%s, type=%s, level=%s, %v", req.DeviceID, req.MediaType, req.AntiFingerprintLevel, err)
+	return wrapRequestError(fiber.StatusInternalServerError, apierr.OutputInvalid, "Output failed verification",
+		&services.TransientError{Err: err})
+}
+
+// measureQuality scores the output against its source when requested or configured
+// Outputs below a configured minimum are deleted; measurement errors only fail the request when minimums are set
+func (h *ConverterHandler) measureQuality(ctx context.Context, req *models.ConvertRequest, source []byte, outputPath string) (*models.QualityReport, error) {
+	if !h.quality.Wanted(req.MediaType, req.QualityMetrics) {
+		return nil, nil
+	}
+
+	scores, err := h.quality.Measure(ctx, source, outputPath)
+	if err != nil {
+		reqid.Printf(ctx, "⚠️  Quality measurement failed: device=%s, err=%v", req.DeviceID, err)
+		if !h.quality.Enforced() {
+			return nil, nil
+		}
+		os.Remove(outputPath)
+		return nil, h.conversionError(ctx, "Quality measurement failed", err)
+	}
+
+	if err := h.quality.Check(scores); err != nil {
+		reqid.Printf(ctx, "📉 Quality too low: device=%s, type=%s, level=%s, %v", req.DeviceID, req.MediaType, req.AntiFingerprintLevel, err)
+		os.Remove(outputPath)
+		return nil, wrapRequestError(fiber.StatusUnprocessableEntity, apierr.QualityTooLow, "Output quality below the configured minimum", err)
+	}
+
+	return &models.QualityReport{SSIM: scores.SSIM, PSNR: scores.PSNR, VMAF: scores.VMAF}, nil
+}
+
+// parseConvertOptions validates the optional processing fields of a request
+// Options that don't apply to the media type are ignored
+func parseConvertOptions(req *models.ConvertRequest) (services.ConvertOptions, error) {
+	var opts services.ConvertOptions
+	if (req.MediaType == "image" || req.MediaType == "video") && req.MaxResolution != "" {
+		longEdge, shortEdge, err := services.ParseMaxResolution(req.MaxResolution)
+		if err != nil {
+			return opts, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Invalid max_resolution", err.Error())
+		}
+		opts.MaxLongEdge, opts.MaxShortEdge = longEdge, shortEdge
+	}
+	if req.MediaType == "video" && req.FrameRate != "" {
+		frameRate, err := services.ParseFrameRate(req.FrameRate)
+		if err != nil {
+			return opts, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Invalid frame_rate", err.Error())
+		}
+		opts.FrameRate = frameRate
+	}
+	if req.MediaType == "video" {
+		opts.DropAudio = req.DropAudio
+	}
+
+	if req.MediaType == "image" {
+		imageFormat, err := services.ParseImageFormat(req.ImageFormat)
+		if err != nil {
+			return opts, newRequestError(fiber.StatusBadRequest, apierr.UnsupportedFormat, "Invalid image_format", err.Error())
+		}
+		opts.ImageFormat = imageFormat
+	}
+
+	if req.MediaType == "audio" {
+		audioFormat, err := services.ParseAudioFormat(req.AudioFormat)
+		if err != nil {
+			return opts, newRequestError(fiber.StatusBadRequest, apierr.UnsupportedFormat, "Invalid audio_format", err.Error())
+		}
+		opts.AudioFormat = audioFormat
+	}
+
+	if req.Watermark != nil && (req.MediaType == "image" || req.MediaType == "video") {
+		wm := &services.Watermark{
+			Text:      req.Watermark.Text,
+			LogoURL:   req.Watermark.ImageURL,
+			Position:  req.Watermark.Position,
+			Opacity:   req.Watermark.Opacity,
+			FontSize:  req.Watermark.FontSize,
+			FontColor: req.Watermark.FontColor,
+			Scale:     req.Watermark.Scale,
+		}
+		if err := wm.Normalize(); err != nil {
+			return opts, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Invalid watermark", err.Error())
+		}
+		opts.Watermark = wm
+	}
+
+	return opts, nil
+}
+
+// runConverter converts inputData with the converter for mediaType (local or remote) and describes the output
+// Output goes to the media-specific subdirectory of the cache dir (under the tenant's storage prefix)
+func (h *ConverterHandler) runConverter(ctx context.Context, t *tenant.Tenant, deviceID, keyHash, mediaType, level string, inputData []byte, opts services.ConvertOptions) (*services.ConversionResult, error) {
+	if mediaType == "image" {
+		// Images default to the input's format, so the file is named after what will be written
+		opts.ImageFormat = h.imageConverter.OutputFormat(inputData, opts)
+	}
+	outputPath, err := h.outputPath(t, deviceID, keyHash, mediaType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// A streamed download relays the first encode to the client as ffmpeg produces it
+	ctx = attachLive(ctx, outputPath, mediaType)
+	var result *services.ConversionResult
+	err = writeOutput(outputPath, func(tempPath string) error {
+		var err error
+		switch {
+		case h.remoteConverter.Handles(mediaType):
+			// The remote encode and its transfer can't be told apart, so all of it counts as encode
+			defer services.StagesFrom(ctx).Since("encode", time.Now())
+			result, err = h.remoteConverter.Convert(ctx, mediaType, inputData, level, tempPath, opts)
+		case mediaType == "audio":
+			result, err = h.audioConverter.Convert(ctx, inputData, level, tempPath, opts)
+		case mediaType == "image":
+			result, err = h.imageConverter.Convert(ctx, inputData, level, tempPath, opts)
+		default:
+			result, err = h.videoConverter.Convert(ctx, inputData, level, tempPath, opts)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Path, err = fixExtension(ctx, outputPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fixExtension renames outputPath when its extension doesn't match the format inside
+// The cache records the path as returned, so hits are served under the right name too
+func fixExtension(ctx context.Context, outputPath string) (string, error) {
+	outputType, ok := services.DetectOutputFileType(outputPath)
+	ext := filepath.Ext(outputPath)
+	if !ok || ext == outputType.Ext || (ext == ".jpeg" && outputType.Ext == ".jpg") {
+		return outputPath, nil
+	}
+	fixed := strings.TrimSuffix(outputPath, ext) + outputType.Ext
+	if err := os.Rename(outputPath, fixed); err != nil {
+		return "", fmt.Errorf("failed to rename output: %w", err)
+	}
+	reqid.Printf(ctx, "🏷️  Output renamed to match its content: %s -> %s", filepath.Base(outputPath), filepath.Base(fixed))
+	return fixed, nil
+}
+
+// outputPath returns a new output path in the media type's cache directory, creating the directory
+func (h *ConverterHandler) outputPath(t *tenant.Tenant, deviceID, keyHash, mediaType string, opts services.ConvertOptions) (string, error) {
+	mediaCacheDir := h.mediaDir(t, mediaType)
+
+	// Ensure media subdirectory exists
+	if err := os.MkdirAll(mediaCacheDir, 0755); err != nil {
+		log.Printf("❌ Failed to create directory %s: %v", mediaCacheDir, err)
+		return "", fmt.Errorf("failed to create media cache directory: %w", err)
+	}
+
+	var outputPath string
+	switch mediaType {
+	case "audio":
+		outputPath = h.audioConverter.GenerateOutputPath(mediaCacheDir, deviceID, keyHash, opts.AudioFormat)
+	case "image":
+		outputPath = h.imageConverter.GenerateOutputPath(mediaCacheDir, deviceID, keyHash)
+		if opts.ImageFormat != "" {
+			outputPath = h.imageConverter.OutputPathFor(outputPath, opts.ImageFormat)
+		}
+	case "video":
+		outputPath = h.videoConverter.GenerateOutputPath(mediaCacheDir, deviceID, keyHash)
+	default:
+		return "", fmt.Errorf("unsupported media_type: %s", mediaType)
+	}
+	if err := confine(h.cacheDir, outputPath); err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// confine fails unless path lies inside root, so no request value can place a file outside the cache dir
+func confine(root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		log.Printf("🚫 Path outside the cache dir rejected: %s", path)
+		return fmt.Errorf("output path is outside the cache directory")
+	}
+	return nil
+}
+
+// writeOutput runs write against a hidden temp file next to outputPath and renames it into place
+// A crash or failure mid-write never leaves a truncated file at outputPath; empty outputs are rejected
+func writeOutput(outputPath string, write func(tempPath string) error) error {
+	tempPath := filepath.Join(filepath.Dir(outputPath), ".tmp-"+filepath.Base(outputPath))
+	defer os.Remove(tempPath)
+
+	if err := write(tempPath); err != nil {
+		return err
+	}
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		return fmt.Errorf("output was not written: %w", err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("output is empty")
+	}
+	return os.Rename(tempPath, outputPath)
+}
+
+// recordUsage adds a request to the usage rollups; failures are logged, not returned
+func (h *ConverterHandler) recordUsage(t *tenant.Tenant, deviceID string, cacheHit bool, bytesIn, bytesOut int64, runtime time.Duration) {
+	var tenantID string
+	if t != nil {
+		tenantID = t.ID
+	}
+
+	err := h.usage.Record(usage.Event{
+		TenantID: tenantID,
+		DeviceID: deviceID,
+		CacheHit: cacheHit,
+		BytesIn:  bytesIn,
+		BytesOut: bytesOut,
+		Runtime:  runtime,
 	})
+	if err != nil {
+		log.Printf("⚠️  Failed to record usage: %v", err)
+	}
+}
+
+// auditConvert records the outcome of a convert request
+func (h *ConverterHandler) auditConvert(ctx context.Context, req *models.ConvertRequest, start time.Time, resp *models.ConvertResponse, err error) {
+	entry := newAuditEntry(ctx, "convert", req.DeviceID, start)
+	entry.DurationMs = time.Since(start).Milliseconds()
+	entry.SourceHash = hashSource(req.URL)
+	entry.MediaType = req.MediaType
+	entry.Level = req.AntiFingerprintLevel
+	entry.Experiment = req.Experiment
+	entry.Variant = req.Variant
+	entry.Status = fiber.StatusOK
+	if err != nil {
+		entry.Status = errorStatus(err)
+		entry.ErrorCode = errorCode(err)
+		entry.Error = err.Error()
+	}
+	if resp != nil {
+		entry.Success = true
+		entry.CacheHit = resp.CacheHit
+		entry.OriginalSize = resp.OriginalSize
+		entry.ProcessedSize = resp.ProcessedSize
+		entry.Fallback = resp.Fallback
+	}
+	h.audit.Record(entry)
+}
+
+// newAuditEntry starts an audit entry for a request handled under ctx
+func newAuditEntry(ctx context.Context, operation, deviceID string, start time.Time) *models.AuditEntry {
+	return &models.AuditEntry{
+		Time:      start.UTC(),
+		Operation: operation,
+		Caller:    audit.CallerFromContext(ctx),
+		RequestID: reqid.FromContext(ctx),
+		TenantID:  tenant.IDFromContext(ctx),
+		DeviceID:  deviceID,
+	}
+}
+
+// finishAudit completes an entry from the HTTP response and records it
+// Used by handlers that write responses directly instead of returning errors
+func (h *ConverterHandler) finishAudit(c fiber.Ctx, entry *models.AuditEntry) {
+	entry.DurationMs = time.Since(entry.Time).Milliseconds()
+	entry.Status = c.Response().StatusCode()
+	entry.Success = entry.Status < fiber.StatusBadRequest
+	if !entry.Success {
+		var errResp models.ErrorResponse
+		if json.Unmarshal(c.Response().Body(), &errResp) == nil {
+			entry.Error = errResp.Error
+			entry.ErrorCode = errResp.Code
+			if errResp.Details != "" {
+				entry.Error += ": " + errResp.Details
+			}
+		}
+	}
+	h.audit.Record(entry)
+}
+
+// mediaDir returns the output directory for mediaType
+func (h *ConverterHandler) mediaDir(t *tenant.Tenant, mediaType string) string {
+	return filepath.Join(h.cacheDir, t.StorageDir(), getMediaSubdir(mediaType))
 }
 
 // GetCacheStats handles GET /api/cache/stats/:deviceID
@@ -295,8 +1373,8 @@ func (h *ConverterHandler) GetCacheStats(c fiber.Ctx) error {
 		})
 	}
 
-	// Return device-specific stats
-	deviceStats := h.cache.GetDeviceStats(deviceID)
+	// Return device-specific stats (within the caller's tenant)
+	deviceStats := h.cache.GetDeviceStats(h.tenants.Get(tenant.IDFromFiber(c)).DeviceKey(deviceID))
 	globalStats := h.cache.GetGlobalStats()
 
 	return c.JSON(models.CacheStatsResponse{
@@ -306,12 +1384,137 @@ func (h *ConverterHandler) GetCacheStats(c fiber.Ctx) error {
 	})
 }
 
+// InvalidateCache handles DELETE /api/cache/:deviceID?url=
+// Drops the device's outputs of url (all its outputs without url), so the next request converts again,
+// and purges the URLs they were uploaded to from the CDN
+func (h *ConverterHandler) InvalidateCache(c fiber.Ctx) error {
+	deviceID := c.Params("deviceID")
+	sourceURL := c.Query("url")
+
+	removed := h.cache.Invalidate(h.tenants.Get(tenant.IDFromFiber(c)).DeviceKey(deviceID), sourceURL)
+	resp := models.CacheInvalidateResponse{
+		DeviceID:    deviceID,
+		URL:         sourceURL,
+		Invalidated: len(removed),
+		Purged:      []string{},
+	}
+	var uploads []string
+	for _, entry := range removed {
+		uploads = append(uploads, entry.Uploads...)
+	}
+	if h.cdn != nil {
+		for _, upload := range uploads {
+			resp.Purged = append(resp.Purged, h.cdn.PublicURL(upload))
+		}
+		h.cdn.Invalidate("cache invalidation", uploads...)
+	}
+	return c.JSON(resp)
+}
+
+// PinCache handles POST /api/cache/:deviceID/pin?url=
+// The device's outputs of url stay cached past the TTLs until they are unpinned
+func (h *ConverterHandler) PinCache(c fiber.Ctx) error {
+	deviceID := c.Params("deviceID")
+	sourceURL := c.Query("url")
+	if sourceURL == "" {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest, "url is required", "")
+	}
+
+	pinned, err := h.cache.Pin(h.tenants.Get(tenant.IDFromFiber(c)).DeviceKey(deviceID), sourceURL)
+	var quotaErr *cache.PinQuotaError
+	switch {
+	case errors.Is(err, cache.ErrNotCached):
+		return apierr.Write(c, fiber.StatusNotFound, apierr.NotCached, "No cached output to pin", err.Error())
+	case errors.As(err, &quotaErr):
+		return apierr.Write(c, fiber.StatusConflict, apierr.PinQuotaExceeded, "Pinned output quota reached", err.Error())
+	case err != nil:
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError, "Failed to pin output", err.Error())
+	}
+	return c.JSON(models.CachePinResponse{DeviceID: deviceID, URL: sourceURL, Pinned: len(pinned)})
+}
+
+// UnpinCache handles DELETE /api/cache/:deviceID/pin?url=
+// Without url, everything the device has pinned is unpinned
+func (h *ConverterHandler) UnpinCache(c fiber.Ctx) error {
+	deviceID := c.Params("deviceID")
+	sourceURL := c.Query("url")
+
+	unpinned := h.cache.Unpin(h.tenants.Get(tenant.IDFromFiber(c)).DeviceKey(deviceID), sourceURL)
+	return c.JSON(models.CachePinResponse{DeviceID: deviceID, URL: sourceURL, Unpinned: unpinned})
+}
+
+// CheckCache handles GET and HEAD /api/cache/check?device_id=&url=
+// Options are taken from the query string, with the /convert field names, and resolved as a
+// conversion would resolve them. Nothing is downloaded or converted, and the hit rate is untouched.
+// HEAD answers 404 on a miss; GET always answers 200 with "cached".
+func (h *ConverterHandler) CheckCache(c fiber.Ctx) error {
+	req := models.ConvertRequest{
+		DeviceID:             c.Query("device_id"),
+		URL:                  c.Query("url"),
+		MediaType:            c.Query("media_type"),
+		AntiFingerprintLevel: c.Query("anti_fingerprint_level"),
+		Profile:              c.Query("profile"),
+		MaxResolution:        c.Query("max_resolution"),
+		FrameRate:            c.Query("frame_rate"),
+		ExtractAudio:         c.Query("extract_audio") == "true",
+		DropAudio:            c.Query("drop_audio") == "true",
+		AudioFormat:          c.Query("audio_format"),
+		ImageFormat:          c.Query("image_format"),
+	}
+	if req.URL == "" {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest, "url is required", "")
+	}
+	if services.IsDataURI(req.URL) {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest, "Inline data is never cached", "")
+	}
+
+	t := h.tenants.Get(tenant.IDFromFiber(c))
+	sourceURL := req.URL
+	opts, err := h.prepareRequest(&req, t)
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	resp := models.CacheCheckResponse{DeviceID: req.DeviceID, URL: sourceURL}
+	entry, ok := h.cache.Peek(t.DeviceKey(req.DeviceID), cacheKeyFor(&req, opts))
+	if ok {
+		// The entry outlives its file when the file was deleted underneath it
+		if _, err := os.Stat(entry.ProcessedPath); err == nil {
+			resp.Cached = true
+			resp.MediaType = entry.MediaType
+			resp.Size = entry.Size
+			resp.Pinned = entry.Pinned
+			if !entry.Pinned {
+				resp.CacheExpires = entry.CacheExpires.Format(time.RFC3339)
+				resp.FileExpires = entry.FileExpires.Format(time.RFC3339)
+			}
+		}
+	}
+
+	if c.Method() == fiber.MethodHead {
+		c.Set("X-Cache-Status", "MISS")
+		if !resp.Cached {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		c.Set("X-Cache-Status", "HIT")
+		if resp.CacheExpires != "" {
+			c.Set("X-Cache-Expires", resp.CacheExpires)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+	return c.JSON(resp)
+}
+
 // Health handles GET /api/health
 func (h *ConverterHandler) Health(c fiber.Ctx) error {
 	// Check FFmpeg availability
 	ffmpegVersion := "unknown"
-	if output, err := exec.Command("ffmpeg", "-version").Output(); err == nil {
-		lines := strings.Split(string(output), "\n")
+	var output bytes.Buffer
+	cmd := services.FFmpeg("-version")
+	cmd.Stdout = &output
+	cmd.Timeout = 5 * time.Second
+	if _, err := services.RunCommand(c.Context(), cmd); err == nil {
+		lines := strings.Split(output.String(), "\n")
 		if len(lines) > 0 {
 			ffmpegVersion = strings.TrimSpace(lines[0])
 		}
@@ -320,18 +1523,39 @@ func (h *ConverterHandler) Health(c fiber.Ctx) error {
 	workerStats := h.workerPool.GetStats()
 	bufferStats := h.bufferPool.GetStats()
 	cacheStats := h.cache.GetGlobalStats()
+	if cdnStats := h.cdn.Stats(); cdnStats != nil {
+		cacheStats["cdn"] = cdnStats
+	}
+	cacheStats["fast_lane"] = map[string]interface{}{
+		"hits":         h.fastLane.hits.Load(),
+		"misses":       h.fastLane.misses.Load(),
+		"avg_hit_time": h.fastLane.avgHitTime().String(),
+	}
+	cacheStats["probe"] = services.ProbeCacheStats()
+
+	// An open circuit means a media type is failing fast; the service itself still answers
+	status := "healthy"
+	for _, circuit := range h.breakers {
+		if circuit.State().State != breaker.Closed {
+			status = "degraded"
+		}
+	}
+	if h.cache.LowDisk() || h.admission.Overloaded() {
+		status = "degraded"
+	}
 
 	return c.JSON(models.HealthResponse{
-		Status:        "healthy",
+		Status:        status,
 		Timestamp:     time.Now().Format(time.RFC3339),
 		FFmpegVersion: ffmpegVersion,
 		WorkerPool: map[string]interface{}{
-			"max_workers":    workerStats.MaxWorkers,
-			"active_workers": workerStats.ActiveWorkers,
-			"total_tasks":    workerStats.TotalTasks,
-			"failed_tasks":   workerStats.FailedTasks,
-			"avg_exec_time":  workerStats.AvgExecTime.String(),
-			"queue_size":     workerStats.QueueSize,
+			"max_workers":        workerStats.MaxWorkers,
+			"active_workers":     workerStats.ActiveWorkers,
+			"total_tasks":        workerStats.TotalTasks,
+			"failed_tasks":       workerStats.FailedTasks,
+			"avg_exec_time":      workerStats.AvgExecTime.String(),
+			"queue_size":         workerStats.QueueSize,
+			"active_conversions": h.active.Load(),
 		},
 		BufferPool: map[string]interface{}{
 			"allocated": bufferStats.Allocated,
@@ -339,72 +1563,120 @@ func (h *ConverterHandler) Health(c fiber.Ctx) error {
 			"available": bufferStats.Available,
 			"hit_rate":  fmt.Sprintf("%.2f%%", bufferStats.HitRate),
 		},
-		Cache: cacheStats,
+		Cache:      cacheStats,
+		Converters: h.converterStats(),
+		OpenHosts:  h.downloader.OpenHosts(),
+		Processes: map[string]interface{}{
+			"running": services.RunningProcesses(),
+			"reaped":  services.ReapedProcesses(),
+		},
+		Admission: h.admission.Stats(),
+		Runtime:   runtimeTuning(),
 	})
 }
 
-// Helper functions
-
-func hashURL(url string) string {
-	hash := md5.Sum([]byte(url))
-	return hex.EncodeToString(hash[:])
+// ActiveConversions reports conversions currently holding a slot; cache hits don't count
+func (h *ConverterHandler) ActiveConversions() int64 {
+	return h.active.Load()
 }
 
-func truncateURL(url string) string {
-	if len(url) > 60 {
-		return url[:57] + "..."
+// converterStats reports per-media conversion counters with failures by category
+func (h *ConverterHandler) converterStats() map[string]interface{} {
+	stats := map[string]interface{}{}
+	for _, media := range h.mediaStats() {
+		stats[media.name] = map[string]interface{}{
+			"total_conversions":   media.total,
+			"failed_conversions":  media.failed,
+			"avg_conversion_time": media.avgTime.String(),
+			"failure_reasons":     media.failureReasons,
+			"circuit":             h.breakers[media.name].State(),
+		}
 	}
-	return url
+	return stats
 }
 
-// detectMediaType detects media type from URL extension
-func detectMediaType(url string) string {
-	urlLower := strings.ToLower(url)
+// mediaStat is one converter's stats in a media-agnostic shape
+type mediaStat struct {
+	name           string
+	total          int64
+	failed         int64
+	avgTime        time.Duration
+	failureReasons map[string]int64
+}
 
-	// Audio extensions
-	if strings.HasSuffix(urlLower, ".mp3") ||
-		strings.HasSuffix(urlLower, ".opus") ||
-		strings.HasSuffix(urlLower, ".ogg") ||
-		strings.HasSuffix(urlLower, ".m4a") ||
-		strings.HasSuffix(urlLower, ".wav") ||
-		strings.HasSuffix(urlLower, ".aac") {
-		return "audio"
+// mediaStats snapshots the audio, image and video converters in a stable order
+func (h *ConverterHandler) mediaStats() []mediaStat {
+	audio := h.audioConverter.GetStats()
+	image := h.imageConverter.GetStats()
+	video := h.videoConverter.GetStats()
+	return []mediaStat{
+		{"audio", audio.TotalConversions, audio.FailedConversions, audio.AvgConversionTime, allCategories(audio.FailureReasons)},
+		{"image", image.TotalConversions, image.FailedConversions, image.AvgConversionTime, allCategories(image.FailureReasons)},
+		{"video", video.TotalConversions, video.FailedConversions, video.AvgConversionTime, allCategories(video.FailureReasons)},
 	}
+}
 
-	// Image extensions
-	if strings.HasSuffix(urlLower, ".jpg") ||
-		strings.HasSuffix(urlLower, ".jpeg") ||
-		strings.HasSuffix(urlLower, ".png") ||
-		strings.HasSuffix(urlLower, ".webp") ||
-		strings.HasSuffix(urlLower, ".gif") {
-		return "image"
+// allCategories fills in zero counts so every failure category is always reported
+func allCategories(reasons map[string]int64) map[string]int64 {
+	filled := make(map[string]int64, len(services.FailureCategories))
+	for _, category := range services.FailureCategories {
+		filled[category] = reasons[category]
 	}
+	return filled
+}
 
-	// Video extensions
-	if strings.HasSuffix(urlLower, ".mp4") ||
-		strings.HasSuffix(urlLower, ".avi") ||
-		strings.HasSuffix(urlLower, ".mov") ||
-		strings.HasSuffix(urlLower, ".mkv") ||
-		strings.HasSuffix(urlLower, ".webm") ||
-		strings.HasSuffix(urlLower, ".flv") {
-		return "video"
+// runtimeTuning reports the GC settings in effect (GOGC, GOMEMLIMIT) and current heap use
+func runtimeTuning() map[string]interface{} {
+	samples := []metrics.Sample{
+		{Name: "/gc/gogc:percent"},
+		{Name: "/gc/gomemlimit:bytes"},
+		{Name: "/memory/classes/heap/objects:bytes"},
 	}
+	metrics.Read(samples)
 
-	return ""
+	// Disabled settings read back as huge values
+	var gogc, memLimit interface{} = "off", "off"
+	if percent := samples[0].Value.Uint64(); percent <= math.MaxInt32 {
+		gogc = percent
+	}
+	if limit := samples[1].Value.Uint64(); limit < math.MaxInt64 {
+		memLimit = limit
+	}
+	return map[string]interface{}{
+		"gogc":             gogc,
+		"gomemlimit_bytes": memLimit,
+		"heap_bytes":       samples[2].Value.Uint64(),
+		"goroutines":       runtime.NumGoroutine(),
+	}
 }
 
-// getDefaultAFLevel returns the recommended AF level for media type
-func getDefaultAFLevel(mediaType string) string {
-	switch mediaType {
-	case "audio":
-		return "moderate"
-	case "image":
-		return "moderate"
-	case "video":
-		return "basic"
-	default:
-		return "moderate"
+// Helper functions
+
+func hashURL(url string) string {
+	hash := md5.Sum([]byte(url))
+	return hex.EncodeToString(hash[:])
+}
+
+// hashSource returns the SHA-256 of a source URL (or inline payload) for audit records
+func hashSource(source string) string {
+	if source == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncateURL(url string) string {
+	url = remote.Redact(url) // FTP/SFTP URLs may carry a password
+	if len(url) > 60 {
+		return url[:57] + "..."
 	}
+	return url
+}
+
+// isSupportedMediaType reports whether a converter exists for the media type
+func isSupportedMediaType(mediaType string) bool {
+	return mediaType == "audio" || mediaType == "image" || mediaType == "video"
 }
 
 // getMediaSubdir returns the subdirectory for the media type
@@ -421,35 +1693,46 @@ func getMediaSubdir(mediaType string) string {
 	}
 }
 
-// sendFile streams file to client with appropriate content type
-func (h *ConverterHandler) sendFile(c fiber.Ctx, filePath, mediaType string) error {
-	// Set appropriate content type
-	var contentType string
-	var fileName string
-
+// outputContentType returns the MIME type of an output file of mediaType
+// The file's content decides; the extension and media type are the fallback for files still being written
+func outputContentType(filePath, mediaType string) string {
+	if outputType, ok := services.DetectOutputFileType(filePath); ok {
+		return outputType.MIME
+	}
 	switch mediaType {
 	case "audio":
-		contentType = "audio/ogg"
-		fileName = filepath.Base(filePath)
+		if strings.HasSuffix(filePath, ".mp3") {
+			return "audio/mpeg"
+		}
+		return "audio/ogg; codecs=opus"
 	case "image":
-		// Detect if JPEG or PNG
-		if strings.HasSuffix(filePath, ".jpg") || strings.HasSuffix(filePath, ".jpeg") {
-			contentType = "image/jpeg"
-		} else {
-			contentType = "image/png"
+		switch path.Ext(filePath) {
+		case ".jpg", ".jpeg":
+			return "image/jpeg"
+		case ".webp":
+			return "image/webp"
+		case ".avif":
+			return "image/avif"
+		case ".jxl":
+			return "image/jxl"
 		}
-		fileName = filepath.Base(filePath)
+		return "image/png"
 	case "video":
-		contentType = "video/mp4"
-		fileName = filepath.Base(filePath)
+		return "video/mp4"
 	default:
-		contentType = "application/octet-stream"
-		fileName = filepath.Base(filePath)
+		return "application/octet-stream"
+	}
+}
+
+// sendFile streams file to client with appropriate content type
+func (h *ConverterHandler) sendFile(c fiber.Ctx, filePath, mediaType string) error {
+	if err := confine(h.cacheDir, filePath); err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError, "Failed to send output file", "")
 	}
 
 	// Set headers
-	c.Set("Content-Type", contentType)
-	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.Set("Content-Type", outputContentType(filePath, mediaType))
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(filePath)))
 
 	// Send file
 	return c.SendFile(filePath)
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/jobs"
+	"fingerprint-converter/internal/models"
+)
+
+// dashboardInterval is how often the dashboard samples its counters
+const dashboardInterval = 10 * time.Second
+
+// dashboardWindows are the periods rates are averaged over, shortest first
+var dashboardWindows = []struct {
+	name   string
+	period time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// counterSample is the cumulative counters read at one point in time
+type counterSample struct {
+	at       time.Time
+	counters map[string]int64
+}
+
+// DashboardHandler serves a consolidated operational snapshot (GET /api/admin/dashboard)
+// Counters are sampled in the background so rates are available without Prometheus
+type DashboardHandler struct {
+	converter *ConverterHandler
+	jobs      *jobs.Manager // nil when async jobs are disabled
+	started   time.Time
+	mu        sync.Mutex
+	samples   []counterSample // Oldest first, spanning the longest window
+	stop      chan struct{}
+}
+
+// NewDashboardHandler creates a dashboard handler and starts sampling; started is the process start time
+func NewDashboardHandler(converter *ConverterHandler, jobManager *jobs.Manager, started time.Time) *DashboardHandler {
+	h := &DashboardHandler{
+		converter: converter,
+		jobs:      jobManager,
+		started:   started,
+		stop:      make(chan struct{}),
+	}
+	h.sample()
+	go h.sampleLoop()
+	return h
+}
+
+// Stop ends background sampling
+func (h *DashboardHandler) Stop() {
+	close(h.stop)
+}
+
+// sampleLoop records the counters every dashboardInterval
+func (h *DashboardHandler) sampleLoop() {
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.sample()
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// sample records the current counters and drops samples older than the longest window
+func (h *DashboardHandler) sample() {
+	now := time.Now()
+	current := counterSample{at: now, counters: h.counters()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, current)
+	longest := dashboardWindows[len(dashboardWindows)-1].period
+	for len(h.samples) > 1 && now.Sub(h.samples[1].at) >= longest {
+		h.samples = h.samples[1:]
+	}
+}
+
+// counters reads every cumulative counter the dashboard reports rates for
+func (h *DashboardHandler) counters() map[string]int64 {
+	ch := h.converter
+	counters := map[string]int64{}
+	for _, media := range ch.mediaStats() {
+		counters["conversions"] += media.total
+		counters["conversion_failures"] += media.failed
+	}
+	counters["cache_hits"], counters["cache_misses"] = ch.cache.HitCounts()
+	counters["slow_conversions"], _ = ch.slow.Stats()
+
+	downloads := ch.downloader.GetStats()
+	counters["downloads"] = downloads.Downloads
+	counters["download_failures"] = downloads.Failed
+	counters["download_bytes"] = downloads.Bytes
+
+	workers := ch.workerPool.GetStats()
+	counters["worker_tasks"] = workers.TotalTasks
+	counters["worker_failures"] = workers.FailedTasks
+
+	if h.jobs != nil {
+		jobStats := h.jobs.GetStats()
+		counters["jobs_completed"] = jobStats.Completed
+		counters["jobs_failed"] = jobStats.Failed
+		counters["jobs_retried"] = jobStats.Retried
+	}
+	return counters
+}
+
+// rates compares now with the oldest sample inside each window
+// A window longer than the samples kept so far is averaged over what there is
+func (h *DashboardHandler) rates(now counterSample) map[string]models.RateStat {
+	h.mu.Lock()
+	samples := h.samples
+	h.mu.Unlock()
+
+	rates := make(map[string]models.RateStat, len(now.counters))
+	for name, total := range now.counters {
+		rates[name] = models.RateStat{Total: total, PerMin: make(map[string]float64, len(dashboardWindows))}
+	}
+	for _, window := range dashboardWindows {
+		var base *counterSample
+		for i := range samples {
+			if now.at.Sub(samples[i].at) <= window.period {
+				base = &samples[i]
+				break
+			}
+		}
+		if base == nil {
+			base = &samples[len(samples)-1]
+		}
+		elapsed := now.at.Sub(base.at).Minutes()
+		for name, total := range now.counters {
+			perMin := 0.0
+			if elapsed > 0 {
+				perMin = math.Round(float64(total-base.counters[name])/elapsed*100) / 100
+			}
+			rates[name].PerMin[window.name] = perMin
+		}
+	}
+	return rates
+}
+
+// Dashboard handles GET /api/admin/dashboard
+// Worker, buffer, cache, converter, downloader and job-queue stats with 1m/5m/15m rates in one response
+func (h *DashboardHandler) Dashboard(c fiber.Ctx) error {
+	ch := h.converter
+	now := counterSample{at: time.Now(), counters: h.counters()}
+
+	workerStats := ch.workerPool.GetStats()
+	bufferStats := ch.bufferPool.GetStats()
+	downloads := ch.downloader.GetStats()
+
+	resp := models.DashboardResponse{
+		Timestamp: now.at.Format(time.RFC3339),
+		Uptime:    time.Since(h.started).Round(time.Second).String(),
+		WorkerPool: map[string]interface{}{
+			"max_workers":        workerStats.MaxWorkers,
+			"active_workers":     workerStats.ActiveWorkers,
+			"queue_size":         workerStats.QueueSize,
+			"avg_exec_time":      workerStats.AvgExecTime.String(),
+			"active_conversions": ch.active.Load(),
+		},
+		BufferPool: map[string]interface{}{
+			"allocated": bufferStats.Allocated,
+			"in_use":    bufferStats.InUse,
+			"available": bufferStats.Available,
+			"hit_rate":  fmt.Sprintf("%.2f%%", bufferStats.HitRate),
+		},
+		Cache:      ch.cache.GetGlobalStats(),
+		Converters: ch.converterStats(),
+		Downloader: map[string]interface{}{
+			"downloads":  downloads.Downloads,
+			"failed":     downloads.Failed,
+			"total_mb":   downloads.Bytes / (1024 * 1024),
+			"open_hosts": ch.downloader.OpenHosts(),
+		},
+		Rates: h.rates(now),
+	}
+	if h.jobs != nil {
+		jobStats := h.jobs.GetStats()
+		resp.Jobs = map[string]interface{}{
+			"workers":   jobStats.Workers,
+			"queued":    jobStats.Queued,
+			"completed": jobStats.Completed,
+			"failed":    jobStats.Failed,
+			"retried":   jobStats.Retried,
+		}
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/models"
+)
+
+// recentPauses is how many of the latest GC pauses Runtime reports
+const recentPauses = 16
+
+// DebugHandler serves runtime diagnostics (GET /api/debug/runtime)
+type DebugHandler struct {
+	started time.Time
+}
+
+// NewDebugHandler creates a new debug handler; started is the process start time
+func NewDebugHandler(started time.Time) *DebugHandler {
+	return &DebugHandler{started: started}
+}
+
+// Runtime handles GET /api/debug/runtime
+// Reports goroutines, heap usage and GC pauses without rebuilding with instrumentation
+func (h *DebugHandler) Runtime(c fiber.Ctx) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	// PauseNs is a circular buffer; the latest pause is at (NumGC+255)%256
+	pauses := make([]float64, 0, recentPauses)
+	for i := uint32(0); i < min(mem.NumGC, recentPauses); i++ {
+		ns := mem.PauseNs[(mem.NumGC-1-i)%uint32(len(mem.PauseNs))]
+		pauses = append(pauses, float64(ns)/1e6)
+	}
+
+	lastGC := ""
+	if mem.LastGC > 0 {
+		lastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+	}
+
+	return c.JSON(models.RuntimeResponse{
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Uptime:     time.Since(h.started).Round(time.Second).String(),
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		Heap: models.HeapStats{
+			Alloc:      mem.HeapAlloc,
+			Sys:        mem.HeapSys,
+			InUse:      mem.HeapInuse,
+			Idle:       mem.HeapIdle,
+			Released:   mem.HeapReleased,
+			Objects:    mem.HeapObjects,
+			TotalAlloc: mem.TotalAlloc,
+			NextGC:     mem.NextGC,
+			TotalSys:   mem.Sys,
+		},
+		GC: models.GCStatsReply{
+			NumGC:         mem.NumGC,
+			LastGC:        lastGC,
+			PauseTotalMs:  float64(mem.PauseTotalNs) / 1e6,
+			RecentPauseMs: pauses,
+			CPUFraction:   mem.GCCPUFraction,
+		},
+	})
+}
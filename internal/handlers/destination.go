@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// checkDestination rejects a destination_url that can't take the prepared request's output
+func checkDestination(req *models.ConvertRequest) error {
+	if req.DestinationURL == "" {
+		return nil
+	}
+	u, err := url.Parse(req.DestinationURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "destination_url must be an http(s) URL", "")
+	}
+	if req.Packaging != "" {
+		return newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"destination_url can't be combined with packaging", "packages are uploaded with upload: true")
+	}
+	return nil
+}
+
+// deliver PUTs the output to the request's destination_url and reports where it went in processed_url
+// The output stays cached, so a repeated request uploads it again without converting.
+// The CDN in front of the destination is purged, since the object may have replaced an older output.
+func (h *ConverterHandler) deliver(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, resp *models.ConvertResponse) (*models.ConvertResponse, error) {
+	if req.DestinationURL == "" {
+		return resp, nil
+	}
+
+	start := time.Now()
+	destination := services.StripQuery(req.DestinationURL)
+	if err := services.UploadFile(ctx, req.DestinationURL, resp.ProcessedPath, outputContentType(resp.ProcessedPath, resp.MediaType)); err != nil {
+		reqid.Printf(ctx, "❌ Destination upload failed: device=%s, destination=%s, error=%v", req.DeviceID, destination, err)
+		return nil, wrapRequestError(fiber.StatusBadGateway, apierr.UploadFailed, "Failed to upload to destination_url", err)
+	}
+
+	resp.ProcessedURL = destination
+	if resp.Timings != nil {
+		resp.Timings.UploadMs += time.Since(start).Milliseconds()
+	}
+	h.cache.RecordUpload(t.DeviceKey(req.DeviceID), resp.ProcessedPath, destination)
+	h.cdn.Invalidate("destination upload", destination)
+	reqid.Printf(ctx, "☁️  Uploaded output: device=%s, destination=%s, size=%d, duration=%dms",
+		req.DeviceID, destination, resp.ProcessedSize, time.Since(start).Milliseconds())
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/devices"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// DeviceHandler manages registered devices and their per-device defaults
+// Tenants only see and change their own devices
+type DeviceHandler struct {
+	store *devices.Store
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(store *devices.Store) *DeviceHandler {
+	return &DeviceHandler{store: store}
+}
+
+// List handles GET /api/devices
+func (h *DeviceHandler) List(c fiber.Ctx) error {
+	list, err := h.store.List(tenant.IDFromFiber(c))
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to list devices", err.Error())
+	}
+	return c.JSON(models.DeviceListResponse{Devices: list, Count: len(list)})
+}
+
+// Get handles GET /api/devices/:deviceID
+func (h *DeviceHandler) Get(c fiber.Ctx) error {
+	device, err := h.store.Get(tenant.IDFromFiber(c), c.Params("deviceID"))
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to load device", err.Error())
+	}
+	if device == nil {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.DeviceNotFound, "Device not registered", "")
+	}
+	return c.JSON(device)
+}
+
+// Put handles PUT /api/devices/:deviceID (register or replace settings)
+func (h *DeviceHandler) Put(c fiber.Ctx) error {
+	deviceID := c.Params("deviceID")
+	if len(deviceID) > 256 {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Invalid device ID", "device IDs are limited to 256 characters")
+	}
+
+	var req models.DeviceRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+	if _, ok := services.Profile(req.Profile); req.Profile != "" && !ok {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			fmt.Sprintf("Unknown profile: %s", req.Profile),
+			"Defined profiles: "+strings.Join(services.ProfileNames(), ", "))
+	}
+
+	device, err := h.store.Put(tenant.IDFromFiber(c), models.Device{
+		DeviceID:          deviceID,
+		Label:             req.Label,
+		DefaultLevel:      req.DefaultLevel,
+		Profile:           req.Profile,
+		AllowedMediaTypes: req.AllowedMediaTypes,
+	})
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to save device", err.Error())
+	}
+	return c.JSON(device)
+}
+
+// Delete handles DELETE /api/devices/:deviceID
+// Cached outputs and history are kept; only the per-device defaults go away
+func (h *DeviceHandler) Delete(c fiber.Ctx) error {
+	found, err := h.store.Delete(tenant.IDFromFiber(c), c.Params("deviceID"))
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to delete device", err.Error())
+	}
+	if !found {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.DeviceNotFound, "Device not registered", "")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/reqid"
+)
+
+// disconnectPoll is how often a request being processed checks that its client is still connected
+const disconnectPoll = 500 * time.Millisecond
+
+// processContext returns the context a request is processed under: the request timeout, cancelled
+// early when the client disconnects so the download and ffmpeg stop. With keep the work runs to the
+// end regardless, so the output is still cached.
+func (h *ConverterHandler) processContext(c fiber.Ctx, keep bool) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(h.requestContext(c), h.requestTimeout)
+	if keep {
+		return ctx, cancel
+	}
+	conn := c.Context().Conn()
+	if tlsConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = tlsConn.NetConn()
+	}
+
+	go func() {
+		ticker := time.NewTicker(disconnectPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if connClosed(conn) {
+					reqid.Printf(ctx, "🔌 Client disconnected, cancelling its conversion")
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package handlers
+
+import "net"
+
+// connClosed is not implemented on this platform; requests then run to the end after a disconnect
+func connClosed(conn net.Conn) bool {
+	return false
+}
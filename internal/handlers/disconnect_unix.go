@@ -0,0 +1,35 @@
+//go:build unix
+
+package handlers
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// connClosed reports whether the peer has closed conn, without consuming anything it sent
+func connClosed(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	closed := false
+	raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == nil:
+			closed = n == 0 // Orderly shutdown; pipelined bytes mean the client is still there
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+		default:
+			closed = true // Reset or otherwise broken
+		}
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/validation"
+)
+
+// RequestError is a pipeline failure that knows which HTTP status and error code it maps to
+type RequestError struct {
+	Status  int
+	Code    string // apierr code
+	Message string
+	Details string
+	Fields  []models.InvalidParam // Field-level validation failures, if any
+	Err     error                 // Underlying cause, if any
+}
+
+func (e *RequestError) Error() string {
+	if e.Details == "" {
+		return e.Message
+	}
+	return e.Message + ": " + e.Details
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode implements apierr.Coded
+func (e *RequestError) ErrorCode() string {
+	return e.Code
+}
+
+// newRequestError creates a RequestError; details may be empty
+func newRequestError(status int, code, message, details string) *RequestError {
+	return &RequestError{Status: status, Code: code, Message: message, Details: details}
+}
+
+// wrapRequestError creates a RequestError that keeps err as its cause
+func wrapRequestError(status int, code, message string, err error) *RequestError {
+	return &RequestError{Status: status, Code: code, Message: message, Details: err.Error(), Err: err}
+}
+
+// downloadError maps a downloader failure to 503, 413, 422 or 400
+func downloadError(message string, err error) *RequestError {
+	var hostErr *services.HostUnavailableError
+	if errors.As(err, &hostErr) {
+		return wrapRequestError(fiber.StatusServiceUnavailable, apierr.SourceUnavailable, message, err)
+	}
+	if errors.Is(err, services.ErrFileTooLarge) {
+		return wrapRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge, message, err)
+	}
+	var mismatchErr *services.MismatchError
+	if errors.As(err, &mismatchErr) {
+		return wrapRequestError(fiber.StatusUnprocessableEntity, apierr.ContentMismatch, "File content does not match media_type", err)
+	}
+	return wrapRequestError(fiber.StatusBadRequest, apierr.DownloadFailed, message, err)
+}
+
+// conversionError maps an ffmpeg failure to 504 when ctx ran out of time, 500 otherwise
+// Details carry the translated reason; raw stderr is only exposed in debug mode
+func (h *ConverterHandler) conversionError(ctx context.Context, message string, err error) *RequestError {
+	reqErr := wrapRequestError(fiber.StatusInternalServerError, apierr.ConversionFailed, message, err)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reqErr.Status, reqErr.Code = fiber.StatusGatewayTimeout, apierr.FFmpegTimeout
+		reqErr.Details = "processing exceeded the request timeout"
+		return reqErr
+	}
+
+	// A disk that filled up mid-write is reported like one that was full before
+	if services.IsOutOfSpace(err) {
+		reqid.Printf(ctx, "❌ %s: %v", message, err)
+		reqErr = storageError(err)
+		reqErr.Details = "server ran out of disk space"
+		return reqErr
+	}
+
+	var ffErr *services.FFmpegError
+	isFFmpeg := errors.As(err, &ffErr)
+	switch {
+	case isFFmpeg && h.debug:
+		reqErr.Details = err.Error() + ", stderr: " + strings.TrimSpace(ffErr.Stderr)
+	case !isFFmpeg && !h.debug:
+		// File system errors name local paths; keep them in the server log
+		reqid.Printf(ctx, "❌ %s: %v", message, err)
+		reqErr.Details = "internal processing error"
+	}
+	return reqErr
+}
+
+// asRequestError classifies err, falling back to timeout or internal error
+func asRequestError(err error) *RequestError {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return wrapRequestError(fiber.StatusGatewayTimeout, apierr.FFmpegTimeout, "Request timed out", err)
+	}
+	return wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError, "Internal Server Error", err)
+}
+
+// errorStatus returns the HTTP status err maps to
+func errorStatus(err error) int {
+	return asRequestError(err).Status
+}
+
+// errorCode returns the API error code err maps to
+func errorCode(err error) string {
+	return asRequestError(err).Code
+}
+
+// respondError writes err as a problem details response
+func respondError(c fiber.Ctx, err error) error {
+	reqErr := asRequestError(err)
+	problem := apierr.Problem(c, reqErr.Status, reqErr.Code, reqErr.Message, reqErr.Details)
+	problem.InvalidParams = reqErr.Fields
+	return apierr.WriteProblem(c, problem)
+}
+
+// bindJSON parses the body into out and enforces its validate tags
+func bindJSON(c fiber.Ctx, out any) error {
+	err := c.Bind().JSON(out)
+	if err == nil {
+		return nil
+	}
+	return requestBodyError(err)
+}
+
+// requestBodyError maps decode and struct validation failures to 400
+func requestBodyError(err error) error {
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		reqErr := wrapRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Validation failed", err)
+		reqErr.Fields = invalid.Fields
+		return reqErr
+	}
+	return wrapRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Invalid request body", err)
+}
+
+// ErrorHandler is the Fiber error handler; it keeps framework errors in problem details form
+func ErrorHandler(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	message := "Internal Server Error"
+
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		status = e.Code
+		message = e.Message
+	}
+	return apierr.Write(c, status, apierr.CodeForStatus(status), message, "")
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/experiments"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// ExperimentHandler reports A/B experiment outcomes (admin, cross-tenant)
+type ExperimentHandler struct {
+	tracker *experiments.Tracker
+}
+
+// NewExperimentHandler creates a new experiment handler
+func NewExperimentHandler(tracker *experiments.Tracker) *ExperimentHandler {
+	return &ExperimentHandler{tracker: tracker}
+}
+
+// Stats handles GET /api/admin/experiments
+func (h *ExperimentHandler) Stats(c fiber.Ctx) error {
+	return c.JSON(models.ExperimentsResponse{
+		Experiments: h.tracker.Report(services.Experiments()),
+	})
+}
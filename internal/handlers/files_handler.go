@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+)
+
+// ConvertFiles handles POST /api/convert/files
+// Each file may be a different media type and level; files are converted concurrently through the regular pipeline
+// (cached, audited and limited on their own), and one failing file doesn't fail the others.
+// With ?download=zip (or true) the outputs come back as a zip named after the tags.
+func (h *ConverterHandler) ConvertFiles(c fiber.Ctx) error {
+	start := time.Now()
+
+	var req models.FilesRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+	seen := make(map[string]int, len(req.Files))
+	for i, file := range req.Files {
+		if j, ok := seen[file.Tag]; ok {
+			return respondError(c, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+				fmt.Sprintf("Duplicate tag %q", file.Tag), fmt.Sprintf("files[%d] and files[%d] have the same tag", j, i)))
+		}
+		seen[file.Tag] = i
+	}
+	downloadMode := c.Query("download") == "true" || c.Query("download") == "zip"
+
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+	reqid.Printf(ctx, "📎 FILES: device=%s, files=%d", req.DeviceID, len(req.Files))
+
+	// Encodes still queue for workers and admission, so a request can't take more than its share
+	results := make([]models.FileResult, len(req.Files))
+	responses := make([]*models.ConvertResponse, len(req.Files))
+	errs := make([]error, len(req.Files))
+	var wg sync.WaitGroup
+	for i, file := range req.Files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fileReq := fileRequest(req.DeviceID, file)
+			responses[i], errs[i] = h.Process(ctx, fileReq)
+			results[i].MediaType = fileReq.MediaType
+		}()
+	}
+	wg.Wait()
+
+	resp := models.FilesResponse{Files: make(map[string]models.FileResult, len(req.Files))}
+	var firstErr error
+	bundle := newBundleBuilder()
+	flatten := strings.NewReplacer("/", "_", "\\", "_")
+	for i, file := range req.Files {
+		result := results[i]
+		if err := errs[i]; err != nil {
+			reqErr := asRequestError(err)
+			result.Error, result.Code = reqErr.Error(), reqErr.Code
+			resp.Failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			bundle.addFailure(file.Tag, result.MediaType, err)
+			reqid.Printf(ctx, "❌ File failed: device=%s, tag=%s, error=%v", req.DeviceID, file.Tag, err)
+		} else {
+			result.Success = true
+			result.Result = responses[i]
+			resp.Converted++
+			bundle.add(file.Tag, flatten.Replace(file.Tag), responses[i])
+		}
+		resp.Files[file.Tag] = result
+	}
+
+	resp.Success = resp.Failed == 0
+	resp.ProcessingTime = fmt.Sprintf("%d", time.Since(start).Milliseconds())
+	reqid.Printf(ctx, "✅ FILES DONE: device=%s, converted=%d, failed=%d, time=%sms",
+		req.DeviceID, resp.Converted, resp.Failed, resp.ProcessingTime)
+
+	if downloadMode {
+		if resp.Converted == 0 {
+			return respondError(c, firstErr)
+		}
+		return bundle.send(c, "files.zip")
+	}
+	return c.JSON(resp)
+}
+
+// fileRequest turns one file of a files request into a convert request
+func fileRequest(deviceID string, file models.FileSpec) *models.ConvertRequest {
+	return &models.ConvertRequest{
+		DeviceID:             deviceID,
+		URL:                  file.URL,
+		Data:                 file.Data,
+		MediaType:            file.MediaType,
+		AntiFingerprintLevel: file.AntiFingerprintLevel,
+		Profile:              file.Profile,
+		MaxResolution:        file.MaxResolution,
+		FrameRate:            file.FrameRate,
+		ExtractAudio:         file.ExtractAudio,
+		DropAudio:            file.DropAudio,
+		AudioFormat:          file.AudioFormat,
+		ImageFormat:          file.ImageFormat,
+	}
+}
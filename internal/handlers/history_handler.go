@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/history"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/tenant"
+)
+
+// HistoryHandler serves recent conversions per device
+type HistoryHandler struct {
+	store *history.Store
+}
+
+// NewHistoryHandler creates a new history handler
+func NewHistoryHandler(store *history.Store) *HistoryHandler {
+	return &HistoryHandler{store: store}
+}
+
+// Get handles GET /api/devices/:deviceID/history?limit=
+// Tenants only see their own devices
+func (h *HistoryHandler) Get(c fiber.Ctx) error {
+	deviceID := c.Params("deviceID")
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"Invalid limit", "limit must be a positive integer")
+		}
+		limit = parsed
+	}
+
+	entries := h.store.Recent(tenant.IDFromFiber(c), deviceID, limit)
+	return c.JSON(models.DeviceHistoryResponse{
+		DeviceID: deviceID,
+		Entries:  entries,
+		Count:    len(entries),
+	})
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/jobs"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/tenant"
+)
+
+// maxJobWait caps ?wait= on GET /api/jobs/:id, keeping held connections well inside WRITE_TIMEOUT
+const maxJobWait = 60 * time.Second
+
+// JobHandler handles async conversion jobs
+type JobHandler struct {
+	manager       *jobs.Manager
+	converter     *ConverterHandler
+	scheduleAhead time.Duration // Furthest schedule_at accepted
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(manager *jobs.Manager, converter *ConverterHandler, scheduleAhead time.Duration) *JobHandler {
+	return &JobHandler{
+		manager:       manager,
+		converter:     converter,
+		scheduleAhead: scheduleAhead,
+	}
+}
+
+// Submit handles POST /api/jobs
+func (h *JobHandler) Submit(c fiber.Ctx) error {
+	var req models.JobSubmitRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+
+	scheduled := req.ScheduleAt != nil && req.ScheduleAt.After(time.Now())
+	if scheduled && time.Until(*req.ScheduleAt) > h.scheduleAhead {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"schedule_at is too far ahead", fmt.Sprintf("max: %s from now", h.scheduleAhead))
+	}
+
+	// Reject invalid requests up front instead of failing the job later
+	// An open circuit only matters for jobs that run now; it may have closed by a scheduled job's turn
+	validate := h.converter.ValidateRequest
+	if scheduled {
+		validate = h.converter.validateRequest
+	}
+	if err := validate(h.converter.requestContext(c), &req.ConvertRequest); err != nil {
+		return respondError(c, err)
+	}
+
+	job, err := h.manager.Submit(tenant.IDFromFiber(c), reqid.FromFiber(c), req.ConvertRequest, req.Retry, req.ScheduleAt)
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			return apierr.Write(c, fiber.StatusServiceUnavailable, apierr.QueueFull,
+				"Job queue is full, retry later", "")
+		}
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to submit job", err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// Get handles GET /api/jobs/:id?wait=
+// With wait the response is held until the job completes or fails, or the wait runs out
+func (h *JobHandler) Get(c fiber.Ctx) error {
+	wait, err := parseWait(c.Query("wait"))
+	if err != nil {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Invalid wait", err.Error())
+	}
+
+	job, err := h.manager.Get(c.Params("id"))
+	if err == nil && wait > 0 && job != nil && job.TenantID == tenant.IDFromFiber(c) {
+		// Stops early if the client goes away
+		ctx, cancel := h.converter.processContext(c, false)
+		ctx, cancelWait := context.WithTimeout(ctx, wait)
+		job, err = h.manager.Wait(ctx, job.ID)
+		cancelWait()
+		cancel()
+	}
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to load job", err.Error())
+	}
+	// Other tenants' jobs are reported as missing
+	if job == nil || job.TenantID != tenant.IDFromFiber(c) {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.JobNotFound, "Job not found", "")
+	}
+
+	return c.JSON(job)
+}
+
+// parseWait parses ?wait= as a duration ("30s") or whole seconds ("30")
+func parseWait(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, err
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 || wait > maxJobWait {
+		return 0, fmt.Errorf("must be between 0 and %s", maxJobWait)
+	}
+	return wait, nil
+}
+
+// DeadLetter handles GET /api/jobs/dead-letter?device_id=&limit=
+func (h *JobHandler) DeadLetter(c fiber.Ctx) error {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"limit must be between 1 and 1000", "")
+		}
+		limit = parsed
+	}
+
+	list, err := h.manager.DeadLetter(tenant.IDFromFiber(c), c.Query("device_id"), limit)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to list dead-letter jobs", err.Error())
+	}
+
+	return c.JSON(models.JobListResponse{
+		Jobs:  list,
+		Count: len(list),
+	})
+}
+
+// Requeue handles POST /api/jobs/:id/requeue
+func (h *JobHandler) Requeue(c fiber.Ctx) error {
+	id := c.Params("id")
+
+	owned, err := h.manager.Get(id)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to load job", err.Error())
+	}
+	if owned == nil || owned.TenantID != tenant.IDFromFiber(c) {
+		return apierr.Write(c, fiber.StatusNotFound, apierr.JobNotFound, "Job not found", "")
+	}
+
+	job, err := h.manager.Requeue(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			return apierr.Write(c, fiber.StatusNotFound, apierr.JobNotFound, "Job not found", "")
+		case errors.Is(err, jobs.ErrJobNotFailed):
+			return apierr.Write(c, fiber.StatusConflict, apierr.JobNotFailed,
+				"Job is not in the dead-letter list", err.Error())
+		}
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to requeue job", err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// List handles GET /api/jobs?status=&device_id=&limit=
+func (h *JobHandler) List(c fiber.Ctx) error {
+	filter := jobs.Filter{
+		Status:   c.Query("status"),
+		DeviceID: c.Query("device_id"),
+		TenantID: tenant.IDFromFiber(c),
+		Limit:    100,
+	}
+
+	switch filter.Status {
+	case "", models.JobStatusQueued, models.JobStatusProcessing, models.JobStatusCompleted, models.JobStatusFailed:
+	default:
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Invalid status filter", "Supported: queued, processing, completed, failed")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"limit must be between 1 and 1000", "")
+		}
+		filter.Limit = parsed
+	}
+
+	list, err := h.manager.List(filter)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to list jobs", err.Error())
+	}
+
+	return c.JSON(models.JobListResponse{
+		Jobs:  list,
+		Count: len(list),
+	})
+}
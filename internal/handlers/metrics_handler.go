@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/breaker"
+	"fingerprint-converter/internal/services"
+)
+
+// Metrics handles GET /metrics in the Prometheus text exposition format
+func (h *ConverterHandler) Metrics(c fiber.Ctx) error {
+	var b strings.Builder
+	stats := h.mediaStats()
+
+	writeFamily(&b, "fingerprint_conversions_total", "counter", "Successful conversions by media type")
+	for _, media := range stats {
+		fmt.Fprintf(&b, "fingerprint_conversions_total{media=%q} %d\n", media.name, media.total)
+	}
+
+	writeFamily(&b, "fingerprint_conversion_failures_total", "counter", "Failed conversions by media type and failure category")
+	for _, media := range stats {
+		for _, category := range services.FailureCategories {
+			fmt.Fprintf(&b, "fingerprint_conversion_failures_total{media=%q,reason=%q} %d\n",
+				media.name, category, media.failureReasons[category])
+		}
+	}
+
+	writeFamily(&b, "fingerprint_conversion_avg_seconds", "gauge", "Average duration of successful conversions")
+	for _, media := range stats {
+		fmt.Fprintf(&b, "fingerprint_conversion_avg_seconds{media=%q} %g\n", media.name, media.avgTime.Seconds())
+	}
+
+	writeFamily(&b, "fingerprint_fast_lane_hits_total", "counter", "Cache hits answered before the conversion pipeline")
+	fmt.Fprintf(&b, "fingerprint_fast_lane_hits_total %d\n", h.fastLane.hits.Load())
+	writeFamily(&b, "fingerprint_fast_lane_misses_total", "counter", "Fast-lane lookups that went on to the conversion pipeline")
+	fmt.Fprintf(&b, "fingerprint_fast_lane_misses_total %d\n", h.fastLane.misses.Load())
+	writeFamily(&b, "fingerprint_fast_lane_avg_seconds", "gauge", "Average time to answer a fast-lane cache hit")
+	fmt.Fprintf(&b, "fingerprint_fast_lane_avg_seconds %g\n", h.fastLane.avgHitTime().Seconds())
+
+	probeHits, probeMisses := services.ProbeCacheCounts()
+	writeFamily(&b, "fingerprint_probe_cache_hits_total", "counter", "Input probes answered from the probe cache")
+	fmt.Fprintf(&b, "fingerprint_probe_cache_hits_total %d\n", probeHits)
+	writeFamily(&b, "fingerprint_probe_cache_misses_total", "counter", "Input probes that ran ffprobe")
+	fmt.Fprintf(&b, "fingerprint_probe_cache_misses_total %d\n", probeMisses)
+
+	writeFamily(&b, "fingerprint_circuit_open", "gauge", "Whether the media type's ffmpeg circuit breaker rejects requests (1 = open or half-open)")
+	for _, media := range stats {
+		open := 0
+		if h.breakers[media.name].State().State != breaker.Closed {
+			open = 1
+		}
+		fmt.Fprintf(&b, "fingerprint_circuit_open{media=%q} %d\n", media.name, open)
+	}
+
+	writeFamily(&b, "fingerprint_download_hosts_open", "gauge", "Source hosts whose download circuit breaker rejects requests")
+	fmt.Fprintf(&b, "fingerprint_download_hosts_open %d\n", len(h.downloader.OpenHosts()))
+
+	workerStats := h.workerPool.GetStats()
+	writeFamily(&b, "fingerprint_workers_active", "gauge", "Workers currently running a task")
+	fmt.Fprintf(&b, "fingerprint_workers_active %d\n", workerStats.ActiveWorkers)
+	writeFamily(&b, "fingerprint_worker_queue_size", "gauge", "Tasks waiting for a worker")
+	fmt.Fprintf(&b, "fingerprint_worker_queue_size %d\n", workerStats.QueueSize)
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(b.String())
+}
+
+// writeFamily writes the HELP and TYPE lines that precede a metric's samples
+func writeFamily(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
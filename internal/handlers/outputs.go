@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// prepareOutputs resolves each output of a multi-output request into a request of its own
+// Outputs inherit the request's fields; the input's media type is resolved once and stored in req
+func (h *ConverterHandler) prepareOutputs(req *models.ConvertRequest, t *tenant.Tenant) ([]*models.ConvertRequest, []services.ConvertOptions, error) {
+	if req.ExtractAudio {
+		return nil, nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"extract_audio can't be combined with outputs", "set extract_audio on the outputs that need it")
+	}
+	if req.DestinationURL != "" {
+		return nil, nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"destination_url can't be combined with outputs", "a destination URL takes one file")
+	}
+
+	// The base request validates the shared fields and settles the input's media type
+	base := *req
+	base.Outputs = nil
+	if _, err := h.prepareRequest(&base, t); err != nil {
+		return nil, nil, err
+	}
+	req.MediaType = base.MediaType
+
+	children := make([]*models.ConvertRequest, len(req.Outputs))
+	opts := make([]services.ConvertOptions, len(req.Outputs))
+	seen := make(map[string]int, len(req.Outputs))
+	for i, spec := range req.Outputs {
+		if spec.ExtractAudio && req.MediaType != "video" {
+			return nil, nil, outputError(i, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+				"extract_audio needs a video input", "input media_type: "+req.MediaType))
+		}
+
+		// Packaging applies to the whole set, after every output is converted
+		child := *req
+		child.Outputs, child.Packaging, child.Upload = nil, "", false
+		child.ExtractAudio = spec.ExtractAudio
+		child.DropAudio = req.DropAudio || spec.DropAudio
+		if spec.AntiFingerprintLevel != "" {
+			child.AntiFingerprintLevel = spec.AntiFingerprintLevel
+		}
+		if spec.MaxResolution != "" {
+			child.MaxResolution = spec.MaxResolution
+		}
+		if spec.FrameRate != "" {
+			child.FrameRate = spec.FrameRate
+		}
+		if spec.AudioFormat != "" {
+			child.AudioFormat = spec.AudioFormat
+		}
+		if spec.ImageFormat != "" {
+			child.ImageFormat = spec.ImageFormat
+		}
+
+		childOpts, err := h.prepareRequest(&child, t)
+		if err != nil {
+			return nil, nil, outputError(i, err)
+		}
+
+		// Outputs share a cache entry unless their encode settings differ (the AF level isn't part of the key)
+		key := child.MediaType + "#" + childOpts.Signature()
+		if j, ok := seen[key]; ok {
+			return nil, nil, outputError(i, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+				fmt.Sprintf("Same rendition as outputs[%d]", j), "outputs must differ in format, resolution, frame rate or audio"))
+		}
+		seen[key] = i
+		children[i], opts[i] = &child, childOpts
+	}
+
+	if req.Packaging != "" && !slices.ContainsFunc(children, func(child *models.ConvertRequest) bool { return child.MediaType == "video" }) {
+		return nil, nil, newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"packaging needs a video output", "every output extracts audio")
+	}
+	return children, opts, nil
+}
+
+// executeOutputs runs every output of a multi-output request through the pipeline
+// The input is loaded at most once and shared; each output is encoded and cached on its own
+// The response describes the first output and lists all of them in Outputs
+func (h *ConverterHandler) executeOutputs(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, load func() ([]byte, error)) (*models.ConvertResponse, error) {
+	start := time.Now()
+	children, opts, err := h.prepareOutputs(req, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outputs that are cache hits never trigger the download
+	var input []byte
+	var loadErr error
+	loaded := false
+	shared := func() ([]byte, error) {
+		if !loaded {
+			input, loadErr = load()
+			loaded = true
+		}
+		return input, loadErr
+	}
+
+	results := make([]models.OutputResult, 0, len(children))
+	responses := make([]*models.ConvertResponse, 0, len(children))
+	cacheHit := true
+	for i, child := range children {
+		resp, err := h.execute(ctx, t, child, opts[i], shared)
+		if err != nil {
+			return nil, outputError(i, err)
+		}
+		cacheHit = cacheHit && resp.CacheHit
+		responses = append(responses, resp)
+		results = append(results, models.OutputResult{Name: outputName(req.Outputs[i], i), ConvertResponse: *resp})
+	}
+
+	reqid.Printf(ctx, "🧩 OUTPUTS: device=%s, url=%s, outputs=%d, downloaded=%t, time=%dms",
+		req.DeviceID, truncateURL(req.URL), len(results), loaded, time.Since(start).Milliseconds())
+
+	resp := results[0].ConvertResponse
+	resp.CacheHit = cacheHit
+	resp.ProcessingTime = fmt.Sprintf("%d", time.Since(start).Milliseconds())
+	resp.Timings = sumTimings(responses...)
+	resp.Outputs = results
+	return &resp, nil
+}
+
+// outputName is the output's name, or its index when unnamed
+func outputName(spec models.OutputSpec, i int) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	return strconv.Itoa(i)
+}
+
+// outputError prefixes err's message with the failing output's position
+// The error is copied because failures may be shared through the failure cache
+func outputError(i int, err error) error {
+	reqErr := *asRequestError(err)
+	reqErr.Message = fmt.Sprintf("outputs[%d]: %s", i, reqErr.Message)
+	return &reqErr
+}
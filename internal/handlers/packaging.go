@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/tenant"
+)
+
+// checkPackaging rejects packaging options that can't apply to the prepared request
+func (h *ConverterHandler) checkPackaging(req *models.ConvertRequest) error {
+	if req.Packaging != "" && req.MediaType != "video" {
+		return newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest,
+			"packaging needs a video conversion", "media_type: "+req.MediaType)
+	}
+	if req.Upload && req.Packaging == "" {
+		return newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "upload needs packaging", "set packaging to hls or dash")
+	}
+	if req.Upload && !h.packager.CanUpload() {
+		return newRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Package uploads are not configured", "PACKAGE_UPLOAD_URL is not set")
+	}
+	return nil
+}
+
+// packageResponse segments the response's video output(s) into the requested package
+// Packages live under the tenant's cache dir and are reused while their renditions are cached
+func (h *ConverterHandler) packageResponse(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, resp *models.ConvertResponse) (*models.ConvertResponse, error) {
+	if req.Packaging == "" {
+		return resp, nil
+	}
+
+	var renditions []string
+	if len(resp.Outputs) > 0 {
+		for _, output := range resp.Outputs {
+			if output.MediaType == "video" {
+				renditions = append(renditions, output.ProcessedPath)
+			}
+		}
+	} else {
+		renditions = append(renditions, resp.ProcessedPath)
+	}
+
+	packageStart := time.Now()
+	pkg, err := h.packager.Package(ctx, req.Packaging, filepath.Join(h.cacheDir, t.StorageDir(), "packages"), renditions)
+	if err != nil {
+		return nil, h.conversionError(ctx, "Packaging failed", err)
+	}
+	if resp.Timings != nil {
+		resp.Timings.EncodeMs += time.Since(packageStart).Milliseconds()
+	}
+
+	if req.Upload {
+		uploadStart := time.Now()
+		if err := h.packager.Upload(ctx, pkg, path.Join(t.StorageDir(), filepath.Base(pkg.Dir))); err != nil {
+			reqid.Printf(ctx, "❌ Package upload failed: device=%s, error=%v", req.DeviceID, err)
+			return nil, wrapRequestError(fiber.StatusBadGateway, apierr.UploadFailed, "Failed to upload package", err)
+		}
+		if resp.Timings != nil {
+			resp.Timings.UploadMs += time.Since(uploadStart).Milliseconds()
+		}
+		// A package regenerated after its files expired replaces the one the CDN may still serve
+		h.cdn.Invalidate("package upload", pkg.Uploaded...)
+	}
+
+	resp.Package = &models.PackageResult{
+		Format:       pkg.Format,
+		PlaylistPath: pkg.Playlist,
+		Files:        pkg.Files,
+		PlaylistURL:  pkg.PlaylistURL,
+	}
+	return resp, nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/hooks"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// postProcess runs the tenant's post-processor chain (or the default one) on a finished conversion
+// Cache hits are post-processed too; processors see cache_hit in the event and can skip them
+func (h *ConverterHandler) postProcess(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, resp *models.ConvertResponse) (*models.ConvertResponse, error) {
+	event := &hooks.Event{DeviceID: req.DeviceID, Source: remote.Redact(req.URL), Response: resp}
+	var chain []string
+	if t != nil {
+		event.TenantID, chain = t.ID, t.PostProcessors
+	}
+
+	// Required processors usually fail on a service that is down, so jobs retry them
+	if err := h.postProcessors.Run(ctx, chain, event); err != nil {
+		return nil, wrapRequestError(fiber.StatusBadGateway, apierr.PostProcessFailed, "Post-processing failed",
+			&services.TransientError{Err: err})
+	}
+	return resp, nil
+}
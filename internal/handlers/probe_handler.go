@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// Probe handles POST /api/probe
+// Reports container, streams, codecs, duration, bitrate, dimensions and rotation without converting
+func (h *ConverterHandler) Probe(c fiber.Ctx) error {
+	var req models.ProbeRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+
+	ctx, cancel := h.processContext(c, false)
+	defer cancel()
+
+	if _, err := h.tenantFor(ctx); err != nil {
+		return respondError(c, err)
+	}
+
+	var data []byte
+	var err error
+	if req.Data != "" || services.IsDataURI(req.URL) {
+		data, err = decodeData(&models.ConvertRequest{URL: req.URL, Data: req.Data})
+	} else if data, err = h.downloader.Download(ctx, req.URL); err != nil {
+		err = downloadError("Failed to download file", err)
+	}
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	info, err := services.ProbeMedia(ctx, data)
+	if err != nil {
+		return respondError(c, inspectionError(err))
+	}
+
+	resp := models.ProbeResponse{
+		MediaType: services.DetectMediaTypeFromContent("", data),
+		Container: info.Container,
+		Duration:  seconds(info.Duration),
+		BitRate:   info.BitRate,
+		Size:      info.Size,
+		Width:     info.Width,
+		Height:    info.Height,
+		Rotation:  info.Rotation,
+		Streams:   make([]models.ProbeStream, 0, len(info.Streams)),
+	}
+	for _, stream := range info.Streams {
+		resp.Streams = append(resp.Streams, models.ProbeStream{
+			Index:       stream.Index,
+			Type:        stream.Type,
+			Codec:       stream.Codec,
+			Profile:     stream.Profile,
+			Duration:    seconds(stream.Duration),
+			BitRate:     stream.BitRate,
+			Width:       stream.Width,
+			Height:      stream.Height,
+			Rotation:    stream.Rotation,
+			FrameRate:   math.Round(stream.FrameRate*1000) / 1000,
+			PixelFormat: stream.PixelFormat,
+			SampleRate:  stream.SampleRate,
+			Channels:    stream.Channels,
+		})
+	}
+	return c.JSON(resp)
+}
+
+// seconds renders a duration as seconds with millisecond precision
+func seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// errUploadTooLarge stops a raw upload that exceeds the size limit
+var errUploadTooLarge = errors.New("upload too large")
+
+// ConvertRaw handles POST /api/convert/raw
+// The body is the media itself; options come from the query string or X- headers.
+// The body is streamed to a temp file rather than buffered under BODY_LIMIT.
+func (h *ConverterHandler) ConvertRaw(c fiber.Ctx) error {
+	req := models.ConvertRequest{
+		DeviceID:             rawParam(c, "device_id", "X-Device-ID"),
+		MediaType:            rawParam(c, "media_type", "X-Media-Type"),
+		AntiFingerprintLevel: rawParam(c, "anti_fingerprint_level", "X-AF-Level"),
+		Profile:              rawParam(c, "profile", "X-AF-Profile"),
+		AudioFormat:          rawParam(c, "audio_format", "X-Audio-Format"),
+		DestinationURL:       rawParam(c, "destination_url", "X-Destination-URL"),
+		KeepProcessing:       rawParam(c, "keep_processing", "X-Keep-Processing") == "true",
+	}
+	filename := rawParam(c, "filename", "X-Filename")
+	downloadMode := c.Query("download") == "true" && req.DestinationURL == ""
+
+	path, err := h.spoolBody(c, req.MediaType)
+	if err != nil {
+		return respondError(c, err)
+	}
+	defer os.Remove(path)
+
+	// Converters take the input in memory; the spool only keeps it out of the request buffer
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return respondError(c, wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to read upload", err))
+	}
+
+	if req.MediaType == "" && services.DetectMediaType(filename) == "" {
+		req.MediaType = services.DetectMediaTypeFromContent(c.Get(fiber.HeaderContentType), data)
+	}
+
+	if downloadMode && c.Query("stream") == "true" {
+		return h.streamDownload(c, req.KeepProcessing, func(ctx context.Context) (*models.ConvertResponse, error) {
+			return h.ProcessData(ctx, &req, filename, data)
+		})
+	}
+
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+
+	resp, err := h.ProcessData(ctx, &req, filename, data)
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	if downloadMode {
+		return h.sendFile(c, resp.ProcessedPath, resp.MediaType)
+	}
+	return c.JSON(resp)
+}
+
+// rawParam reads an option of the raw endpoint from the query string or a header
+func rawParam(c fiber.Ctx, query, header string) string {
+	if value := c.Query(query); value != "" {
+		return value
+	}
+	return c.Get(header)
+}
+
+// uploadLimit returns the largest upload accepted for mediaType and the error message for bigger ones
+// Uploads without a declared media type get the download size limit until their type is detected
+func (h *ConverterHandler) uploadLimit(mediaType string) (int64, string) {
+	if limit := h.mediaSizeLimit(nil, mediaType); limit > 0 {
+		return limit, sizeLimitMessage(mediaType)
+	}
+	return h.downloader.MaxSize(), "Upload too large"
+}
+
+// spoolBody copies the request body to a temp file under the cache dir, capped at the upload limit for mediaType
+func (h *ConverterHandler) spoolBody(c fiber.Ctx, mediaType string) (string, error) {
+	maxSize, message := h.uploadLimit(mediaType)
+	if length := int64(c.Request().Header.ContentLength()); length > maxSize {
+		return "", newRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge,
+			message, fmt.Sprintf("max: %d bytes", maxSize))
+	}
+
+	dir := filepath.Join(h.cacheDir, "tmp")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to create upload directory", err)
+	}
+	file, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return "", wrapRequestError(fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to create upload file", err)
+	}
+
+	var stream io.Reader = c.Request().BodyStream()
+	if stream == nil {
+		stream = bytes.NewReader(c.Request().Body())
+	} else if conn := c.Context().Conn(); conn != nil && c.App().Config().ReadTimeout > 0 {
+		stream = &idleReader{r: stream, conn: conn, timeout: c.App().Config().ReadTimeout}
+	}
+	// Compressed uploads are inflated while spooling; the size cap applies to the inflated bytes
+	body, err := decodingReader(c.Get(fiber.HeaderContentEncoding), stream)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(file, io.LimitReader(body, maxSize+1))
+	if err == nil && n > maxSize {
+		err = errUploadTooLarge
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		if errors.Is(err, errUploadTooLarge) {
+			return "", newRequestError(fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge,
+				message, fmt.Sprintf("max: %d bytes", maxSize))
+		}
+		return "", wrapRequestError(fiber.StatusBadRequest, apierr.InvalidRequest, "Failed to read request body", err)
+	}
+	return file.Name(), nil
+}
+
+// idleReader renews the connection's read deadline before every read, so READ_TIMEOUT limits
+// pauses in a streamed upload rather than the whole transfer, however large it is
+type idleReader struct {
+	r       io.Reader
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(r.timeout))
+	return r.r.Read(p)
+}
+
+// LimitBody caps bodies read into memory at limit bytes. Needed because StreamRequestBody
+// lets larger bodies through to the handlers; paths in skip read the stream themselves.
+func LimitBody(limit int, skip ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if slices.Contains(skip, c.Path()) {
+			return c.Next()
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length > limit {
+			return apierr.Write(c, fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge,
+				"Request body too large", fmt.Sprintf("max: %d bytes", limit))
+		}
+
+		// Chunked bodies have no length up front, so read them here under the cap
+		if stream := c.Request().BodyStream(); stream != nil && length < 0 {
+			data, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+			if err != nil {
+				return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+					"Failed to read request body", err.Error())
+			}
+			if len(data) > limit {
+				return apierr.Write(c, fiber.StatusRequestEntityTooLarge, apierr.FileTooLarge,
+					"Request body too large", fmt.Sprintf("max: %d bytes", limit))
+			}
+			c.Request().SetBody(data)
+		}
+		return c.Next()
+	}
+}
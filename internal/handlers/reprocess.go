@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"os"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// passthrough handles inputs that are our own outputs as REPROCESS_MODE says, without re-encoding them
+// Returns the output path and the mode applied, or an empty path when the input must be converted:
+// it isn't marked, the request changes its streams or format, or the remux failed
+func (h *ConverterHandler) passthrough(ctx context.Context, t *tenant.Tenant, req *models.ConvertRequest, urlHash string, inputData []byte, inputInfo *services.MediaInfo, opts services.ConvertOptions) (string, string) {
+	marker := services.CurrentOutputMarker()
+	if h.reprocess == services.ReprocessConvert || marker == nil || req.MediaType == "image" || !keepsStreams(opts) {
+		return "", ""
+	}
+
+	if inputInfo == nil {
+		info, err := services.ProbeMedia(ctx, inputData)
+		if err != nil {
+			return "", "" // The converter reports unreadable inputs
+		}
+		inputInfo = info
+	}
+	if !marker.Marked(inputInfo) {
+		return "", ""
+	}
+
+	// Only a file already in the requested format can be returned as is
+	format := services.PassthroughFormat(req.MediaType, inputInfo)
+	wanted := "mp4"
+	if req.MediaType == "audio" {
+		wanted = opts.AudioFormat
+		if wanted == "" {
+			wanted = "opus"
+		}
+	}
+	if format != wanted {
+		reqid.Printf(ctx, "♻️  Already processed input needs another format, converting: device=%s, type=%s, format=%q",
+			req.DeviceID, req.MediaType, format)
+		return "", ""
+	}
+
+	outputPath, err := h.outputPath(t, req.DeviceID, urlHash, req.MediaType, opts)
+	if err == nil {
+		err = writeOutput(outputPath, func(tempPath string) error {
+			if h.reprocess == services.ReprocessSkip {
+				return os.WriteFile(tempPath, inputData, 0644)
+			}
+			return services.Remux(ctx, inputData, format, tempPath, nil)
+		})
+	}
+	if err != nil {
+		reqid.Printf(ctx, "⚠️  Passthrough failed, converting: device=%s, mode=%s, error=%v", req.DeviceID, h.reprocess, err)
+		os.Remove(outputPath)
+		return "", ""
+	}
+
+	reqid.Printf(ctx, "♻️  Already processed input: device=%s, type=%s, mode=%s", req.DeviceID, req.MediaType, h.reprocess)
+	return outputPath, h.reprocess
+}
+
+// keepsStreams reports whether opts leave the streams as they are
+// Resizing, frame rate changes, audio removal and watermarks always need an encode
+func keepsStreams(opts services.ConvertOptions) bool {
+	return opts.MaxLongEdge == 0 && opts.FrameRate == "" && !opts.DropAudio && opts.Watermark == nil
+}
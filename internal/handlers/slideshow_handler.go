@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/devices"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+// Slideshow handles POST /api/slideshow
+func (h *ConverterHandler) Slideshow(c fiber.Ctx) error {
+	start := time.Now()
+	auditEntry := newAuditEntry(h.requestContext(c), "slideshow", "", start)
+	defer h.finishAudit(c, auditEntry)
+
+	var req models.SlideshowRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+
+	downloadMode := c.Query("download") == "true"
+	auditEntry.DeviceID = req.DeviceID
+
+	if req.FrameDuration == 0 {
+		req.FrameDuration = 3
+	}
+
+	if req.Resolution == "" {
+		req.Resolution = "720x1280" // Vertical story format
+	}
+	width, height, err := services.ParseResolution(req.Resolution)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Invalid resolution", err.Error())
+	}
+
+	ctx, cancel := h.processContext(c, req.KeepProcessing)
+	defer cancel()
+
+	t, err := h.tenantFor(ctx)
+	if err == nil {
+		err = checkMediaAllowed(t, "video")
+	}
+	var device *models.Device
+	if err == nil {
+		device, err = h.deviceFor(t, req.DeviceID, "video")
+	}
+	if err != nil {
+		return respondError(c, err)
+	}
+	deviceKey := t.DeviceKey(req.DeviceID)
+
+	if req.AntiFingerprintLevel == "" {
+		req.AntiFingerprintLevel = devices.DefaultLevel(device, "video")
+		if req.AntiFingerprintLevel == "" {
+			req.AntiFingerprintLevel = t.DefaultLevel()
+		}
+		if req.AntiFingerprintLevel == "" {
+			req.AntiFingerprintLevel = "moderate"
+		}
+	}
+
+	// Cache key covers every input and layout parameter
+	cacheKey := fmt.Sprintf("slideshow:%s|audio=%s|d=%.3f|r=%dx%d",
+		strings.Join(req.Images, "|"), req.AudioURL, req.FrameDuration, width, height)
+
+	auditEntry.MediaType = "video"
+	auditEntry.Level = req.AntiFingerprintLevel
+	auditEntry.SourceHash = hashSource(cacheKey)
+
+	if cachedEntry := h.cache.Get(deviceKey, cacheKey); cachedEntry != nil {
+		fileInfo, err := os.Stat(cachedEntry.ProcessedPath)
+		if err == nil {
+			reqid.Printf(ctx, "✅ CACHE HIT: device=%s, slideshow of %d images, path=%s",
+				req.DeviceID, len(req.Images), cachedEntry.ProcessedPath)
+			h.recordUsage(t, req.DeviceID, true, 0, fileInfo.Size(), 0)
+			auditEntry.CacheHit = true
+			auditEntry.ProcessedSize = fileInfo.Size()
+
+			if downloadMode {
+				return h.sendFile(c, cachedEntry.ProcessedPath, cachedEntry.MediaType)
+			}
+
+			return c.JSON(models.ConvertResponse{
+				Success:        true,
+				ProcessedPath:  cachedEntry.ProcessedPath,
+				CacheHit:       true,
+				MediaType:      cachedEntry.MediaType,
+				ProcessedSize:  fileInfo.Size(),
+				CacheExpires:   cachedEntry.CacheExpires.Format(time.RFC3339),
+				FileExpires:    cachedEntry.FileExpires.Format(time.RFC3339),
+				ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+			})
+		}
+	}
+
+	if err := h.checkSpace(); err != nil {
+		return respondError(c, err)
+	}
+	if err := h.admit(ctx, "video"); err != nil {
+		return respondError(c, err)
+	}
+	if err := acquireSlot(t); err != nil {
+		return respondError(c, err)
+	}
+	defer t.Release()
+	h.active.Add(1)
+	defer h.active.Add(-1)
+
+	images, err := h.downloadAll(ctx, t, "image", req.Images)
+	if err != nil {
+		return respondError(c, h.inputDownloadError(t, "image", "Failed to download images", err))
+	}
+
+	originalSize := int64(0)
+	for _, img := range images {
+		originalSize += int64(len(img))
+	}
+
+	var audio []byte
+	if req.AudioURL != "" {
+		audio, err = h.downloader.DownloadChecked(ctx, req.AudioURL, h.downloadCheck(t, "audio"))
+		if err != nil {
+			return respondError(c, h.inputDownloadError(t, "audio", "Failed to download audio", err))
+		}
+		if err := h.checkFileSize(t, "audio", int64(len(audio))); err != nil {
+			return respondError(c, err)
+		}
+		originalSize += int64(len(audio))
+	}
+
+	err = h.scanInput(ctx, images...)
+	if err == nil && audio != nil {
+		err = h.scanInput(ctx, audio)
+	}
+	for i := 0; err == nil && i < len(images); i++ {
+		_, err = h.inspectInput(ctx, "image", images[i])
+	}
+	if err == nil && audio != nil {
+		_, err = h.inspectInput(ctx, "audio", audio)
+	}
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	mediaCacheDir := h.mediaDir(t, "video")
+	if err := os.MkdirAll(mediaCacheDir, 0755); err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to create media cache directory", err.Error())
+	}
+
+	outputPath := h.slideshowBuilder.GenerateOutputPath(mediaCacheDir, req.DeviceID, hashURL(cacheKey))
+	if err := confine(h.cacheDir, outputPath); err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to create output file", "")
+	}
+
+	buildStart := time.Now()
+	err = writeOutput(outputPath, func(tempPath string) error {
+		return h.slideshowBuilder.Build(ctx, services.SlideshowSpec{
+			Images:        images,
+			Audio:         audio,
+			FrameDuration: req.FrameDuration,
+			Width:         width,
+			Height:        height,
+			Level:         req.AntiFingerprintLevel,
+		}, tempPath)
+	})
+	if err != nil {
+		return respondError(c, h.conversionError(ctx, "Slideshow build failed", err))
+	}
+
+	fileInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to stat output file", err.Error())
+	}
+	processedSize := fileInfo.Size()
+	auditEntry.OriginalSize = originalSize
+	auditEntry.ProcessedSize = processedSize
+	h.recordUsage(t, req.DeviceID, false, originalSize, processedSize, time.Since(buildStart))
+
+	if err := h.cache.Set(deviceKey, cacheKey, outputPath, "video", processedSize); err != nil {
+		reqid.Printf(ctx, "⚠️  Failed to cache file: %v", err)
+	}
+
+	cacheExpires := ""
+	fileExpires := ""
+	if cacheEntry := h.cache.Get(deviceKey, cacheKey); cacheEntry != nil {
+		cacheExpires = cacheEntry.CacheExpires.Format(time.RFC3339)
+		fileExpires = cacheEntry.FileExpires.Format(time.RFC3339)
+	}
+
+	reqid.Printf(ctx, "✅ SLIDESHOW: device=%s, images=%d, audio=%v, level=%s, size=%d, time=%dms",
+		req.DeviceID, len(images), len(audio) > 0, req.AntiFingerprintLevel,
+		processedSize, time.Since(buildStart).Milliseconds())
+
+	if downloadMode {
+		return h.sendFile(c, outputPath, "video")
+	}
+
+	sizeIncrease := float64(processedSize-originalSize) / float64(originalSize) * 100
+
+	return c.JSON(models.ConvertResponse{
+		Success:        true,
+		ProcessedPath:  outputPath,
+		CacheHit:       false,
+		MediaType:      "video",
+		OriginalSize:   originalSize,
+		ProcessedSize:  processedSize,
+		SizeIncrease:   fmt.Sprintf("%.2f%%", sizeIncrease),
+		ProcessingTime: fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		CacheExpires:   cacheExpires,
+		FileExpires:    fileExpires,
+	})
+}
+
+// downloadAll fetches several URLs of mediaType concurrently, preserving order
+// Each file must fit the size limit for mediaType and the tenant's size quota
+func (h *ConverterHandler) downloadAll(ctx context.Context, t *tenant.Tenant, mediaType string, urls []string) ([][]byte, error) {
+	results := make([][]byte, len(urls))
+	errs := make([]error, len(urls))
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i], errs[i] = h.downloader.DownloadChecked(ctx, url, h.downloadCheck(t, mediaType))
+		}(i, url)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", truncateURL(urls[i]), err)
+		}
+		if err := h.limits.CheckSize(mediaType, int64(len(results[i]))); err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", truncateURL(urls[i]), services.ErrFileTooLarge, err)
+		}
+		if t.FileTooLarge(int64(len(results[i]))) {
+			return nil, fmt.Errorf("%s: %w: exceeds tenant size limit of %d bytes",
+				truncateURL(urls[i]), services.ErrFileTooLarge, t.Quota.MaxFileSize)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+)
+
+// errLiveMismatch cuts off a streamed response whose bytes aren't the output that was kept
+// (safe-mode retries, outputs replaced after the encode)
+var errLiveMismatch = errors.New("streamed bytes differ from the final output")
+
+// liveKey carries the liveStream of a streamed download through the conversion pipeline
+type liveKey struct{}
+
+// liveStream relays the output of a request's encode to the client while ffmpeg produces it
+// Writes are buffered and never fail or block, so a slow or departed client can't stall the encode
+type liveStream struct {
+	mu        sync.Mutex
+	ready     *sync.Cond
+	started   chan struct{} // Closed by the first write
+	attached  bool          // An encode writes to the stream; later ones (safe-mode retries) don't
+	name      string        // Output file name, for Content-Disposition
+	mediaType string
+	chunks    [][]byte
+	written   int64
+	done      bool
+	err       error // Set with done; nil = the stream ends cleanly
+	closed    bool  // The client stopped reading
+}
+
+// newLiveStream creates an empty liveStream
+func newLiveStream() *liveStream {
+	s := &liveStream{started: make(chan struct{})}
+	s.ready = sync.NewCond(&s.mu)
+	return s
+}
+
+// withLiveStream has the first audio or video encode under ctx written to s as well
+func withLiveStream(ctx context.Context, s *liveStream) context.Context {
+	return context.WithValue(ctx, liveKey{}, s)
+}
+
+// attachLive returns ctx with the encode of outputPath relayed to the request's liveStream
+// Only the first encode of a streamed request is relayed; ctx is returned as is otherwise
+func attachLive(ctx context.Context, outputPath, mediaType string) context.Context {
+	s, _ := ctx.Value(liveKey{}).(*liveStream)
+	if s == nil || (mediaType != "audio" && mediaType != "video") {
+		return ctx
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attached {
+		return ctx
+	}
+	s.attached, s.name, s.mediaType = true, filepath.Base(outputPath), mediaType
+	return services.WithLiveOutput(ctx, s)
+}
+
+// Write queues a copy of p for the client
+func (s *liveStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.written == 0 && len(p) > 0 {
+		close(s.started)
+	}
+	s.written += int64(len(p))
+	if !s.closed {
+		s.chunks = append(s.chunks, append([]byte(nil), p...))
+		s.ready.Broadcast()
+	}
+	return len(p), nil
+}
+
+// Read hands queued bytes to the response, waiting for more until the stream is finished
+func (s *liveStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.chunks) == 0 && !s.done {
+		s.ready.Wait()
+	}
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, s.chunks[0])
+	if n == len(s.chunks[0]) {
+		s.chunks = s.chunks[1:]
+	} else {
+		s.chunks[0] = s.chunks[0][n:]
+	}
+	return n, nil
+}
+
+// Close drops what is queued once the response stops reading
+func (s *liveStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed, s.chunks = true, nil
+	return nil
+}
+
+// finish ends the stream once the request is done; it ends with an error unless resp is exactly what was sent
+// Returns the error the stream ended with
+func (s *liveStream) finish(resp *models.ConvertResponse, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case err != nil:
+	case resp.Fallback || resp.ProcessedSize != s.written || filepath.Base(resp.ProcessedPath) != s.name:
+		err = errLiveMismatch
+	}
+	s.done, s.err = true, err
+	s.ready.Broadcast()
+	return err
+}
+
+// streamDownload answers ?download=true&stream=true: the output is sent, chunked, while it is encoded
+// Nothing is sent before the encode's first bytes, so earlier errors, cache hits, images and remote
+// encodes get the usual response. A failure after that cuts the response off instead of a JSON error.
+// A client that disconnects cancels the encode unless keep is set.
+func (h *ConverterHandler) streamDownload(c fiber.Ctx, keep bool, process func(ctx context.Context) (*models.ConvertResponse, error)) error {
+	// The encode outlives the handler, so its timeout is released when it ends rather than on return
+	ctx, cancel := h.processContext(c, keep)
+	live := newLiveStream()
+
+	type result struct {
+		resp *models.ConvertResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer cancel()
+		resp, err := process(withLiveStream(ctx, live))
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return respondError(c, r.err)
+		}
+		return h.sendFile(c, r.resp.ProcessedPath, r.resp.MediaType)
+	case <-live.started:
+	}
+
+	go func() {
+		r := <-done
+		if err := live.finish(r.resp, r.err); err != nil {
+			reqid.Printf(ctx, "✂️  Streamed download cut off: file=%s, error=%v", live.name, err)
+		}
+	}()
+
+	c.Set("Content-Type", outputContentType(live.name, live.mediaType))
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", live.name))
+	return c.SendStream(live)
+}
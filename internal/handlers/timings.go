@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// timingsOf reports the stages a conversion recorded; stages it didn't go through are 0
+func timingsOf(stages *services.Stages) *models.Timings {
+	ms := stages.Milliseconds()
+	return &models.Timings{
+		QueueWaitMs: ms["queue_wait"],
+		DownloadMs:  ms["download"],
+		ProbeMs:     ms["probe"],
+		EncodeMs:    ms["encode"],
+		WriteMs:     ms["write"],
+		VerifyMs:    ms["verify"],
+		UploadMs:    ms["upload"],
+	}
+}
+
+// sumTimings adds up the timings of several conversions, such as the outputs of one request
+func sumTimings(responses ...*models.ConvertResponse) *models.Timings {
+	total := &models.Timings{}
+	for _, resp := range responses {
+		if t := resp.Timings; t != nil {
+			total.QueueWaitMs += t.QueueWaitMs
+			total.DownloadMs += t.DownloadMs
+			total.ProbeMs += t.ProbeMs
+			total.EncodeMs += t.EncodeMs
+			total.WriteMs += t.WriteMs
+			total.VerifyMs += t.VerifyMs
+			total.UploadMs += t.UploadMs
+		}
+	}
+	return total
+}
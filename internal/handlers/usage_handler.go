@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/usage"
+)
+
+// maxUsageDays bounds a single usage query
+const maxUsageDays = 366
+
+// UsageHandler reports usage rollups for chargeback
+type UsageHandler struct {
+	store *usage.Store
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(store *usage.Store) *UsageHandler {
+	return &UsageHandler{store: store}
+}
+
+// Get handles GET /api/usage?from=&to=&device_id=
+// Dates are YYYY-MM-DD (UTC, inclusive); the default range is the last 30 days
+func (h *UsageHandler) Get(c fiber.Ctx) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	from := today.AddDate(0, 0, -29)
+
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(usage.DateLayout, raw)
+		if err != nil {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"Invalid to date", "Expected format: YYYY-MM-DD")
+		}
+		to = parsed
+		from = to.AddDate(0, 0, -29)
+	}
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(usage.DateLayout, raw)
+		if err != nil {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				"Invalid from date", "Expected format: YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	if from.After(to) || to.Sub(from) > maxUsageDays*24*time.Hour {
+		return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+			"Invalid date range", "from must not be after to, and the range is limited to 366 days")
+	}
+
+	// Tenants only see their own usage
+	records, err := h.store.Query(usage.Filter{
+		From:     from,
+		To:       to,
+		TenantID: tenant.IDFromFiber(c),
+		DeviceID: c.Query("device_id"),
+	})
+	if err != nil {
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to query usage", err.Error())
+	}
+
+	var totals models.UsageCounters
+	for _, record := range records {
+		totals.Conversions += record.Conversions
+		totals.CacheHits += record.CacheHits
+		totals.BytesIn += record.BytesIn
+		totals.BytesOut += record.BytesOut
+		totals.CPUSeconds += record.CPUSeconds
+	}
+
+	return c.JSON(models.UsageResponse{
+		From:   from.Format(usage.DateLayout),
+		To:     to.Format(usage.DateLayout),
+		Days:   records,
+		Totals: totals,
+	})
+}
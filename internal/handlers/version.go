@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// CurrentAPIVersion is the newest versioned route group
+const CurrentAPIVersion = "v1"
+
+// apiVersions lists the versioned route groups being served
+var apiVersions = map[string]bool{"v1": true}
+
+// legacyVersion is the version the unversioned /api routes are pinned to
+// Breaking response changes ship as a new version; /api keeps serving v1 shapes
+const legacyVersion = "v1"
+
+// APIVersion tags responses under prefix (e.g. /api) with the version that produced them
+// Unversioned paths are served as v1 and point clients at their versioned successor
+func APIVersion(prefix string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		rest := strings.TrimPrefix(c.Path(), prefix)
+		version, _, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+
+		if isAPIVersion(version) {
+			if apiVersions[version] {
+				c.Set("API-Version", version)
+			}
+			return c.Next()
+		}
+
+		c.Set("API-Version", legacyVersion)
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, "<"+prefix+"/"+legacyVersion+rest+`>; rel="successor-version"`)
+		return c.Next()
+	}
+}
+
+// isAPIVersion reports whether segment names a version route group (v1, v2, ...)
+func isAPIVersion(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+	for _, r := range segment[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
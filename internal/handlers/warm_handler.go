@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+	"fingerprint-converter/internal/warmup"
+)
+
+// WarmHandler queues conversions ahead of time
+type WarmHandler struct {
+	warmer    *warmup.Warmer
+	converter *ConverterHandler
+}
+
+// NewWarmHandler creates a new warm-up handler
+func NewWarmHandler(warmer *warmup.Warmer, converter *ConverterHandler) *WarmHandler {
+	return &WarmHandler{
+		warmer:    warmer,
+		converter: converter,
+	}
+}
+
+// Warm handles POST /api/cache/warm
+func (h *WarmHandler) Warm(c fiber.Ctx) error {
+	var req models.WarmRequest
+	if err := bindJSON(c, &req); err != nil {
+		return respondError(c, err)
+	}
+
+	// Items are checked like jobs, so the batch is rejected before anything is queued
+	ctx := h.converter.requestContext(c)
+	for i := range req.Items {
+		item := &req.Items[i]
+		if item.Data != "" || item.IsBase64 || services.IsDataURI(item.URL) {
+			return apierr.Write(c, fiber.StatusBadRequest, apierr.InvalidRequest,
+				fmt.Sprintf("items[%d]: warm-up only takes URLs", i), "")
+		}
+		if err := h.converter.ValidateRequest(ctx, item); err != nil {
+			reqErr := *asRequestError(err)
+			reqErr.Message = fmt.Sprintf("items[%d]: %s", i, reqErr.Message)
+			return respondError(c, &reqErr)
+		}
+	}
+
+	queued, err := h.warmer.Enqueue(tenant.IDFromFiber(c), req.Items)
+	if err != nil {
+		if errors.Is(err, warmup.ErrQueueFull) {
+			return apierr.Write(c, fiber.StatusServiceUnavailable, apierr.QueueFull,
+				"Warm-up queue is full, retry later", "")
+		}
+		return apierr.Write(c, fiber.StatusInternalServerError, apierr.InternalError,
+			"Failed to queue warm-up", err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.WarmResponse{
+		Queued:  queued,
+		Skipped: len(req.Items) - queued,
+	})
+}
+
+// Stats handles GET /api/cache/warm
+func (h *WarmHandler) Stats(c fiber.Ctx) error {
+	return c.JSON(h.warmer.GetStats())
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+)
+
+const (
+	wsIdleTimeout      = 60 * time.Second // Max wait between client messages
+	wsWriteTimeout     = 30 * time.Second
+	wsChunkSize        = 64 * 1024   // Size of binary frames sent back
+	wsProgressInterval = 1024 * 1024 // Upload progress is reported every 1MB
+)
+
+// WebSocketHandler serves the realtime conversion API on /api/ws
+//
+// Protocol (one conversion at a time, the socket can be reused):
+//
+//	client → {"type":"start", "device_id":..., "media_type":..., ...convert options}
+//	client → binary frames with the media bytes
+//	client → {"type":"end"}
+//	server → progress events, a result event, binary frames with the output, {"type":"done"}
+type WebSocketHandler struct {
+	converter      *ConverterHandler
+	upgrader       websocket.FastHTTPUpgrader
+	maxSize        int64
+	requestTimeout time.Duration
+}
+
+// NewWebSocketHandler creates a new WebSocket handler
+func NewWebSocketHandler(converter *ConverterHandler, maxSize int64, requestTimeout time.Duration) *WebSocketHandler {
+	if maxSize <= 0 {
+		maxSize = 500 * 1024 * 1024 // 500MB default
+	}
+	if requestTimeout <= 0 {
+		requestTimeout = 5 * time.Minute
+	}
+
+	return &WebSocketHandler{
+		converter: converter,
+		upgrader: websocket.FastHTTPUpgrader{
+			ReadBufferSize:  wsChunkSize,
+			WriteBufferSize: wsChunkSize,
+		},
+		maxSize:        maxSize,
+		requestTimeout: requestTimeout,
+	}
+}
+
+// Handle handles GET /api/ws
+func (h *WebSocketHandler) Handle(c fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.Context()) {
+		return apierr.Write(c, fiber.StatusUpgradeRequired, apierr.UpgradeRequired,
+			"WebSocket upgrade required", "")
+	}
+
+	// Locals don't outlive the upgrade, so capture the tenant and caller now
+	base := h.converter.requestContext(c)
+	err := h.upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+		h.serve(conn, base)
+	})
+	if err != nil {
+		// The upgrader already wrote an error response
+		log.Printf("⚠️  WebSocket upgrade failed: %v", err)
+	}
+	return nil
+}
+
+// serve runs the message loop of one connection
+func (h *WebSocketHandler) serve(conn *websocket.Conn, base context.Context) {
+	defer conn.Close()
+	conn.SetReadLimit(h.maxSize)
+
+	var start *models.WSStartMessage
+	var upload bytes.Buffer
+	var reported int
+	limit, message := h.maxSize, "Upload too large"
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("⚠️  WebSocket closed: %v", err)
+			}
+			return
+		}
+
+		if msgType == websocket.BinaryMessage {
+			if start == nil {
+				h.send(conn, models.WSEvent{Type: "error", Code: apierr.InvalidRequest, Error: "Send a start message before media data"})
+				continue
+			}
+			if int64(upload.Len()+len(data)) > limit {
+				h.send(conn, models.WSEvent{
+					Type:    "error",
+					Code:    apierr.FileTooLarge,
+					Error:   message,
+					Details: fmt.Sprintf("max: %d bytes", limit),
+				})
+				start, upload, reported = nil, bytes.Buffer{}, 0
+				continue
+			}
+
+			upload.Write(data)
+			if upload.Len()-reported >= wsProgressInterval {
+				reported = upload.Len()
+				h.send(conn, models.WSEvent{Type: "progress", Stage: "uploading", Bytes: int64(reported)})
+			}
+			continue
+		}
+
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			h.send(conn, models.WSEvent{Type: "error", Code: apierr.InvalidRequest, Error: "Invalid message", Details: err.Error()})
+			continue
+		}
+
+		switch msg.Type {
+		case "start":
+			start = &models.WSStartMessage{}
+			if err := json.Unmarshal(data, start); err != nil {
+				start = nil
+				h.send(conn, models.WSEvent{Type: "error", Code: apierr.InvalidRequest, Error: "Invalid start message", Details: err.Error()})
+				continue
+			}
+			upload, reported = bytes.Buffer{}, 0
+			// A declared media type may have a tighter limit than the connection's
+			limit, message = h.converter.uploadLimit(start.MediaType)
+			if limit > h.maxSize {
+				limit, message = h.maxSize, "Upload too large"
+			}
+			h.send(conn, models.WSEvent{Type: "progress", Stage: "ready"})
+
+		case "end":
+			if start == nil {
+				h.send(conn, models.WSEvent{Type: "error", Code: apierr.InvalidRequest, Error: "No conversion in progress"})
+				continue
+			}
+			if !h.convert(conn, base, start, upload.Bytes()) {
+				return
+			}
+			start, upload, reported = nil, bytes.Buffer{}, 0
+
+		default:
+			h.send(conn, models.WSEvent{
+				Type:    "error",
+				Code:    apierr.InvalidRequest,
+				Error:   fmt.Sprintf("Unknown message type: %q", msg.Type),
+				Details: "Supported: start, end",
+			})
+		}
+	}
+}
+
+// convert processes an uploaded file and streams the result back
+// Returns false if the connection is no longer usable
+func (h *WebSocketHandler) convert(conn *websocket.Conn, base context.Context, start *models.WSStartMessage, data []byte) bool {
+	if !h.send(conn, models.WSEvent{Type: "progress", Stage: "received", Bytes: int64(len(data))}) {
+		return false
+	}
+	if !h.send(conn, models.WSEvent{Type: "progress", Stage: "processing"}) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(base, h.requestTimeout)
+	defer cancel()
+
+	req := start.ConvertRequest
+	resp, err := h.converter.ProcessData(ctx, &req, start.Filename, data)
+	if err != nil {
+		return h.send(conn, wsErrorEvent(err))
+	}
+
+	file, err := os.Open(resp.ProcessedPath)
+	if err != nil {
+		return h.send(conn, models.WSEvent{Type: "error", Code: apierr.InternalError, Error: "Failed to open output file", Details: err.Error()})
+	}
+	defer file.Close()
+
+	if !h.send(conn, models.WSEvent{Type: "result", Result: resp}) {
+		return false
+	}
+
+	// Stream the output back in binary frames
+	var sent int64
+	chunk := make([]byte, wsChunkSize)
+	for {
+		n, err := file.Read(chunk)
+		if n > 0 {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.BinaryMessage, chunk[:n]); err != nil {
+				reqid.Printf(ctx, "⚠️  WebSocket write failed: %v", err)
+				return false
+			}
+			sent += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return h.send(conn, models.WSEvent{Type: "error", Code: apierr.InternalError, Error: "Failed to read output file", Details: err.Error()})
+		}
+	}
+
+	return h.send(conn, models.WSEvent{Type: "done", Bytes: sent})
+}
+
+// send writes a JSON event; returns false if the write failed
+func (h *WebSocketHandler) send(conn *websocket.Conn, event models.WSEvent) bool {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := conn.WriteJSON(event); err != nil {
+		log.Printf("⚠️  WebSocket write failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// wsErrorEvent converts a pipeline error into an error event
+func wsErrorEvent(err error) models.WSEvent {
+	reqErr := asRequestError(err)
+	return models.WSEvent{Type: "error", Code: reqErr.Code, Error: reqErr.Message, Details: reqErr.Details}
+}
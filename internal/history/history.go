@@ -0,0 +1,112 @@
+package history
+
+import (
+	"container/list"
+	"sync"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Store keeps the most recent conversions of each device in memory for support investigations
+// It is an audit sink, so it sees every request the audit log does
+// A nil Store records nothing
+type Store struct {
+	mu         sync.Mutex
+	perDevice  int                      // Entries kept per device
+	maxDevices int                      // Devices tracked before the least recently active is dropped
+	devices    map[string]*list.Element // Device key -> element in lru
+	lru        *list.List               // *deviceHistory, most recently active first
+}
+
+// deviceHistory is a fixed-size ring of one device's entries
+type deviceHistory struct {
+	key     string
+	entries []models.AuditEntry
+	next    int // Slot the next entry goes to
+	full    bool
+}
+
+// NewStore creates a store keeping perDevice entries for up to maxDevices devices
+func NewStore(perDevice, maxDevices int) *Store {
+	return &Store{
+		perDevice:  perDevice,
+		maxDevices: maxDevices,
+		devices:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Key scopes a device to its tenant, matching tenant.DeviceKey
+func Key(tenantID, deviceID string) string {
+	if tenantID == "" {
+		return deviceID
+	}
+	return tenantID + "/" + deviceID
+}
+
+// Write records entry in its device's history (audit.Sink)
+func (s *Store) Write(entry *models.AuditEntry) error {
+	if s == nil || entry.DeviceID == "" {
+		return nil
+	}
+	key := Key(entry.TenantID, entry.DeviceID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history *deviceHistory
+	if element, ok := s.devices[key]; ok {
+		s.lru.MoveToFront(element)
+		history = element.Value.(*deviceHistory)
+	} else {
+		history = &deviceHistory{key: key, entries: make([]models.AuditEntry, s.perDevice)}
+		s.devices[key] = s.lru.PushFront(history)
+		if s.lru.Len() > s.maxDevices {
+			oldest := s.lru.Remove(s.lru.Back()).(*deviceHistory)
+			delete(s.devices, oldest.key)
+		}
+	}
+
+	history.entries[history.next] = *entry
+	history.next = (history.next + 1) % len(history.entries)
+	if history.next == 0 {
+		history.full = true
+	}
+	return nil
+}
+
+// Close is a no-op; history lives only in memory (audit.Sink)
+func (s *Store) Close() error {
+	return nil
+}
+
+// Recent returns up to limit entries of a device, newest first (limit <= 0 returns all)
+func (s *Store) Recent(tenantID, deviceID string, limit int) []models.AuditEntry {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.devices[Key(tenantID, deviceID)]
+	if !ok {
+		return []models.AuditEntry{}
+	}
+	history := element.Value.(*deviceHistory)
+
+	count := history.next
+	if history.full {
+		count = len(history.entries)
+	}
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	recent := make([]models.AuditEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		slot := (history.next - i + len(history.entries)) % len(history.entries)
+		recent = append(recent, history.entries[slot])
+	}
+	return recent
+}
@@ -0,0 +1,123 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandConfig defines an external post-processor in the config file's hooks section
+type CommandConfig struct {
+	Command  []string      `yaml:"command" json:"command"`             // Program and arguments (no shell)
+	Timeout  time.Duration `yaml:"timeout" json:"timeout,omitempty"`   // Default 30s
+	Required bool          `yaml:"required" json:"required,omitempty"` // Fail the request when the command fails
+}
+
+// Validate checks that the hook names a program and a usable timeout
+func (c CommandConfig) Validate() error {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return errors.New("command is required")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative (got %v)", c.Timeout)
+	}
+	return nil
+}
+
+// Command runs an external program for each event
+// The event is written to its stdin as JSON; it may print {"tags": {...}, "processed_url": "..."}
+// to tag the response or report where it put the output
+type Command struct {
+	name    string
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+// commandOutput is what a command hook may print on stdout
+type commandOutput struct {
+	Tags         map[string]string `json:"tags"`
+	ProcessedURL string            `json:"processed_url"`
+}
+
+// NewCommand creates a command post-processor; the program must be installed
+func NewCommand(name string, cfg CommandConfig) (*Command, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	path, err := exec.LookPath(cfg.Command[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %w", cfg.Command[0], err)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &Command{name: name, path: path, args: cfg.Command[1:], timeout: cfg.Timeout}, nil
+}
+
+// Name identifies the hook in chains and logs
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Process runs the command with e on stdin and applies what it prints
+func (c *Command) Process(ctx context.Context, e *Event) error {
+	input, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.path, c.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"FC_TENANT_ID="+e.TenantID,
+		"FC_DEVICE_ID="+e.DeviceID,
+		"FC_MEDIA_TYPE="+e.Response.MediaType,
+		"FC_PROCESSED_PATH="+e.Response.ProcessedPath,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait on children that keep the pipes open
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", c.timeout)
+		}
+		if line := lastLine(stderr.String()); line != "" {
+			return fmt.Errorf("%w: %s", err, line)
+		}
+		return err
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return nil
+	}
+	var result commandOutput
+	if err := json.Unmarshal(out, &result); err != nil {
+		return fmt.Errorf("invalid output (expected a JSON object or nothing): %w", err)
+	}
+	for key, value := range result.Tags {
+		e.Tag(key, value)
+	}
+	if result.ProcessedURL != "" {
+		e.Response.ProcessedURL = result.ProcessedURL
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,146 @@
+// Package hooks runs post-processors on finished conversions (upload, notify, tagging, ...)
+// Processors are Go types registered at startup or external commands from the config file,
+// chained per tenant, so integrations don't need changes to the handlers
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Event is a finished conversion handed to each post-processor in turn
+type Event struct {
+	TenantID string                  `json:"tenant_id,omitempty"`
+	DeviceID string                  `json:"device_id"`
+	Source   string                  `json:"source"` // Source URL (password masked), or upload:<hash> for inline data
+	Response *models.ConvertResponse `json:"response"`
+}
+
+// Tag sets a tag on the response; later processors see it and may overwrite it
+func (e *Event) Tag(key, value string) {
+	if e.Response.Tags == nil {
+		e.Response.Tags = make(map[string]string)
+	}
+	e.Response.Tags[key] = value
+}
+
+// PostProcessor runs after a successful conversion
+type PostProcessor interface {
+	// Process may tag the response or set its processed_url; an error is logged, or fails the
+	// request when the processor is required
+	Process(ctx context.Context, e *Event) error
+	// Name identifies the processor in chains and logs
+	Name() string
+}
+
+// Error reports a required post-processor that failed
+type Error struct {
+	Processor string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("post-processor %s failed: %v", e.Processor, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// entry is a registered processor
+type entry struct {
+	processor PostProcessor
+	required  bool
+}
+
+// Pipeline holds the registered post-processors and the default chain
+// A nil Pipeline runs nothing
+type Pipeline struct {
+	processors map[string]entry
+	defaults   []string // Chain for requests without a tenant chain
+}
+
+// NewPipeline creates a pipeline whose default chain is the named processors, in order
+func NewPipeline(defaults []string) *Pipeline {
+	return &Pipeline{processors: make(map[string]entry), defaults: defaults}
+}
+
+// Register adds a processor; when required, its failure fails the request instead of being logged
+func (p *Pipeline) Register(processor PostProcessor, required bool) error {
+	name := processor.Name()
+	if name == "" || strings.ContainsAny(name, ", ") {
+		return fmt.Errorf("invalid post-processor name %q", name)
+	}
+	if _, exists := p.processors[name]; exists {
+		return fmt.Errorf("duplicate post-processor %q", name)
+	}
+	p.processors[name] = entry{processor: processor, required: required}
+	return nil
+}
+
+// Check reports chain entries that name no registered processor
+func (p *Pipeline) Check(chain []string) error {
+	var unknown []string
+	for _, name := range chain {
+		if p == nil || p.processors[name].processor == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown post-processors: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Names returns the registered processor names, sorted
+func (p *Pipeline) Names() []string {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(p.processors))
+	for name := range p.processors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Run passes e through chain in order (the default chain when chain is nil)
+// Optional processors that fail are logged and skipped; the first required one that fails stops
+// the chain with an *Error
+func (p *Pipeline) Run(ctx context.Context, chain []string, e *Event) error {
+	if p == nil {
+		return nil
+	}
+	if chain == nil {
+		chain = p.defaults
+	}
+
+	for _, name := range chain {
+		registered, ok := p.processors[name]
+		if !ok {
+			// Tenants files are reloaded at runtime, so a chain may name a processor that is gone
+			log.Printf("⚠️  Unknown post-processor %s skipped: device=%s", name, e.DeviceID)
+			continue
+		}
+
+		start := time.Now()
+		err := registered.processor.Process(ctx, e)
+		if err == nil {
+			log.Printf("🪝 Post-processed by %s: device=%s, time=%dms", name, e.DeviceID, time.Since(start).Milliseconds())
+			continue
+		}
+		if registered.required {
+			log.Printf("❌ Post-processor %s failed: device=%s, error=%v", name, e.DeviceID, err)
+			return &Error{Processor: name, Err: err}
+		}
+		log.Printf("⚠️  Post-processor %s failed (optional): device=%s, error=%v", name, e.DeviceID, err)
+	}
+	return nil
+}
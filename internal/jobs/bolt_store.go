@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"fingerprint-converter/internal/models"
+)
+
+var jobsBucket = []byte("jobs")
+
+// BoltStore persists jobs in an embedded bbolt database so they survive restarts
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (or creates) the job database at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Save inserts or replaces a job
+func (s *BoltStore) Save(job *models.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+// Get returns the job with the given ID, or nil if it doesn't exist
+func (s *BoltStore) Get(id string) (*models.Job, error) {
+	var job *models.Job
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		job = &models.Job{}
+		return json.Unmarshal(data, job)
+	})
+	return job, err
+}
+
+// List returns jobs matching filter, newest first
+func (s *BoltStore) List(filter Filter) ([]*models.Job, error) {
+	jobs := []*models.Job{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			job := &models.Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return err
+			}
+			if filter.matches(job) {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.apply(jobs), nil
+}
+
+// DeleteFinishedBefore removes completed/failed jobs that finished before cutoff
+func (s *BoltStore) DeleteFinishedBefore(cutoff time.Time) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobsBucket)
+		stale := [][]byte{}
+
+		err := bucket.ForEach(func(key, data []byte) error {
+			job := &models.Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return err
+			}
+			if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+				stale = append(stale, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range stale {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Close closes the underlying database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/services"
+)
+
+// estimate fills in when a newly queued job is expected to start and finish
+// Every job ahead of it, running ones included, takes the average conversion time of all media,
+// spread over the workers; the job itself takes the average of its media type. Nothing is filled
+// in until a conversion has been timed.
+func (m *Manager) estimate(job *models.Job) {
+	avg, ok := services.AvgConversionTime("")
+	if !ok {
+		return
+	}
+	own, ok := services.AvgConversionTime(job.Request.MediaType)
+	if !ok {
+		own = avg // Media type unknown until the job runs, or not converted yet
+	}
+
+	start := time.Now()
+	if job.ScheduledAt != nil {
+		start = *job.ScheduledAt
+	} else {
+		ahead := m.queue.Ahead(Lane{Tenant: job.TenantID, Device: job.DeviceID})
+		busy := ahead + int(atomic.LoadInt64(&m.running))
+		if waits := busy - m.workers + 1; waits > 0 {
+			start = start.Add(time.Duration(waits) * avg / time.Duration(m.workers))
+		}
+	}
+	completion := start.Add(own)
+	job.EstimatedStartAt, job.EstimatedCompletionAt = &start, &completion
+}
@@ -0,0 +1,138 @@
+package jobs
+
+// Lane is who a job is queued for; the local queue takes turns between lanes
+type Lane struct {
+	Tenant string
+	Device string
+}
+
+// fairQueue holds waiting job IDs and hands them out round-robin: one tenant after another, and
+// within a tenant one device after another. Each device's own jobs keep their order, so a device
+// submitting 200 files only delays itself. Not safe for concurrent use.
+type fairQueue struct {
+	tenants  []*tenantLanes // Whose turn it is next comes first
+	byTenant map[string]*tenantLanes
+	size     int
+}
+
+// tenantLanes holds the devices of one tenant with waiting jobs
+type tenantLanes struct {
+	id       string
+	devices  []*deviceLane // Whose turn it is next comes first
+	byDevice map[string]*deviceLane
+}
+
+// deviceLane holds one device's waiting jobs in submission order
+type deviceLane struct {
+	id    string
+	items []string
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{byTenant: make(map[string]*tenantLanes)}
+}
+
+// push appends id to its device's lane; a new lane waits for a full round
+func (f *fairQueue) push(lane Lane, id string) {
+	t, ok := f.byTenant[lane.Tenant]
+	if !ok {
+		t = &tenantLanes{id: lane.Tenant, byDevice: make(map[string]*deviceLane)}
+		f.byTenant[lane.Tenant] = t
+		f.tenants = append(f.tenants, t)
+	}
+	d, ok := t.byDevice[lane.Device]
+	if !ok {
+		d = &deviceLane{id: lane.Device}
+		t.byDevice[lane.Device] = d
+		t.devices = append(t.devices, d)
+	}
+	d.items = append(d.items, id)
+	f.size++
+}
+
+// pop takes the next ID of the tenant and device whose turn it is, and moves them to the back
+func (f *fairQueue) pop() (string, bool) {
+	if f.size == 0 {
+		return "", false
+	}
+	t := f.tenants[0]
+	d := t.devices[0]
+	id := d.items[0]
+	d.items = d.items[1:]
+	f.size--
+
+	t.devices = t.devices[1:]
+	if len(d.items) > 0 {
+		t.devices = append(t.devices, d)
+	} else {
+		delete(t.byDevice, d.id)
+	}
+	f.tenants = f.tenants[1:]
+	if len(t.devices) > 0 {
+		f.tenants = append(f.tenants, t)
+	} else {
+		delete(f.byTenant, t.id)
+	}
+	return id, true
+}
+
+// len returns the number of waiting IDs
+func (f *fairQueue) len() int {
+	return f.size
+}
+
+// ahead returns how many waiting IDs pop before a new ID pushed to lane now
+// Turns are replayed on counts alone, so it costs one pass over the waiting IDs
+func (f *fairQueue) ahead(lane Lane) int {
+	type device struct {
+		id      string
+		waiting int
+	}
+	type tenant struct {
+		id      string
+		devices []*device
+	}
+
+	// Copy the rings with the new ID added at the back of its lane
+	var target *device
+	tenants := make([]*tenant, 0, len(f.tenants)+1)
+	for _, t := range f.tenants {
+		copied := &tenant{id: t.id}
+		for _, d := range t.devices {
+			copied.devices = append(copied.devices, &device{id: d.id, waiting: len(d.items)})
+			if t.id == lane.Tenant && d.id == lane.Device {
+				target = copied.devices[len(copied.devices)-1]
+			}
+		}
+		if t.id == lane.Tenant && target == nil {
+			target = &device{id: lane.Device}
+			copied.devices = append(copied.devices, target)
+		}
+		tenants = append(tenants, copied)
+	}
+	if target == nil {
+		target = &device{id: lane.Device}
+		tenants = append(tenants, &tenant{id: lane.Tenant, devices: []*device{target}})
+	}
+	target.waiting++
+
+	popped := 0
+	for {
+		t := tenants[0]
+		d := t.devices[0]
+		d.waiting--
+		if d == target && d.waiting == 0 {
+			return popped
+		}
+		popped++
+
+		t.devices = t.devices[1:]
+		if d.waiting > 0 {
+			t.devices = append(t.devices, d)
+		}
+		tenants = tenants[1:]
+		if len(t.devices) > 0 {
+			tenants = append(tenants, t)
+		}
+	}
+}
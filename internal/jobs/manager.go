@@ -0,0 +1,485 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/apierr"
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/services"
+	"fingerprint-converter/internal/tenant"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when the pending queue is at capacity
+	ErrQueueFull = errors.New("job queue is full")
+
+	// ErrJobNotFound is returned when a job ID is unknown
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobNotFailed is returned when requeueing a job that isn't in the dead-letter list
+	ErrJobNotFailed = errors.New("only failed jobs can be requeued")
+)
+
+// maxBackoff caps the exponential retry delay
+const maxBackoff = 15 * time.Minute
+
+// Processor runs a single conversion for a job
+type Processor func(ctx context.Context, req *models.ConvertRequest) (*models.ConvertResponse, error)
+
+// Manager runs async conversion jobs backed by a persistent Store and a Queue
+type Manager struct {
+	store      Store
+	queue      Queue
+	process    Processor
+	workers    int
+	maxQueued  int
+	jobTimeout time.Duration
+	retention  time.Duration
+	heartbeat  time.Duration      // How often running jobs renew their queue lease
+	retry      models.RetryPolicy // Default policy for jobs submitted without one
+	workerWg   sync.WaitGroup
+	quit       chan struct{}
+	mu         sync.Mutex // Serializes read-modify-write of job records within this instance
+	completed  int64
+	failed     int64
+	retried    int64
+	running    int64 // Jobs being processed by this instance's workers
+
+	changedMu sync.Mutex
+	changed   chan struct{} // Closed and replaced whenever a job run here changes state; see Wait
+}
+
+// ManagerStats reports job manager activity
+type ManagerStats struct {
+	Workers   int
+	Queued    int
+	Completed int64
+	Failed    int64
+	Retried   int64
+}
+
+// NewManager creates a job manager; call Start to recover persisted jobs and begin processing
+// heartbeat should be well below the queue's visibility timeout (ignored by the local queue)
+func NewManager(store Store, queue Queue, process Processor, workers, maxQueued int, jobTimeout, retention, heartbeat time.Duration, retry models.RetryPolicy) *Manager {
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxQueued <= 0 {
+		maxQueued = 1000
+	}
+	if jobTimeout <= 0 {
+		jobTimeout = 5 * time.Minute
+	}
+	if heartbeat <= 0 {
+		heartbeat = 30 * time.Second
+	}
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = 3
+	}
+	if retry.BackoffSeconds <= 0 {
+		retry.BackoffSeconds = 10
+	}
+
+	return &Manager{
+		store:      store,
+		queue:      queue,
+		process:    process,
+		workers:    workers,
+		maxQueued:  maxQueued,
+		jobTimeout: jobTimeout,
+		retention:  retention,
+		heartbeat:  heartbeat,
+		retry:      retry,
+		quit:       make(chan struct{}),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Start recovers unfinished jobs from the store and starts the workers
+// Durable queues keep their own state, so recovery only runs for the local queue
+func (m *Manager) Start() error {
+	if !m.queue.Durable() {
+		if err := m.recover(); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < m.workers; i++ {
+		m.workerWg.Add(1)
+		go m.worker()
+	}
+
+	if m.retention > 0 {
+		go m.purgeLoop()
+	}
+
+	return nil
+}
+
+// recover re-queues jobs left queued or processing by a previous run
+// Interrupted jobs that used up their attempts are failed when a worker picks them up
+func (m *Manager) recover() error {
+	pending, err := m.store.List(Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to load jobs: %w", err)
+	}
+
+	requeued := 0
+	// List is newest first; re-queue oldest first to keep submission order
+	for i := len(pending) - 1; i >= 0; i-- {
+		job := pending[i]
+		if job.Status != models.JobStatusQueued && job.Status != models.JobStatusProcessing {
+			continue
+		}
+		if err := m.queue.Push(job.ID, Lane{Tenant: job.TenantID, Device: job.DeviceID}, job.NextAttemptAt); err != nil {
+			return fmt.Errorf("failed to recover job %s: %w", job.ID, err)
+		}
+		requeued++
+	}
+
+	if requeued > 0 {
+		log.Printf("♻️  Job recovery: requeued=%d", requeued)
+	}
+	return nil
+}
+
+// Submit persists a new job and queues it for processing
+// A nil retry policy (or zero fields) falls back to the manager defaults
+// A future scheduleAt holds the job until then; it is persisted, so the schedule survives restarts
+// tenantID is empty when multi-tenancy is disabled; requestID (X-Request-ID) is logged and forwarded by every attempt
+func (m *Manager) Submit(tenantID, requestID string, req models.ConvertRequest, retry *models.RetryPolicy, scheduleAt *time.Time) (*models.Job, error) {
+	if m.queue.Len() >= m.maxQueued {
+		return nil, ErrQueueFull
+	}
+
+	policy := m.retry
+	if retry != nil {
+		if retry.MaxAttempts > 0 {
+			policy.MaxAttempts = retry.MaxAttempts
+		}
+		if retry.BackoffSeconds > 0 {
+			policy.BackoffSeconds = retry.BackoffSeconds
+		}
+	}
+
+	job := &models.Job{
+		ID:        newJobID(),
+		Status:    models.JobStatusQueued,
+		DeviceID:  req.DeviceID,
+		TenantID:  tenantID,
+		RequestID: requestID,
+		Request:   req,
+		Retry:     policy,
+		CreatedAt: time.Now(),
+	}
+	if scheduleAt != nil && scheduleAt.After(job.CreatedAt) {
+		job.ScheduledAt = scheduleAt
+		job.NextAttemptAt = scheduleAt // Recovery re-queues with the delay intact
+	}
+
+	if err := m.store.Save(job); err != nil {
+		return nil, err
+	}
+
+	if job.ScheduledAt != nil {
+		log.Printf("⏰ Job %s scheduled for %s", job.ID, job.ScheduledAt.Format(time.RFC3339))
+	}
+	m.estimate(job) // Before the push, so the job doesn't count itself
+	if err := m.queue.Push(job.ID, Lane{Tenant: job.TenantID, Device: job.DeviceID}, job.NextAttemptAt); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+	return job, nil
+}
+
+// Get returns a job by ID (nil if unknown)
+func (m *Manager) Get(id string) (*models.Job, error) {
+	return m.store.Get(id)
+}
+
+// List returns jobs matching filter, newest first
+func (m *Manager) List(filter Filter) ([]*models.Job, error) {
+	return m.store.List(filter)
+}
+
+// DeadLetter returns permanently failed jobs, newest first
+func (m *Manager) DeadLetter(tenantID, deviceID string, limit int) ([]*models.Job, error) {
+	return m.store.List(Filter{
+		Status:   models.JobStatusFailed,
+		DeviceID: deviceID,
+		TenantID: tenantID,
+		Limit:    limit,
+	})
+}
+
+// Requeue moves a failed job back to the queue with a fresh set of attempts
+func (m *Manager) Requeue(id string) (*models.Job, error) {
+	var notFailed bool
+	job, err := m.transition(id, func(job *models.Job) bool {
+		if job.Status != models.JobStatusFailed {
+			notFailed = true
+			return false
+		}
+		job.Status = models.JobStatusQueued
+		job.Attempts = 0
+		job.Error = ""
+		job.ErrorCode = ""
+		job.Result = nil
+		job.StartedAt = nil
+		job.FinishedAt = nil
+		job.NextAttemptAt = nil
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	if notFailed {
+		return nil, ErrJobNotFailed
+	}
+
+	if err := m.queue.Push(job.ID, Lane{Tenant: job.TenantID, Device: job.DeviceID}, nil); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+	log.Printf("♻️  Job %s requeued from dead-letter", id)
+	return job, nil
+}
+
+// backoff returns the delay before the next attempt (doubles on each retry)
+func backoff(policy models.RetryPolicy, attempts int) time.Duration {
+	delay := time.Duration(policy.BackoffSeconds) * time.Second
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// worker processes queued jobs until the manager stops
+func (m *Manager) worker() {
+	defer m.workerWg.Done()
+
+	for {
+		id, ok := m.queue.Pop()
+		if !ok {
+			return
+		}
+		atomic.AddInt64(&m.running, 1)
+		m.run(id)
+		atomic.AddInt64(&m.running, -1)
+		m.notify()
+		if err := m.queue.Ack(id); err != nil {
+			log.Printf("⚠️  Failed to acknowledge job %s: %v", id, err)
+		}
+	}
+}
+
+// run executes one job and persists the outcome
+func (m *Manager) run(id string) {
+	started := false
+	var queueWait time.Duration // From when the attempt was due until it started
+	job, err := m.transition(id, func(job *models.Job) bool {
+		if job.Retry.MaxAttempts <= 0 {
+			job.Retry = m.retry // Jobs persisted before retry policies existed
+		}
+
+		switch job.Status {
+		case models.JobStatusQueued:
+		case models.JobStatusProcessing:
+			// Interrupted by a restart or redelivered after its worker stopped heartbeating
+			if job.Attempts >= job.Retry.MaxAttempts {
+				now := time.Now()
+				job.Status = models.JobStatusFailed
+				job.Error = fmt.Sprintf("interrupted after %d attempts", job.Attempts)
+				job.ErrorCode = apierr.JobInterrupted
+				job.FinishedAt = &now
+				atomic.AddInt64(&m.failed, 1)
+				log.Printf("❌ Job %s failed: %s", id, job.Error)
+				return true
+			}
+			log.Printf("♻️  Resuming interrupted job %s", id)
+		default:
+			return false // Queued twice or already handled
+		}
+		now := time.Now()
+		due := job.CreatedAt
+		if job.NextAttemptAt != nil {
+			due = *job.NextAttemptAt
+		} else if job.ScheduledAt != nil {
+			due = *job.ScheduledAt
+		}
+		queueWait = max(now.Sub(due), 0)
+		job.Status = models.JobStatusProcessing
+		job.Attempts++
+		job.StartedAt = &now
+		job.NextAttemptAt = nil
+		started = true
+		return true
+	})
+	if err != nil {
+		log.Printf("⚠️  Job %s could not be started: %v", id, err)
+		return
+	}
+	if !started {
+		return
+	}
+
+	// Keep the queue lease alive while ffmpeg runs
+	stopHeartbeat := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(m.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.queue.Heartbeat(id); err != nil {
+					log.Printf("⚠️  Job %s heartbeat failed: %v", id, err)
+				}
+			case <-stopHeartbeat:
+				return
+			}
+		}
+	}()
+
+	// Jobs run with the quotas and namespace of the tenant that submitted them
+	ctx := audit.WithCaller(tenant.WithID(context.Background(), job.TenantID), "job:"+id)
+	ctx = reqid.WithID(ctx, job.RequestID)
+	ctx, cancel := context.WithTimeout(ctx, m.jobTimeout)
+	req := job.Request
+	result, procErr := m.process(ctx, &req)
+	cancel()
+	close(stopHeartbeat)
+	if result != nil && result.Timings != nil {
+		result.Timings.QueueWaitMs += queueWait.Milliseconds()
+	}
+
+	// Transient failures are retried with backoff until attempts run out
+	var retryAt *time.Time
+	if procErr != nil && services.IsTransient(procErr) && job.Attempts < job.Retry.MaxAttempts {
+		at := time.Now().Add(backoff(job.Retry, job.Attempts))
+		retryAt = &at
+	}
+
+	_, err = m.transition(id, func(job *models.Job) bool {
+		if procErr != nil {
+			job.Error = procErr.Error()
+			job.ErrorCode = apierr.CodeOf(procErr)
+			if retryAt != nil {
+				job.Status = models.JobStatusQueued
+				job.NextAttemptAt = retryAt
+				return true
+			}
+			now := time.Now()
+			job.Status = models.JobStatusFailed
+			job.FinishedAt = &now
+			return true
+		}
+		now := time.Now()
+		job.Status = models.JobStatusCompleted
+		job.Result = result
+		job.Error = ""
+		job.ErrorCode = ""
+		job.FinishedAt = &now
+		return true
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to persist job %s result: %v", id, err)
+	}
+
+	switch {
+	case procErr == nil:
+		atomic.AddInt64(&m.completed, 1)
+		log.Printf("✅ Job %s completed", id)
+	case retryAt != nil:
+		atomic.AddInt64(&m.retried, 1)
+		log.Printf("🔁 Job %s attempt %d/%d failed, retrying at %s: %v",
+			id, job.Attempts, job.Retry.MaxAttempts, retryAt.Format(time.RFC3339), procErr)
+		if err := m.queue.Push(id, Lane{Tenant: job.TenantID, Device: job.DeviceID}, retryAt); err != nil {
+			log.Printf("⚠️  Failed to schedule retry of job %s: %v", id, err)
+		}
+	default:
+		atomic.AddInt64(&m.failed, 1)
+		log.Printf("❌ Job %s failed after %d attempt(s): %v", id, job.Attempts, procErr)
+	}
+}
+
+// transition applies update to the stored job under the manager lock
+// update returns false to leave the job untouched
+func (m *Manager) transition(id string, update func(job *models.Job) bool) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil || job == nil {
+		return nil, err
+	}
+
+	if !update(job) {
+		return job, nil
+	}
+	if err := m.store.Save(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// purgeLoop periodically deletes finished jobs older than the retention period
+func (m *Manager) purgeLoop() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := m.store.DeleteFinishedBefore(time.Now().Add(-m.retention))
+			if err != nil {
+				log.Printf("⚠️  Job purge failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("🧹 Purged %d finished jobs", deleted)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// Stop stops accepting work and waits for running jobs to finish
+// Jobs still queued stay persisted and are picked up on the next start
+func (m *Manager) Stop() {
+	close(m.quit)
+	m.queue.Close()
+	m.workerWg.Wait()
+	log.Println("🛑 Job manager stopped")
+}
+
+// GetStats returns current statistics
+func (m *Manager) GetStats() ManagerStats {
+	return ManagerStats{
+		Workers:   m.workers,
+		Queued:    m.queue.Len(),
+		Completed: atomic.LoadInt64(&m.completed),
+		Failed:    atomic.LoadInt64(&m.failed),
+		Retried:   atomic.LoadInt64(&m.retried),
+	}
+}
+
+func newJobID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// Queue hands job IDs to workers
+type Queue interface {
+	// Push makes a job of lane available now, or once at has passed (nil = now)
+	Push(id string, lane Lane, at *time.Time) error
+	// Pop blocks until a job is available; returns false once the queue is closed
+	Pop() (string, bool)
+	// Heartbeat extends the lease on a popped job while it is processing
+	Heartbeat(id string) error
+	// Ack releases a popped job once its outcome is persisted
+	Ack(id string) error
+	// Len returns the number of waiting jobs (including delayed retries)
+	Len() int
+	// Ahead returns how many waiting jobs would start before a job of lane pushed now
+	Ahead(lane Lane) int
+	// Durable reports whether queued IDs survive restarts without recovery from the store
+	Durable() bool
+	Close()
+}
+
+// localQueue is an in-process queue of job IDs with blocking pop
+// Capacity limits are enforced by the Manager at submit time
+type localQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   *fairQueue
+	fair    bool // Take turns between tenants and devices; otherwise first in, first out
+	delayed int
+	closed  bool
+}
+
+// NewLocalQueue creates an in-memory queue for single-instance deployments
+// A fair queue takes turns between tenants, and between the devices of a tenant, instead of
+// running jobs strictly in submission order
+func NewLocalQueue(fair bool) Queue {
+	q := &localQueue{items: newFairQueue(), fair: fair}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push appends a job ID to its lane and wakes one waiting worker
+func (q *localQueue) Push(id string, lane Lane, at *time.Time) error {
+	if !q.fair {
+		lane = Lane{} // A single lane is plain FIFO
+	}
+	if at != nil && at.After(time.Now()) {
+		q.mu.Lock()
+		q.delayed++
+		q.mu.Unlock()
+
+		time.AfterFunc(time.Until(*at), func() {
+			q.mu.Lock()
+			q.delayed--
+			q.mu.Unlock()
+			q.push(id, lane)
+		})
+		return nil
+	}
+
+	q.push(id, lane)
+	return nil
+}
+
+func (q *localQueue) push(id string, lane Lane) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return // Still persisted as queued; recovered on the next start
+	}
+	q.items.push(lane, id)
+	q.cond.Signal()
+}
+
+// Pop blocks until an ID is available; returns false once the queue is closed
+func (q *localQueue) Pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.items.len() == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return "", false
+	}
+	return q.items.pop()
+}
+
+// Heartbeat is a no-op: local jobs can't be lost to another instance
+func (q *localQueue) Heartbeat(string) error { return nil }
+
+// Ack is a no-op: popped IDs are already removed
+func (q *localQueue) Ack(string) error { return nil }
+
+// Len returns the number of queued and delayed IDs
+func (q *localQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.len() + q.delayed
+}
+
+// Ahead replays the turns between lanes; delayed jobs aren't counted until they are due
+func (q *localQueue) Ahead(lane Lane) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.fair {
+		return q.items.len()
+	}
+	return q.items.ahead(lane)
+}
+
+// Durable is false: the Manager re-queues unfinished jobs from the store on start
+func (q *localQueue) Durable() bool { return false }
+
+// Close wakes all waiting workers and makes Pop return false
+func (q *localQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"fingerprint-converter/internal/models"
+)
+
+// ConnectRedis opens a Redis client from a redis:// URL and checks connectivity
+func ConnectRedis(url string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return client, nil
+}
+
+// RedisStore keeps job records in a Redis hash shared by all instances
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore creates a store under the given key prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, key: prefix + "jobs:data"}
+}
+
+// Save inserts or replaces a job
+func (s *RedisStore) Save(job *models.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+	return s.client.HSet(context.Background(), s.key, job.ID, data).Err()
+}
+
+// Get returns the job with the given ID, or nil if it doesn't exist
+func (s *RedisStore) Get(id string) (*models.Job, error) {
+	data, err := s.client.HGet(context.Background(), s.key, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// List returns jobs matching filter, newest first
+func (s *RedisStore) List(filter Filter) ([]*models.Job, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*models.Job{}
+	for _, job := range all {
+		if filter.matches(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return filter.apply(jobs), nil
+}
+
+// DeleteFinishedBefore removes completed/failed jobs that finished before cutoff
+func (s *RedisStore) DeleteFinishedBefore(cutoff time.Time) (int, error) {
+	all, err := s.all()
+	if err != nil {
+		return 0, err
+	}
+
+	stale := []string{}
+	for _, job := range all {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			stale = append(stale, job.ID)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := s.client.HDel(context.Background(), s.key, stale...).Result()
+	return int(deleted), err
+}
+
+// Close is a no-op; the client is shared with the queue and closed by its owner
+func (s *RedisStore) Close() error {
+	return nil
+}
+
+func (s *RedisStore) all() ([]*models.Job, error) {
+	values, err := s.client.HGetAll(context.Background(), s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*models.Job, 0, len(values))
+	for _, data := range values {
+		job := &models.Job{}
+		if err := json.Unmarshal([]byte(data), job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RedisQueue is a job queue shared by several converter instances
+//
+// Workers pop IDs from the pending list into a processing list and hold a lease
+// (visibility timeout) that heartbeats extend. When a worker dies its lease expires
+// and any instance moves the job back to pending. Delayed retries wait in a sorted set.
+type RedisQueue struct {
+	client     *redis.Client
+	pending    string
+	processing string
+	leases     string
+	delayed    string
+	visibility time.Duration
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewRedisQueue creates a queue under the given key prefix and starts its reaper
+func NewRedisQueue(client *redis.Client, prefix string, visibility time.Duration) *RedisQueue {
+	if visibility <= 0 {
+		visibility = 2 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &RedisQueue{
+		client:     client,
+		pending:    prefix + "jobs:pending",
+		processing: prefix + "jobs:processing",
+		leases:     prefix + "jobs:leases",
+		delayed:    prefix + "jobs:delayed",
+		visibility: visibility,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	q.wg.Add(1)
+	go q.reapLoop()
+	return q
+}
+
+// Push makes a job available now, or once at has passed
+// The lane is ignored: instances pop from one shared list, in submission order
+func (q *RedisQueue) Push(id string, _ Lane, at *time.Time) error {
+	if at != nil && at.After(time.Now()) {
+		return q.client.ZAdd(context.Background(), q.delayed, redis.Z{
+			Score:  float64(at.UnixMilli()),
+			Member: id,
+		}).Err()
+	}
+	return q.client.LPush(context.Background(), q.pending, id).Err()
+}
+
+// Pop blocks until a job is available and takes a lease on it
+func (q *RedisQueue) Pop() (string, bool) {
+	for {
+		id, err := q.client.BLMove(q.ctx, q.pending, q.processing, "RIGHT", "LEFT", time.Second).Result()
+		if q.ctx.Err() != nil {
+			return "", false
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️  Redis queue pop failed: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-q.ctx.Done():
+				return "", false
+			}
+			continue
+		}
+
+		if err := q.Heartbeat(id); err != nil {
+			// The reaper grants a lease to unleased jobs, so the job isn't lost
+			log.Printf("⚠️  Failed to lease job %s: %v", id, err)
+		}
+		return id, true
+	}
+}
+
+// Heartbeat extends the lease on a popped job
+func (q *RedisQueue) Heartbeat(id string) error {
+	return q.client.ZAdd(context.Background(), q.leases, redis.Z{
+		Score:  float64(time.Now().Add(q.visibility).UnixMilli()),
+		Member: id,
+	}).Err()
+}
+
+// Ack removes a popped job from the processing list and drops its lease
+func (q *RedisQueue) Ack(id string) error {
+	ctx := context.Background()
+	if err := q.client.LRem(ctx, q.processing, 1, id).Err(); err != nil {
+		return err
+	}
+	return q.client.ZRem(ctx, q.leases, id).Err()
+}
+
+// Len returns the number of pending and delayed jobs across all instances
+func (q *RedisQueue) Len() int {
+	ctx := context.Background()
+	pending, _ := q.client.LLen(ctx, q.pending).Result()
+	delayed, _ := q.client.ZCard(ctx, q.delayed).Result()
+	return int(pending + delayed)
+}
+
+// Ahead returns the jobs pending in Redis; they start in submission order whatever the lane
+func (q *RedisQueue) Ahead(Lane) int {
+	pending, _ := q.client.LLen(context.Background(), q.pending).Result()
+	return int(pending)
+}
+
+// Durable is true: queued IDs live in Redis
+func (q *RedisQueue) Durable() bool { return true }
+
+// Close stops the reaper and makes Pop return false
+func (q *RedisQueue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// reapLoop periodically promotes due retries and recovers jobs with expired leases
+func (q *RedisQueue) reapLoop() {
+	defer q.wg.Done()
+
+	interval := q.visibility / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > 5*time.Second {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := q.promoteDelayed(); err != nil {
+				log.Printf("⚠️  Redis queue promote failed: %v", err)
+			}
+			if err := q.recoverExpired(); err != nil {
+				log.Printf("⚠️  Redis queue recovery failed: %v", err)
+			}
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// promoteDelayed moves retries whose time has come to the pending list
+func (q *RedisQueue) promoteDelayed() error {
+	ctx := context.Background()
+	due, err := q.client.ZRangeByScore(ctx, q.delayed, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range due {
+		// Only the instance that removes the entry promotes it
+		removed, err := q.client.ZRem(ctx, q.delayed, id).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 1 {
+			if err := q.client.LPush(ctx, q.pending, id).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recoverExpired moves jobs whose worker stopped heartbeating back to the front of pending
+func (q *RedisQueue) recoverExpired() error {
+	ctx := context.Background()
+	ids, err := q.client.LRange(ctx, q.processing, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		deadline, err := q.client.ZScore(ctx, q.leases, id).Result()
+		if errors.Is(err, redis.Nil) {
+			// Popped but never leased (worker died right after the pop): start the clock now
+			q.client.ZAddNX(ctx, q.leases, redis.Z{
+				Score:  float64(now.Add(q.visibility).UnixMilli()),
+				Member: id,
+			})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if int64(deadline) > now.UnixMilli() {
+			continue
+		}
+
+		// Only the instance that removes the entry re-queues it
+		removed, err := q.client.LRem(ctx, q.processing, 1, id).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 1 {
+			q.client.ZRem(ctx, q.leases, id)
+			if err := q.client.RPush(ctx, q.pending, id).Err(); err != nil {
+				return err
+			}
+			log.Printf("♻️  Job %s lease expired, re-queued", id)
+		}
+	}
+	return nil
+}
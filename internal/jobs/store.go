@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"sort"
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Store persists job records
+type Store interface {
+	// Save inserts or replaces a job
+	Save(job *models.Job) error
+	// Get returns the job with the given ID, or nil if it doesn't exist
+	Get(id string) (*models.Job, error)
+	// List returns jobs matching filter, newest first
+	List(filter Filter) ([]*models.Job, error)
+	// DeleteFinishedBefore removes completed/failed jobs that finished before cutoff
+	DeleteFinishedBefore(cutoff time.Time) (int, error)
+	Close() error
+}
+
+// Filter narrows down job listings (empty fields match everything)
+type Filter struct {
+	Status   string
+	DeviceID string
+	TenantID string
+	Limit    int
+}
+
+// matches reports whether job passes the status, device and tenant filters
+func (f Filter) matches(job *models.Job) bool {
+	if f.Status != "" && job.Status != f.Status {
+		return false
+	}
+	if f.DeviceID != "" && job.DeviceID != f.DeviceID {
+		return false
+	}
+	if f.TenantID != "" && job.TenantID != f.TenantID {
+		return false
+	}
+	return true
+}
+
+// apply sorts jobs newest first and truncates to the limit
+func (f Filter) apply(jobs []*models.Job) []*models.Job {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+
+	if f.Limit > 0 && len(jobs) > f.Limit {
+		jobs = jobs[:f.Limit]
+	}
+	return jobs
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// waitPoll is how often Wait reloads the job, for jobs run by other instances sharing the store
+const waitPoll = time.Second
+
+// Wait returns the job once it has completed or failed, or as it is when ctx ends
+// Jobs run by this instance wake their waiters right away; others are noticed within waitPoll.
+// Returns nil when the job doesn't exist.
+func (m *Manager) Wait(ctx context.Context, id string) (*models.Job, error) {
+	ticker := time.NewTicker(waitPoll)
+	defer ticker.Stop()
+
+	for {
+		changed := m.changes() // Before loading, so a change in between isn't missed
+		job, err := m.store.Get(id)
+		if err != nil || job == nil || finished(job) {
+			return job, err
+		}
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return job, nil
+		}
+	}
+}
+
+// changes returns a channel closed the next time a job run by this instance changes state
+func (m *Manager) changes() <-chan struct{} {
+	m.changedMu.Lock()
+	defer m.changedMu.Unlock()
+	return m.changed
+}
+
+// notify wakes everyone waiting on changes
+func (m *Manager) notify() {
+	m.changedMu.Lock()
+	defer m.changedMu.Unlock()
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// finished reports whether job has reached a final state
+func finished(job *models.Job) bool {
+	return job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed
+}
@@ -1,27 +1,288 @@
 package models
 
+import "time"
+
 // ConvertRequest represents a media conversion request
 type ConvertRequest struct {
-	DeviceID             string `json:"device_id" validate:"required"` // Device identifier for caching
-	URL                  string `json:"url" validate:"required"`       // S3/HTTP URL or base64 data
-	MediaType            string `json:"media_type"`                    // audio/image/video (auto-detected if not provided)
-	AntiFingerprintLevel string `json:"anti_fingerprint_level"`        // none/basic/moderate/paranoid (auto-set if not provided)
-	IsBase64             bool   `json:"is_base64"`                     // If true, URL is base64 encoded data
+	DeviceID             string            `json:"device_id" validate:"required,max=256,deviceid"`                                 // Device identifier for caching
+	URL                  string            `json:"url" validate:"required_without=Data"`                                           // S3/HTTP URL
+	Data                 string            `json:"data,omitempty"`                                                                 // Base64 media content, instead of url
+	MediaType            string            `json:"media_type" validate:"omitempty,oneof=audio image video"`                        // audio/image/video (auto-detected if not provided)
+	AntiFingerprintLevel string            `json:"anti_fingerprint_level" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid (auto-set if not provided)
+	Profile              string            `json:"profile,omitempty" validate:"omitempty,max=64"`                                  // Named AF profile from the config file (used when no level is given)
+	IsBase64             bool              `json:"is_base64,omitempty"`                                                            // Deprecated: url holds base64 data (use data instead)
+	MaxResolution        string            `json:"max_resolution,omitempty"`                                                       // Image/video: WxH cap (e.g. 1280x720) or preset sd/hd/fhd
+	FrameRate            string            `json:"frame_rate,omitempty"`                                                           // Video only: output fps (e.g. 30, 30000/1001) or "preserve"
+	ExtractAudio         bool              `json:"extract_audio,omitempty"`                                                        // Pull the audio track out of a video and process it as audio
+	DropAudio            bool              `json:"drop_audio,omitempty"`                                                           // Video only: remove the audio stream from the output
+	AudioFormat          string            `json:"audio_format,omitempty" validate:"omitempty,oneof=opus mp3"`                     // Audio only: opus (default) or mp3
+	ImageFormat          string            `json:"image_format,omitempty" validate:"omitempty,oneof=jpeg jpg png webp avif jxl"`   // Image only: output format (default: same as the input)
+	Watermark            *WatermarkOptions `json:"watermark,omitempty"`                                                            // Image/video only: visible text or logo overlay
+	QualityMetrics       bool              `json:"quality_metrics,omitempty"`                                                      // Image/video only: include SSIM/PSNR (and VMAF) against the source
+	Outputs              []OutputSpec      `json:"outputs,omitempty" validate:"omitempty,max=8,dive"`                              // Several renditions from one download; see OutputSpec
+	Packaging            string            `json:"packaging,omitempty" validate:"omitempty,oneof=hls dash"`                        // Video only: segment the output(s) into an HLS or DASH package
+	Upload               bool              `json:"upload,omitempty"`                                                               // Upload the package to PACKAGE_UPLOAD_URL (needs packaging)
+	DestinationURL       string            `json:"destination_url,omitempty" validate:"omitempty,max=8192"`                        // Presigned PUT URL the output is uploaded to; the response then only carries metadata
+	KeepProcessing       bool              `json:"keep_processing,omitempty"`                                                      // Finish (and cache) the conversion even if the client disconnects
+	Experiment           string            `json:"experiment,omitempty"`                                                           // Set by the server: experiment that picked the level
+	Variant              string            `json:"variant,omitempty"`                                                              // Set by the server: assigned variant
+}
+
+// OutputSpec is one rendition of a multi-output request
+// Unset fields take the request's value; each output is cached on its own, like a single request with the same fields
+type OutputSpec struct {
+	Name                 string `json:"name,omitempty" validate:"omitempty,max=64"`                                               // Echoed in the response (default: the output's index)
+	AntiFingerprintLevel string `json:"anti_fingerprint_level,omitempty" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid
+	ExtractAudio         bool   `json:"extract_audio,omitempty"`                                                                  // Video inputs: output the audio track only
+	MaxResolution        string `json:"max_resolution,omitempty"`                                                                 // Image/video: WxH cap or preset sd/hd/fhd
+	FrameRate            string `json:"frame_rate,omitempty"`                                                                     // Video only: output fps or "preserve"
+	DropAudio            bool   `json:"drop_audio,omitempty"`                                                                     // Video only: remove the audio stream
+	AudioFormat          string `json:"audio_format,omitempty" validate:"omitempty,oneof=opus mp3"`                               // Audio outputs: opus or mp3
+	ImageFormat          string `json:"image_format,omitempty" validate:"omitempty,oneof=jpeg jpg png webp avif jxl"`             // Image only: jpeg, png, webp, avif or jxl
+}
+
+// WatermarkOptions describes a visible overlay for images and videos
+type WatermarkOptions struct {
+	Text      string  `json:"text,omitempty"`                                                                                   // Text to draw
+	ImageURL  string  `json:"image_url,omitempty"`                                                                              // PNG logo URL (takes precedence over text)
+	Position  string  `json:"position,omitempty" validate:"omitempty,oneof=top-left top-right bottom-left bottom-right center"` // Default bottom-right
+	Opacity   float64 `json:"opacity,omitempty" validate:"gte=0,lte=1"`                                                         // 0-1 (default 0.5)
+	FontSize  int     `json:"font_size,omitempty" validate:"gte=0,lte=500"`                                                     // Text size in pixels (default: 1/24 of frame height)
+	FontColor string  `json:"font_color,omitempty"`                                                                             // Text color (default white)
+	Scale     float64 `json:"scale,omitempty" validate:"omitempty,gte=0.01,lte=1"`                                              // Logo width as a fraction of frame width (default 0.2)
+}
+
+// SlideshowRequest represents an image slideshow (or single-image video) build request
+type SlideshowRequest struct {
+	DeviceID             string   `json:"device_id" validate:"required,max=256,deviceid"`                                 // Device identifier for caching
+	Images               []string `json:"images" validate:"required,min=1,max=30,dive,required"`                          // Image URLs in display order
+	AudioURL             string   `json:"audio_url,omitempty"`                                                            // Optional soundtrack URL
+	FrameDuration        float64  `json:"frame_duration" validate:"omitempty,gte=0.5,lte=60"`                             // Seconds per image (default 3)
+	Resolution           string   `json:"resolution,omitempty"`                                                           // Output WxH (default 720x1280)
+	AntiFingerprintLevel string   `json:"anti_fingerprint_level" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid (default moderate)
+	KeepProcessing       bool     `json:"keep_processing,omitempty"`                                                      // Finish (and cache) the build even if the client disconnects
+}
+
+// ConcatRequest represents a request to join several clips into one processed output
+type ConcatRequest struct {
+	DeviceID             string   `json:"device_id" validate:"required,max=256,deviceid"`                                 // Device identifier for caching
+	URLs                 []string `json:"urls" validate:"required,min=2,max=20,dive,required"`                            // Clip URLs in playback order
+	MediaType            string   `json:"media_type" validate:"omitempty,oneof=audio video"`                              // video/audio (auto-detected from the first URL if not provided)
+	AntiFingerprintLevel string   `json:"anti_fingerprint_level" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid (auto-set if not provided)
+	MaxResolution        string   `json:"max_resolution,omitempty"`                                                       // Video only: WxH cap or preset sd/hd/fhd
+	FrameRate            string   `json:"frame_rate,omitempty"`                                                           // Video only: output fps or "preserve"
+	AudioFormat          string   `json:"audio_format,omitempty" validate:"omitempty,oneof=opus mp3"`                     // Audio only: opus (default) or mp3
+	KeepProcessing       bool     `json:"keep_processing,omitempty"`                                                      // Finish (and cache) the output even if the client disconnects
+}
+
+// ArchiveRequest represents a zip archive whose media files are each converted
+// Options apply to the entries they fit (max_resolution to images and videos, audio_format to audio, ...)
+type ArchiveRequest struct {
+	DeviceID             string `json:"device_id" validate:"required,max=256,deviceid"`                                 // Device identifier for caching
+	URL                  string `json:"url" validate:"required_without=Data"`                                           // Zip archive URL
+	Data                 string `json:"data,omitempty"`                                                                 // Base64 zip content, instead of url
+	AntiFingerprintLevel string `json:"anti_fingerprint_level" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid (auto-set per entry if not provided)
+	Profile              string `json:"profile,omitempty" validate:"omitempty,max=64"`                                  // Named AF profile from the config file
+	MaxResolution        string `json:"max_resolution,omitempty"`                                                       // Image/video: WxH cap or preset sd/hd/fhd
+	FrameRate            string `json:"frame_rate,omitempty"`                                                           // Video only: output fps or "preserve"
+	AudioFormat          string `json:"audio_format,omitempty" validate:"omitempty,oneof=opus mp3"`                     // Audio only: opus (default) or mp3
+	ImageFormat          string `json:"image_format,omitempty" validate:"omitempty,oneof=jpeg jpg png webp avif jxl"`   // Image only: output format (default: same as the input)
+	KeepProcessing       bool   `json:"keep_processing,omitempty"`                                                      // Finish (and cache) every entry even if the client disconnects
+}
+
+// FilesRequest converts several inputs of any media type at once, e.g. the attachments of one message
+type FilesRequest struct {
+	DeviceID       string     `json:"device_id" validate:"required,max=256,deviceid"` // Device identifier for caching
+	Files          []FileSpec `json:"files" validate:"required,min=1,max=10,dive"`    // Converted concurrently; see FileSpec
+	KeepProcessing bool       `json:"keep_processing,omitempty"`                      // Finish (and cache) every file even if the client disconnects
+}
+
+// FileSpec is one input of a files request; it is converted like a single /convert request with the same fields
+type FileSpec struct {
+	Tag                  string `json:"tag" validate:"required,max=64"`                                                           // Keys the file's result in the response
+	URL                  string `json:"url" validate:"required_without=Data"`                                                     // S3/HTTP URL
+	Data                 string `json:"data,omitempty"`                                                                           // Base64 media content, instead of url
+	MediaType            string `json:"media_type,omitempty" validate:"omitempty,oneof=audio image video"`                        // audio/image/video (auto-detected if not provided)
+	AntiFingerprintLevel string `json:"anti_fingerprint_level,omitempty" validate:"omitempty,oneof=none basic moderate paranoid"` // none/basic/moderate/paranoid (auto-set if not provided)
+	Profile              string `json:"profile,omitempty" validate:"omitempty,max=64"`                                            // Named AF profile from the config file
+	MaxResolution        string `json:"max_resolution,omitempty"`                                                                 // Image/video: WxH cap or preset sd/hd/fhd
+	FrameRate            string `json:"frame_rate,omitempty"`                                                                     // Video only: output fps or "preserve"
+	ExtractAudio         bool   `json:"extract_audio,omitempty"`                                                                  // Pull the audio track out of a video and process it as audio
+	DropAudio            bool   `json:"drop_audio,omitempty"`                                                                     // Video only: remove the audio stream
+	AudioFormat          string `json:"audio_format,omitempty" validate:"omitempty,oneof=opus mp3"`                               // Audio only: opus (default) or mp3
+	ImageFormat          string `json:"image_format,omitempty" validate:"omitempty,oneof=jpeg jpg png webp avif jxl"`             // Image only: output format (default: same as the input)
+}
+
+// ProbeRequest asks for the container and stream details of a media file, without converting it
+type ProbeRequest struct {
+	URL  string `json:"url" validate:"required_without=Data"` // S3/HTTP URL
+	Data string `json:"data,omitempty"`                       // Base64 media content, instead of url
+}
+
+// ProbeResponse describes a media file as ffprobe reads it
+type ProbeResponse struct {
+	MediaType string        `json:"media_type,omitempty"` // audio/image/video, as detected from the content
+	Container string        `json:"container"`            // Demuxer names, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Duration  float64       `json:"duration_seconds"`     // 0 for still images
+	BitRate   int64         `json:"bit_rate"`             // Overall bits per second (0 = unknown)
+	Size      int64         `json:"size_bytes"`
+	Width     int           `json:"width,omitempty"`    // First video stream, as coded
+	Height    int           `json:"height,omitempty"`   // First video stream, as coded
+	Rotation  int           `json:"rotation,omitempty"` // Clockwise degrees players turn the picture
+	Streams   []ProbeStream `json:"streams"`
+}
+
+// ProbeStream describes one stream of a probed file
+type ProbeStream struct {
+	Index       int     `json:"index"`
+	Type        string  `json:"type"`  // video, audio, subtitle, data, ...
+	Codec       string  `json:"codec"` // e.g. h264, aac, mjpeg
+	Profile     string  `json:"profile,omitempty"`
+	Duration    float64 `json:"duration_seconds,omitempty"`
+	BitRate     int64   `json:"bit_rate,omitempty"`
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	Rotation    int     `json:"rotation,omitempty"`
+	FrameRate   float64 `json:"frame_rate,omitempty"`
+	PixelFormat string  `json:"pixel_format,omitempty"`
+	SampleRate  int     `json:"sample_rate,omitempty"`
+	Channels    int     `json:"channels,omitempty"`
+}
+
+// ArchiveResponse lists the outcome of every media file of an archive
+type ArchiveResponse struct {
+	Success        bool                 `json:"success"`            // Every media file was converted
+	Entries        []ArchiveEntryResult `json:"entries"`            // Media files in archive order
+	Skipped        []ArchiveSkipped     `json:"skipped,omitempty"`  // Files that were not converted (unsupported type, unsafe path)
+	Converted      int                  `json:"converted"`          // Entries converted successfully
+	Failed         int                  `json:"failed"`             // Entries whose conversion failed
+	ProcessingTime string               `json:"processing_time_ms"` // Time taken for the whole archive
+}
+
+// ArchiveEntryResult is the conversion of one archive entry
+type ArchiveEntryResult struct {
+	Name      string           `json:"name"`       // Path inside the archive
+	MediaType string           `json:"media_type"` // audio/image/video
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`  // Why the entry failed
+	Code      string           `json:"code,omitempty"`   // Error code of the failure
+	Result    *ConvertResponse `json:"result,omitempty"` // Conversion of the entry, when it succeeded
+}
+
+// FilesResponse holds the outcome of every file of a files request
+type FilesResponse struct {
+	Success        bool                  `json:"success"`            // Every file was converted
+	Files          map[string]FileResult `json:"files"`              // Keyed by the files' tags
+	Converted      int                   `json:"converted"`          // Files converted successfully
+	Failed         int                   `json:"failed"`             // Files whose conversion failed
+	ProcessingTime string                `json:"processing_time_ms"` // Time taken for all files
+}
+
+// FileResult is the conversion of one file of a files request
+type FileResult struct {
+	MediaType string           `json:"media_type,omitempty"` // audio/image/video, once known
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`  // Why the file failed
+	Code      string           `json:"code,omitempty"`   // Error code of the failure
+	Result    *ConvertResponse `json:"result,omitempty"` // Conversion of the file, when it succeeded
+}
+
+// ArchiveSkipped is an archive file that was not converted
+type ArchiveSkipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// BundleManifest is the manifest.json of a zip download holding several outputs
+type BundleManifest struct {
+	Created   string        `json:"created"`   // When the bundle was built (RFC 3339)
+	Converted int           `json:"converted"` // Outputs in the bundle
+	Failed    int           `json:"failed"`    // Inputs whose conversion failed (listed without a file)
+	Files     []BundleEntry `json:"files"`     // One entry per input or output, in request order
+}
+
+// BundleEntry describes one file of a bundle
+type BundleEntry struct {
+	File           string         `json:"file,omitempty"`                // Path inside the zip; empty when the conversion failed
+	Source         string         `json:"source"`                        // Archive entry or output name it was produced from
+	MediaType      string         `json:"media_type"`                    // audio/image/video
+	Success        bool           `json:"success"`                       // Whether the conversion succeeded
+	Error          string         `json:"error,omitempty"`               // Why the conversion failed
+	Code           string         `json:"code,omitempty"`                // Error code of the failure
+	CacheHit       bool           `json:"cache_hit,omitempty"`           // Whether the output came from cache
+	Fallback       bool           `json:"fallback,omitempty"`            // Encoded in safe mode after a failure
+	OriginalSize   int64          `json:"original_size_bytes,omitempty"` // Input size
+	ProcessedSize  int64          `json:"processed_size_bytes,omitempty"`
+	ProcessingTime string         `json:"processing_time_ms,omitempty"`
+	Quality        *QualityReport `json:"quality,omitempty"` // Output vs source scores, when computed
 }
 
 // ConvertResponse represents the conversion response
 type ConvertResponse struct {
-	Success        bool   `json:"success"`
-	ProcessedPath  string `json:"processed_path"`          // Local path to processed file
-	ProcessedURL   string `json:"processed_url,omitempty"` // S3 URL if uploaded
-	CacheHit       bool   `json:"cache_hit"`               // Whether result came from cache
-	MediaType      string `json:"media_type"`              // audio/image/video
-	OriginalSize   int64  `json:"original_size_bytes"`     // Original file size
-	ProcessedSize  int64  `json:"processed_size_bytes"`    // Processed file size
-	SizeIncrease   string `json:"size_increase_percent"`   // Percentage increase
-	ProcessingTime string `json:"processing_time_ms"`      // Time taken to process
-	CacheExpires   string `json:"cache_expires,omitempty"` // When cache becomes invalid
-	FileExpires    string `json:"file_expires,omitempty"`  // When file will be deleted
+	Success        bool              `json:"success"`
+	ProcessedPath  string            `json:"processed_path"`          // Local path to processed file
+	ProcessedURL   string            `json:"processed_url,omitempty"` // Where the output was uploaded (destination_url without its query string)
+	CacheHit       bool              `json:"cache_hit"`               // Whether result came from cache
+	MediaType      string            `json:"media_type"`              // audio/image/video
+	OriginalSize   int64             `json:"original_size_bytes"`     // Original file size
+	ProcessedSize  int64             `json:"processed_size_bytes"`    // Processed file size
+	SizeIncrease   string            `json:"size_increase_percent"`   // Percentage increase
+	ProcessingTime string            `json:"processing_time_ms"`      // Time taken to process
+	Timings        *Timings          `json:"timings,omitempty"`       // Where the processing time went
+	CacheExpires   string            `json:"cache_expires,omitempty"` // When cache becomes invalid
+	FileExpires    string            `json:"file_expires,omitempty"`  // When file will be deleted
+	Experiment     string            `json:"experiment,omitempty"`    // A/B experiment that picked the AF level
+	Variant        string            `json:"variant,omitempty"`       // Variant the device is assigned to
+	Quality        *QualityReport    `json:"quality,omitempty"`       // Output vs source scores (fresh image/video conversions only)
+	Fallback       bool              `json:"fallback,omitempty"`      // Encoded in safe mode after a failure: no AF and no filter-based options
+	Reprocess      string            `json:"reprocess,omitempty"`     // "skip" or "remux" when the input was one of our outputs and wasn't re-encoded
+	Encode         *EncodeDetails    `json:"encode,omitempty"`        // How the output was encoded (fresh conversions only)
+	Outputs        []OutputResult    `json:"outputs,omitempty"`       // Multi-output requests: every rendition in request order; the fields above describe the first
+	Package        *PackageResult    `json:"package,omitempty"`       // HLS/DASH package of the video output(s), when packaging was requested
+	Tags           map[string]string `json:"tags,omitempty"`          // Set by post-processors
+	RequestID      string            `json:"request_id,omitempty"`    // X-Request-ID the request was handled under
+}
+
+// Timings breaks a request's processing time down by stage, in milliseconds
+// Stages a request didn't go through are 0; cache hits are all 0
+type Timings struct {
+	QueueWaitMs int64 `json:"queue_wait_ms"` // Waiting for an overloaded host (admission control) or in the job queue
+	DownloadMs  int64 `json:"download_ms"`   // Input and watermark logo
+	ProbeMs     int64 `json:"probe_ms"`
+	EncodeMs    int64 `json:"encode_ms"` // ffmpeg, including safe-mode retries and packaging
+	WriteMs     int64 `json:"write_ms"`
+	VerifyMs    int64 `json:"verify_ms"` // Playability and quality checks
+	UploadMs    int64 `json:"upload_ms"` // destination_url and package uploads
+}
+
+// PackageResult describes a segmented HLS/DASH package
+type PackageResult struct {
+	Format       string   `json:"format"`                 // hls or dash
+	PlaylistPath string   `json:"playlist_path"`          // Master playlist (HLS) or manifest (DASH)
+	Files        []string `json:"files"`                  // Playlists and segments, relative to the playlist's directory
+	PlaylistURL  string   `json:"playlist_url,omitempty"` // Uploaded playlist, when upload was requested
+}
+
+// OutputResult is one rendition of a multi-output request
+type OutputResult struct {
+	Name string `json:"name"`
+	ConvertResponse
+}
+
+// QualityReport holds the scores of an output compared with its source; higher is closer
+type QualityReport struct {
+	SSIM float64  `json:"ssim"`           // 0-1
+	PSNR float64  `json:"psnr_db"`        // Decibels, 100 = identical
+	VMAF *float64 `json:"vmaf,omitempty"` // 0-100, only when ffmpeg has libvmaf
+}
+
+// EncodeDetails describes a fresh conversion's output and the encoder settings drawn for it
+type EncodeDetails struct {
+	Format   string            `json:"format"`          // Output format, e.g. "webp"
+	Width    int               `json:"width,omitempty"` // Image/video dimensions, when probed
+	Height   int               `json:"height,omitempty"`
+	FFmpegMs int64             `json:"ffmpeg_ms,omitempty"` // Time spent running ffmpeg (0 for remote conversions)
+	Params   map[string]string `json:"params,omitempty"`    // AF parameters, e.g. "crf": "23"
 }
 
 // CacheStatsResponse represents cache statistics
@@ -31,6 +292,34 @@ type CacheStatsResponse struct {
 	DeviceStats map[string]interface{} `json:"device_stats,omitempty"`
 }
 
+// CacheInvalidateResponse represents DELETE /api/cache/:deviceID
+type CacheInvalidateResponse struct {
+	DeviceID    string   `json:"device_id"`
+	URL         string   `json:"url,omitempty"` // Source URL whose outputs were dropped (empty = every output of the device)
+	Invalidated int      `json:"invalidated"`   // Cache entries removed
+	Purged      []string `json:"purged"`        // Public URLs sent to the CDN for purging
+}
+
+// CachePinResponse represents POST and DELETE /api/cache/:deviceID/pin
+type CachePinResponse struct {
+	DeviceID string `json:"device_id"`
+	URL      string `json:"url,omitempty"`
+	Pinned   int    `json:"pinned,omitempty"`   // Entries pinned for the URL (POST)
+	Unpinned int    `json:"unpinned,omitempty"` // Entries returned to the TTLs (DELETE)
+}
+
+// CacheCheckResponse represents GET /api/cache/check
+type CacheCheckResponse struct {
+	DeviceID     string `json:"device_id"`
+	URL          string `json:"url"`
+	Cached       bool   `json:"cached"`
+	MediaType    string `json:"media_type,omitempty"`
+	Size         int64  `json:"size,omitempty"`          // Bytes of the cached output
+	CacheExpires string `json:"cache_expires,omitempty"` // RFC3339; absent while pinned
+	FileExpires  string `json:"file_expires,omitempty"`  // RFC3339; absent while pinned
+	Pinned       bool   `json:"pinned,omitempty"`
+}
+
 // HealthResponse represents health check response
 type HealthResponse struct {
 	Status        string                 `json:"status"`
@@ -39,11 +328,325 @@ type HealthResponse struct {
 	WorkerPool    map[string]interface{} `json:"worker_pool"`
 	BufferPool    map[string]interface{} `json:"buffer_pool"`
 	Cache         map[string]interface{} `json:"cache"`
+	Converters    map[string]interface{} `json:"converters"`
+	OpenHosts     []string               `json:"open_source_hosts"` // Source hosts skipped by the download circuit breaker
+	Processes     map[string]interface{} `json:"processes"`         // ffmpeg/ffprobe processes running and reaped
+	Admission     map[string]interface{} `json:"admission"`         // Host load, memory use and conversions held back
+	Runtime       map[string]interface{} `json:"runtime"`
 }
 
-// ErrorResponse represents an error response
+// DashboardResponse represents GET /api/admin/dashboard
+type DashboardResponse struct {
+	Timestamp  string                 `json:"timestamp"`
+	Uptime     string                 `json:"uptime"`
+	WorkerPool map[string]interface{} `json:"worker_pool"`
+	BufferPool map[string]interface{} `json:"buffer_pool"`
+	Cache      map[string]interface{} `json:"cache"`
+	Converters map[string]interface{} `json:"converters"`
+	Downloader map[string]interface{} `json:"downloader"`
+	Jobs       map[string]interface{} `json:"jobs,omitempty"` // Omitted when async jobs are disabled
+	Rates      map[string]RateStat    `json:"rates"`          // Counters with their rolling rates
+}
+
+// RateStat is a counter since startup and its average rate over the last 1, 5 and 15 minutes
+type RateStat struct {
+	Total  int64              `json:"total"`
+	PerMin map[string]float64 `json:"per_min"` // "1m", "5m", "15m"; shorter while uptime is below the window
+}
+
+// ReloadResponse represents POST /admin/reload
+type ReloadResponse struct {
+	Success bool     `json:"success"`
+	Changes []string `json:"changes"` // Settings that changed, e.g. "MAX_WORKERS: 8 → 16"
+}
+
+// RuntimeResponse represents GET /api/debug/runtime
+type RuntimeResponse struct {
+	Timestamp  string       `json:"timestamp"`
+	Uptime     string       `json:"uptime"`
+	GoVersion  string       `json:"go_version"`
+	NumCPU     int          `json:"num_cpu"`
+	GOMAXPROCS int          `json:"gomaxprocs"`
+	Goroutines int          `json:"goroutines"`
+	Heap       HeapStats    `json:"heap"`
+	GC         GCStatsReply `json:"gc"`
+}
+
+// HeapStats is a subset of runtime.MemStats, in bytes
+type HeapStats struct {
+	Alloc      uint64 `json:"alloc_bytes"`       // Live heap objects
+	Sys        uint64 `json:"sys_bytes"`         // Heap memory obtained from the OS
+	InUse      uint64 `json:"inuse_bytes"`       // Bytes in in-use spans
+	Idle       uint64 `json:"idle_bytes"`        // Bytes in idle spans
+	Released   uint64 `json:"released_bytes"`    // Idle bytes returned to the OS
+	Objects    uint64 `json:"objects"`           // Live heap object count
+	TotalAlloc uint64 `json:"total_alloc_bytes"` // Cumulative allocations
+	NextGC     uint64 `json:"next_gc_bytes"`     // Heap size target of the next GC
+	TotalSys   uint64 `json:"total_sys_bytes"`   // All memory obtained from the OS
+}
+
+// GCStatsReply summarizes garbage collector activity
+type GCStatsReply struct {
+	NumGC         uint32    `json:"num_gc"`
+	LastGC        string    `json:"last_gc,omitempty"`
+	PauseTotalMs  float64   `json:"pause_total_ms"`
+	RecentPauseMs []float64 `json:"recent_pauses_ms"` // Newest first, up to 16
+	CPUFraction   float64   `json:"cpu_fraction"`     // Share of CPU time used by GC since start
+}
+
+// ErrorResponse is an RFC 7807 problem details body
+// Success/Error/Details are kept for clients written before error codes existed
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Type      string `json:"type"`               // Problem type URI, derived from Code
+	Title     string `json:"title"`              // HTTP status text
+	Status    int    `json:"status"`             // HTTP status code
+	Detail    string `json:"detail"`             // Human-readable message
+	Instance  string `json:"instance,omitempty"` // Request path
+	Code      string `json:"code"`               // Stable machine-readable error code
+	Success   bool   `json:"success"`
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // X-Request-ID, for support and log correlation
+
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"` // Field-level validation failures
+}
+
+// InvalidParam is one request field that failed validation
+type InvalidParam struct {
+	Name   string `json:"name"`   // JSON path of the field, e.g. watermark.opacity
+	Rule   string `json:"rule"`   // Failed rule, e.g. required or oneof
+	Reason string `json:"reason"` // Human-readable explanation
+}
+
+// WSStartMessage opens a conversion on the WebSocket API
+// Media bytes follow as binary frames, terminated by {"type":"end"}
+type WSStartMessage struct {
+	Type     string `json:"type"`               // "start"
+	Filename string `json:"filename,omitempty"` // Original file name, used to detect media_type
+	ConvertRequest
+}
+
+// WSEvent is a server message on the WebSocket API
+type WSEvent struct {
+	Type    string           `json:"type"`              // progress/result/done/error
+	Stage   string           `json:"stage,omitempty"`   // ready/uploading/received/processing/sending
+	Bytes   int64            `json:"bytes,omitempty"`   // Bytes received or sent so far
+	Result  *ConvertResponse `json:"result,omitempty"`  // Set on result events
+	Error   string           `json:"error,omitempty"`   // Set on error events
+	Code    string           `json:"code,omitempty"`    // Error code on error events
+	Details string           `json:"details,omitempty"` // Error details
+}
+
+// QueueRequest is a convert request consumed from Kafka or RabbitMQ
+type QueueRequest struct {
+	RequestID string `json:"request_id,omitempty"` // Echoed on the result for correlation
+	TenantID  string `json:"tenant_id,omitempty"`  // Required when multi-tenancy is enabled
+	ConvertRequest
+}
+
+// QueueResult is published to the response topic/queue for each consumed request
+type QueueResult struct {
+	RequestID string           `json:"request_id,omitempty"`
+	Success   bool             `json:"success"`
+	Result    *ConvertResponse `json:"result,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Code      string           `json:"code,omitempty"`
+	Details   string           `json:"details,omitempty"`
+}
+
+// Job status values
+const (
+	JobStatusQueued     = "queued"
+	JobStatusProcessing = "processing"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+)
+
+// RetryPolicy controls how transient job failures are retried
+type RetryPolicy struct {
+	MaxAttempts    int `json:"max_attempts" validate:"gte=0,lte=10"` // Total attempts including the first one
+	BackoffSeconds int `json:"backoff_seconds" validate:"gte=0"`     // Delay before the first retry, doubled on each retry
+}
+
+// JobSubmitRequest is a convert request plus an optional retry policy
+type JobSubmitRequest struct {
+	ConvertRequest
+	Retry      *RetryPolicy `json:"retry,omitempty"`       // Defaults from JOB_MAX_ATTEMPTS / JOB_RETRY_BACKOFF
+	ScheduleAt *time.Time   `json:"schedule_at,omitempty"` // Run no earlier than this (RFC 3339); omitted or past = now
+}
+
+// WarmRequest lists conversions to run ahead of time so later requests hit the cache
+// Items take the same fields as /convert; only URL inputs are accepted
+type WarmRequest struct {
+	Items []ConvertRequest `json:"items" validate:"required,min=1,max=1000,dive"`
+}
+
+// WarmResponse reports how many warm-up items were queued
+type WarmResponse struct {
+	Queued  int `json:"queued"`
+	Skipped int `json:"skipped"` // Duplicates of items already queued
+}
+
+// Job represents an async conversion job
+type Job struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"`                // queued/processing/completed/failed
+	DeviceID   string           `json:"device_id"`             // Copied from the request for filtering
+	TenantID   string           `json:"tenant_id,omitempty"`   // Owner when multi-tenancy is enabled
+	RequestID  string           `json:"request_id,omitempty"`  // X-Request-ID of the submitting request, reused by every attempt
+	Request    ConvertRequest   `json:"request"`               // Original request
+	Result     *ConvertResponse `json:"result,omitempty"`      // Set when completed
+	Error      string           `json:"error,omitempty"`       // Set when failed
+	ErrorCode  string           `json:"error_code,omitempty"`  // Machine-readable code when failed
+	Attempts   int              `json:"attempts"`              // Number of processing attempts
+	Retry      RetryPolicy      `json:"retry"`                 // Retry policy for transient failures
+	CreatedAt  time.Time        `json:"created_at"`            // When the job was submitted
+	StartedAt  *time.Time       `json:"started_at,omitempty"`  // When the last attempt started
+	FinishedAt *time.Time       `json:"finished_at,omitempty"` // When the job completed or failed
+
+	ScheduledAt   *time.Time `json:"scheduled_at,omitempty"`    // Requested start time for scheduled jobs
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // When a queued retry (or scheduled job) becomes due
+
+	// Set in the submit response only, from the queue and recent conversion times
+	EstimatedStartAt      *time.Time `json:"estimated_start_at,omitempty"`
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at,omitempty"`
+}
+
+// JobListResponse represents a filtered list of jobs
+type JobListResponse struct {
+	Jobs  []*Job `json:"jobs"`
+	Count int    `json:"count"`
+}
+
+// UsageCounters holds usage totals for chargeback
+type UsageCounters struct {
+	Conversions int64   `json:"conversions"` // Conversions actually run (cache misses)
+	CacheHits   int64   `json:"cache_hits"`  // Requests served from cache
+	BytesIn     int64   `json:"bytes_in"`    // Input bytes converted
+	BytesOut    int64   `json:"bytes_out"`   // Output bytes produced
+	CPUSeconds  float64 `json:"cpu_seconds"` // Estimated from ffmpeg runtime
+}
+
+// UsageRecord is the daily rollup for one device
+type UsageRecord struct {
+	Date     string `json:"date"` // YYYY-MM-DD (UTC)
+	TenantID string `json:"tenant_id,omitempty"`
+	DeviceID string `json:"device_id"`
+	UsageCounters
+}
+
+// UsageResponse represents usage for a date range
+type UsageResponse struct {
+	From   string        `json:"from"`
+	To     string        `json:"to"`
+	Days   []UsageRecord `json:"days"`
+	Totals UsageCounters `json:"totals"`
+}
+
+// AuditEntry records one conversion request for compliance
+type AuditEntry struct {
+	Time          time.Time `json:"time"`
+	Operation     string    `json:"operation"`                      // convert/slideshow/concat
+	Caller        string    `json:"caller,omitempty"`               // ip:<addr>, job:<id> or queue:<request_id>
+	RequestID     string    `json:"request_id,omitempty"`           // X-Request-ID of the request (or of the one that submitted the job)
+	TenantID      string    `json:"tenant_id,omitempty"`            // Set when multi-tenancy is enabled
+	DeviceID      string    `json:"device_id"`                      // Device the output was produced for
+	SourceHash    string    `json:"source_hash,omitempty"`          // SHA-256 of the source URL(s) or payload
+	MediaType     string    `json:"media_type,omitempty"`           // audio/image/video
+	Level         string    `json:"level,omitempty"`                // Anti-fingerprint level
+	Status        int       `json:"status"`                         // HTTP status of the outcome
+	Success       bool      `json:"success"`                        // Whether an output was produced
+	Error         string    `json:"error,omitempty"`                // Set when failed
+	ErrorCode     string    `json:"error_code,omitempty"`           // Machine-readable code when failed
+	CacheHit      bool      `json:"cache_hit"`                      // Whether result came from cache
+	Experiment    string    `json:"experiment,omitempty"`           // A/B experiment the device took part in
+	Variant       string    `json:"variant,omitempty"`              // Variant of that experiment
+	Fallback      bool      `json:"fallback,omitempty"`             // Output was encoded in safe mode
+	OriginalSize  int64     `json:"original_size_bytes,omitempty"`  // Input size
+	ProcessedSize int64     `json:"processed_size_bytes,omitempty"` // Output size
+	DurationMs    int64     `json:"duration_ms"`                    // Total request time
+}
+
+// SlowConversion reports a conversion that took longer than the threshold for its media type
+// Sent as JSON to SLOW_CONVERSION_WEBHOOK_URL
+type SlowConversion struct {
+	Time        time.Time        `json:"time"`
+	TenantID    string           `json:"tenant_id,omitempty"`
+	RequestID   string           `json:"request_id,omitempty"`
+	DeviceID    string           `json:"device_id"`
+	MediaType   string           `json:"media_type"`
+	Level       string           `json:"level,omitempty"`
+	SourceHash  string           `json:"source_hash,omitempty"` // SHA-256 of the source URL or payload
+	Success     bool             `json:"success"`
+	Error       string           `json:"error,omitempty"`
+	DurationMs  int64            `json:"duration_ms"`  // Cache lookup to result
+	ThresholdMs int64            `json:"threshold_ms"` // SLOW_CONVERSION_<MEDIA> in effect
+	StagesMs    map[string]int64 `json:"stages_ms"`    // download/probe/encode/write/verify
+}
+
+// DeviceHistoryResponse represents a device's recent conversions, newest first
+type DeviceHistoryResponse struct {
+	DeviceID string       `json:"device_id"`
+	Entries  []AuditEntry `json:"entries"`
+	Count    int          `json:"count"`
+}
+
+// DeviceRequest registers or updates a device (PUT /api/devices/:deviceID)
+type DeviceRequest struct {
+	Label             string   `json:"label,omitempty" validate:"max=256"`                                              // Free-form name shown in listings
+	DefaultLevel      string   `json:"default_level,omitempty" validate:"omitempty,oneof=none basic moderate paranoid"` // Used when a request sets no level
+	Profile           string   `json:"profile,omitempty" validate:"omitempty,max=64"`                                   // Named AF profile; takes precedence over default_level
+	AllowedMediaTypes []string `json:"allowed_media_types,omitempty" validate:"max=3,dive,oneof=audio image video"`     // Empty = all the tenant allows
+}
+
+// Device is a registered device with its per-device defaults
+type Device struct {
+	DeviceID          string    `json:"device_id"`
+	Label             string    `json:"label,omitempty"`
+	DefaultLevel      string    `json:"default_level,omitempty"`
+	Profile           string    `json:"profile,omitempty"`
+	AllowedMediaTypes []string  `json:"allowed_media_types,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// DeviceListResponse represents the registered devices of the caller's tenant
+type DeviceListResponse struct {
+	Devices []Device `json:"devices"`
+	Count   int      `json:"count"`
+}
+
+// ExperimentsResponse represents GET /api/admin/experiments
+type ExperimentsResponse struct {
+	Experiments []ExperimentReport `json:"experiments"`
+}
+
+// ExperimentReport is one experiment with outcomes per variant
+type ExperimentReport struct {
+	Name       string          `json:"name"`
+	MediaTypes []string        `json:"media_types,omitempty"`
+	Active     bool            `json:"active"` // false once removed from the config (stats are kept until restart)
+	Variants   []VariantReport `json:"variants"`
+}
+
+// VariantReport holds the outcomes of one variant since startup
+type VariantReport struct {
+	Name                   string  `json:"name"`
+	Profile                string  `json:"profile,omitempty"`
+	Weight                 int     `json:"weight,omitempty"`
+	Requests               int64   `json:"requests"`
+	Failures               int64   `json:"failures"`
+	SuccessRate            float64 `json:"success_rate"` // 0-1
+	CacheHits              int64   `json:"cache_hits"`
+	Conversions            int64   `json:"conversions"`               // Successful non-cached runs; the averages cover these
+	AvgProcessedSize       int64   `json:"avg_processed_size_bytes"`  // Output size
+	AvgSizeIncreasePercent float64 `json:"avg_size_increase_percent"` // Output vs input size
+	AvgDurationMs          int64   `json:"avg_duration_ms"`           // Request latency
+}
+
+// AuditListResponse represents audit entries for one day
+type AuditListResponse struct {
+	Date    string        `json:"date"`
+	Entries []*AuditEntry `json:"entries"`
+	Count   int           `json:"count"`
 }
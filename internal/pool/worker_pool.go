@@ -21,6 +21,7 @@ type WorkerPool struct {
 	contextQueue chan contextTask
 	workerWg     sync.WaitGroup
 	quit         chan struct{}
+	retire       chan struct{} // Each receive stops one worker (see Resize)
 	activeCount  int32
 	totalTasks   int64
 	failedTasks  int64
@@ -46,6 +47,7 @@ func NewWorkerPool(maxWorkers int) *WorkerPool {
 		taskQueue:    make(chan Task, maxWorkers*10), // Buffered queue
 		contextQueue: make(chan contextTask, maxWorkers*10),
 		quit:         make(chan struct{}),
+		retire:       make(chan struct{}),
 	}
 }
 
@@ -123,12 +125,50 @@ func (p *WorkerPool) worker(id int) {
 				}
 			}
 
+		case <-p.retire:
+			return
+
 		case <-p.quit:
 			return
 		}
 	}
 }
 
+// Resize changes the number of workers at runtime
+// Extra workers are retired as they go idle, so in-flight tasks always finish.
+// Queue capacity is fixed at creation and does not change.
+func (p *WorkerPool) Resize(maxWorkers int) {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := p.maxWorkers
+	p.maxWorkers = maxWorkers
+	if !p.started || maxWorkers == current {
+		return
+	}
+
+	for i := current; i < maxWorkers; i++ {
+		p.workerWg.Add(1)
+		go p.worker(i)
+	}
+	if excess := current - maxWorkers; excess > 0 {
+		quit := p.quit
+		go func() {
+			for i := 0; i < excess; i++ {
+				select {
+				case p.retire <- struct{}{}:
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+}
+
 // Submit adds a task to the queue
 func (p *WorkerPool) Submit(task Task) error {
 	p.mu.RLock()
@@ -214,8 +254,12 @@ type WorkerPoolStats struct {
 
 // GetStats returns current statistics
 func (p *WorkerPool) GetStats() WorkerPoolStats {
+	p.mu.RLock()
+	maxWorkers := p.maxWorkers
+	p.mu.RUnlock()
+
 	return WorkerPoolStats{
-		MaxWorkers:    p.maxWorkers,
+		MaxWorkers:    maxWorkers,
 		ActiveWorkers: atomic.LoadInt32(&p.activeCount),
 		TotalTasks:    atomic.LoadInt64(&p.totalTasks),
 		FailedTasks:   atomic.LoadInt64(&p.failedTasks),
@@ -0,0 +1,180 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ftpConn is a logged-in FTP control connection
+type ftpConn struct {
+	client *Client
+	tp     *textproto.Conn
+	host   string // Data connections go to the control host, whatever address PASV names
+	close  func()
+}
+
+// fetchFTP downloads t over FTP in binary mode
+func (c *Client) fetchFTP(ctx context.Context, t *target, maxSize int64) ([]byte, error) {
+	conn, err := c.openFTP(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.quit()
+
+	data, closeData, err := conn.dataConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeData()
+	if _, _, err := conn.cmd(1, "RETR %s", ftpPath(t.path)); err != nil {
+		return nil, ftpError("RETR", err)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(data, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ftp transfer failed: %w", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, &PermanentError{Err: fmt.Errorf("%w: more than %d bytes", ErrFileTooLarge, maxSize)}
+	}
+	closeData()
+	if _, _, err := conn.tp.ReadResponse(2); err != nil {
+		return nil, ftpError("RETR", err)
+	}
+	return body, nil
+}
+
+// putFTP uploads r to t over FTP, creating its parent directories first
+func (c *Client) putFTP(ctx context.Context, t *target, r io.Reader) error {
+	conn, err := c.openFTP(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer conn.quit()
+
+	// MKD fails on directories that already exist; STOR reports a directory that is really missing
+	dir := ""
+	for _, part := range strings.Split(path.Dir(ftpPath(t.path)), "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		dir = path.Join(dir, part)
+		conn.cmd(2, "MKD %s", dir)
+	}
+
+	data, closeData, err := conn.dataConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeData()
+	if _, _, err := conn.cmd(1, "STOR %s", ftpPath(t.path)); err != nil {
+		return ftpError("STOR", err)
+	}
+	if _, err := io.Copy(data, r); err != nil {
+		return fmt.Errorf("ftp transfer failed: %w", err)
+	}
+	closeData()
+	if _, _, err := conn.tp.ReadResponse(2); err != nil {
+		return ftpError("STOR", err)
+	}
+	return nil
+}
+
+// openFTP connects to t's server, logs in and switches to binary mode
+func (c *Client) openFTP(ctx context.Context, t *target) (*ftpConn, error) {
+	netConn, closeConn, err := c.dial(ctx, t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ftp connect failed: %w", err)
+	}
+	host, _, _ := net.SplitHostPort(t.addr)
+	conn := &ftpConn{client: c, tp: textproto.NewConn(netConn), host: host, close: closeConn}
+
+	if _, _, err := conn.tp.ReadResponse(2); err != nil {
+		conn.close()
+		return nil, ftpError("greeting", err)
+	}
+	code, _, err := conn.cmd(2, "USER %s", t.login.User)
+	if code == 331 {
+		_, _, err = conn.cmd(2, "PASS %s", t.login.Password)
+	}
+	if err != nil {
+		conn.close()
+		return nil, ftpError("login", err)
+	}
+	if _, _, err := conn.cmd(2, "TYPE I"); err != nil {
+		conn.close()
+		return nil, ftpError("TYPE", err)
+	}
+	return conn, nil
+}
+
+// cmd sends a command and reads its reply, which must start with expectCode
+func (f *ftpConn) cmd(expectCode int, format string, args ...any) (int, string, error) {
+	if _, err := f.tp.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	return f.tp.ReadResponse(expectCode)
+}
+
+// dataConn opens a passive data connection, preferring EPSV
+// The address in a PASV reply is ignored, so a server can't point the transfer at another host
+func (f *ftpConn) dataConn(ctx context.Context) (net.Conn, func(), error) {
+	port := 0
+	if _, msg, err := f.cmd(229, "EPSV"); err == nil {
+		// 229 Entering Extended Passive Mode (|||port|)
+		if start := strings.Index(msg, "(|||"); start >= 0 {
+			fields := strings.Split(msg[start+4:], "|")
+			port, _ = strconv.Atoi(fields[0])
+		}
+	} else if _, msg, err := f.cmd(227, "PASV"); err == nil {
+		// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+		if start >= 0 && end > start {
+			fields := strings.Split(msg[start+1:end], ",")
+			if len(fields) == 6 {
+				high, _ := strconv.Atoi(fields[4])
+				low, _ := strconv.Atoi(fields[5])
+				port = high<<8 | low
+			}
+		}
+	} else {
+		return nil, nil, ftpError("PASV", err)
+	}
+	if port <= 0 || port > 65535 {
+		return nil, nil, fmt.Errorf("ftp server sent no usable passive port")
+	}
+
+	conn, closeConn, err := f.client.dial(ctx, net.JoinHostPort(f.host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("ftp data connection failed: %w", err)
+	}
+	return conn, closeConn, nil
+}
+
+// quit says goodbye and closes the control connection
+func (f *ftpConn) quit() {
+	f.cmd(2, "QUIT")
+	f.close()
+}
+
+// ftpPath maps a URL path to the server path; like curl, paths are relative to the login directory
+// and a leading %2F makes them absolute
+func ftpPath(urlPath string) string {
+	return strings.TrimPrefix(urlPath, "/")
+}
+
+// ftpError wraps a failed exchange; 5xx replies (no such file, login refused) are permanent
+func ftpError(op string, err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return &PermanentError{Err: fmt.Errorf("ftp %s failed: %w", op, err)}
+	}
+	return fmt.Errorf("ftp %s failed: %w", op, err)
+}
@@ -0,0 +1,245 @@
+// Package remote fetches and stores files on FTP and SFTP servers
+// Partners that only expose file drops are read from and written to like HTTP sources and targets
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrFileTooLarge is returned when a remote file exceeds the size limit
+var ErrFileTooLarge = errors.New("remote file too large")
+
+// Login is a user and password for a remote host
+type Login struct {
+	User     string
+	Password string
+}
+
+// Config configures FTP/SFTP access
+type Config struct {
+	Credentials     map[string]Login // Logins per host or host:port, used when the URL carries none (see ParseCredentials)
+	KeyFile         string           // Private key offered to SFTP servers ("" = password only)
+	KnownHosts      string           // known_hosts file SFTP host keys are checked against
+	InsecureHostKey bool             // Accept any SFTP host key (testing only)
+	Timeout         time.Duration    // Connect and idle timeout per transfer
+}
+
+// Client transfers files over FTP and SFTP
+type Client struct {
+	credentials map[string]Login
+	signers     []ssh.Signer
+	hostKey     ssh.HostKeyCallback // nil = SFTP refused until known hosts are configured
+	timeout     time.Duration
+}
+
+// New creates a client; the key and known_hosts files are read up front so mistakes fail at startup
+func New(cfg Config) (*Client, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	c := &Client{credentials: cfg.Credentials, timeout: cfg.Timeout}
+
+	if cfg.KeyFile != "" {
+		pem, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SFTP key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP key: %w", err)
+		}
+		c.signers = append(c.signers, signer)
+	}
+
+	switch {
+	case cfg.InsecureHostKey:
+		c.hostKey = ssh.InsecureIgnoreHostKey()
+	case cfg.KnownHosts != "":
+		callback, err := knownhosts.New(cfg.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SFTP known hosts: %w", err)
+		}
+		c.hostKey = callback
+	}
+	return c, nil
+}
+
+// ParseCredentials parses comma-separated user:password@host[:port] logins
+// The last @ separates the host, so passwords may contain @ (but not commas)
+func ParseCredentials(value string) (map[string]Login, error) {
+	credentials := make(map[string]Login)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		at := strings.LastIndex(entry, "@")
+		if at <= 0 || at == len(entry)-1 {
+			return nil, fmt.Errorf("login %q must be user:password@host", redactLogin(entry))
+		}
+		user, password, _ := strings.Cut(entry[:at], ":")
+		if user == "" {
+			return nil, fmt.Errorf("login %q has no user", redactLogin(entry))
+		}
+		credentials[entry[at+1:]] = Login{User: user, Password: password}
+	}
+	return credentials, nil
+}
+
+// redactLogin keeps a malformed login out of error messages, except for its host
+func redactLogin(entry string) string {
+	if at := strings.LastIndex(entry, "@"); at >= 0 {
+		return "...@" + entry[at+1:]
+	}
+	return "..."
+}
+
+// Supported reports whether rawURL uses a scheme this package handles
+func Supported(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "ftp://") || strings.HasPrefix(rawURL, "sftp://")
+}
+
+// Fetch downloads the file at rawURL, failing with ErrFileTooLarge past maxSize bytes
+func (c *Client) Fetch(ctx context.Context, rawURL string, maxSize int64) ([]byte, error) {
+	target, err := c.parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if target.scheme == "sftp" {
+		return c.fetchSFTP(ctx, target, maxSize)
+	}
+	return c.fetchFTP(ctx, target, maxSize)
+}
+
+// Put uploads r to rawURL, creating missing parent directories
+func (c *Client) Put(ctx context.Context, rawURL string, r io.Reader) error {
+	target, err := c.parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if target.scheme == "sftp" {
+		return c.putSFTP(ctx, target, r)
+	}
+	return c.putFTP(ctx, target, r)
+}
+
+// target is a parsed remote URL with the login to use
+type target struct {
+	scheme string
+	addr   string // host:port
+	path   string
+	login  Login
+}
+
+// parse splits rawURL and picks its login: the URL's own, then the configured one for the host
+func (c *Client) parse(rawURL string) (*target, error) {
+	if c == nil {
+		return nil, fmt.Errorf("FTP/SFTP transfers are not enabled")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &PermanentError{Err: fmt.Errorf("invalid URL: %w", err)}
+	}
+	if u.Scheme != "ftp" && u.Scheme != "sftp" {
+		return nil, &PermanentError{Err: fmt.Errorf("unsupported scheme %q", u.Scheme)}
+	}
+	if u.Hostname() == "" || u.Path == "" || strings.HasSuffix(u.Path, "/") {
+		return nil, &PermanentError{Err: fmt.Errorf("URL must name a file")}
+	}
+
+	// Paths and logins end up in FTP commands, so line breaks could smuggle in extra ones
+	password, _ := u.User.Password()
+	if strings.ContainsAny(u.Path+u.User.Username()+password, "\r\n\x00") {
+		return nil, &PermanentError{Err: fmt.Errorf("URL contains control characters")}
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"ftp": "21", "sftp": "22"}[u.Scheme]
+	}
+	t := &target{scheme: u.Scheme, addr: net.JoinHostPort(u.Hostname(), port), path: u.Path}
+
+	if u.User != nil {
+		t.login.User = u.User.Username()
+		t.login.Password, _ = u.User.Password()
+	} else if login, ok := c.credentials[t.addr]; ok {
+		t.login = login
+	} else {
+		t.login = c.credentials[u.Hostname()]
+	}
+
+	if t.login.User == "" && t.scheme == "ftp" {
+		t.login = Login{User: "anonymous", Password: "anonymous@"}
+	}
+	if t.login.User == "" {
+		return nil, &PermanentError{Err: fmt.Errorf("no SFTP login for %s", u.Hostname())}
+	}
+	return t, nil
+}
+
+// dial opens a TCP connection to addr that is closed when ctx ends
+// The connection fails once it sits idle for the client's timeout
+func (c *Client) dial(ctx context.Context, addr string) (net.Conn, func(), error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	return &idleConn{Conn: conn, timeout: c.timeout}, func() { stop(); conn.Close() }, nil
+}
+
+// idleConn pushes its deadline back on every read and write
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(p)
+}
+
+// PermanentError marks a failure that retrying won't fix (missing file, rejected login, bad URL)
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err (or anything it wraps) is a PermanentError
+func IsPermanent(err error) bool {
+	var permanentErr *PermanentError
+	return errors.As(err, &permanentErr)
+}
+
+// Redact masks the password in rawURL so it can be logged
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,503 @@
+package remote
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTP version 3 packet types (draft-ietf-secsh-filexfer-02)
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpWrite    = 6
+	sftpFstat    = 8
+	sftpMkdir    = 14
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpAttrs    = 105
+	sftpProtocol = 3
+)
+
+// SFTP open flags and status codes
+const (
+	sftpFlagRead     = 0x01
+	sftpFlagWrite    = 0x02
+	sftpFlagCreate   = 0x08
+	sftpFlagTruncate = 0x10
+
+	sftpStatusOK          = 0
+	sftpStatusEOF         = 1
+	sftpStatusNoSuchFile  = 2
+	sftpStatusPermission  = 3
+	sftpStatusBadMessage  = 5
+	sftpStatusUnsupported = 8
+)
+
+const (
+	sftpChunkSize = 32 * 1024  // Largest read or write every server accepts
+	sftpWindow    = 64         // Requests kept in flight, so throughput doesn't wait on round trips
+	sftpMaxPacket = 256 * 1024 // Larger replies are treated as a broken server
+	sftpAttrSize  = 0x01       // ATTRS flag: size present
+)
+
+// sftpConn is an SFTP session on an SSH connection
+type sftpConn struct {
+	w      io.Writer
+	r      io.Reader
+	nextID uint32
+	close  func()
+}
+
+// sftpStatusError is a failure reported by the server
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp status %d: %s", e.Code, e.Message)
+}
+
+// fetchSFTP downloads t over SFTP
+func (c *Client) fetchSFTP(ctx context.Context, t *target, maxSize int64) ([]byte, error) {
+	conn, err := c.openSFTP(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	handle, err := conn.open(sftpPath(t.path), sftpFlagRead)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.closeHandle(handle)
+
+	if size, err := conn.size(handle); err == nil && size > maxSize {
+		return nil, &PermanentError{Err: fmt.Errorf("%w: %d bytes (max: %d)", ErrFileTooLarge, size, maxSize)}
+	}
+	return conn.readAll(handle, maxSize)
+}
+
+// putSFTP uploads r to t over SFTP, creating its parent directories first
+func (c *Client) putSFTP(ctx context.Context, t *target, r io.Reader) error {
+	conn, err := c.openSFTP(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	// MKDIR fails on directories that already exist; OPEN reports a directory that is really missing
+	remotePath := sftpPath(t.path)
+	dir := ""
+	if strings.HasPrefix(remotePath, "/") {
+		dir = "/"
+	}
+	for _, part := range strings.Split(path.Dir(remotePath), "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		dir = path.Join(dir, part)
+		conn.mkdir(dir)
+	}
+
+	handle, err := conn.open(remotePath, sftpFlagWrite|sftpFlagCreate|sftpFlagTruncate)
+	if err != nil {
+		return err
+	}
+	if err := conn.writeAll(handle, r); err != nil {
+		conn.closeHandle(handle)
+		return err
+	}
+	return conn.closeHandle(handle)
+}
+
+// openSFTP connects and authenticates over SSH and starts the sftp subsystem
+func (c *Client) openSFTP(ctx context.Context, t *target) (*sftpConn, error) {
+	if c.hostKey == nil {
+		return nil, &PermanentError{Err: fmt.Errorf("SFTP host keys can't be verified: SFTP_KNOWN_HOSTS is not set")}
+	}
+
+	var auth []ssh.AuthMethod
+	if len(c.signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(c.signers...))
+	}
+	if t.login.Password != "" {
+		auth = append(auth, ssh.Password(t.login.Password))
+	}
+	config := &ssh.ClientConfig{
+		User:            t.login.User,
+		Auth:            auth,
+		HostKeyCallback: c.hostKey,
+		Timeout:         c.timeout,
+	}
+
+	netConn, closeConn, err := c.dial(ctx, t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("sftp connect failed: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, t.addr, config)
+	if err != nil {
+		closeConn()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) || strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, &PermanentError{Err: fmt.Errorf("sftp login failed: %w", err)}
+		}
+		return nil, fmt.Errorf("sftp handshake failed: %w", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	closeAll := func() {
+		client.Close()
+		closeConn()
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		closeAll()
+		return nil, fmt.Errorf("sftp session failed: %w", err)
+	}
+	w, err := session.StdinPipe()
+	if err == nil {
+		var r io.Reader
+		r, err = session.StdoutPipe()
+		if err == nil {
+			err = session.RequestSubsystem("sftp")
+		}
+		if err == nil {
+			conn := &sftpConn{w: w, r: r, close: closeAll}
+			if err = conn.init(); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	closeAll()
+	return nil, fmt.Errorf("sftp subsystem failed: %w", err)
+}
+
+// init negotiates protocol version 3
+func (s *sftpConn) init() error {
+	if err := s.send(sftpInit, uint32(sftpProtocol)); err != nil {
+		return err
+	}
+	typ, _, err := s.recv()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion {
+		return fmt.Errorf("unexpected reply %d to init", typ)
+	}
+	return nil
+}
+
+// open opens path with flags and returns its handle
+func (s *sftpConn) open(path string, flags uint32) (string, error) {
+	body, err := s.call(sftpHandle, sftpOpen, path, flags, uint32(0))
+	if err != nil {
+		return "", err
+	}
+	return body.string()
+}
+
+// size returns the size of the open file; errors when the server doesn't report it
+func (s *sftpConn) size(handle string) (int64, error) {
+	body, err := s.call(sftpAttrs, sftpFstat, handle)
+	if err != nil {
+		return 0, err
+	}
+	flags, err := body.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if flags&sftpAttrSize == 0 {
+		return 0, fmt.Errorf("size not reported")
+	}
+	size, err := body.uint64()
+	return int64(size), err
+}
+
+// mkdir creates dir
+func (s *sftpConn) mkdir(dir string) error {
+	_, err := s.call(sftpStatus, sftpMkdir, dir, uint32(0))
+	return err
+}
+
+// closeHandle closes an open file
+func (s *sftpConn) closeHandle(handle string) error {
+	_, err := s.call(sftpStatus, sftpClose, handle)
+	return err
+}
+
+// readAll reads the open file with several reads in flight, failing past maxSize bytes
+func (s *sftpConn) readAll(handle string, maxSize int64) ([]byte, error) {
+	type request struct {
+		offset int64
+		length int
+	}
+	inFlight := make(map[uint32]request, sftpWindow)
+	limit := maxSize + 1 // One byte over the cap shows the file is too large
+	var data []byte
+	var next, end int64
+	eof := int64(-1)
+
+	send := func(req request) error {
+		id := s.id()
+		inFlight[id] = req
+		return s.sendID(sftpRead, id, handle, uint64(req.offset), uint32(req.length))
+	}
+
+	for {
+		for eof < 0 && len(inFlight) < sftpWindow && next < limit {
+			length := int(min(sftpChunkSize, limit-next))
+			if err := send(request{offset: next, length: length}); err != nil {
+				return nil, err
+			}
+			next += int64(length)
+		}
+		if len(inFlight) == 0 {
+			break
+		}
+
+		typ, body, err := s.recv()
+		if err != nil {
+			return nil, err
+		}
+		id, err := body.uint32()
+		if err != nil {
+			return nil, err
+		}
+		req, ok := inFlight[id]
+		if !ok {
+			return nil, fmt.Errorf("sftp reply to unknown request %d", id)
+		}
+		delete(inFlight, id)
+
+		switch typ {
+		case sftpData:
+			chunk, err := body.string()
+			if err != nil {
+				return nil, err
+			}
+			chunkEnd := req.offset + int64(len(chunk))
+			if int64(len(data)) < chunkEnd {
+				data = append(data, make([]byte, chunkEnd-int64(len(data)))...)
+			}
+			copy(data[req.offset:], chunk)
+			end = max(end, chunkEnd)
+			// Servers may return less than asked for; ask again for the rest
+			if len(chunk) > 0 && len(chunk) < req.length {
+				if err := send(request{offset: chunkEnd, length: req.length - len(chunk)}); err != nil {
+					return nil, err
+				}
+			}
+		case sftpStatus:
+			if err := statusError(body); err != nil {
+				return nil, err
+			}
+			if eof < 0 || req.offset < eof {
+				eof = req.offset
+			}
+		default:
+			return nil, fmt.Errorf("unexpected sftp reply %d to read", typ)
+		}
+	}
+
+	if eof >= 0 {
+		end = min(end, eof)
+	}
+	if end > maxSize {
+		return nil, &PermanentError{Err: fmt.Errorf("%w: more than %d bytes", ErrFileTooLarge, maxSize)}
+	}
+	return data[:end], nil
+}
+
+// writeAll writes r to the open file with several writes in flight
+func (s *sftpConn) writeAll(handle string, r io.Reader) error {
+	buf := make([]byte, sftpChunkSize)
+	var offset int64
+	inFlight := 0
+	done := false
+
+	for {
+		for !done && inFlight < sftpWindow {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				if err := s.sendID(sftpWrite, s.id(), handle, uint64(offset), buf[:n]); err != nil {
+					return err
+				}
+				offset += int64(n)
+				inFlight++
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				done = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if inFlight == 0 {
+			return nil
+		}
+
+		typ, body, err := s.recv()
+		if err != nil {
+			return err
+		}
+		if typ != sftpStatus {
+			return fmt.Errorf("unexpected sftp reply %d to write", typ)
+		}
+		body.uint32() // Request id; every write must succeed, so which one doesn't matter
+		if err := statusError(body); err != nil {
+			return err
+		}
+		inFlight--
+	}
+}
+
+// call sends a request and reads its reply, which must be of type want (or an OK status)
+func (s *sftpConn) call(want byte, typ byte, fields ...any) (sftpPacket, error) {
+	id := s.id()
+	if err := s.sendID(typ, id, fields...); err != nil {
+		return nil, err
+	}
+	replyType, body, err := s.recv()
+	if err != nil {
+		return nil, err
+	}
+	if replyID, err := body.uint32(); err != nil || replyID != id {
+		return nil, fmt.Errorf("sftp reply out of order")
+	}
+	if replyType == sftpStatus {
+		if err := statusError(body); err != nil {
+			return nil, err
+		}
+		if want == sftpStatus {
+			return body, nil
+		}
+		return nil, fmt.Errorf("sftp server sent no reply data")
+	}
+	if replyType != want {
+		return nil, fmt.Errorf("unexpected sftp reply %d", replyType)
+	}
+	return body, nil
+}
+
+func (s *sftpConn) id() uint32 {
+	s.nextID++
+	return s.nextID
+}
+
+// sendID sends a request packet that starts with a request id
+func (s *sftpConn) sendID(typ byte, id uint32, fields ...any) error {
+	return s.send(typ, append([]any{id}, fields...)...)
+}
+
+// send writes one packet; fields are uint32, uint64, string or []byte (sent as a string)
+func (s *sftpConn) send(typ byte, fields ...any) error {
+	packet := []byte{0, 0, 0, 0, typ}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			packet = binary.BigEndian.AppendUint32(packet, v)
+		case uint64:
+			packet = binary.BigEndian.AppendUint64(packet, v)
+		case string:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		case []byte:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		default:
+			panic(fmt.Sprintf("sftp: unsupported field type %T", field))
+		}
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	_, err := s.w.Write(packet)
+	return err
+}
+
+// recv reads one packet
+func (s *sftpConn) recv() (byte, sftpPacket, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp connection lost: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp packet of %d bytes", length)
+	}
+	body := make([]byte, length-1)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return 0, nil, fmt.Errorf("sftp connection lost: %w", err)
+	}
+	return header[4], body, nil
+}
+
+// sftpPacket is the unread rest of a packet
+type sftpPacket []byte
+
+func (p *sftpPacket) uint32() (uint32, error) {
+	if len(*p) < 4 {
+		return 0, errShortPacket
+	}
+	v := binary.BigEndian.Uint32(*p)
+	*p = (*p)[4:]
+	return v, nil
+}
+
+func (p *sftpPacket) uint64() (uint64, error) {
+	if len(*p) < 8 {
+		return 0, errShortPacket
+	}
+	v := binary.BigEndian.Uint64(*p)
+	*p = (*p)[8:]
+	return v, nil
+}
+
+func (p *sftpPacket) string() (string, error) {
+	n, err := p.uint32()
+	if err != nil || uint32(len(*p)) < n {
+		return "", errShortPacket
+	}
+	v := string((*p)[:n])
+	*p = (*p)[n:]
+	return v, nil
+}
+
+var errShortPacket = errors.New("sftp packet too short")
+
+// statusError reads a STATUS body (after its id); nil for OK and EOF
+// Missing files, denied access and unsupported requests are permanent
+func statusError(body sftpPacket) error {
+	code, err := body.uint32()
+	if err != nil {
+		return err
+	}
+	if code == sftpStatusOK || code == sftpStatusEOF {
+		return nil
+	}
+	message, _ := body.string()
+	statusErr := &sftpStatusError{Code: code, Message: message}
+	switch code {
+	case sftpStatusNoSuchFile, sftpStatusPermission, sftpStatusBadMessage, sftpStatusUnsupported:
+		return &PermanentError{Err: statusErr}
+	}
+	return statusErr
+}
+
+// sftpPath maps a URL path to the server path; like curl, /~/ starts at the login's home directory
+func sftpPath(urlPath string) string {
+	if rest, ok := strings.CutPrefix(urlPath, "/~/"); ok {
+		return rest
+	}
+	return urlPath
+}
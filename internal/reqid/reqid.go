@@ -0,0 +1,98 @@
+// Package reqid carries the X-Request-ID of a request through logs, responses and outbound calls,
+// so one conversion can be followed across the caller's systems and ours
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Header is the request and response header carrying the request ID
+const Header = "X-Request-ID"
+
+const (
+	localsKey = "request_id"
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// Middleware takes the caller's X-Request-ID, or generates one when it is missing or unusable,
+// and echoes it on the response
+func Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id := c.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+		c.Locals(localsKey, id)
+		c.Set(Header, id)
+		return c.Next()
+	}
+}
+
+// FromFiber returns the request ID set by Middleware ("" outside it)
+func FromFiber(c fiber.Ctx) string {
+	id, _ := c.Locals(localsKey).(string)
+	return id
+}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether id can be used as is: up to 128 letters, digits and - _ . : /
+// Anything else is replaced so IDs can't inject into logs or headers
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':' || r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a context carrying the request ID; unusable IDs are ignored
+func WithID(ctx context.Context, id string) context.Context {
+	if !Valid(id) {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx ("" if none)
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Apply forwards the request ID carried by ctx on an outbound request
+func Apply(ctx context.Context, req *http.Request) {
+	if id := FromContext(ctx); id != "" {
+		req.Header.Set(Header, id)
+	}
+}
+
+// Printf logs like log.Printf, with the request ID carried by ctx appended as request_id=<id>
+func Printf(ctx context.Context, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if id := FromContext(ctx); id != "" {
+		msg += ", request_id=" + id
+	}
+	log.Output(2, msg)
+}
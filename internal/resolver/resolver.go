@@ -0,0 +1,82 @@
+// Package resolver turns platform page URLs (YouTube, Vimeo, ...) into direct media URLs
+// so the downloader can fetch them like any other source
+package resolver
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Resolver maps page URLs it recognizes to a direct media URL
+type Resolver interface {
+	// Match reports whether the resolver handles rawURL
+	Match(rawURL string) bool
+	// Resolve returns a direct media URL (file or HLS/DASH stream) for rawURL
+	Resolve(ctx context.Context, rawURL string) (string, error)
+	// Name identifies the resolver in logs
+	Name() string
+}
+
+// ResolveError reports a URL the resolver could not turn into media (private, removed, geo-blocked, ...)
+type ResolveError struct {
+	Resolver string
+	Reason   string
+}
+
+func (e *ResolveError) Error() string {
+	return e.Resolver + " could not resolve URL: " + e.Reason
+}
+
+// Chain tries resolvers in order; the first that matches resolves the URL
+type Chain []Resolver
+
+// Match reports whether any resolver in the chain handles rawURL
+func (c Chain) Match(rawURL string) bool {
+	return c.find(rawURL) != nil
+}
+
+// Resolve resolves rawURL with the first matching resolver, or returns it unchanged
+func (c Chain) Resolve(ctx context.Context, rawURL string) (string, error) {
+	if r := c.find(rawURL); r != nil {
+		return r.Resolve(ctx, rawURL)
+	}
+	return rawURL, nil
+}
+
+// Name identifies the chain in logs
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, r := range c {
+		names[i] = r.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) find(rawURL string) Resolver {
+	for _, r := range c {
+		if r.Match(rawURL) {
+			return r
+		}
+	}
+	return nil
+}
+
+// HostMatcher matches URLs whose host is one of hosts or a subdomain of one
+type HostMatcher []string
+
+// Match reports whether rawURL's host is covered
+func (m HostMatcher) Match(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range m {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	return false
+}
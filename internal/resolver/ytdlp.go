@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultHosts are the platforms resolved with yt-dlp when no host list is configured
+var DefaultHosts = []string{
+	"youtube.com", "youtu.be", "vimeo.com", "tiktok.com", "instagram.com",
+	"facebook.com", "fb.watch", "twitter.com", "x.com", "dailymotion.com", "twitch.tv",
+}
+
+// YtDlp resolves platform URLs with the yt-dlp binary
+type YtDlp struct {
+	path    string
+	format  string
+	timeout time.Duration
+	hosts   HostMatcher
+}
+
+// NewYtDlp creates a yt-dlp resolver for hosts; the binary must be installed
+func NewYtDlp(path, format string, hosts []string, timeout time.Duration) (*YtDlp, error) {
+	if path == "" {
+		path = "yt-dlp"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp not found: %w", err)
+	}
+	if format == "" {
+		format = "best"
+	}
+	if len(hosts) == 0 {
+		hosts = DefaultHosts
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &YtDlp{path: resolved, format: format, timeout: timeout, hosts: hosts}, nil
+}
+
+// Name identifies the resolver in logs
+func (y *YtDlp) Name() string {
+	return "yt-dlp"
+}
+
+// Match reports whether rawURL is on one of the configured platforms
+func (y *YtDlp) Match(rawURL string) bool {
+	return y.hosts.Match(rawURL)
+}
+
+// Resolve asks yt-dlp for the direct URL of the selected format
+// Formats that need merging (separate video and audio) can't be fetched as one file, so the
+// first URL is used; the default "best" always picks a single file
+func (y *YtDlp) Resolve(ctx context.Context, rawURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, y.timeout)
+	defer cancel()
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, y.path,
+		"--ignore-config",
+		"--no-playlist",
+		"--no-warnings",
+		"--format", y.format,
+		"--get-url",
+		"--", rawURL,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("yt-dlp timed out after %s: %w", y.timeout, ctx.Err())
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", &ResolveError{Resolver: y.Name(), Reason: errorLine(stderr.String())}
+		}
+		return "", fmt.Errorf("failed to run yt-dlp: %w", err)
+	}
+
+	mediaURL, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	if !strings.HasPrefix(mediaURL, "http://") && !strings.HasPrefix(mediaURL, "https://") {
+		return "", &ResolveError{Resolver: y.Name(), Reason: "no downloadable media URL returned"}
+	}
+	log.Printf("🔗 Resolved with yt-dlp in %dms: %s", time.Since(start).Milliseconds(), rawURL)
+	return mediaURL, nil
+}
+
+// errorLine picks yt-dlp's ERROR: line from stderr, or its last line
+func errorLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for _, line := range lines {
+		if reason, ok := strings.CutPrefix(strings.TrimSpace(line), "ERROR: "); ok {
+			return reason
+		}
+	}
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return "yt-dlp failed"
+}
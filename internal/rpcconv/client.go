@@ -0,0 +1,231 @@
+package rpcconv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"fingerprint-converter/internal/rpcconv/convpb"
+	"fingerprint-converter/internal/services"
+)
+
+// ClientConfig configures the connection to a converter node
+type ClientConfig struct {
+	Address    string   // host:port of the node (or of a load balancer in front of the pool)
+	MediaTypes []string // Media types converted remotely; the others stay local
+	TLS        bool     // Connect with TLS
+	CAFile     string   // CA bundle the node's certificate is checked against ("" = system roots)
+	Token      string   // Shared secret sent with every call ("" = none)
+}
+
+// Client converts media on a remote converter node
+// A nil Client converts nothing remotely
+type Client struct {
+	conn       *grpc.ClientConn
+	converter  convpb.ConverterClient
+	address    string
+	mediaTypes []string
+	token      string
+}
+
+// NewClient creates a client for the node at cfg.Address
+// The connection is made lazily, so a node that is still starting doesn't stop the API
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("address is required")
+	}
+	for _, mediaType := range cfg.MediaTypes {
+		if mediaType != "audio" && mediaType != "image" && mediaType != "video" {
+			return nil, fmt.Errorf("unknown media type %q", mediaType)
+		}
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(cfg.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:       conn,
+		converter:  convpb.NewConverterClient(conn),
+		address:    cfg.Address,
+		mediaTypes: cfg.MediaTypes,
+		token:      cfg.Token,
+	}, nil
+}
+
+// Handles reports whether mediaType is converted remotely
+func (c *Client) Handles(mediaType string) bool {
+	return c != nil && slices.Contains(c.mediaTypes, mediaType)
+}
+
+// Address returns the node address, for logs
+func (c *Client) Address() string {
+	if c == nil {
+		return ""
+	}
+	return c.address
+}
+
+// Check asks the node's health service whether it is serving; circuit breakers probe with it
+func (c *Client) Check(ctx context.Context) error {
+	resp, err := grpc_health_v1.NewHealthClient(c.conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return remoteError(err)
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("converter node is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Convert converts inputData on the node and writes the result to outputPath
+// The output format follows the extension of outputPath, as it does locally
+func (c *Client) Convert(ctx context.Context, mediaType string, inputData []byte, level, outputPath string, opts services.ConvertOptions) (*services.ConversionResult, error) {
+	if len(inputData) == 0 {
+		return nil, fmt.Errorf("empty input data")
+	}
+	start := time.Now()
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, tokenKey, "Bearer "+c.token)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.converter.Convert(ctx)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	// Input is sent while the output is awaited, so a node that rejects the call early isn't fed the whole file
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- sendInput(stream, &convpb.ConvertHeader{
+			MediaType:    mediaType,
+			Level:        level,
+			OutputFormat: strings.TrimPrefix(filepath.Ext(outputPath), "."),
+			Options:      toProto(opts),
+			InputSize:    int64(len(inputData)),
+		}, inputData)
+	}()
+
+	if err := receiveOutput(stream, outputPath); err != nil {
+		os.Remove(outputPath)
+		cancel()
+		<-sendErr
+		return nil, remoteError(err)
+	}
+	if err := <-sendErr; err != nil && !errors.Is(err, io.EOF) {
+		os.Remove(outputPath)
+		return nil, remoteError(err)
+	}
+
+	// The node doesn't report its settings, so only what is known here is filled in
+	format := strings.TrimPrefix(filepath.Ext(outputPath), ".")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	return &services.ConversionResult{Path: outputPath, Format: format, Duration: time.Since(start)}, nil
+}
+
+// sendInput streams the header and the input, then closes the sending side
+func sendInput(stream convpb.Converter_ConvertClient, header *convpb.ConvertHeader, inputData []byte) error {
+	err := stream.Send(&convpb.ConvertRequest{Payload: &convpb.ConvertRequest_Header{Header: header}})
+	for offset := 0; err == nil && offset < len(inputData); offset += chunkSize {
+		end := min(offset+chunkSize, len(inputData))
+		err = stream.Send(&convpb.ConvertRequest{Payload: &convpb.ConvertRequest_Chunk{Chunk: inputData[offset:end]}})
+	}
+	if err != nil {
+		return err // io.EOF: the node ended the call; its status comes from Recv
+	}
+	return stream.CloseSend()
+}
+
+// receiveOutput writes the streamed output to outputPath
+func receiveOutput(stream convpb.Converter_ConvertClient, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	received := 0
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(msg.GetChunk()); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		received += len(msg.GetChunk())
+	}
+	if received == 0 {
+		return fmt.Errorf("converter node returned no output")
+	}
+	return file.Close()
+}
+
+// remoteError maps a failed call to the errors local conversions return,
+// so safe-mode retries, circuit breakers and job retries treat both alike
+func remoteError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.FailedPrecondition:
+		return &services.FFmpegError{Reason: st.Message(), Stderr: st.Message(), Err: err}
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		log.Printf("⚠️  Converter node unavailable: %v", err)
+		return &services.TransientError{Err: fmt.Errorf("converter node unavailable: %s", st.Message())}
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Canceled:
+		return context.Canceled
+	}
+	return fmt.Errorf("converter node failed: %s", st.Message())
+}
@@ -0,0 +1,93 @@
+// Package rpcconv delegates conversions to remote converter nodes over gRPC
+// Heavy media types (usually video) can run on a GPU node pool while the rest stays on local ffmpeg;
+// the contract is convpb/converter.proto, and cmd/node serves it with the same converters as the API
+package rpcconv
+
+import (
+	"fingerprint-converter/internal/rpcconv/convpb"
+	"fingerprint-converter/internal/services"
+)
+
+// chunkSize is the payload of each streamed message, well below gRPC's 4MB message limit
+const chunkSize = 256 * 1024
+
+// tokenKey is the metadata key carrying the shared node token
+const tokenKey = "authorization"
+
+// toProto converts processing options to their wire form
+func toProto(opts services.ConvertOptions) *convpb.Options {
+	out := &convpb.Options{
+		MaxLongEdge:  int32(opts.MaxLongEdge),
+		MaxShortEdge: int32(opts.MaxShortEdge),
+		FrameRate:    opts.FrameRate,
+		DropAudio:    opts.DropAudio,
+		AudioFormat:  opts.AudioFormat,
+		ImageFormat:  opts.ImageFormat,
+		SafeMode:     opts.SafeMode,
+		Techniques:   opts.Techniques,
+		AudioMinKbps: int32(opts.AudioBitrateKbps.Min),
+		AudioMaxKbps: int32(opts.AudioBitrateKbps.Max),
+	}
+	if wm := opts.Watermark; wm != nil {
+		out.Watermark = &convpb.Watermark{
+			Text:      wm.Text,
+			Logo:      wm.Logo,
+			Position:  wm.Position,
+			Opacity:   wm.Opacity,
+			FontSize:  int32(wm.FontSize),
+			FontColor: wm.FontColor,
+			Scale:     wm.Scale,
+		}
+	}
+	return out
+}
+
+// fromProto converts wire options back, validating them like the API does
+func fromProto(in *convpb.Options) (services.ConvertOptions, error) {
+	if in == nil {
+		return services.ConvertOptions{}, nil
+	}
+	opts := services.ConvertOptions{
+		MaxLongEdge:  int(in.GetMaxLongEdge()),
+		MaxShortEdge: int(in.GetMaxShortEdge()),
+		DropAudio:    in.GetDropAudio(),
+		SafeMode:     in.GetSafeMode(),
+		Techniques:   in.GetTechniques(),
+	}
+
+	var err error
+	if in.GetAudioMaxKbps() > 0 {
+		opts.AudioBitrateKbps = services.IntRange{Min: int(in.GetAudioMinKbps()), Max: int(in.GetAudioMaxKbps())}
+		if err = services.ValidateAudioBitrateBounds(opts.AudioBitrateKbps); err != nil {
+			return opts, err
+		}
+	}
+	if opts.FrameRate, err = services.ParseFrameRate(in.GetFrameRate()); err != nil {
+		return opts, err
+	}
+	if opts.AudioFormat, err = services.ParseAudioFormat(in.GetAudioFormat()); err != nil {
+		return opts, err
+	}
+	if opts.ImageFormat, err = services.ParseImageFormat(in.GetImageFormat()); err != nil {
+		return opts, err
+	}
+
+	if wm := in.GetWatermark(); wm != nil {
+		opts.Watermark = &services.Watermark{
+			Text:      wm.GetText(),
+			Logo:      wm.GetLogo(),
+			Position:  wm.GetPosition(),
+			Opacity:   wm.GetOpacity(),
+			FontSize:  int(wm.GetFontSize()),
+			FontColor: wm.GetFontColor(),
+			Scale:     wm.GetScale(),
+		}
+		if len(wm.GetLogo()) > 0 {
+			opts.Watermark.LogoURL = "inline" // Normalize requires text or a logo source
+		}
+		if err := opts.Watermark.Normalize(); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
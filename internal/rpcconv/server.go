@@ -0,0 +1,185 @@
+package rpcconv
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"fingerprint-converter/internal/rpcconv/convpb"
+	"fingerprint-converter/internal/services"
+)
+
+// outputFormats are the output file extensions of the local converters
+var outputFormats = map[string]bool{
+	"mp4": true, "opus": true, "mp3": true, "jpg": true, "jpeg": true, "png": true, "webp": true,
+	"avif": true, "jxl": true,
+}
+
+// Server serves the Converter contract with the local converters
+type Server struct {
+	convpb.UnimplementedConverterServer
+
+	audio   *services.AudioConverter
+	image   *services.ImageConverter
+	video   *services.VideoConverter
+	maxSize int64  // Largest input accepted, in bytes
+	token   string // Shared secret callers must send ("" = none)
+}
+
+// NewServer creates a converter node service
+func NewServer(audio *services.AudioConverter, image *services.ImageConverter, video *services.VideoConverter, maxSize int64, token string) *Server {
+	return &Server{audio: audio, image: image, video: video, maxSize: maxSize, token: token}
+}
+
+// Convert receives one input, converts it and streams the output back
+func (s *Server) Convert(stream convpb.Converter_ConvertServer) error {
+	ctx := stream.Context()
+	if err := s.authorize(ctx); err != nil {
+		return err
+	}
+
+	header, inputData, err := s.receiveInput(stream)
+	if err != nil {
+		return err
+	}
+	opts, err := fromProto(header.GetOptions())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid options: %v", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "node-*")
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+	outputPath := filepath.Join(workDir, "output."+header.GetOutputFormat())
+
+	start := time.Now()
+	var result *services.ConversionResult
+	switch header.GetMediaType() {
+	case "audio":
+		result, err = s.audio.Convert(ctx, inputData, header.GetLevel(), outputPath, opts)
+	case "image":
+		result, err = s.image.Convert(ctx, inputData, header.GetLevel(), outputPath, opts)
+	case "video":
+		result, err = s.video.Convert(ctx, inputData, header.GetLevel(), outputPath, opts)
+	}
+	if err != nil {
+		log.Printf("❌ Remote %s conversion failed: level=%s, error=%v", header.GetMediaType(), header.GetLevel(), err)
+		return conversionStatus(ctx, err)
+	}
+	log.Printf("✅ Remote %s conversion: level=%s, size=%d, time=%dms",
+		header.GetMediaType(), header.GetLevel(), len(inputData), time.Since(start).Milliseconds())
+	return sendOutput(stream, result.Path)
+}
+
+// authorize checks the caller's token when one is configured
+func (s *Server) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(tokenKey) {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// receiveInput reads the header and the input chunks that follow it
+func (s *Server) receiveInput(stream convpb.Converter_ConvertServer) (*convpb.ConvertHeader, []byte, error) {
+	msg, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+	header := msg.GetHeader()
+	if header == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "first message must be the header")
+	}
+	switch header.GetMediaType() {
+	case "audio", "image", "video":
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "unsupported media_type %q", header.GetMediaType())
+	}
+	if !services.IsValidLevel(header.GetLevel()) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid level %q", header.GetLevel())
+	}
+	if !outputFormats[header.GetOutputFormat()] {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "unsupported output_format %q", header.GetOutputFormat())
+	}
+	if header.GetInputSize() > s.maxSize {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "input too large: %d bytes (max: %d)", header.GetInputSize(), s.maxSize)
+	}
+
+	var input bytes.Buffer
+	input.Grow(int(max(header.GetInputSize(), 0)))
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		input.Write(msg.GetChunk())
+		if int64(input.Len()) > s.maxSize {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "input too large (max: %d bytes)", s.maxSize)
+		}
+	}
+	if input.Len() == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "empty input")
+	}
+	return header, input.Bytes(), nil
+}
+
+// sendOutput streams the converted file back in chunks
+func sendOutput(stream convpb.Converter_ConvertServer, outputPath string) error {
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open output file: %v", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&convpb.ConvertResponse{Chunk: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read output file: %v", err)
+		}
+	}
+}
+
+// conversionStatus maps a failed conversion to the status codes of the contract
+func conversionStatus(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if services.IsTransient(err) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	var ffErr *services.FFmpegError
+	if errors.As(err, &ffErr) {
+		return status.Error(codes.FailedPrecondition, ffErr.Reason)
+	}
+	return status.Error(codes.Internal, fmt.Sprintf("conversion failed: %v", err))
+}
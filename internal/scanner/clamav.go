@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 * 1024
+
+// ClamAV scans data with a clamd daemon using the INSTREAM command
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV creates a clamd client for tcp://host:port, unix:///path or host:port
+func NewClamAV(address string, timeout time.Duration) (*ClamAV, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("clamd address is empty")
+	}
+
+	return &ClamAV{network: network, address: address, timeout: timeout}, nil
+}
+
+// Name identifies the scanner in logs
+func (c *ClamAV) Name() string {
+	return "clamav"
+}
+
+// Ping checks that clamd is reachable
+func (c *ClamAV) Ping(ctx context.Context) error {
+	reply, err := c.command(ctx, "zPING\x00", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+	return nil
+}
+
+// Scan streams data to clamd and interprets the verdict
+func (c *ClamAV) Scan(ctx context.Context, data []byte) error {
+	reply, err := c.command(ctx, "zINSTREAM\x00", data)
+	if err != nil {
+		return err
+	}
+
+	// Replies: "stream: OK", "stream: <signature> FOUND" or "<reason> ERROR"
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &InfectedError{Signature: signature}
+	case strings.HasSuffix(reply, "OK"):
+		return nil
+	default:
+		return fmt.Errorf("clamd scan failed: %s", reply)
+	}
+}
+
+// command sends a z-prefixed (NUL-terminated) command, optionally streaming data, and reads the reply
+func (c *ClamAV) command(ctx context.Context, cmd string, data []byte) (string, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := io.WriteString(conn, cmd); err != nil {
+		return "", fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	if data != nil {
+		// INSTREAM: length-prefixed chunks terminated by a zero-length chunk
+		var size [4]byte
+		for offset := 0; offset < len(data); offset += clamdChunkSize {
+			end := min(offset+clamdChunkSize, len(data))
+			binary.BigEndian.PutUint32(size[:], uint32(end-offset))
+			if _, err := conn.Write(size[:]); err != nil {
+				return "", fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+			if _, err := conn.Write(data[offset:end]); err != nil {
+				return "", fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+		}
+		binary.BigEndian.PutUint32(size[:], 0)
+		if _, err := conn.Write(size[:]); err != nil {
+			return "", fmt.Errorf("failed to stream to clamd: %w", err)
+		}
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Scanner checks media bytes for malware before they reach ffmpeg
+type Scanner interface {
+	// Scan returns an *InfectedError if data is flagged, or another error if the scan could not run
+	Scan(ctx context.Context, data []byte) error
+	// Name identifies the scanner in logs
+	Name() string
+}
+
+// InfectedError reports a file flagged by the scanner
+type InfectedError struct {
+	Signature string
+}
+
+func (e *InfectedError) Error() string {
+	return fmt.Sprintf("malware detected: %s", e.Signature)
+}
+
+// failOpen lets inputs through when the wrapped scanner can't run
+type failOpen struct {
+	Scanner
+}
+
+// FailOpen wraps s so scanner outages are logged and ignored; infected files are still rejected
+func FailOpen(s Scanner) Scanner {
+	return failOpen{s}
+}
+
+func (f failOpen) Scan(ctx context.Context, data []byte) error {
+	err := f.Scanner.Scan(ctx, data)
+	var infected *InfectedError
+	if err != nil && !errors.As(err, &infected) {
+		log.Printf("⚠️  %s scan skipped (fail-open): %v", f.Name(), err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidArchive is returned when an archive input can't be read as a zip file
+var ErrInvalidArchive = errors.New("input is not a valid zip archive")
+
+// ArchiveLimits caps what is extracted from one archive
+// Sizes are enforced on the inflated bytes; the sizes declared in the zip directory are not trusted
+type ArchiveLimits struct {
+	MaxEntries   int   // Most media entries extracted
+	MaxSize      int64 // Most inflated bytes across all entries
+	MaxEntrySize int64 // Most inflated bytes of a single entry
+}
+
+// ArchiveEntry is a media file extracted from an archive
+type ArchiveEntry struct {
+	Name      string // Cleaned path inside the archive
+	MediaType string // audio/image/video
+	Data      []byte
+}
+
+// SkippedEntry is an archive entry that was not extracted
+type SkippedEntry struct {
+	Name   string
+	Reason string
+}
+
+// IsZipArchive reports whether data starts with a zip local file header
+func IsZipArchive(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06"))
+}
+
+// ExtractArchive reads the media files out of a zip archive
+// Directories, hidden files and entries that aren't audio, image or video are skipped;
+// entries whose names escape the archive root are skipped rather than written anywhere
+func ExtractArchive(data []byte, limits ArchiveLimits) ([]ArchiveEntry, []SkippedEntry, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var entries []ArchiveEntry
+	var skipped []SkippedEntry
+	var total int64
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := archiveEntryName(file.Name)
+		if !ok {
+			skipped = append(skipped, SkippedEntry{Name: file.Name, Reason: "unsafe path"})
+			continue
+		}
+		if isHiddenEntry(name) {
+			continue
+		}
+
+		entryData, err := readArchiveEntry(file, limits.MaxEntrySize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mediaType := DetectMediaType(name)
+		if mediaType == "" {
+			mediaType = DetectMediaTypeFromContent("", entryData)
+		}
+		if mediaType == "" {
+			skipped = append(skipped, SkippedEntry{Name: name, Reason: "unsupported file type"})
+			continue
+		}
+
+		if limits.MaxEntries > 0 && len(entries) >= limits.MaxEntries {
+			return nil, nil, &LimitError{Limit: "archive media files", Actual: strconv.Itoa(limits.MaxEntries+1) + "+", Max: strconv.Itoa(limits.MaxEntries)}
+		}
+		total += int64(len(entryData))
+		if limits.MaxSize > 0 && total > limits.MaxSize {
+			return nil, nil, &LimitError{Limit: "archive size", Actual: formatBytes(total) + "+", Max: formatBytes(limits.MaxSize)}
+		}
+		entries = append(entries, ArchiveEntry{Name: name, MediaType: mediaType, Data: entryData})
+	}
+
+	if len(entries) == 0 {
+		return nil, skipped, fmt.Errorf("%w: no audio, image or video files found", ErrInvalidArchive)
+	}
+	return entries, skipped, nil
+}
+
+// readArchiveEntry inflates file, stopping once it grows past maxSize (zip bombs)
+func readArchiveEntry(file *zip.File, maxSize int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, file.Name, err)
+	}
+	defer rc.Close()
+
+	var reader io.Reader = rc
+	if maxSize > 0 {
+		reader = io.LimitReader(rc, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, file.Name, err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, &LimitError{Limit: "size of " + file.Name, Actual: formatBytes(maxSize) + "+", Max: formatBytes(maxSize)}
+	}
+	return data, nil
+}
+
+// archiveEntryName cleans a zip entry name; false when it is absolute or climbs out of the archive
+func archiveEntryName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || strings.Contains(name, ":") {
+		return "", false
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// isHiddenEntry reports dotfiles and macOS resource forks (__MACOSX/), which are never media
+func isHiddenEntry(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes renders n as a short human-readable size
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
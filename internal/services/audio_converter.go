@@ -4,9 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"math/rand"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,6 +19,7 @@ import (
 type AudioConverter struct {
 	workerPool *pool.WorkerPool
 	bufferPool *pool.BufferPool
+	rng        RNG
 	mu         sync.RWMutex
 	stats      AudioStats
 }
@@ -29,44 +29,83 @@ type AudioStats struct {
 	TotalConversions  int64
 	FailedConversions int64
 	AvgConversionTime time.Duration
+	FailureReasons    map[string]int64 // Failed conversions by category (FailureDecode, ...)
+}
+
+// DefaultAudioBitrateKbps bounds the source-scaled audio bitrate when the profile sets no bounds
+// Low-bitrate voice isn't inflated past what it carries, and music is capped where mono Opus stops gaining
+var DefaultAudioBitrateKbps = IntRange{32, 128}
+
+// ValidateAudioBitrateBounds checks an audio bitrate floor-ceiling against what the encoders accept
+func ValidateAudioBitrateBounds(bounds IntRange) error {
+	if bounds.Min < 8 || bounds.Max > 320 || bounds.Min > bounds.Max {
+		return fmt.Errorf("bounds %d-%d must satisfy 8 <= min <= max <= 320", bounds.Min, bounds.Max)
+	}
+	return nil
 }
 
 // NewAudioConverter creates a new audio converter
-func NewAudioConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool) *AudioConverter {
+func NewAudioConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool, rng RNG) *AudioConverter {
 	return &AudioConverter{
 		workerPool: workerPool,
 		bufferPool: bufferPool,
+		rng:        orDefaultRNG(rng),
 	}
 }
 
 // Convert processes audio with anti-fingerprinting
-func (ac *AudioConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string) error {
+// Video inputs are accepted too: only the first audio stream is kept
+func (ac *AudioConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string, opts ConvertOptions) (*ConversionResult, error) {
 	start := time.Now()
 
 	// Validate input
 	if len(inputData) == 0 {
-		return fmt.Errorf("empty input data")
+		return nil, fmt.Errorf("empty input data")
+	}
+
+	// Scale the output bitrate from the source's, so music isn't crushed and voice isn't inflated
+	sourceKbps := 0
+	if info, err := ProbeMedia(ctx, inputData); err == nil {
+		// Outputs are mono: keep the source's bitrate per channel
+		kbps, channels := info.AudioBitrate()
+		sourceKbps = kbps / max(channels, 1)
 	}
 
 	// Get randomized parameters based on level
-	params := ac.getRandomizedParams(level)
+	params := ac.getRandomizedParams(level, sourceKbps, opts.AudioBitrateKbps)
 
 	// Build FFmpeg command with anti-fingerprinting
-	cmd := exec.CommandContext(ctx, "ffmpeg",
+	cmd := FFmpeg(
 		"-hide_banner",
 		"-loglevel", "error",
 		"-i", "pipe:0", // Input from stdin
 		"-vn",           // No video
 		"-map", "0:a:0", // First audio stream
-		"-c:a", "libopus",
-		"-b:a", params.bitrate,
-		"-vbr", "on",
-		"-compression_level", strconv.Itoa(params.compression),
-		"-application", "voip",
-		"-ar", "48000",
-		"-ac", "1", // Mono
 	)
 
+	// Codec settings
+	outputFormat := "opus"
+	if opts.AudioFormat == "mp3" {
+		outputFormat = "mp3"
+		cmd.Args = append(cmd.Args,
+			"-c:a", "libmp3lame",
+			"-b:a", params.bitrate,
+			"-compression_level", strconv.Itoa(min(max(params.compression-7, 0), 9)), // Opus 7-10 maps to libmp3lame 0-3 (0 = best)
+			"-ar", "44100",
+			"-ac", "1", // Mono
+		)
+	} else {
+		cmd.Args = append(cmd.Args,
+			"-c:a", "libopus",
+			"-b:a", params.bitrate,
+			"-vbr", "on",
+			"-compression_level", strconv.Itoa(params.compression),
+			"-application", "voip",
+			"-ar", "48000",
+			"-ac", "1", // Mono
+		)
+	}
+
 	// Add anti-fingerprint filters
 	filters := []string{}
 	
@@ -80,6 +119,10 @@ func (ac *AudioConverter) Convert(ctx context.Context, inputData []byte, level s
 		filters = append(filters, fmt.Sprintf("asetrate=48000*%.6f,aresample=48000", params.pitchShift))
 	}
 
+	// Extra techniques from the profile
+	techniqueFilters, techniqueArgs := opts.applyTechniques("audio", level, ac.rng)
+	filters = append(filters, techniqueFilters...)
+
 	// Add subtle noise (paranoid only)
 	if params.addNoise {
 		filters = append(filters, fmt.Sprintf("anoisesrc=d=%d:c=pink:r=48000:a=0.001,amix=inputs=2:weights=1 %.6f", 
@@ -91,39 +134,52 @@ func (ac *AudioConverter) Convert(ctx context.Context, inputData []byte, level s
 	}
 
 	// Output settings
+	cmd.Args = append(cmd.Args, techniqueArgs...)
+	cmd.Args = append(cmd.Args, CurrentOutputMarker().args(ac.rng)...)
 	cmd.Args = append(cmd.Args,
-		"-f", "opus",
-		"-threads", "0",
+		"-f", outputFormat,
+		"-threads", threadsArg(),
 		"pipe:1", // Output to stdout
 	)
 
 	// Set up pipes
 	cmd.Stdin = bytes.NewReader(inputData)
 	var outputBuffer bytes.Buffer
-	var errorBuffer bytes.Buffer
-	cmd.Stdout = &outputBuffer
-	cmd.Stderr = &errorBuffer
+	cmd.Stdout = outputSink(ctx, &outputBuffer)
 
 	// Execute conversion
-	if err := cmd.Run(); err != nil {
-		ac.recordFailure()
-		return fmt.Errorf("ffmpeg error: %v, stderr: %s", err, errorBuffer.String())
+	encodeStart := time.Now()
+	stderr, err := RunCommand(ctx, cmd)
+	ffmpegTime := time.Since(encodeStart)
+	StagesFrom(ctx).Since("encode", encodeStart)
+	if err != nil {
+		err = ffmpegError(err, stderr)
+		ac.recordFailure(failureCategory(ctx, err))
+		return nil, err
 	}
 
 	output := outputBuffer.Bytes()
 	if len(output) == 0 {
-		ac.recordFailure()
-		return fmt.Errorf("ffmpeg produced no output")
+		ac.recordFailure(FailureDecode)
+		return nil, fmt.Errorf("ffmpeg produced no output")
 	}
 
 	// Write to file
+	writeStart := time.Now()
 	if err := os.WriteFile(outputPath, output, 0644); err != nil {
-		ac.recordFailure()
-		return fmt.Errorf("failed to write output file: %w", err)
+		ac.recordFailure(FailureWrite)
+		return nil, fmt.Errorf("failed to write output file: %w", err)
 	}
+	StagesFrom(ctx).Since("write", writeStart)
 
 	ac.recordSuccess(time.Since(start))
-	return nil
+	return &ConversionResult{
+		Path:       outputPath,
+		Format:     outputFormat,
+		Duration:   time.Since(start),
+		FFmpegTime: ffmpegTime,
+		Params:     params.describe(),
+	}, nil
 }
 
 type audioParams struct {
@@ -135,43 +191,66 @@ type audioParams struct {
 	noiseLevel     float64
 }
 
-func (ac *AudioConverter) getRandomizedParams(level string) audioParams {
+// describe lists the parameters for ConversionResult.Params
+func (p audioParams) describe() map[string]string {
+	params := map[string]string{
+		"bitrate":           p.bitrate,
+		"compression_level": strconv.Itoa(p.compression),
+	}
+	if p.silencePadding > 0 {
+		params["silence_padding_ms"] = strconv.Itoa(p.silencePadding)
+	}
+	if p.pitchShift != 0 {
+		params["pitch_shift"] = fmt.Sprintf("%.6f", p.pitchShift)
+	}
+	if p.addNoise {
+		params["noise_level"] = fmt.Sprintf("%.6f", p.noiseLevel)
+	}
+	return params
+}
+
+// getRandomizedParams draws the level's parameters; sourceKbps is the source bitrate per channel (0 = unknown)
+func (ac *AudioConverter) getRandomizedParams(level string, sourceKbps int, bounds IntRange) audioParams {
+	if bounds.Max <= 0 {
+		bounds = DefaultAudioBitrateKbps
+	}
 	params := audioParams{
-		bitrate:     "72k",
+		bitrate:     fmt.Sprintf("%dk", scaleAudioBitrate(72, sourceKbps, bounds)),
 		compression: 10,
 	}
 
-	switch level {
-	case "basic":
-		// Minimal randomization
-		params.bitrate = fmt.Sprintf("%dk", 70+rand.Intn(5)) // 70-74k
-		params.compression = 8 + rand.Intn(3)                // 8-10
-		params.silencePadding = 1 + rand.Intn(3)            // 1-3ms
-
-	case "moderate":
-		// Moderate randomization (default)
-		params.bitrate = fmt.Sprintf("%dk", 70+rand.Intn(5)) // 70-74k
-		params.compression = 8 + rand.Intn(3)                // 8-10
-		params.silencePadding = 1 + rand.Intn(3)            // 1-3ms
-		params.pitchShift = 1.0 + (float64(rand.Intn(20)-10) / 10000.0) // ±0.001
-
-	case "paranoid":
-		// Maximum randomization
-		params.bitrate = fmt.Sprintf("%dk", 68+rand.Intn(9)) // 68-76k
-		params.compression = 7 + rand.Intn(4)                // 7-10
-		params.silencePadding = 1 + rand.Intn(5)            // 1-5ms
-		params.pitchShift = 1.0 + (float64(rand.Intn(40)-20) / 10000.0) // ±0.002
-		params.addNoise = true
-		params.noiseLevel = 0.0005 + float64(rand.Intn(10))/100000.0 // 0.0005-0.0006
+	// "none" keeps the fixed defaults; ranges are tunable per level (AF_AUDIO_*)
+	ranges, ok := currentAFRanges().Audio.For(level)
+	if !ok {
+		return params
+	}
 
-	default: // "none"
-		params.bitrate = "72k"
-		params.compression = 10
+	kbps := scaleAudioBitrate(ranges.BitrateKbps.Pick(ac.rng), sourceKbps, bounds)
+	if variation := int(float64(kbps) * ranges.BitrateJitter.Pick(ac.rng)); variation > 0 {
+		kbps = min(max(kbps+variation-ac.rng.IntN(variation*2), bounds.Min), bounds.Max)
+	}
+	params.bitrate = fmt.Sprintf("%dk", kbps)
+	params.compression = ranges.Compression.Pick(ac.rng)
+	params.silencePadding = ranges.SilencePadding.Pick(ac.rng)
+	if ranges.PitchShift > 0 {
+		params.pitchShift = 1.0 + ranges.PitchShift.Pick(ac.rng)
+	}
+	if ranges.NoiseLevel.Enabled() {
+		params.addNoise = true
+		params.noiseLevel = ranges.NoiseLevel.Pick(ac.rng)
 	}
 
 	return params
 }
 
+// scaleAudioBitrate follows the source bitrate within bounds; fallback applies when the source's is unknown
+func scaleAudioBitrate(fallback, sourceKbps int, bounds IntRange) int {
+	if sourceKbps <= 0 {
+		return fallback
+	}
+	return min(max(sourceKbps, bounds.Min), bounds.Max)
+}
+
 func (ac *AudioConverter) recordSuccess(duration time.Duration) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
@@ -180,27 +259,34 @@ func (ac *AudioConverter) recordSuccess(duration time.Duration) {
 	ac.stats.AvgConversionTime = (ac.stats.AvgConversionTime*time.Duration(ac.stats.TotalConversions-1) + duration) / time.Duration(ac.stats.TotalConversions)
 }
 
-func (ac *AudioConverter) recordFailure() {
+func (ac *AudioConverter) recordFailure(category string) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
 	ac.stats.FailedConversions++
+	if ac.stats.FailureReasons == nil {
+		ac.stats.FailureReasons = make(map[string]int64)
+	}
+	ac.stats.FailureReasons[category]++
 }
 
 // GetStats returns current statistics
 func (ac *AudioConverter) GetStats() AudioStats {
 	ac.mu.RLock()
 	defer ac.mu.RUnlock()
-	return ac.stats
+	stats := ac.stats
+	stats.FailureReasons = maps.Clone(ac.stats.FailureReasons)
+	return stats
 }
 
-// GetOutputExtension returns the file extension for this converter
-func (ac *AudioConverter) GetOutputExtension() string {
+// GetOutputExtension returns the file extension for the given audio format
+func (ac *AudioConverter) GetOutputExtension(format string) string {
+	if format == "mp3" {
+		return ".mp3"
+	}
 	return ".opus"
 }
 
 // GenerateOutputPath creates a unique output path
-func (ac *AudioConverter) GenerateOutputPath(cacheDir, deviceID, urlHash string) string {
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%s_%s_%d%s", deviceID, urlHash[:8], timestamp, ac.GetOutputExtension())
-	return filepath.Join(cacheDir, filename)
+func (ac *AudioConverter) GenerateOutputPath(cacheDir, deviceID, urlHash, format string) string {
+	return filepath.Join(cacheDir, OutputName(deviceID, urlHash, ac.GetOutputExtension(format)))
 }
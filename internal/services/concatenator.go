@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fingerprint-converter/internal/pool"
+)
+
+// Concatenator joins several clips into one normalized intermediate
+// The result is then run through the regular converter so AF processing is identical
+type Concatenator struct {
+	workerPool *pool.WorkerPool
+	bufferPool *pool.BufferPool
+	mu         sync.RWMutex
+	stats      ConcatStats
+}
+
+// ConcatStats tracks concat metrics
+type ConcatStats struct {
+	TotalJoins  int64
+	FailedJoins int64
+	AvgJoinTime time.Duration
+}
+
+// NewConcatenator creates a new concatenator
+func NewConcatenator(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool) *Concatenator {
+	return &Concatenator{
+		workerPool: workerPool,
+		bufferPool: bufferPool,
+	}
+}
+
+// Concat joins clips in order and returns the intermediate media data
+// mediaType "video" yields Matroska (H.264 + AAC), "audio" yields FLAC
+func (cc *Concatenator) Concat(ctx context.Context, clips [][]byte, mediaType string) ([]byte, error) {
+	start := time.Now()
+
+	if len(clips) < 2 {
+		return nil, fmt.Errorf("at least 2 clips are required")
+	}
+	if mediaType != "video" && mediaType != "audio" {
+		return nil, fmt.Errorf("concat supports video or audio, got %q", mediaType)
+	}
+
+	workDir, err := os.MkdirTemp("", "concat-*")
+	if err != nil {
+		cc.recordFailure()
+		return nil, fmt.Errorf("failed to create work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// Stage clips on disk and probe them (durations are needed to fill missing audio)
+	paths := make([]string, len(clips))
+	infos := make([]*MediaInfo, len(clips))
+	for i, clip := range clips {
+		paths[i] = filepath.Join(workDir, fmt.Sprintf("clip_%03d", i))
+		if err := os.WriteFile(paths[i], clip, 0644); err != nil {
+			cc.recordFailure()
+			return nil, fmt.Errorf("failed to stage clip %d: %w", i, err)
+		}
+		infos[i], err = ProbeFile(ctx, paths[i])
+		if err != nil {
+			cc.recordFailure()
+			return nil, fmt.Errorf("failed to probe clip %d: %w", i, err)
+		}
+		if mediaType == "video" && !infos[i].HasVideo {
+			cc.recordFailure()
+			return nil, fmt.Errorf("clip %d has no video stream", i)
+		}
+		if mediaType == "audio" && !infos[i].HasAudio {
+			cc.recordFailure()
+			return nil, fmt.Errorf("clip %d has no audio stream", i)
+		}
+	}
+
+	cmd := FFmpeg(
+		"-hide_banner",
+		"-loglevel", "error",
+	)
+	for _, path := range paths {
+		cmd.Args = append(cmd.Args, "-i", path)
+	}
+
+	// First clip defines the canvas (as displayed, since ffmpeg applies rotation); the rest are letterboxed into it
+	width, height := infos[0].DisplaySize()
+	width, height = width&^1, height&^1
+
+	graph := []string{}
+	labels := ""
+	for i, info := range infos {
+		if mediaType == "video" {
+			graph = append(graph, fmt.Sprintf(
+				"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p[v%d]",
+				i, width, height, width, height, i))
+			labels += fmt.Sprintf("[v%d]", i)
+		}
+
+		if info.HasAudio {
+			graph = append(graph, fmt.Sprintf(
+				"[%d:a:0]aformat=sample_rates=48000:channel_layouts=stereo,aresample=async=1[a%d]", i, i))
+		} else {
+			// Silent filler keeps audio aligned with the video timeline
+			graph = append(graph, fmt.Sprintf(
+				"anullsrc=r=48000:cl=stereo,atrim=duration=%.3f[a%d]", info.Duration.Seconds(), i))
+		}
+		labels += fmt.Sprintf("[a%d]", i)
+	}
+
+	if mediaType == "video" {
+		graph = append(graph, fmt.Sprintf("%sconcat=n=%d:v=1:a=1[vout][aout]", labels, len(clips)))
+		cmd.Args = append(cmd.Args,
+			"-filter_complex", strings.Join(graph, ";"),
+			"-map", "[vout]",
+			"-map", "[aout]",
+			"-c:v", "libx264",
+			"-crf", "16", // Near-lossless: the final AF pass re-encodes anyway
+			"-preset", "veryfast",
+			"-c:a", "aac",
+			"-b:a", "192k",
+			"-f", "matroska",
+		)
+	} else {
+		graph = append(graph, fmt.Sprintf("%sconcat=n=%d:v=0:a=1[aout]", labels, len(clips)))
+		cmd.Args = append(cmd.Args,
+			"-filter_complex", strings.Join(graph, ";"),
+			"-map", "[aout]",
+			"-c:a", "flac",
+			"-f", "flac",
+		)
+	}
+
+	cmd.Args = append(cmd.Args,
+		"-threads", threadsArg(),
+		"pipe:1",
+	)
+
+	var outputBuffer bytes.Buffer
+	cmd.Stdout = &outputBuffer
+
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		cc.recordFailure()
+		return nil, ffmpegError(err, stderr)
+	}
+
+	if outputBuffer.Len() == 0 {
+		cc.recordFailure()
+		return nil, fmt.Errorf("ffmpeg produced no output")
+	}
+
+	cc.recordSuccess(time.Since(start))
+	return outputBuffer.Bytes(), nil
+}
+
+func (cc *Concatenator) recordSuccess(duration time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.stats.TotalJoins++
+	cc.stats.AvgJoinTime = (cc.stats.AvgJoinTime*time.Duration(cc.stats.TotalJoins-1) + duration) / time.Duration(cc.stats.TotalJoins)
+}
+
+func (cc *Concatenator) recordFailure() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.stats.FailedJoins++
+}
+
+// GetStats returns current statistics
+func (cc *Concatenator) GetStats() ConcatStats {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.stats
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// conversionTimeWeight is how much each new conversion moves the average (exponential moving average)
+const conversionTimeWeight = 0.2
+
+// conversionTimes keeps a moving average of conversion times per media type ("" = all types)
+var conversionTimes = struct {
+	sync.Mutex
+	avg map[string]time.Duration
+}{avg: make(map[string]time.Duration)}
+
+// RecordConversionTime adds a finished conversion, from download to cached output, to the averages
+// Cache hits and failures aren't recorded, so the averages describe real work
+func RecordConversionTime(mediaType string, d time.Duration) {
+	conversionTimes.Lock()
+	defer conversionTimes.Unlock()
+	for _, key := range []string{mediaType, ""} {
+		avg, ok := conversionTimes.avg[key]
+		if !ok {
+			avg = d
+		}
+		conversionTimes.avg[key] = avg + time.Duration(conversionTimeWeight*float64(d-avg))
+	}
+}
+
+// AvgConversionTime returns the moving average conversion time of mediaType ("" = all types)
+// False until a conversion of that type has finished
+func AvgConversionTime(mediaType string) (time.Duration, bool) {
+	conversionTimes.Lock()
+	defer conversionTimes.Unlock()
+	avg, ok := conversionTimes.avg[mediaType]
+	return avg, ok
+}
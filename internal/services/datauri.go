@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// IsDataURI reports whether rawURL is an inline data: URI
+func IsDataURI(rawURL string) bool {
+	return len(rawURL) >= 5 && strings.EqualFold(rawURL[:5], "data:")
+}
+
+// ParseDataURI decodes a data:[<mime type>][;base64],<data> URI (RFC 2397)
+// The declared MIME type is returned as given and may be empty
+func ParseDataURI(uri string) (string, []byte, error) {
+	if !IsDataURI(uri) {
+		return "", nil, errors.New("not a data URI")
+	}
+	header, payload, ok := strings.Cut(uri[5:], ",")
+	if !ok {
+		return "", nil, errors.New("data URI has no comma before its data")
+	}
+
+	params := strings.Split(header, ";")
+	mimeType := strings.TrimSpace(params[0])
+	isBase64 := len(params) > 1 && strings.EqualFold(strings.TrimSpace(params[len(params)-1]), "base64")
+
+	// Either form may be percent-encoded when the URI was copied out of a URL or HTML attribute
+	payload, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("data URI is not properly escaped: %w", err)
+	}
+	if !isBase64 {
+		return mimeType, []byte(payload), nil
+	}
+
+	// Line breaks are common in URIs wrapped by editors and mail clients
+	payload = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, payload)
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("data URI has invalid base64: %w", err)
+	}
+	return mimeType, data, nil
+}
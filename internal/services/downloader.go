@@ -2,28 +2,75 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"fingerprint-converter/internal/breaker"
 	"fingerprint-converter/internal/pool"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/reqid"
+	"fingerprint-converter/internal/resolver"
 )
 
-// Downloader handles file downloads from URLs (S3, HTTP, HTTPS)
+// ErrFileTooLarge is returned when a download exceeds the configured max size
+var ErrFileTooLarge = errors.New("file too large")
+
+// previewSize is how much of an HTTP(S) source is fetched to check it before the full download
+// Enough for every signature SniffFormat knows and for IsStreamManifest
+const previewSize = 4096
+
+// DownloadCheck is what an HTTP(S) source is checked against before its body is transferred
+type DownloadCheck struct {
+	MediaType string // The leading bytes must be able to hold this media type ("" = not checked)
+	MaxSize   int64  // Cap below the downloader's, e.g. a tenant's (0 = the downloader's)
+}
+
+// Downloader handles file downloads from URLs (S3, HTTP, HTTPS, FTP, SFTP, local files)
 type Downloader struct {
-	client     *http.Client
-	bufferPool *pool.BufferPool
-	maxSize    int64
+	client            *http.Client
+	remote            *remote.Client // FTP/SFTP sources (nil = refused)
+	localRoots        []string       // Directories file:// URLs may read from (nil = refused)
+	bufferPool        *pool.BufferPool
+	maxSize           int64
+	streamMaxDuration time.Duration     // Longest part of an HLS/DASH stream that is pulled
+	resolver          resolver.Resolver // Platform URL resolution (nil = URLs are fetched as given)
+	hosts             *hostBreakers     // nil = no per-host circuit breaking
+	precheck          bool              // Preview checked HTTP(S) sources with a ranged GET first
+	downloads         atomic.Int64      // Downloads that returned data
+	failures          atomic.Int64      // Downloads that failed, including rejected sources
+	bytes             atomic.Int64      // Bytes returned by downloads
+}
+
+// DownloaderStats counts downloads since startup
+type DownloaderStats struct {
+	Downloads int64
+	Failed    int64
+	Bytes     int64
 }
 
 // NewDownloader creates a new downloader with optimized HTTP client
-func NewDownloader(bufferPool *pool.BufferPool, maxSize int64, timeout time.Duration) *Downloader {
+// HLS/DASH URLs are pulled by ffmpeg up to streamMaxDuration
+// urlResolver turns platform page URLs into media URLs first (nil = disabled)
+// remoteClient fetches ftp:// and sftp:// URLs (nil = only HTTP(S) is accepted)
+// file:// URLs are read from under localRoots (nil = refused)
+// hostBreaker enables a circuit breaker per source host (nil = disabled)
+// precheck fetches the first bytes of checked HTTP(S) sources before downloading them (see DownloadChecked)
+func NewDownloader(bufferPool *pool.BufferPool, maxSize int64, timeout, streamMaxDuration time.Duration, urlResolver resolver.Resolver, remoteClient *remote.Client, localRoots []string, hostBreaker *breaker.Settings, precheck bool) *Downloader {
 	if timeout <= 0 {
 		timeout = 30 * time.Second
 	}
 
+	if streamMaxDuration <= 0 {
+		streamMaxDuration = 10 * time.Minute
+	}
+
 	if maxSize <= 0 {
 		maxSize = 500 * 1024 * 1024 // 500MB default
 	}
@@ -40,22 +87,72 @@ func NewDownloader(bufferPool *pool.BufferPool, maxSize int64, timeout time.Dura
 		},
 	}
 
-	return &Downloader{
-		client:     client,
-		bufferPool: bufferPool,
-		maxSize:    maxSize,
+	downloader := &Downloader{
+		client:            client,
+		remote:            remoteClient,
+		localRoots:        resolveRoots(localRoots),
+		bufferPool:        bufferPool,
+		maxSize:           maxSize,
+		streamMaxDuration: streamMaxDuration,
+		resolver:          urlResolver,
+		precheck:          precheck,
+	}
+	if hostBreaker != nil {
+		downloader.hosts = newHostBreakers(*hostBreaker)
 	}
+	return downloader
 }
 
-// Download fetches a file from URL (S3, HTTP, HTTPS)
+// Download fetches a file from URL (S3, HTTP, HTTPS, FTP, SFTP, local files)
+// HLS playlists and DASH manifests are detected by content and pulled into a single file
 func (d *Downloader) Download(ctx context.Context, url string) ([]byte, error) {
+	return d.DownloadChecked(ctx, url, DownloadCheck{})
+}
+
+// DownloadChecked is Download, with HTTP(S) sources rejected as soon as they fail check
+// With precheck on, a ranged GET for the first bytes comes first: files whose Content-Range or
+// Content-Length is over the limit, or whose signature can't be check.MediaType, are rejected
+// without transferring them. Servers that ignore ranges only cost the preview's few KB.
+func (d *Downloader) DownloadChecked(ctx context.Context, url string, check DownloadCheck) ([]byte, error) {
+	data, err := d.downloadChecked(ctx, url, check)
+	if err != nil {
+		d.failures.Add(1)
+	} else {
+		d.downloads.Add(1)
+		d.bytes.Add(int64(len(data)))
+	}
+	return data, err
+}
+
+// downloadChecked is DownloadChecked without the counters
+func (d *Downloader) downloadChecked(ctx context.Context, url string, check DownloadCheck) ([]byte, error) {
 	// Validate URL
 	if url == "" {
 		return nil, fmt.Errorf("empty URL")
 	}
 
+	if remote.Supported(url) && d.remote != nil {
+		return d.fetchRemote(ctx, url)
+	}
+	if strings.HasPrefix(url, "file://") && len(d.localRoots) > 0 {
+		return d.fetchLocal(url)
+	}
+
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		return nil, fmt.Errorf("invalid URL scheme: must be http:// or https://")
+		return nil, fmt.Errorf("invalid URL scheme: must be %s", d.schemes())
+	}
+
+	// Platform pages (YouTube, ...) are swapped for the media URL behind them
+	if d.Resolves(url) {
+		mediaURL, err := d.resolver.Resolve(ctx, url)
+		if err != nil {
+			var resolveErr *resolver.ResolveError
+			if errors.As(err, &resolveErr) {
+				return nil, err
+			}
+			return nil, transient(err)
+		}
+		url = mediaURL
 	}
 
 	// Create request with context
@@ -63,22 +160,181 @@ func (d *Downloader) Download(ctx context.Context, url string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	reqid.Apply(ctx, req)
+
+	// Hosts that keep failing are skipped until their cooldown ends, so a dead CDN
+	// doesn't tie up every download slot waiting for timeouts
+	host := req.URL.Host
+	circuit := d.hosts.get(host)
+	if err := circuit.Allow(); err != nil {
+		return nil, transient(&HostUnavailableError{Host: host, Err: err})
+	}
+
+	limit := d.maxSize
+	if check.MaxSize > 0 && check.MaxSize < limit {
+		limit = check.MaxSize
+	}
+
+	var data []byte
+	if d.precheck && check != (DownloadCheck{}) {
+		data, err = d.preview(req, limit, check.MediaType)
+	}
+	if err == nil && data == nil {
+		data, err = d.fetch(req, limit)
+	}
+	switch {
+	case ctx.Err() != nil:
+		circuit.Skip()
+	case err != nil && IsTransient(err):
+		circuit.Record(err) // Unreachable, 5xx, throttled or cut off mid-transfer
+	default:
+		circuit.Record(nil) // The host answered, even if with a 404 or an oversized file
+	}
+
+	if err == nil && IsStreamManifest(data) {
+		return d.pullStream(ctx, url)
+	}
+	return data, err
+}
+
+// fetchRemote downloads an ftp:// or sftp:// URL, behind the same per-host circuit as HTTP sources
+func (d *Downloader) fetchRemote(ctx context.Context, rawURL string) ([]byte, error) {
+	host := rawURL
+	if u, err := neturl.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+	circuit := d.hosts.get(host)
+	if err := circuit.Allow(); err != nil {
+		return nil, transient(&HostUnavailableError{Host: host, Err: err})
+	}
 
+	data, err := d.remote.Fetch(ctx, rawURL, d.maxSize)
+	switch {
+	case err == nil:
+	case errors.Is(err, remote.ErrFileTooLarge):
+		err = fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, d.maxSize)
+	case !remote.IsPermanent(err) && ctx.Err() == nil:
+		err = transient(fmt.Errorf("download failed: %w", err))
+	default:
+		err = fmt.Errorf("download failed: %w", err)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		circuit.Skip()
+	case err != nil && IsTransient(err):
+		circuit.Record(err)
+	default:
+		circuit.Record(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("downloaded file is empty")
+	}
+	return data, nil
+}
+
+// schemes lists the URL schemes this downloader accepts, for error messages
+func (d *Downloader) schemes() string {
+	schemes := []string{"http://", "https://"}
+	if d.remote != nil {
+		schemes = append(schemes, "ftp://", "sftp://")
+	}
+	if len(d.localRoots) > 0 {
+		schemes = append(schemes, "file://")
+	}
+	return strings.Join(schemes[:len(schemes)-1], ", ") + " or " + schemes[len(schemes)-1]
+}
+
+// preview fetches the first previewSize bytes of req's URL and rejects the source when its size
+// is over limit or its signature can't hold mediaType
+// Returns the whole file when it fit in the preview, and nil when the full download should follow
+func (d *Downloader) preview(req *http.Request, limit int64, mediaType string) ([]byte, error) {
+	rangeReq := req.Clone(req.Context())
+	rangeReq.Header.Set("Range", fmt.Sprintf("bytes=0-%d", previewSize-1))
+
+	resp, err := d.client.Do(rangeReq)
+	if err != nil {
+		return nil, transient(fmt.Errorf("download failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	// The total size is in Content-Range, or in Content-Length when the server ignored the range
+	size := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		size = rangeTotal(resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		size = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil // Empty file; the full download reports it
+	default:
+		return nil, statusError(resp.StatusCode)
+	}
+	if size > limit {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrFileTooLarge, size, limit)
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, previewSize+1))
+	if err != nil {
+		return nil, transient(fmt.Errorf("read failed: %w", err))
+	}
+	complete := int64(len(head)) == size || (resp.StatusCode == http.StatusOK && len(head) <= previewSize)
+	head = head[:min(len(head), previewSize)]
+
+	// Manifests are text (DASH is XML); the stream they point to is checked after it is pulled
+	if mediaType != "" && !IsStreamManifest(head) {
+		if err := CheckSignature(mediaType, head); err != nil {
+			return nil, err
+		}
+	}
+	if complete && len(head) > 0 {
+		return head, nil
+	}
+	return nil, nil
+}
+
+// rangeTotal returns the complete length from a Content-Range header ("bytes 0-4095/1234567"), -1 when unknown
+func rangeTotal(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// statusError describes a failed download response (server errors and throttling are worth retrying)
+func statusError(status int) error {
+	err := fmt.Errorf("download failed: HTTP %d", status)
+	if status >= 500 || status == http.StatusTooManyRequests {
+		return transient(err)
+	}
+	return err
+}
+
+// fetch executes a prepared download request, accepting up to limit bytes
+func (d *Downloader) fetch(req *http.Request, limit int64) ([]byte, error) {
 	// Execute request
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("download failed: %w", err)
+		return nil, transient(fmt.Errorf("download failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode)
 	}
 
 	// Check content length
-	if resp.ContentLength > d.maxSize {
-		return nil, fmt.Errorf("file too large: %d bytes (max: %d)", resp.ContentLength, d.maxSize)
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrFileTooLarge, resp.ContentLength, limit)
 	}
 
 	// Use buffer pool for efficient memory management
@@ -91,25 +347,30 @@ func (d *Downloader) Download(ctx context.Context, url string) ([]byte, error) {
 
 			n, err := io.ReadFull(resp.Body, buf)
 			if err != nil && err != io.ErrUnexpectedEOF {
-				return nil, fmt.Errorf("read failed: %w", err)
+				return nil, transient(fmt.Errorf("read failed: %w", err))
 			}
 			data = make([]byte, n)
 			copy(data, buf[:n])
 		} else {
 			// Too large for pool, read directly
-			data, err = io.ReadAll(io.LimitReader(resp.Body, d.maxSize))
+			data, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
 			if err != nil {
-				return nil, fmt.Errorf("read failed: %w", err)
+				return nil, transient(fmt.Errorf("read failed: %w", err))
 			}
 		}
 	} else {
 		// Unknown size - use limited reader
-		data, err = io.ReadAll(io.LimitReader(resp.Body, d.maxSize))
+		data, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
 		if err != nil {
-			return nil, fmt.Errorf("read failed: %w", err)
+			return nil, transient(fmt.Errorf("read failed: %w", err))
 		}
 	}
 
+	// One byte past the limit means the body was cut off, not that it fit exactly
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, limit)
+	}
+
 	if len(data) == 0 {
 		return nil, fmt.Errorf("downloaded file is empty")
 	}
@@ -117,6 +378,30 @@ func (d *Downloader) Download(ctx context.Context, url string) ([]byte, error) {
 	return data, nil
 }
 
+// Resolves reports whether url is a platform URL that is resolved before downloading
+func (d *Downloader) Resolves(url string) bool {
+	return d.resolver != nil && d.resolver.Match(url)
+}
+
+// OpenHosts lists source hosts whose circuit currently rejects downloads
+func (d *Downloader) OpenHosts() []string {
+	return d.hosts.open()
+}
+
+// GetStats returns the download counters
+func (d *Downloader) GetStats() DownloaderStats {
+	return DownloaderStats{
+		Downloads: d.downloads.Load(),
+		Failed:    d.failures.Load(),
+		Bytes:     d.bytes.Load(),
+	}
+}
+
+// MaxSize returns the largest file the downloader accepts
+func (d *Downloader) MaxSize() int64 {
+	return d.maxSize
+}
+
 // DownloadToFile downloads directly to a file (for large files)
 func (d *Downloader) DownloadToFile(ctx context.Context, url, destPath string) error {
 	// TODO: Implement streaming download to file for very large files
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// TransientError marks a failure that may succeed when retried
+// (network errors, upstream 5xx, ffmpeg killed by the OOM killer)
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// transient wraps err as a TransientError
+func transient(err error) error {
+	return &TransientError{Err: err}
+}
+
+// IsTransient reports whether err (or anything it wraps) is worth retrying
+func IsTransient(err error) bool {
+	var transientErr *TransientError
+	if errors.As(err, &transientErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// FFmpegError is a failed ffmpeg run
+// Error() only carries a friendly reason; the raw stderr (local paths, library versions)
+// stays in Stderr for server logs and debug responses
+type FFmpegError struct {
+	Reason string // Client-safe description of the failure
+	Stderr string // Raw ffmpeg stderr
+	Err    error  // Process error
+}
+
+func (e *FFmpegError) Error() string {
+	return "ffmpeg failed: " + e.Reason
+}
+
+func (e *FFmpegError) Unwrap() error {
+	return e.Err
+}
+
+// ffmpegFailures maps stderr fragments to client-safe reasons, most specific first
+var ffmpegFailures = []struct {
+	pattern string
+	reason  string
+}{
+	{"moov atom not found", "input file is truncated or incomplete"},
+	{"Invalid data found when processing input", "input is not a valid media file"},
+	{"could not find codec parameters", "input is not a valid media file"},
+	{"Decoder not found", "input codec is not supported"},
+	{"Unknown decoder", "input codec is not supported"},
+	{"Encoder not found", "output codec is not available on this server"},
+	{"Unknown encoder", "output codec is not available on this server"},
+	{"does not contain any stream", "input has no usable audio or video stream"},
+	{"matches no streams", "input has no usable audio or video stream"},
+	{"Stream map", "input has no usable audio or video stream"},
+	{"Error while decoding stream", "input file is corrupted"},
+	{"corrupt", "input file is corrupted"},
+	{"Cannot allocate memory", "server ran out of memory"},
+	{"No space left on device", "server ran out of disk space"},
+	{"Error initializing filter", "processing options are not supported for this input"},
+	{"Error reinitializing filters", "processing options are not supported for this input"},
+	{"Invalid argument", "processing options are not supported for this input"},
+}
+
+// fallbackReasons are failures caused by filters or encoder settings rather than the input itself
+var fallbackReasons = map[string]bool{
+	"output codec is not available on this server":        true,
+	"processing options are not supported for this input": true,
+	"media could not be processed":                        true,
+}
+
+// CanFallback reports whether a failed encode is worth retrying in safe mode
+// Broken inputs, missing ffmpeg, timeouts and resource exhaustion would fail again
+func CanFallback(err error) bool {
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) || IsTransient(err) || errors.Is(err, exec.ErrNotFound) {
+		return false
+	}
+	return fallbackReasons[ffErr.Reason]
+}
+
+// inputReasons are failures caused by the input itself; they say nothing about ffmpeg's health
+var inputReasons = map[string]bool{
+	"input file is truncated or incomplete":     true,
+	"input is not a valid media file":           true,
+	"input codec is not supported":              true,
+	"input has no usable audio or video stream": true,
+	"input file is corrupted":                   true,
+}
+
+// IsInputFault reports whether a failed encode was caused by the input rather than the server
+func IsInputFault(err error) bool {
+	var ffErr *FFmpegError
+	return errors.As(err, &ffErr) && inputReasons[ffErr.Reason]
+}
+
+// IsOutOfSpace reports whether a failed encode or write ran out of disk space
+func IsOutOfSpace(err error) bool {
+	var ffErr *FFmpegError
+	if errors.As(err, &ffErr) && ffErr.Reason == "server ran out of disk space" {
+		return true
+	}
+	return errors.Is(err, syscall.ENOSPC)
+}
+
+// DescribeFFmpegFailure turns ffmpeg/ffprobe stderr into a client-safe reason
+func DescribeFFmpegFailure(stderr string) string {
+	for _, failure := range ffmpegFailures {
+		if strings.Contains(stderr, failure.pattern) {
+			return failure.reason
+		}
+	}
+	return "media could not be processed"
+}
+
+// ffmpegError builds the error for a failed ffmpeg run and logs the raw stderr
+// Processes killed by a signal (OOM killer, timeouts) are reported as transient
+func ffmpegError(err error, stderr string) error {
+	log.Printf("❌ FFmpeg failed: %v, stderr: %s", err, strings.TrimSpace(stderr))
+
+	ffErr := &FFmpegError{Reason: DescribeFFmpegFailure(stderr), Stderr: stderr, Err: err}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		ffErr.Reason = "ffmpeg is not available on this server"
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			ffErr.Reason = "processing was interrupted"
+			return transient(ffErr)
+		}
+	}
+	return ffErr
+}
+
+// Failure categories reported in converter stats
+const (
+	FailureDecode   = "decode_error" // ffmpeg rejected or could not process the input
+	FailureTimeout  = "timeout"      // Request deadline hit while converting
+	FailureCanceled = "canceled"     // Client went away or the server is shutting down
+	FailureWrite    = "write_error"  // Output could not be written to disk
+	FailureOther    = "other"        // Everything else (ffmpeg missing, staging errors)
+)
+
+// FailureCategories lists every category so metrics can report zeros
+var FailureCategories = []string{FailureDecode, FailureTimeout, FailureCanceled, FailureWrite, FailureOther}
+
+// failureCategory buckets a failed ffmpeg run; the context tells timeouts from bad input
+func failureCategory(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		return FailureCanceled
+	case errors.Is(err, exec.ErrNotFound):
+		return FailureOther
+	}
+
+	var ffErr *FFmpegError
+	if errors.As(err, &ffErr) && strings.Contains(ffErr.Stderr, "No space left on device") {
+		return FailureWrite
+	}
+	return FailureDecode
+}
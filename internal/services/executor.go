@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Command is one ffmpeg or ffprobe run
+// Start it with FFmpeg or FFprobe and append to Args, as with exec.Cmd
+type Command struct {
+	Program string        // ffmpeg or ffprobe
+	Args    []string      // Arguments after the program name
+	Stdin   io.Reader     // nil = no input
+	Stdout  io.Writer     // nil = discarded
+	Timeout time.Duration // Limit on top of the context's deadline (0 = none)
+}
+
+// FFmpeg starts an ffmpeg command
+func FFmpeg(args ...string) *Command {
+	return &Command{Program: "ffmpeg", Args: args}
+}
+
+// FFprobe starts an ffprobe command
+func FFprobe(args ...string) *Command {
+	return &Command{Program: "ffprobe", Args: args}
+}
+
+// String renders the command line for logs
+// Passwords and query strings in URL arguments (stream inputs) are redacted
+func (c *Command) String() string {
+	parts := make([]string, 0, len(c.Args)+1)
+	parts = append(parts, c.Program)
+	for _, arg := range c.Args {
+		arg = redactArg(arg)
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// redactArg masks credentials in URL arguments
+func redactArg(arg string) string {
+	if !strings.Contains(arg, "://") {
+		return arg
+	}
+	u, err := url.Parse(arg)
+	if err != nil || u.Scheme == "" {
+		return arg
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.Redacted()
+}
+
+// Executor runs ffmpeg and ffprobe commands
+// Every converter goes through the installed executor (see SetExecutor), so tests can swap in
+// a fake and operators can log, dry-run or sabotage runs without touching the converters
+type Executor interface {
+	// Run runs cmd and returns what it wrote to stderr, whether or not it failed
+	Run(ctx context.Context, cmd *Command) (string, error)
+}
+
+// pipeWaitDelay is how long Run waits for a killed process's output pipes to close
+const pipeWaitDelay = 5 * time.Second
+
+// LocalExecutor runs commands as local processes, each in its own process group where supported
+type LocalExecutor struct {
+	LogCommands bool // Log every command line (redacted) with its duration
+}
+
+func (e LocalExecutor) Run(ctx context.Context, cmd *Command) (string, error) {
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	process := exec.CommandContext(ctx, cmd.Program, cmd.Args...)
+	process.Stdin = cmd.Stdin
+	process.Stdout = cmd.Stdout
+	var stderr bytes.Buffer
+	process.Stderr = &stderr
+	startProcessGroup(process)
+	// Once killed, don't wait forever for pipes a stray child still holds open
+	process.WaitDelay = pipeWaitDelay
+
+	start := time.Now()
+	err := process.Start()
+	if err == nil {
+		trackProcess(ctx, cmd.Program, process)
+		err = process.Wait()
+		untrackProcess(process)
+	}
+	if e.LogCommands {
+		if err != nil {
+			log.Printf("🎬 %s: %dms, error=%v", cmd, time.Since(start).Milliseconds(), err)
+		} else {
+			log.Printf("🎬 %s: %dms", cmd, time.Since(start).Milliseconds())
+		}
+	}
+	return stderr.String(), err
+}
+
+// DryRunExecutor logs ffmpeg commands instead of running them; ffprobe still runs on next
+// Commands that pipe their output return the input unchanged, so requests complete end to end
+type DryRunExecutor struct {
+	Next Executor
+}
+
+func (e DryRunExecutor) Run(ctx context.Context, cmd *Command) (string, error) {
+	if cmd.Program != "ffmpeg" {
+		return e.Next.Run(ctx, cmd)
+	}
+	log.Printf("🧪 Dry run: %s", cmd)
+	if cmd.Stdin != nil && cmd.Stdout != nil {
+		if _, err := io.Copy(cmd.Stdout, cmd.Stdin); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// errChaos is the failure ChaosExecutor injects; it looks like ffmpeg exiting with an error
+var errChaos = errors.New("exit status 1 (injected by FFMPEG_CHAOS_RATE)")
+
+// ChaosExecutor fails a share of ffmpeg runs, to exercise safe mode, circuit breakers and retries
+// Failures are drawn from math/rand, not the AF generator, so AF_SEED runs stay reproducible
+type ChaosExecutor struct {
+	Next        Executor
+	FailureRate float64 // 0-1
+}
+
+func (e ChaosExecutor) Run(ctx context.Context, cmd *Command) (string, error) {
+	if cmd.Program != "ffmpeg" || rand.Float64() >= e.FailureRate {
+		return e.Next.Run(ctx, cmd)
+	}
+	log.Printf("🐒 Chaos: failing %s", cmd)
+	return "Injected failure", errChaos
+}
+
+// executorHolder wraps the installed executor, since atomic.Value needs one concrete type
+type executorHolder struct {
+	Executor
+}
+
+// executor holds the installed Executor; empty means a LocalExecutor without logging
+var executor atomic.Value
+
+// SetExecutor installs the executor every ffmpeg and ffprobe run goes through
+func SetExecutor(e Executor) {
+	executor.Store(executorHolder{e})
+}
+
+// RunCommand runs cmd with the installed executor
+func RunCommand(ctx context.Context, cmd *Command) (string, error) {
+	if holder, ok := executor.Load().(executorHolder); ok && holder.Executor != nil {
+		return holder.Run(ctx, cmd)
+	}
+	return LocalExecutor{}.Run(ctx, cmd)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
+// Experiment splits devices between AF profile variants, defined in the config file's experiments section
+// Only requests that end up with no explicit level, profile or device setting take part
+type Experiment struct {
+	MediaTypes []string  `yaml:"media_types" json:"media_types,omitempty"` // Empty = every media type
+	Variants   []Variant `yaml:"variants" json:"variants"`
+}
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name    string `yaml:"name" json:"name"`
+	Profile string `yaml:"profile" json:"profile"`         // Named AF profile applied to the variant's devices
+	Weight  int    `yaml:"weight" json:"weight,omitempty"` // Relative share of devices (0 = 1)
+}
+
+// Assignment is the variant a device falls into
+type Assignment struct {
+	Experiment string
+	Variant    string
+	Level      string   // Variant profile's level for the media type
+	Techniques []string // Variant profile's techniques for the media type
+
+	AudioBitrateKbps IntRange // Variant profile's audio bitrate floor and ceiling (zero = default)
+}
+
+// Validate checks the variants against the defined profiles
+func (e Experiment) Validate(profiles map[string]AFProfile) error {
+	if len(e.Variants) < 2 {
+		return fmt.Errorf("needs at least two variants")
+	}
+	for _, mediaType := range e.MediaTypes {
+		if mediaType != "audio" && mediaType != "image" && mediaType != "video" {
+			return fmt.Errorf("unknown media type %q (audio, image, video)", mediaType)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, variant := range e.Variants {
+		switch {
+		case variant.Name == "":
+			return fmt.Errorf("every variant needs a name")
+		case seen[variant.Name]:
+			return fmt.Errorf("variant %s is defined twice", variant.Name)
+		case variant.Weight < 0:
+			return fmt.Errorf("variant %s: weight must not be negative", variant.Name)
+		}
+		if _, ok := profiles[variant.Profile]; !ok {
+			return fmt.Errorf("variant %s: unknown profile %q", variant.Name, variant.Profile)
+		}
+		seen[variant.Name] = true
+	}
+	return nil
+}
+
+// covers reports whether the experiment applies to mediaType
+func (e Experiment) covers(mediaType string) bool {
+	return len(e.MediaTypes) == 0 || slices.Contains(e.MediaTypes, mediaType)
+}
+
+// pick hashes the device into a weighted bucket, so a device always lands in the same variant
+func (e Experiment) pick(name, deviceKey string) Variant {
+	total := 0
+	for _, variant := range e.Variants {
+		total += max(variant.Weight, 1)
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(name + "\x00" + deviceKey))
+	bucket := int(hash.Sum64() % uint64(total))
+	for _, variant := range e.Variants {
+		if bucket < max(variant.Weight, 1) {
+			return variant
+		}
+		bucket -= max(variant.Weight, 1)
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// ValidateExperiments checks every experiment and that no media type is in two of them
+func ValidateExperiments(named map[string]Experiment, profiles map[string]AFProfile) error {
+	owner := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		experiment := named[name]
+		if err := experiment.Validate(profiles); err != nil {
+			return fmt.Errorf("experiment %s: %w", name, err)
+		}
+		for _, mediaType := range []string{"audio", "image", "video"} {
+			if !experiment.covers(mediaType) {
+				continue
+			}
+			if other, taken := owner[mediaType]; taken {
+				return fmt.Errorf("experiments %s and %s both cover %s", other, name, mediaType)
+			}
+			owner[mediaType] = name
+		}
+	}
+	return nil
+}
+
+// experiments holds the running experiments; swapped as a whole on reload
+var experiments atomic.Pointer[map[string]Experiment]
+
+// SetExperiments replaces the running experiments (safe to call at runtime)
+// Profiles must be set first: variants are checked against them
+func SetExperiments(named map[string]Experiment) error {
+	current := make(map[string]AFProfile)
+	for _, name := range ProfileNames() {
+		current[name], _ = Profile(name)
+	}
+	if err := ValidateExperiments(named, current); err != nil {
+		return err
+	}
+	experiments.Store(&named)
+	return nil
+}
+
+// Experiments returns the running experiments
+func Experiments() map[string]Experiment {
+	named := experiments.Load()
+	if named == nil {
+		return nil
+	}
+	return *named
+}
+
+// AssignExperiment returns the device's variant in the experiment covering mediaType
+// ok is false when no experiment covers it or the variant's profile sets no level for it
+func AssignExperiment(mediaType, deviceKey string) (assignment Assignment, ok bool) {
+	for name, experiment := range Experiments() {
+		if !experiment.covers(mediaType) {
+			continue
+		}
+		variant := experiment.pick(name, deviceKey)
+		profile, found := Profile(variant.Profile)
+		if !found {
+			return Assignment{}, false
+		}
+		level := profile.LevelFor(mediaType)
+		return Assignment{
+			Experiment:       name,
+			Variant:          variant.Name,
+			Level:            level,
+			Techniques:       profile.TechniquesFor(mediaType),
+			AudioBitrateKbps: profile.AudioBitrateBounds(),
+		}, level != ""
+	}
+	return Assignment{}, false
+}
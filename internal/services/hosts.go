@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"fingerprint-converter/internal/breaker"
+)
+
+// maxTrackedHosts bounds the per-host breakers; closed ones are dropped past it
+const maxTrackedHosts = 1000
+
+// HostUnavailableError is returned without downloading while a source host's circuit is open
+type HostUnavailableError struct {
+	Host string
+	Err  error // *breaker.OpenError
+}
+
+func (e *HostUnavailableError) Error() string {
+	return fmt.Sprintf("source host %s is unavailable: %v", e.Host, e.Err)
+}
+
+func (e *HostUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// hostBreakers keeps one circuit breaker per source host
+// A nil hostBreakers hands out nil breakers, which allow everything
+type hostBreakers struct {
+	settings breaker.Settings
+	mu       sync.Mutex
+	hosts    map[string]*breaker.Breaker
+}
+
+func newHostBreakers(settings breaker.Settings) *hostBreakers {
+	return &hostBreakers{settings: settings, hosts: make(map[string]*breaker.Breaker)}
+}
+
+// get returns the host's breaker, creating it on first use
+func (h *hostBreakers) get(host string) *breaker.Breaker {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if b, ok := h.hosts[host]; ok {
+		return b
+	}
+	if len(h.hosts) >= maxTrackedHosts {
+		for name, b := range h.hosts {
+			if b.State().State == breaker.Closed {
+				delete(h.hosts, name)
+			}
+		}
+	}
+	b := breaker.New("download "+host, h.settings, nil)
+	h.hosts[host] = b
+	return b
+}
+
+// open lists hosts whose circuit currently rejects downloads
+func (h *hostBreakers) open() []string {
+	hosts := []string{}
+	if h == nil {
+		return hosts
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name, b := range h.hosts {
+		if b.State().State != breaker.Closed {
+			hosts = append(hosts, name)
+		}
+	}
+	slices.Sort(hosts)
+	return hosts
+}
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"math/rand"
+	"io"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,6 +20,7 @@ import (
 type ImageConverter struct {
 	workerPool *pool.WorkerPool
 	bufferPool *pool.BufferPool
+	rng        RNG
 	mu         sync.RWMutex
 	stats      ImageStats
 }
@@ -29,23 +30,26 @@ type ImageStats struct {
 	TotalConversions  int64
 	FailedConversions int64
 	AvgConversionTime time.Duration
+	FailureReasons    map[string]int64 // Failed conversions by category (FailureDecode, ...)
 }
 
 // NewImageConverter creates a new image converter
-func NewImageConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool) *ImageConverter {
+func NewImageConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool, rng RNG) *ImageConverter {
 	return &ImageConverter{
 		workerPool: workerPool,
 		bufferPool: bufferPool,
+		rng:        orDefaultRNG(rng),
 	}
 }
 
 // Convert processes image with anti-fingerprinting
-func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string) error {
+// The output is written to outputPath as given; callers name it after OutputFormat
+func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string, opts ConvertOptions) (*ConversionResult, error) {
 	start := time.Now()
 
 	// Validate input
 	if len(inputData) == 0 {
-		return fmt.Errorf("empty input data")
+		return nil, fmt.Errorf("empty input data")
 	}
 
 	// Detect input format
@@ -54,16 +58,32 @@ func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level s
 	// Get randomized parameters based on level
 	params := ic.getRandomizedParams(level, inputFormat)
 
+	// Stage watermark assets before building the command
+	if opts.Watermark != nil {
+		cleanup, err := opts.Watermark.stage()
+		defer cleanup()
+		if err != nil {
+			ic.recordFailure(FailureOther)
+			return nil, err
+		}
+	}
+
 	// Build FFmpeg command with anti-fingerprinting
-	cmd := exec.CommandContext(ctx, "ffmpeg",
+	cmd := FFmpeg(
 		"-hide_banner",
 		"-loglevel", "error",
 		"-i", "pipe:0", // Input from stdin
 	)
+	cmd.Args = append(cmd.Args, opts.Watermark.inputArgs()...)
 
 	// Add anti-fingerprint filters
 	filters := []string{}
 
+	// Downscale oversized inputs first so the remaining filters work on fewer pixels
+	if scale := opts.scaleFilter(); scale != "" {
+		filters = append(filters, scale)
+	}
+
 	// Add noise based on level and format
 	if params.addNoise {
 		filters = append(filters, fmt.Sprintf("noise=alls=%d:allf=t", params.noiseStrength))
@@ -80,14 +100,18 @@ func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level s
 		filters = append(filters, fmt.Sprintf("unsharp=3:3:%.2f", params.blurAmount))
 	}
 
-	if len(filters) > 0 {
-		cmd.Args = append(cmd.Args, "-vf", strings.Join(filters, ","))
-	}
+	// Extra techniques from the profile
+	techniqueFilters, techniqueArgs := opts.applyTechniques("image", level, ic.rng)
+	filters = append(filters, techniqueFilters...)
 
-	// Determine output format (always output as input format or fallback to JPEG)
-	outputFormat := inputFormat
-	if outputFormat != "png" && outputFormat != "jpeg" && outputFormat != "jpg" && outputFormat != "webp" {
-		outputFormat = "jpeg" // Fallback to JPEG for unsupported formats
+	// Watermark is drawn last so AF noise doesn't smear it
+	filterArgs, _ := buildVideoFilterArgs(filters, opts.Watermark)
+	cmd.Args = append(cmd.Args, filterArgs...)
+
+	// Safe mode keeps only the first frame of animations; AVIF and JPEG XL outputs are stills too
+	outputFormat := ic.outputFormat(inputFormat, opts)
+	if opts.SafeMode || outputFormat == "avif" || outputFormat == "jxl" {
+		cmd.Args = append(cmd.Args, "-frames:v", "1")
 	}
 
 	// Output codec and quality settings
@@ -102,6 +126,21 @@ func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level s
 			"-c:v", "libwebp",
 			"-quality", strconv.Itoa(params.quality),
 		)
+	case "avif":
+		encoder := ImageEncoder("avif")
+		cmd.Args = append(cmd.Args,
+			"-c:v", encoder,
+			"-crf", strconv.Itoa(params.avifCRF),
+			"-pix_fmt", "yuv420p", // Alpha is dropped
+		)
+		if encoder == "libaom-av1" {
+			cmd.Args = append(cmd.Args, "-still-picture", "1", "-b:v", "0", "-cpu-used", "6")
+		}
+	case "jxl":
+		cmd.Args = append(cmd.Args,
+			"-c:v", "libjxl",
+			"-distance", strconv.FormatFloat(params.jxlDistance, 'f', 3, 64),
+		)
 	default: // jpeg/jpg
 		cmd.Args = append(cmd.Args,
 			"-c:v", "mjpeg",
@@ -110,40 +149,89 @@ func (ic *ImageConverter) Convert(ctx context.Context, inputData []byte, level s
 	}
 
 	// Output settings
-	cmd.Args = append(cmd.Args,
-		"-f", "image2",
-		"-threads", "0",
-		"pipe:1", // Output to stdout
-	)
+	// The AVIF muxer seeks back to write its index, so it writes the file itself
+	cmd.Args = append(cmd.Args, techniqueArgs...)
+	cmd.Args = append(cmd.Args, "-threads", threadsArg())
+	if outputFormat == "avif" {
+		cmd.Args = append(cmd.Args, "-f", "avif", "-y", outputPath)
+	} else {
+		cmd.Args = append(cmd.Args, "-f", "image2", "pipe:1") // Output to stdout
+	}
 
 	// Set up pipes
 	cmd.Stdin = bytes.NewReader(inputData)
 	var outputBuffer bytes.Buffer
-	var errorBuffer bytes.Buffer
 	cmd.Stdout = &outputBuffer
-	cmd.Stderr = &errorBuffer
 
 	// Execute conversion
-	if err := cmd.Run(); err != nil {
-		ic.recordFailure()
-		return fmt.Errorf("ffmpeg error: %v, stderr: %s", err, errorBuffer.String())
+	encodeStart := time.Now()
+	stderr, err := RunCommand(ctx, cmd)
+	ffmpegTime := time.Since(encodeStart)
+	StagesFrom(ctx).Since("encode", encodeStart)
+	if err != nil {
+		err = ffmpegError(err, stderr)
+		ic.recordFailure(failureCategory(ctx, err))
+		return nil, err
 	}
 
-	output := outputBuffer.Bytes()
-	if len(output) == 0 {
-		ic.recordFailure()
-		return fmt.Errorf("ffmpeg produced no output")
-	}
+	if outputFormat == "avif" {
+		if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+			ic.recordFailure(FailureDecode)
+			return nil, fmt.Errorf("ffmpeg produced no output")
+		}
+	} else {
+		output := outputBuffer.Bytes()
+		if len(output) == 0 {
+			ic.recordFailure(FailureDecode)
+			return nil, fmt.Errorf("ffmpeg produced no output")
+		}
 
-	// Write to file with correct extension
-	finalPath := ic.adjustOutputPath(outputPath, outputFormat)
-	if err := os.WriteFile(finalPath, output, 0644); err != nil {
-		ic.recordFailure()
-		return fmt.Errorf("failed to write output file: %w", err)
+		// Write to file
+		writeStart := time.Now()
+		if err := os.WriteFile(outputPath, output, 0644); err != nil {
+			ic.recordFailure(FailureWrite)
+			return nil, fmt.Errorf("failed to write output file: %w", err)
+		}
+		StagesFrom(ctx).Since("write", writeStart)
 	}
 
 	ic.recordSuccess(time.Since(start))
-	return nil
+	result := &ConversionResult{
+		Path:       outputPath,
+		Format:     outputFormat,
+		Duration:   time.Since(start),
+		FFmpegTime: ffmpegTime,
+		Params:     params.describe(outputFormat),
+	}
+	result.measure(ctx)
+	return result, nil
+}
+
+// OutputFormat returns the format Convert writes inputData in with opts
+// Callers name the output file after it, so its extension matches the content
+func (ic *ImageConverter) OutputFormat(inputData []byte, opts ConvertOptions) string {
+	return ic.outputFormat(ic.detectFormat(inputData), opts)
+}
+
+// outputFormat picks the requested format, else the input's, else JPEG
+func (ic *ImageConverter) outputFormat(inputFormat string, opts ConvertOptions) string {
+	outputFormat := inputFormat
+	if opts.ImageFormat != "" {
+		outputFormat = opts.ImageFormat
+	}
+	switch outputFormat {
+	case "jpg":
+		outputFormat = "jpeg"
+	case "png", "jpeg", "webp", "avif", "jxl":
+	default:
+		outputFormat = "jpeg" // Fallback to JPEG for unsupported formats
+	}
+
+	// Safe mode avoids libwebp and friends (often missing)
+	if opts.SafeMode && optionalImageFormat(outputFormat) {
+		outputFormat = "jpeg"
+	}
+	return outputFormat
 }
 
 type imageParams struct {
@@ -157,6 +245,36 @@ type imageParams struct {
 	contrast         float64
 	addBlur          bool
 	blurAmount       float64
+	avifCRF          int
+	jxlDistance      float64
+}
+
+// describe lists the parameters of format's encoder and the filters for ConversionResult.Params
+func (p imageParams) describe(format string) map[string]string {
+	params := map[string]string{}
+	switch format {
+	case "png":
+		params["compression_level"] = strconv.Itoa(p.compressionLevel)
+	case "webp":
+		params["quality"] = strconv.Itoa(p.quality)
+	case "avif":
+		params["crf"] = strconv.Itoa(p.avifCRF)
+	case "jxl":
+		params["distance"] = strconv.FormatFloat(p.jxlDistance, 'f', 3, 64)
+	default:
+		params["qscale"] = strconv.Itoa(p.jpegQScale)
+	}
+	if p.addNoise {
+		params["noise"] = strconv.Itoa(p.noiseStrength)
+	}
+	if p.colorAdjust {
+		params["brightness"] = fmt.Sprintf("%.6f", p.brightness)
+		params["contrast"] = fmt.Sprintf("%.6f", p.contrast)
+	}
+	if p.addBlur {
+		params["blur"] = fmt.Sprintf("%.2f", p.blurAmount)
+	}
+	return params
 }
 
 func (ic *ImageConverter) getRandomizedParams(level string, format string) imageParams {
@@ -164,54 +282,43 @@ func (ic *ImageConverter) getRandomizedParams(level string, format string) image
 		quality:          90,
 		compressionLevel: 6,
 		jpegQScale:       3,
+		avifCRF:          30,
+		jxlDistance:      1.0,
+	}
+
+	// "none" keeps the fixed defaults; ranges are tunable per level (AF_IMAGE_*)
+	ranges, ok := currentAFRanges().Image.For(level)
+	if !ok {
+		return params
+	}
+
+	params.quality = ranges.Quality.Pick(ic.rng)
+	params.compressionLevel = ranges.CompressionLevel.Pick(ic.rng)
+	params.jpegQScale = ranges.JPEGQScale.Pick(ic.rng)
+	params.avifCRF = min(ranges.AVIFCRF.Pick(ic.rng), 63)
+	if ranges.JXLDistance.Enabled() {
+		params.jxlDistance = ranges.JXLDistance.Pick(ic.rng)
 	}
 
 	// Adjust noise based on format (PNG is more sensitive)
-	isPNG := (format == "png")
-
-	switch level {
-	case "basic":
-		// Minimal randomization
-		params.quality = 88 + rand.Intn(5)          // 88-92
-		params.compressionLevel = 5 + rand.Intn(3)  // 5-7
-		params.jpegQScale = 3 + rand.Intn(2)        // 3-4
-		
-	case "moderate":
-		// Moderate randomization (default, recommended)
-		params.quality = 88 + rand.Intn(5)          // 88-92
-		params.compressionLevel = 5 + rand.Intn(3)  // 5-7
-		params.jpegQScale = 3 + rand.Intn(2)        // 3-4
-		params.addNoise = true
-		if isPNG {
-			params.noiseStrength = 1 + rand.Intn(2) // 1-2 (lower for PNG)
-		} else {
-			params.noiseStrength = 2 + rand.Intn(3) // 2-4
-		}
-		params.colorAdjust = true
-		params.brightness = float64(rand.Intn(3)-1) / 1000.0     // ±0.001
-		params.contrast = 1.0 + float64(rand.Intn(3)-1)/1000.0   // ±0.001
-
-	case "paranoid":
-		// Maximum randomization
-		params.quality = 85 + rand.Intn(8)          // 85-92
-		params.compressionLevel = 4 + rand.Intn(4)  // 4-7
-		params.jpegQScale = 2 + rand.Intn(3)        // 2-4
+	noise := ranges.Noise
+	if format == "png" {
+		noise = ranges.NoisePNG
+	}
+	if noise.Enabled() {
 		params.addNoise = true
-		if isPNG {
-			params.noiseStrength = 1 + rand.Intn(3) // 1-3 (lower for PNG)
-		} else {
-			params.noiseStrength = 3 + rand.Intn(5) // 3-7
-		}
+		params.noiseStrength = noise.Pick(ic.rng)
+	}
+
+	if ranges.Brightness > 0 || ranges.Contrast > 0 {
 		params.colorAdjust = true
-		params.brightness = float64(rand.Intn(5)-2) / 1000.0     // ±0.002
-		params.contrast = 1.0 + float64(rand.Intn(5)-2)/1000.0   // ±0.002
-		params.addBlur = true
-		params.blurAmount = 0.1 + float64(rand.Intn(5))/100.0    // 0.1-0.14
+		params.brightness = ranges.Brightness.Pick(ic.rng)
+		params.contrast = 1.0 + ranges.Contrast.Pick(ic.rng)
+	}
 
-	default: // "none"
-		params.quality = 90
-		params.compressionLevel = 6
-		params.jpegQScale = 3
+	if ranges.Blur.Enabled() {
+		params.addBlur = true
+		params.blurAmount = ranges.Blur.Pick(ic.rng)
 	}
 
 	return params
@@ -240,20 +347,33 @@ func (ic *ImageConverter) detectFormat(data []byte) string {
 	return "unknown"
 }
 
-func (ic *ImageConverter) adjustOutputPath(path, format string) string {
-	ext := filepath.Ext(path)
-	base := strings.TrimSuffix(path, ext)
+// OutputPathFor returns path with the extension of the given output format
+func (ic *ImageConverter) OutputPathFor(path, format string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ic.OutputExtension(format)
+}
 
+// OutputExtension returns the file extension of an output format
+func (ic *ImageConverter) OutputExtension(format string) string {
 	switch format {
-	case "png":
-		return base + ".png"
-	case "webp":
-		return base + ".webp"
+	case "png", "webp", "avif", "jxl":
+		return "." + format
 	default:
-		return base + ".jpg"
+		return ".jpg"
 	}
 }
 
+// OutputExtensionOf returns the extension of Convert's output for the image file at inputPath
+// Only the file's leading bytes are read
+func (ic *ImageConverter) OutputExtensionOf(inputPath string, opts ConvertOptions) string {
+	head := make([]byte, 16)
+	if file, err := os.Open(inputPath); err == nil {
+		n, _ := io.ReadFull(file, head)
+		head = head[:n]
+		file.Close()
+	}
+	return ic.OutputExtension(ic.OutputFormat(head, opts))
+}
+
 func (ic *ImageConverter) recordSuccess(duration time.Duration) {
 	ic.mu.Lock()
 	defer ic.mu.Unlock()
@@ -261,27 +381,31 @@ func (ic *ImageConverter) recordSuccess(duration time.Duration) {
 	ic.stats.AvgConversionTime = (ic.stats.AvgConversionTime*time.Duration(ic.stats.TotalConversions-1) + duration) / time.Duration(ic.stats.TotalConversions)
 }
 
-func (ic *ImageConverter) recordFailure() {
+func (ic *ImageConverter) recordFailure(category string) {
 	ic.mu.Lock()
 	defer ic.mu.Unlock()
 	ic.stats.FailedConversions++
+	if ic.stats.FailureReasons == nil {
+		ic.stats.FailureReasons = make(map[string]int64)
+	}
+	ic.stats.FailureReasons[category]++
 }
 
 // GetStats returns current statistics
 func (ic *ImageConverter) GetStats() ImageStats {
 	ic.mu.RLock()
 	defer ic.mu.RUnlock()
-	return ic.stats
+	stats := ic.stats
+	stats.FailureReasons = maps.Clone(ic.stats.FailureReasons)
+	return stats
 }
 
 // GetOutputExtension returns the file extension for this converter
 func (ic *ImageConverter) GetOutputExtension() string {
-	return ".jpg" // Default, will be adjusted based on input format
+	return ".jpg" // Default; outputs of other formats are named with OutputPathFor
 }
 
 // GenerateOutputPath creates a unique output path
 func (ic *ImageConverter) GenerateOutputPath(cacheDir, deviceID, urlHash string) string {
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%s_%s_%d%s", deviceID, urlHash[:8], timestamp, ic.GetOutputExtension())
-	return filepath.Join(cacheDir, filename)
+	return filepath.Join(cacheDir, OutputName(deviceID, urlHash, ic.GetOutputExtension()))
 }
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// optionalImageEncoders are the ffmpeg encoders of the image formats many builds lack, by preference
+var optionalImageEncoders = map[string][]string{
+	"avif": {"libaom-av1", "libsvtav1"},
+	"jxl":  {"libjxl"},
+}
+
+var (
+	imageEncodersOnce sync.Once
+	imageEncoders     map[string]string // Format -> encoder found in the local ffmpeg
+)
+
+// ImageEncoder returns the ffmpeg encoder used for an optional image format (avif, jxl)
+// Empty when the local ffmpeg has none of its encoders (checked once)
+func ImageEncoder(format string) string {
+	imageEncodersOnce.Do(func() {
+		var output bytes.Buffer
+		cmd := FFmpeg("-hide_banner", "-encoders")
+		cmd.Stdout = &output
+		cmd.Timeout = 10 * time.Second
+		_, err := RunCommand(context.Background(), cmd)
+
+		imageEncoders = make(map[string]string)
+		for format, encoders := range optionalImageEncoders {
+			for _, encoder := range encoders {
+				if err == nil && bytes.Contains(output.Bytes(), []byte(" "+encoder+" ")) {
+					imageEncoders[format] = encoder
+					break
+				}
+			}
+			if imageEncoders[format] == "" {
+				log.Printf("⚠️  ffmpeg has no %s encoder (%s), image_format=%s is rejected",
+					format, strings.Join(encoders, " or "), format)
+			}
+		}
+	})
+	return imageEncoders[format]
+}
+
+// checkImageEncoder returns an error when the local ffmpeg can't write format
+func checkImageEncoder(format string) error {
+	encoders, optional := optionalImageEncoders[format]
+	if optional && ImageEncoder(format) == "" {
+		return fmt.Errorf("image_format %s is not available: ffmpeg is built without %s", format, strings.Join(encoders, " or "))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMedia is returned when ffprobe can't parse an input
+var ErrInvalidMedia = errors.New("input is not a valid media file")
+
+// InputLimits caps what an input may contain before it is encoded (zero = unlimited)
+// Checked with ffprobe, which only reads headers, so oversized inputs are rejected cheaply
+// The byte sizes are checked on download and upload instead and never need a probe
+type InputLimits struct {
+	MaxImageMegapixels float64
+	MaxVideoLongEdge   int
+	MaxVideoShortEdge  int
+	MaxVideoDuration   time.Duration
+	MaxAudioDuration   time.Duration
+	MaxImageSize       int64
+	MaxAudioSize       int64
+	MaxVideoSize       int64
+}
+
+// LimitError reports an input that exceeds a configured limit
+type LimitError struct {
+	Limit  string // Name of the exceeded limit
+	Actual string
+	Max    string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %s exceeds the limit of %s", e.Limit, e.Actual, e.Max)
+}
+
+// Enabled reports whether any limit is set
+func (l InputLimits) Enabled() bool {
+	return l.MaxImageMegapixels > 0 || l.MaxVideoLongEdge > 0 ||
+		l.MaxVideoDuration > 0 || l.MaxAudioDuration > 0
+}
+
+// MaxSize returns the byte limit for inputs of mediaType (0 = none)
+func (l InputLimits) MaxSize(mediaType string) int64 {
+	switch mediaType {
+	case "image":
+		return l.MaxImageSize
+	case "audio":
+		return l.MaxAudioSize
+	case "video":
+		return l.MaxVideoSize
+	}
+	return 0
+}
+
+// CheckSize verifies an input of size bytes against the byte limit for mediaType
+func (l InputLimits) CheckSize(mediaType string, size int64) error {
+	if limit := l.MaxSize(mediaType); limit > 0 && size > limit {
+		return &LimitError{Limit: mediaType + " file size", Actual: formatBytes(size), Max: formatBytes(limit)}
+	}
+	return nil
+}
+
+// Check probes data and verifies it against the limits for mediaType
+// Returns the probe so callers can enforce totals (e.g. concatenated duration)
+func (l InputLimits) Check(ctx context.Context, mediaType string, data []byte) (*MediaInfo, error) {
+	info, err := ProbeMedia(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return info, l.CheckInfo(mediaType, info)
+}
+
+// CheckInfo verifies already-probed media against the limits for mediaType
+func (l InputLimits) CheckInfo(mediaType string, info *MediaInfo) error {
+	switch mediaType {
+	case "image":
+		megapixels := float64(info.Width) * float64(info.Height) / 1e6
+		if l.MaxImageMegapixels > 0 && megapixels > l.MaxImageMegapixels {
+			return &LimitError{
+				Limit:  "image size",
+				Actual: fmt.Sprintf("%.1fMP (%dx%d)", megapixels, info.Width, info.Height),
+				Max:    fmt.Sprintf("%.1fMP", l.MaxImageMegapixels),
+			}
+		}
+	case "video":
+		if l.MaxVideoLongEdge > 0 && info.HasVideo {
+			longEdge, shortEdge := max(info.Width, info.Height), min(info.Width, info.Height)
+			if longEdge > l.MaxVideoLongEdge || shortEdge > l.MaxVideoShortEdge {
+				return &LimitError{
+					Limit:  "video resolution",
+					Actual: fmt.Sprintf("%dx%d", info.Width, info.Height),
+					Max:    fmt.Sprintf("%dx%d", l.MaxVideoLongEdge, l.MaxVideoShortEdge),
+				}
+			}
+		}
+		if l.MaxVideoDuration > 0 && info.Duration > l.MaxVideoDuration {
+			return &LimitError{Limit: "video duration", Actual: info.Duration.Round(time.Second).String(), Max: l.MaxVideoDuration.String()}
+		}
+	case "audio":
+		if l.MaxAudioDuration > 0 && info.Duration > l.MaxAudioDuration {
+			return &LimitError{Limit: "audio duration", Actual: info.Duration.Round(time.Second).String(), Max: l.MaxAudioDuration.String()}
+		}
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of ffprobe's stderr
+func lastLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
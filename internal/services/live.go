@@ -0,0 +1,25 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// liveOutputKey carries the writer an encode's output is copied to while ffmpeg produces it
+type liveOutputKey struct{}
+
+// WithLiveOutput has audio and video encodes under ctx copy their output to w as ffmpeg writes it
+// Both are muxed for a pipe (fragmented MP4, Opus, MP3), so the copy plays from its first byte.
+// w must not block or fail: an error from it fails the encode.
+func WithLiveOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, liveOutputKey{}, w)
+}
+
+// outputSink returns where ffmpeg's stdout goes: buf, and the live output of ctx if it has one
+func outputSink(ctx context.Context, buf *bytes.Buffer) io.Writer {
+	if live, ok := ctx.Value(liveOutputKey{}).(io.Writer); ok && live != nil {
+		return io.MultiWriter(buf, live)
+	}
+	return buf
+}
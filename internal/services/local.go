@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveRoots cleans the local input roots and adds their symlink-resolved form,
+// so both the paths callers use and the resolved paths of their files match
+func resolveRoots(roots []string) []string {
+	var resolved []string
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		resolved = append(resolved, filepath.Clean(root))
+		if real, err := filepath.EvalSymlinks(root); err == nil && real != filepath.Clean(root) {
+			resolved = append(resolved, real)
+		}
+	}
+	return resolved
+}
+
+// fetchLocal reads a file:// URL from under one of the local input roots
+func (d *Downloader) fetchLocal(rawURL string) ([]byte, error) {
+	path, err := d.localPath(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("download failed: file not found")
+		}
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("download failed: not a regular file")
+	}
+	if info.Size() > d.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrFileTooLarge, info.Size(), d.maxSize)
+	}
+
+	// The file may still be growing, so the limit is enforced on what is actually read
+	data, err := io.ReadAll(io.LimitReader(file, d.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	if int64(len(data)) > d.maxSize {
+		return nil, fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, d.maxSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("downloaded file is empty")
+	}
+	return data, nil
+}
+
+// localPath maps a file:// URL to a file under a local input root
+// Symlinks are resolved first, so a link inside a root can't reach files outside it
+func (d *Downloader) localPath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file:// URLs must not name a host (got %q)", u.Host)
+	}
+	if !filepath.IsAbs(u.Path) {
+		return "", fmt.Errorf("file:// URLs need an absolute path")
+	}
+
+	// Checked before and after resolving, so paths outside the roots can't be probed for existence
+	path := filepath.Clean(u.Path)
+	if !d.inLocalRoot(path) {
+		return "", fmt.Errorf("file is outside LOCAL_INPUT_ROOTS")
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("download failed: file not found")
+		}
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if !d.inLocalRoot(path) {
+		return "", fmt.Errorf("file is outside LOCAL_INPUT_ROOTS")
+	}
+	return path, nil
+}
+
+// inLocalRoot reports whether path lies under one of the local input roots
+func (d *Downloader) inLocalRoot(path string) bool {
+	for _, root := range d.localRoots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
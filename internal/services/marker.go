@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// markerTag is the container tag outputs are marked with
+// A common tag name, so the marker doesn't stand out from regular metadata
+const markerTag = "description"
+
+// Reprocess modes: what happens to an input that is one of our own outputs
+const (
+	ReprocessConvert = "process" // Convert it again like any input
+	ReprocessSkip    = "skip"    // Return it unchanged
+	ReprocessRemux   = "remux"   // Copy the streams into a fresh container (new metadata, no re-encode)
+)
+
+// IsValidReprocessMode reports whether mode is a known reprocess mode
+func IsValidReprocessMode(mode string) bool {
+	return mode == ReprocessConvert || mode == ReprocessSkip || mode == ReprocessRemux
+}
+
+// OutputMarker tags audio and video outputs so they are recognized when they come back as inputs
+// The tag is a random nonce and its keyed MAC, so it looks like any other random metadata
+// and outputs can't be linked to each other without the secret
+type OutputMarker struct {
+	key []byte
+}
+
+// NewOutputMarker creates a marker keyed with secret; nil (no marking) when secret is empty
+func NewOutputMarker(secret string) *OutputMarker {
+	if secret == "" {
+		return nil
+	}
+	return &OutputMarker{key: []byte(secret)}
+}
+
+// args returns the ffmpeg output options writing a fresh marker
+// The nonce comes from the converter's RNG, so AF_SEED runs stay reproducible
+func (m *OutputMarker) args(rng RNG) []string {
+	if m == nil {
+		return nil
+	}
+	nonce := make([]byte, 8)
+	for i := range nonce {
+		nonce[i] = byte(rng.IntN(256))
+	}
+	return []string{"-metadata", markerTag + "=" + hex.EncodeToString(nonce) + hex.EncodeToString(m.mac(nonce))}
+}
+
+// Marked reports whether info carries a marker written with this marker's secret
+func (m *OutputMarker) Marked(info *MediaInfo) bool {
+	if m == nil || info == nil {
+		return false
+	}
+	value, err := hex.DecodeString(info.Tags[markerTag])
+	if err != nil || len(value) != 16 {
+		return false
+	}
+	return hmac.Equal(value[8:], m.mac(value[:8]))
+}
+
+func (m *OutputMarker) mac(nonce []byte) []byte {
+	h := hmac.New(sha256.New, m.key)
+	h.Write(nonce)
+	return h.Sum(nil)[:8]
+}
+
+// outputMarker holds the installed marker; nil means outputs are not marked
+var outputMarker atomic.Pointer[OutputMarker]
+
+// SetOutputMarker installs the marker the converters tag outputs with (nil = no marking)
+func SetOutputMarker(m *OutputMarker) {
+	outputMarker.Store(m)
+}
+
+// CurrentOutputMarker returns the installed marker (nil when outputs are not marked)
+func CurrentOutputMarker() *OutputMarker {
+	return outputMarker.Load()
+}
+
+// PassthroughFormat returns the output format a marked input already has ("mp4", "opus" or "mp3"),
+// or "" when it can't be returned as mediaType without converting it
+func PassthroughFormat(mediaType string, info *MediaInfo) string {
+	var audioCodec, videoCodec string
+	for _, stream := range info.Streams {
+		switch {
+		case stream.Type == "audio" && audioCodec == "":
+			audioCodec = stream.Codec
+		case stream.Type == "video" && videoCodec == "":
+			videoCodec = stream.Codec
+		}
+	}
+	switch {
+	case mediaType == "video" && videoCodec == "h264" && strings.Contains(info.Container, "mp4"):
+		return "mp4"
+	case mediaType == "audio" && !info.HasVideo && audioCodec == "opus":
+		return "opus"
+	case mediaType == "audio" && !info.HasVideo && audioCodec == "mp3":
+		return "mp3"
+	}
+	return ""
+}
+
+// Remux copies the streams of inputData into a fresh container of format at outputPath
+// Metadata is dropped and a new marker written, so the file differs from the input without a re-encode
+func Remux(ctx context.Context, inputData []byte, format, outputPath string, rng RNG) error {
+	cmd := FFmpeg(
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-map", "0",
+		"-c", "copy",
+		"-map_metadata", "-1",
+	)
+	cmd.Args = append(cmd.Args, CurrentOutputMarker().args(orDefaultRNG(rng))...)
+	if format == "mp4" {
+		cmd.Args = append(cmd.Args, "-movflags", "frag_keyframe+empty_moov+default_base_moof")
+	}
+	cmd.Args = append(cmd.Args, "-f", format, "pipe:1")
+
+	cmd.Stdin = bytes.NewReader(inputData)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		return ffmpegError(err, stderr)
+	}
+	if output.Len() == 0 {
+		return fmt.Errorf("ffmpeg produced no output")
+	}
+	if err := os.WriteFile(outputPath, output.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
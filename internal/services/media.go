@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// DetectMediaType detects media type from a URL or file name extension
+// URLs are judged by their path, so presigned query strings (?X-Amz-Signature=...) don't hide it
+func DetectMediaType(url string) string {
+	urlLower := strings.ToLower(urlPath(url))
+
+	// Audio extensions
+	if strings.HasSuffix(urlLower, ".mp3") ||
+		strings.HasSuffix(urlLower, ".opus") ||
+		strings.HasSuffix(urlLower, ".ogg") ||
+		strings.HasSuffix(urlLower, ".m4a") ||
+		strings.HasSuffix(urlLower, ".wav") ||
+		strings.HasSuffix(urlLower, ".aac") {
+		return "audio"
+	}
+
+	// Image extensions
+	if strings.HasSuffix(urlLower, ".jpg") ||
+		strings.HasSuffix(urlLower, ".jpeg") ||
+		strings.HasSuffix(urlLower, ".png") ||
+		strings.HasSuffix(urlLower, ".webp") ||
+		strings.HasSuffix(urlLower, ".gif") {
+		return "image"
+	}
+
+	// Video extensions
+	if strings.HasSuffix(urlLower, ".mp4") ||
+		strings.HasSuffix(urlLower, ".avi") ||
+		strings.HasSuffix(urlLower, ".mov") ||
+		strings.HasSuffix(urlLower, ".mkv") ||
+		strings.HasSuffix(urlLower, ".webm") ||
+		strings.HasSuffix(urlLower, ".flv") ||
+		strings.HasSuffix(urlLower, ".m3u8") || // HLS and DASH streams are pulled into one file
+		strings.HasSuffix(urlLower, ".mpd") {
+		return "video"
+	}
+
+	return ""
+}
+
+// urlPath returns the path of a URL with a host; file names and other values are returned as is
+func urlPath(url string) string {
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		return u.Path
+	}
+	return url
+}
+
+// DetectMediaTypeFromContent detects media type from a Content-Type header, falling back to
+// the leading bytes. Containers that may hold either audio or video are treated as video.
+func DetectMediaTypeFromContent(contentType string, head []byte) string {
+	major, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(contentType)), "/")
+	switch major {
+	case "audio", "image", "video":
+		return major
+	}
+
+	format, ok := SniffFormat(head)
+	if !ok {
+		return ""
+	}
+	switch format.Kind {
+	case KindImage, KindAudio, KindVideo:
+		return format.Kind
+	case KindAV:
+		return "video"
+	}
+	return ""
+}
+
+// defaultLevelOverride holds DEFAULT_AF_LEVEL; empty means the per-media defaults apply
+var defaultLevelOverride atomic.Value
+
+// IsValidLevel reports whether level is a known AF level
+func IsValidLevel(level string) bool {
+	switch level {
+	case "none", "basic", "moderate", "paranoid":
+		return true
+	}
+	return false
+}
+
+// SetDefaultAFLevel overrides the per-media defaults for every media type ("" restores them)
+// Safe to call at runtime; requests already being processed keep their level
+func SetDefaultAFLevel(level string) error {
+	if level != "" && !IsValidLevel(level) {
+		return fmt.Errorf("invalid AF level %q (none, basic, moderate, paranoid)", level)
+	}
+	defaultLevelOverride.Store(level)
+	return nil
+}
+
+// DefaultAFLevel returns the recommended AF level for media type
+func DefaultAFLevel(mediaType string) string {
+	if level, _ := defaultLevelOverride.Load().(string); level != "" {
+		return level
+	}
+	switch mediaType {
+	case "audio":
+		return "moderate"
+	case "image":
+		return "moderate"
+	case "video":
+		return "basic"
+	default:
+		return "moderate"
+	}
+}
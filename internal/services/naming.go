@@ -0,0 +1,40 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxDeviceIDInName is the longest device ID prefix kept in output file names
+const maxDeviceIDInName = 64
+
+// OutputName returns a unique file name for an output: device, full key hash, unix seconds and a random suffix
+// Two outputs for the same device and key in the same second still get different names
+func OutputName(deviceID, keyHash, suffix string) string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return fmt.Sprintf("%s_%s_%d_%s%s", SafeFileName(deviceID), keyHash, time.Now().Unix(), hex.EncodeToString(random), suffix)
+}
+
+// SafeFileName reduces name to letters, digits, '-' and '_' so it can't leave the directory it is joined to
+// Anything else becomes '_', and the result is cut to maxDeviceIDInName characters
+func SafeFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if len(safe) > maxDeviceIDInName {
+		safe = safe[:maxDeviceIDInName]
+	}
+	if safe == "" {
+		return "_"
+	}
+	return safe
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConvertOptions carries optional per-request processing settings
+// Zero value means "no extra processing" for every field
+type ConvertOptions struct {
+	// Image/video resolution cap (long edge x short edge, 0 = no cap)
+	MaxLongEdge  int
+	MaxShortEdge int
+
+	// Video output frame rate (e.g. "30" or "30000/1001", empty = preserve source timing)
+	FrameRate string
+
+	// Strip the audio stream from video outputs
+	DropAudio bool
+
+	// Audio output container/codec: opus (default) or mp3
+	AudioFormat string
+
+	// Image output format: jpeg, png, webp, avif or jxl (empty = same as the input)
+	ImageFormat string
+
+	// Visible watermark overlay for images and videos (nil = none)
+	Watermark *Watermark
+
+	// Retry after a failed encode: plain pixel format, re-encoded audio, common image formats
+	SafeMode bool
+
+	// Extra AF techniques from the request's profile, applied in order (see AFTechnique)
+	Techniques []string
+
+	// Floor and ceiling of the audio bitrate scaled from the source, in kbps (zero = DefaultAudioBitrateKbps)
+	AudioBitrateKbps IntRange
+}
+
+// FallbackLevel is the AF level of safe-mode retries (no AF filters)
+const FallbackLevel = "none"
+
+// Fallback returns the settings for a safe-mode retry of a failed encode
+// Every filter-based option is dropped; only choices about the output streams are kept
+func (o ConvertOptions) Fallback() ConvertOptions {
+	imageFormat := o.ImageFormat
+	if optionalImageFormat(imageFormat) {
+		imageFormat = "jpeg" // Safe mode avoids the encoders many builds lack
+	}
+	return ConvertOptions{
+		DropAudio:        o.DropAudio,
+		AudioFormat:      o.AudioFormat,
+		ImageFormat:      imageFormat,
+		AudioBitrateKbps: o.AudioBitrateKbps,
+		SafeMode:         true,
+	}
+}
+
+// resolutionPresets maps preset names to long edge x short edge bounds
+var resolutionPresets = map[string][2]int{
+	"sd":  {854, 480},
+	"hd":  {1280, 720},
+	"fhd": {1920, 1080},
+}
+
+// ParseMaxResolution parses a preset name (sd/hd/fhd) or a WxH string (e.g. 1280x720)
+// Returns the bounds as long edge x short edge so portrait inputs are capped the same way
+func ParseMaxResolution(value string) (int, int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	if preset, ok := resolutionPresets[value]; ok {
+		return preset[0], preset[1], nil
+	}
+
+	parts := strings.Split(value, "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid max_resolution %q: use WxH (e.g. 1280x720) or a preset (sd/hd/fhd)", value)
+	}
+
+	width, err := strconv.Atoi(parts[0])
+	if err != nil || width < 16 {
+		return 0, 0, fmt.Errorf("invalid max_resolution width: %s", parts[0])
+	}
+	height, err := strconv.Atoi(parts[1])
+	if err != nil || height < 16 {
+		return 0, 0, fmt.Errorf("invalid max_resolution height: %s", parts[1])
+	}
+
+	if width < height {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// ParseAudioFormat validates the requested audio output format
+func ParseAudioFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "opus":
+		return "opus", nil
+	case "mp3":
+		return "mp3", nil
+	default:
+		return "", fmt.Errorf("unsupported audio_format %q (supported: opus, mp3)", value)
+	}
+}
+
+// ParseImageFormat validates the requested image output format; empty keeps the input's format
+// avif and jxl are rejected when the local ffmpeg has no encoder for them
+func ParseImageFormat(value string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(value)); format {
+	case "":
+		return "", nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "png", "webp":
+		return format, nil
+	case "avif", "jxl":
+		if err := checkImageEncoder(format); err != nil {
+			return "", err
+		}
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported image_format %q (supported: jpeg, png, webp, avif, jxl)", value)
+	}
+}
+
+// optionalImageFormat reports whether format needs an encoder ffmpeg builds often lack
+func optionalImageFormat(format string) bool {
+	return format == "webp" || format == "avif" || format == "jxl"
+}
+
+// ParseFrameRate validates a requested output frame rate
+// Accepts "preserve" (or empty), a number (e.g. 30, 29.97) or a rational (e.g. 30000/1001)
+func ParseFrameRate(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "preserve" {
+		return "", nil
+	}
+
+	var fps float64
+	if num, den, ok := strings.Cut(value, "/"); ok {
+		n, errN := strconv.Atoi(num)
+		d, errD := strconv.Atoi(den)
+		if errN != nil || errD != nil || n <= 0 || d <= 0 {
+			return "", fmt.Errorf("invalid frame_rate %q", value)
+		}
+		fps = float64(n) / float64(d)
+	} else {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid frame_rate %q", value)
+		}
+		fps = parsed
+	}
+
+	if fps < 1 || fps > 120 {
+		return "", fmt.Errorf("frame_rate must be between 1 and 120, got %s", value)
+	}
+	return value, nil
+}
+
+// Signature returns a stable description of the non-default options
+// Used to keep cache entries for different option sets apart
+func (o ConvertOptions) Signature() string {
+	parts := []string{}
+	if o.MaxLongEdge > 0 && o.MaxShortEdge > 0 {
+		parts = append(parts, fmt.Sprintf("res=%dx%d", o.MaxLongEdge, o.MaxShortEdge))
+	}
+	if o.FrameRate != "" {
+		parts = append(parts, "fps="+o.FrameRate)
+	}
+	if o.DropAudio {
+		parts = append(parts, "noaudio")
+	}
+	if o.AudioFormat != "" && o.AudioFormat != "opus" {
+		parts = append(parts, "afmt="+o.AudioFormat)
+	}
+	if o.ImageFormat != "" {
+		parts = append(parts, "ifmt="+o.ImageFormat)
+	}
+	if o.Watermark != nil {
+		parts = append(parts, "wm="+o.Watermark.Signature())
+	}
+	if len(o.Techniques) > 0 {
+		parts = append(parts, "af="+strings.Join(o.Techniques, ","))
+	}
+	if o.AudioBitrateKbps.Max > 0 {
+		parts = append(parts, fmt.Sprintf("abr=%d-%d", o.AudioBitrateKbps.Min, o.AudioBitrateKbps.Max))
+	}
+	return strings.Join(parts, ";")
+}
+
+// scaleFilter builds an orientation-aware downscale filter that never upscales
+func (o ConvertOptions) scaleFilter() string {
+	if o.MaxLongEdge <= 0 || o.MaxShortEdge <= 0 {
+		return ""
+	}
+
+	long, short := o.MaxLongEdge, o.MaxShortEdge
+	return fmt.Sprintf(
+		"scale=w='if(gte(iw,ih),min(iw,%d),min(iw,%d))':h='if(gte(iw,ih),min(ih,%d),min(ih,%d))':force_original_aspect_ratio=decrease:force_divisible_by=2",
+		long, short, short, long)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// OutputType is the real format of a converted output
+type OutputType struct {
+	MIME string // Content-Type to serve it with
+	Ext  string // File extension, with the dot
+}
+
+// outputTypes maps sniffed formats (see SniffFormat) to how outputs of that format are served
+var outputTypes = map[string]OutputType{
+	"jpeg":       {"image/jpeg", ".jpg"},
+	"png":        {"image/png", ".png"},
+	"gif":        {"image/gif", ".gif"},
+	"webp":       {"image/webp", ".webp"},
+	"mp3":        {"audio/mpeg", ".mp3"},
+	"mpeg-audio": {"audio/mpeg", ".mp3"},
+	"mp4":        {"video/mp4", ".mp4"},
+}
+
+// DetectOutputType identifies an output by its leading bytes; ok is false for unknown content
+// It tells apart what the signatures alone don't: AVIF from MP4, Opus from other Ogg, JPEG XL
+func DetectOutputType(head []byte) (OutputType, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0x0A}), bytes.HasPrefix(head, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")):
+		return OutputType{"image/jxl", ".jxl"}, true
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && (string(head[8:12]) == "avif" || string(head[8:12]) == "avis"):
+		return OutputType{"image/avif", ".avif"}, true
+	case bytes.HasPrefix(head, []byte("OggS")):
+		if bytes.Contains(head, []byte("OpusHead")) {
+			return OutputType{"audio/ogg; codecs=opus", ".opus"}, true
+		}
+		return OutputType{"audio/ogg", ".ogg"}, true
+	}
+	format, ok := SniffFormat(head)
+	if !ok {
+		return OutputType{}, false
+	}
+	outputType, ok := outputTypes[format.Name]
+	return outputType, ok
+}
+
+// DetectOutputFileType identifies the output file at path by its leading bytes
+func DetectOutputFileType(path string) (OutputType, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return OutputType{}, false
+	}
+	defer file.Close()
+
+	head := make([]byte, 64) // The Ogg Opus header sits at byte 28
+	n, _ := io.ReadFull(file, head)
+	return DetectOutputType(head[:n])
+}
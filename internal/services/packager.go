@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/reqid"
+)
+
+// Packaging formats
+const (
+	PackageHLS  = "hls"
+	PackageDASH = "dash"
+)
+
+// uploadedMarker records where a package was uploaded, so cached packages aren't uploaded again
+const uploadedMarker = ".uploaded"
+
+// Packager segments converted videos into HLS playlists or DASH manifests for adaptive streaming
+// Segments are copied from the converted renditions (no re-encode), so they carry the same AF processing
+type Packager struct {
+	root            string // Cache dir; expired packages are deleted below it
+	segmentDuration time.Duration
+	ttl             time.Duration // Packages unused for this long are deleted (0 = kept)
+	uploadURL       string        // Base URL packages are PUT under ("" = uploads disabled)
+	uploadAuth      string        // Authorization header sent with HTTP uploads
+	client          *http.Client
+	remote          *remote.Client // Uploads to ftp:// and sftp:// targets
+	stopCleanup     chan struct{}
+	mu              sync.Mutex
+	stats           PackagerStats
+}
+
+// PackagerStats tracks packaging metrics
+type PackagerStats struct {
+	TotalPackages  int64
+	FailedPackages int64
+	Uploads        int64
+	FailedUploads  int64
+}
+
+// Package is a segmented rendition set on disk
+type Package struct {
+	Format      string
+	Dir         string   // Directory holding the playlists and segments
+	Playlist    string   // Master playlist (HLS) or manifest (DASH)
+	Files       []string // Every file of the package, relative to Dir
+	PlaylistURL string   // Uploaded playlist, when uploaded
+	Uploaded    []string // URLs the last Upload wrote (nil when an earlier upload was reused)
+}
+
+// NewPackager creates a packager that keeps packages under root; uploadURL may be empty to disable uploads
+// ftp:// and sftp:// upload URLs are written through remoteClient
+func NewPackager(root string, segmentDuration, ttl time.Duration, uploadURL, uploadAuth string, uploadTimeout time.Duration, remoteClient *remote.Client) *Packager {
+	if segmentDuration <= 0 {
+		segmentDuration = 6 * time.Second
+	}
+	if uploadTimeout <= 0 {
+		uploadTimeout = 5 * time.Minute
+	}
+	p := &Packager{
+		root:            root,
+		segmentDuration: segmentDuration,
+		ttl:             ttl,
+		uploadURL:       strings.TrimSuffix(uploadURL, "/"),
+		uploadAuth:      uploadAuth,
+		client:          &http.Client{Timeout: uploadTimeout},
+		remote:          remoteClient,
+		stopCleanup:     make(chan struct{}),
+	}
+	if ttl > 0 {
+		go p.cleanupLoop()
+	}
+	return p
+}
+
+// ParsePackaging validates a requested packaging format; empty means none
+func ParsePackaging(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return "", nil
+	case PackageHLS:
+		return PackageHLS, nil
+	case PackageDASH:
+		return PackageDASH, nil
+	default:
+		return "", fmt.Errorf("unsupported packaging %q (supported: hls, dash)", value)
+	}
+}
+
+// CanUpload reports whether an upload target is configured (nil-safe)
+func (p *Packager) CanUpload() bool {
+	return p != nil && p.uploadURL != ""
+}
+
+// Package segments the converted videos at renditions under parentDir and returns the package
+// The same renditions and format always map to the same directory, which is reused while it exists
+func (p *Packager) Package(ctx context.Context, format, parentDir string, renditions []string) (*Package, error) {
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("no video renditions to package")
+	}
+
+	dir := filepath.Join(parentDir, p.packageID(format, renditions))
+	pkg := &Package{Format: format, Dir: dir, Playlist: filepath.Join(dir, playlistName(format))}
+	if _, err := os.Stat(pkg.Playlist); err == nil {
+		// Touch the directory so the janitor keeps packages that are still requested
+		now := time.Now()
+		os.Chtimes(dir, now, now)
+		return pkg, pkg.listFiles()
+	}
+
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create package directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(parentDir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create package directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	switch format {
+	case PackageHLS:
+		err = p.buildHLS(ctx, tmpDir, renditions)
+	case PackageDASH:
+		err = p.buildDASH(ctx, tmpDir, renditions)
+	default:
+		err = fmt.Errorf("unsupported packaging %q", format)
+	}
+	if err != nil {
+		p.record(func(s *PackagerStats) { s.FailedPackages++ })
+		return nil, err
+	}
+
+	// A concurrent request may have finished the same package first; either copy is fine
+	os.Chmod(tmpDir, 0755)
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, statErr := os.Stat(pkg.Playlist); statErr != nil {
+			p.record(func(s *PackagerStats) { s.FailedPackages++ })
+			return nil, fmt.Errorf("failed to store package: %w", err)
+		}
+	}
+	p.record(func(s *PackagerStats) { s.TotalPackages++ })
+	log.Printf("📦 Packaged %s: renditions=%d, dir=%s", format, len(renditions), dir)
+	return pkg, pkg.listFiles()
+}
+
+// packageID derives the package directory name from the format, segment length and renditions
+func (p *Packager) packageID(format string, renditions []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s", format, p.segmentDuration)
+	for _, path := range renditions {
+		fmt.Fprintf(h, "|%s", path)
+	}
+	return format + "_" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// playlistName is the entry point of a package
+func playlistName(format string) string {
+	if format == PackageDASH {
+		return "manifest.mpd"
+	}
+	return "master.m3u8"
+}
+
+// buildHLS segments each rendition into its own media playlist (v0/, v1/, ...) and writes the master playlist
+func (p *Packager) buildHLS(ctx context.Context, dir string, renditions []string) error {
+	var master strings.Builder
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for i, path := range renditions {
+		info, err := ProbeFile(ctx, path)
+		if err != nil {
+			return fmt.Errorf("failed to probe rendition %d: %w", i, err)
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat rendition %d: %w", i, err)
+		}
+
+		name := fmt.Sprintf("v%d", i)
+		subdir := filepath.Join(dir, name)
+		if err := os.MkdirAll(subdir, 0755); err != nil {
+			return fmt.Errorf("failed to create rendition directory: %w", err)
+		}
+		if err := runPackager(ctx,
+			"-i", path,
+			"-map", "0:v", "-map", "0:a?",
+			"-c", "copy",
+			"-f", "hls",
+			"-hls_time", formatSeconds(p.segmentDuration),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(subdir, "seg_%05d.ts"),
+			filepath.Join(subdir, "index.m3u8"),
+		); err != nil {
+			return err
+		}
+
+		// Peak bandwidth is unknown without parsing segments; the average is a usable estimate
+		bandwidth := int64(0)
+		if seconds := info.Duration.Seconds(); seconds > 0 {
+			bandwidth = int64(float64(stat.Size()*8) / seconds)
+		}
+		fmt.Fprintf(&master, "#EXT-X-STREAM-INF:BANDWIDTH=%d", max(bandwidth, 1))
+		if info.Width > 0 && info.Height > 0 {
+			fmt.Fprintf(&master, ",RESOLUTION=%dx%d", info.Width, info.Height)
+		}
+		fmt.Fprintf(&master, "\n%s/index.m3u8\n", name)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, playlistName(PackageHLS)), []byte(master.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write master playlist: %w", err)
+	}
+	return nil
+}
+
+// buildDASH writes one manifest with a video representation per rendition
+// Audio is taken from the first rendition that has an audio track
+func (p *Packager) buildDASH(ctx context.Context, dir string, renditions []string) error {
+	args := []string{}
+	for _, path := range renditions {
+		args = append(args, "-i", path)
+	}
+	for i := range renditions {
+		args = append(args, "-map", fmt.Sprintf("%d:v", i))
+	}
+
+	adaptationSets := "id=0,streams=v"
+	for i, path := range renditions {
+		info, err := ProbeFile(ctx, path)
+		if err != nil {
+			return fmt.Errorf("failed to probe rendition %d: %w", i, err)
+		}
+		if info.HasAudio {
+			args = append(args, "-map", fmt.Sprintf("%d:a", i))
+			adaptationSets += " id=1,streams=a"
+			break
+		}
+	}
+
+	args = append(args,
+		"-c", "copy",
+		"-f", "dash",
+		"-seg_duration", formatSeconds(p.segmentDuration),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-adaptation_sets", adaptationSets,
+		filepath.Join(dir, playlistName(PackageDASH)),
+	)
+	return runPackager(ctx, args...)
+}
+
+// runPackager runs an ffmpeg segmenting command
+func runPackager(ctx context.Context, args ...string) error {
+	cmd := FFmpeg("-hide_banner", "-loglevel", "error", "-y")
+	cmd.Args = append(cmd.Args, args...)
+
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		return ffmpegError(err, stderr)
+	}
+	return nil
+}
+
+// formatSeconds renders d as seconds for ffmpeg options
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// listFiles fills Files with the package's files, relative to Dir
+func (pkg *Package) listFiles() error {
+	pkg.Files = nil
+	return filepath.WalkDir(pkg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(pkg.Dir, path)
+		if err != nil {
+			return err
+		}
+		pkg.Files = append(pkg.Files, filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+// Upload PUTs every file of pkg under the upload URL at prefix and sets PlaylistURL
+// Packages uploaded before are not uploaded again
+func (p *Packager) Upload(ctx context.Context, pkg *Package, prefix string) error {
+	if !p.CanUpload() {
+		return fmt.Errorf("package uploads are not configured")
+	}
+
+	base := p.uploadURL + "/" + strings.Trim(prefix, "/")
+	public := withoutLogin(base) // Callers get the playlist URL, but not the login in the upload URL
+	marker := filepath.Join(pkg.Dir, uploadedMarker)
+	if uploaded, err := os.ReadFile(marker); err == nil && string(uploaded) == public {
+		pkg.PlaylistURL = public + "/" + filepath.Base(pkg.Playlist)
+		return nil
+	}
+
+	pkg.Uploaded = nil
+	for _, file := range pkg.Files {
+		if err := p.put(ctx, base+"/"+file, filepath.Join(pkg.Dir, filepath.FromSlash(file))); err != nil {
+			p.record(func(s *PackagerStats) { s.FailedUploads++ })
+			return &TransientError{Err: fmt.Errorf("failed to upload %s: %w", file, err)}
+		}
+		pkg.Uploaded = append(pkg.Uploaded, public+"/"+file)
+	}
+
+	if err := os.WriteFile(marker, []byte(public), 0644); err != nil {
+		log.Printf("⚠️  Failed to mark package as uploaded: %v", err)
+	}
+	p.record(func(s *PackagerStats) { s.Uploads++ })
+	pkg.PlaylistURL = public + "/" + filepath.Base(pkg.Playlist)
+	log.Printf("☁️  Uploaded package: files=%d, playlist=%s", len(pkg.Files), pkg.PlaylistURL)
+	return nil
+}
+
+// put uploads one file
+func (p *Packager) put(ctx context.Context, url, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if remote.Supported(url) {
+		return p.remote.Put(ctx, url, file)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = stat.Size()
+	req.Header.Set("Content-Type", packageContentType(path))
+	reqid.Apply(ctx, req)
+	if p.uploadAuth != "" {
+		req.Header.Set("Authorization", p.uploadAuth)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// withoutLogin drops the user and password from rawURL
+func withoutLogin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// packageContentType returns the MIME type players expect for a package file
+func packageContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s":
+		return "video/iso.segment"
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// cleanupLoop deletes expired packages every minute until Stop
+func (p *Packager) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.cleanup()
+		case <-p.stopCleanup:
+			return
+		}
+	}
+}
+
+// cleanup deletes packages that haven't been used for the TTL
+// Renditions expire with the cache, so their packages are rebuilt on the next request
+func (p *Packager) cleanup() {
+	cutoff := time.Now().Add(-p.ttl)
+	filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == p.root {
+			return nil
+		}
+		name := d.Name()
+		if !strings.HasPrefix(name, PackageHLS+"_") && !strings.HasPrefix(name, PackageDASH+"_") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if err := os.RemoveAll(path); err != nil {
+				log.Printf("⚠️  Failed to delete package %s: %v", path, err)
+			} else {
+				log.Printf("🗑️  Deleted expired package: %s", path)
+			}
+		}
+		return filepath.SkipDir
+	})
+}
+
+// Stop ends the cleanup loop (nil-safe)
+func (p *Packager) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stopCleanup)
+}
+
+func (p *Packager) record(update func(*PackagerStats)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	update(&p.stats)
+}
+
+// GetStats returns packaging counters (nil-safe)
+func (p *Packager) GetStats() PackagerStats {
+	if p == nil {
+		return PackagerStats{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// probeCache keeps ffprobe results by content hash, so the same source converted for several
+// devices or levels is probed once. Results never go stale (the key is the content), so the
+// least recently used are dropped once the cache is full.
+var probeCache = struct {
+	sync.Mutex
+	size    int                        // Max entries (0 = off)
+	entries map[[32]byte]*list.Element // Content hash -> element in lru
+	lru     *list.List                 // *probeEntry, most recently used first
+	hits    atomic.Int64
+	misses  atomic.Int64
+}{entries: make(map[[32]byte]*list.Element), lru: list.New()}
+
+// probeEntry is one cached probe result
+type probeEntry struct {
+	key  [32]byte
+	info *MediaInfo
+}
+
+// SetProbeCacheSize sets how many probe results are kept (0 = off); shrinking drops the oldest
+func SetProbeCacheSize(size int) {
+	probeCache.Lock()
+	defer probeCache.Unlock()
+	probeCache.size = max(size, 0)
+	for probeCache.lru.Len() > probeCache.size {
+		oldest := probeCache.lru.Remove(probeCache.lru.Back()).(*probeEntry)
+		delete(probeCache.entries, oldest.key)
+	}
+}
+
+// ProbeCacheStats reports probe cache activity
+func ProbeCacheStats() map[string]interface{} {
+	probeCache.Lock()
+	entries, size := probeCache.lru.Len(), probeCache.size
+	probeCache.Unlock()
+	return map[string]interface{}{
+		"entries":  entries,
+		"max_size": size,
+		"hits":     probeCache.hits.Load(),
+		"misses":   probeCache.misses.Load(),
+	}
+}
+
+// ProbeCacheCounts returns the probe cache hits and misses since startup
+func ProbeCacheCounts() (hits, misses int64) {
+	return probeCache.hits.Load(), probeCache.misses.Load()
+}
+
+// probeCached probes data with p, answering repeated contents from the cache
+// Failed probes aren't cached, so a timeout on a busy host doesn't stick to the source
+func probeCached(ctx context.Context, p Prober, data []byte) (*MediaInfo, error) {
+	probeCache.Lock()
+	enabled := probeCache.size > 0
+	probeCache.Unlock()
+	if !enabled {
+		return p.Probe(ctx, data)
+	}
+
+	key := sha256.Sum256(data)
+	probeCache.Lock()
+	if element, ok := probeCache.entries[key]; ok {
+		probeCache.lru.MoveToFront(element)
+		info := element.Value.(*probeEntry).info
+		probeCache.Unlock()
+		probeCache.hits.Add(1)
+		return info.clone(), nil
+	}
+	probeCache.Unlock()
+	probeCache.misses.Add(1)
+
+	info, err := p.Probe(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	probeCache.Lock()
+	defer probeCache.Unlock()
+	if _, ok := probeCache.entries[key]; !ok && probeCache.size > 0 {
+		probeCache.entries[key] = probeCache.lru.PushFront(&probeEntry{key: key, info: info.clone()})
+		if probeCache.lru.Len() > probeCache.size {
+			oldest := probeCache.lru.Remove(probeCache.lru.Back()).(*probeEntry)
+			delete(probeCache.entries, oldest.key)
+		}
+	}
+	return info, nil
+}
+
+// clone copies m, so callers can't change a cached result
+func (m *MediaInfo) clone() *MediaInfo {
+	copied := *m
+	copied.Streams = slices.Clone(m.Streams)
+	copied.Tags = maps.Clone(m.Tags)
+	return &copied
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaInfo is what ffprobe reports about an input
+// Width, Height, Rotation, HasVideo and HasAudio summarize the streams for the common checks
+type MediaInfo struct {
+	Container string        // Demuxer names, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Duration  time.Duration // 0 when unknown (still images)
+	BitRate   int64         // Overall bits per second (0 = unknown)
+	Size      int64         // Bytes
+	Streams   []StreamInfo
+	Tags      map[string]string // Container and stream tags, keys lower-cased (container tags win)
+
+	Width    int // First video stream, as coded (before Rotation)
+	Height   int
+	Rotation int // First video stream, see StreamInfo.Rotation
+	HasVideo bool
+	HasAudio bool
+}
+
+// StreamInfo describes one stream of an input
+type StreamInfo struct {
+	Index       int
+	Type        string // video, audio, subtitle, data, ...
+	Codec       string // e.g. h264, aac, mjpeg
+	Profile     string
+	Duration    time.Duration // 0 when unknown
+	BitRate     int64         // Bits per second (0 = unknown)
+	Width       int
+	Height      int
+	Rotation    int     // Clockwise degrees players turn the picture (0, 90, 180 or 270)
+	FrameRate   float64 // Average frames per second (0 = unknown)
+	PixelFormat string
+	SampleRate  int
+	Channels    int
+}
+
+// VideoBitrate returns the first video stream's bitrate in kbps, or the overall bitrate
+// when the container doesn't record one per stream (MKV, WebM); 0 when neither is known
+func (m *MediaInfo) VideoBitrate() int {
+	for _, stream := range m.Streams {
+		if stream.Type == "video" && stream.BitRate > 0 {
+			return int(stream.BitRate / 1000)
+		}
+	}
+	return int(m.BitRate / 1000)
+}
+
+// AudioBitrate returns the first audio stream's bitrate in kbps and its channel count
+// Audio-only files without a per-stream bitrate (WAV, some Ogg muxes) fall back to the overall bitrate
+func (m *MediaInfo) AudioBitrate() (int, int) {
+	for _, stream := range m.Streams {
+		if stream.Type != "audio" {
+			continue
+		}
+		bitRate := stream.BitRate
+		if bitRate <= 0 && !m.HasVideo {
+			bitRate = m.BitRate
+		}
+		return int(bitRate / 1000), stream.Channels
+	}
+	return 0, 0
+}
+
+// DisplaySize returns the first video stream's dimensions as players show them, after Rotation
+func (m *MediaInfo) DisplaySize() (int, int) {
+	if m.Rotation == 90 || m.Rotation == 270 {
+		return m.Height, m.Width
+	}
+	return m.Width, m.Height
+}
+
+// Prober reads typed media information with ffprobe
+// The zero value is ready to use
+type Prober struct {
+	Timeout time.Duration // Limit per probe on top of the caller's context (0 = none)
+}
+
+// DefaultProber is the prober used by the converters, input limits and output verification
+// ffprobe only reads headers, so a probe that takes longer than this is stuck on a broken input
+var DefaultProber = Prober{Timeout: 30 * time.Second}
+
+// ProbeMedia probes data with DefaultProber
+// Results are cached by content, see SetProbeCacheSize
+func ProbeMedia(ctx context.Context, data []byte) (*MediaInfo, error) {
+	return probeCached(ctx, DefaultProber, data)
+}
+
+// ProbeFile probes a file on disk with DefaultProber
+func ProbeFile(ctx context.Context, path string) (*MediaInfo, error) {
+	return DefaultProber.ProbeFile(ctx, path)
+}
+
+// Probe reads the media information of data
+// The input is staged in a temp file so containers with trailing indexes (MP4 moov at end) probe correctly
+func (p Prober) Probe(ctx context.Context, data []byte) (*MediaInfo, error) {
+	tmp, err := os.CreateTemp("", "probe-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write probe file: %w", err)
+	}
+	return p.ProbeFile(ctx, tmp.Name())
+}
+
+// ProbeFile reads the media information of a file on disk
+// Inputs ffprobe can't parse return ErrInvalidMedia
+func (p Prober) ProbeFile(ctx context.Context, path string) (*MediaInfo, error) {
+	cmd := FFprobe(
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-of", "json",
+		path,
+	)
+	cmd.Timeout = p.Timeout
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	stderr, err := RunCommand(ctx, cmd)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			log.Printf("⚠️  ffprobe rejected input: %s", lastLine(stderr))
+			reason := DescribeFFmpegFailure(stderr)
+			if reason == ErrInvalidMedia.Error() {
+				return nil, ErrInvalidMedia
+			}
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMedia, reason)
+		}
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbe(output.Bytes())
+}
+
+// probeOutput is the part of ffprobe's JSON output MediaInfo is built from
+// ffprobe prints most numbers as strings
+type probeOutput struct {
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Size       string            `json:"size"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index        int               `json:"index"`
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Profile      string            `json:"profile"`
+		Duration     string            `json:"duration"`
+		BitRate      string            `json:"bit_rate"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		PixFmt       string            `json:"pix_fmt"`
+		SampleRate   string            `json:"sample_rate"`
+		Channels     int               `json:"channels"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// parseProbe builds MediaInfo from ffprobe's JSON output
+func parseProbe(output []byte) (*MediaInfo, error) {
+	var probe probeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{
+		Container: probe.Format.FormatName,
+		Duration:  parseSeconds(probe.Format.Duration),
+		BitRate:   parseInt64(probe.Format.BitRate),
+		Size:      parseInt64(probe.Format.Size),
+		Streams:   make([]StreamInfo, 0, len(probe.Streams)),
+		Tags:      make(map[string]string),
+	}
+	for key, value := range probe.Format.Tags {
+		info.Tags[strings.ToLower(key)] = value
+	}
+	for _, s := range probe.Streams {
+		stream := StreamInfo{
+			Index:       s.Index,
+			Type:        s.CodecType,
+			Codec:       s.CodecName,
+			Profile:     s.Profile,
+			Duration:    parseSeconds(s.Duration),
+			BitRate:     parseInt64(s.BitRate),
+			Width:       s.Width,
+			Height:      s.Height,
+			PixelFormat: s.PixFmt,
+			SampleRate:  int(parseInt64(s.SampleRate)),
+			Channels:    s.Channels,
+		}
+		if s.CodecType == "video" {
+			stream.FrameRate = parseRatio(s.AvgFrameRate)
+
+			// Display matrix side data (newer ffmpeg) is counter-clockwise; the legacy rotate tag is clockwise
+			if degrees, err := strconv.Atoi(s.Tags["rotate"]); err == nil {
+				stream.Rotation = ((degrees % 360) + 360) % 360
+			}
+			for _, side := range s.SideDataList {
+				if side.Rotation != 0 {
+					stream.Rotation = ((-int(side.Rotation) % 360) + 360) % 360
+				}
+			}
+		}
+		info.Streams = append(info.Streams, stream)
+
+		// Ogg keeps its comments on the stream
+		for key, value := range s.Tags {
+			if key = strings.ToLower(key); info.Tags[key] == "" {
+				info.Tags[key] = value
+			}
+		}
+
+		switch s.CodecType {
+		case "audio":
+			info.HasAudio = true
+		case "video":
+			if !info.HasVideo {
+				info.HasVideo = true
+				info.Width, info.Height, info.Rotation = stream.Width, stream.Height, stream.Rotation
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseSeconds parses an ffprobe duration ("12.345000"); 0 when absent or "N/A"
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parseInt64 parses an ffprobe integer field; 0 when absent or "N/A"
+func parseInt64(value string) int64 {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+// parseRatio parses an ffprobe rational ("30000/1001"); 0 when absent or "0/0"
+func parseRatio(value string) float64 {
+	num, den, ok := strings.Cut(value, "/")
+	if !ok {
+		rate, _ := strconv.ParseFloat(value, 64)
+		return rate
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reapInterval is how often the reaper looks for overdue processes
+const reapInterval = time.Second
+
+// trackedProcess is a running ffmpeg or ffprobe process and what it was started for
+type trackedProcess struct {
+	program  string
+	deadline time.Time       // Zero = no deadline
+	done     <-chan struct{} // Closed when the conversion that started it is cancelled
+
+	// Set by the reaper
+	orphanedAt time.Time // When the cancellation was first seen
+	reaped     bool
+}
+
+// processes tracks the ffmpeg and ffprobe processes LocalExecutor started, until they exit
+var processes = struct {
+	sync.Mutex
+	running map[*exec.Cmd]*trackedProcess
+}{running: make(map[*exec.Cmd]*trackedProcess)}
+
+// reapedProcesses counts processes killed by the reaper since startup
+var reapedProcesses atomic.Int64
+
+// trackProcess records a started process with the context it serves
+func trackProcess(ctx context.Context, program string, process *exec.Cmd) {
+	tracked := &trackedProcess{program: program, done: ctx.Done()}
+	if deadline, ok := ctx.Deadline(); ok {
+		tracked.deadline = deadline
+	}
+	processes.Lock()
+	processes.running[process] = tracked
+	processes.Unlock()
+}
+
+// untrackProcess forgets a process once it has exited
+func untrackProcess(process *exec.Cmd) {
+	processes.Lock()
+	delete(processes.running, process)
+	processes.Unlock()
+}
+
+// RunningProcesses returns how many ffmpeg and ffprobe processes are running
+func RunningProcesses() int {
+	processes.Lock()
+	defer processes.Unlock()
+	return len(processes.running)
+}
+
+// ReapedProcesses returns how many processes the reaper has killed since startup
+func ReapedProcesses() int64 {
+	return reapedProcesses.Load()
+}
+
+// WaitProcesses waits until every ffmpeg and ffprobe process has exited, or ctx ends
+func WaitProcesses(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for RunningProcesses() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// KillProcesses kills every running ffmpeg and ffprobe process and returns how many there were
+// Used at the end of the shutdown grace period, so no child outlives the server
+func KillProcesses() int {
+	processes.Lock()
+	defer processes.Unlock()
+	for process := range processes.running {
+		killProcessGroup(process)
+	}
+	return len(processes.running)
+}
+
+// StartReaper kills, every second, the process group of any ffmpeg or ffprobe process still running
+// grace after its deadline passed or its conversion was cancelled
+// Normally cancellation kills them right away; this catches the ones that survive it
+func StartReaper(grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reap(grace, time.Now())
+		}
+	}()
+}
+
+// reap kills the processes overdue by more than grace at now
+func reap(grace time.Duration, now time.Time) {
+	processes.Lock()
+	defer processes.Unlock()
+
+	for process, tracked := range processes.running {
+		if tracked.reaped {
+			continue
+		}
+
+		reason := ""
+		switch {
+		case !tracked.deadline.IsZero() && now.Sub(tracked.deadline) > grace:
+			reason = "its deadline"
+		case tracked.orphanedAt.IsZero():
+			select {
+			case <-tracked.done:
+				tracked.orphanedAt = now
+			default:
+			}
+		case now.Sub(tracked.orphanedAt) > grace:
+			reason = "its conversion was cancelled"
+		}
+		if reason == "" {
+			continue
+		}
+
+		tracked.reaped = true
+		if err := killProcessGroup(process); err != nil {
+			log.Printf("⚠️  Failed to reap %s (pid %d): %v", tracked.program, process.Process.Pid, err)
+			continue
+		}
+		reapedProcesses.Add(1)
+		log.Printf("🧟 Reaped %s (pid %d): still running %s after %s", tracked.program, process.Process.Pid, grace, reason)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package services
+
+import "os/exec"
+
+// startProcessGroup is a no-op without process groups; cancellation kills the process only
+func startProcessGroup(process *exec.Cmd) {}
+
+// killProcessGroup kills a started process
+func killProcessGroup(process *exec.Cmd) error {
+	return process.Process.Kill()
+}
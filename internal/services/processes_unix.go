@@ -0,0 +1,22 @@
+//go:build unix
+
+package services
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup runs process in a process group of its own, and makes cancellation kill the
+// whole group, so nothing ffmpeg spawned is left behind
+func startProcessGroup(process *exec.Cmd) {
+	process.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	process.Cancel = func() error {
+		return killProcessGroup(process)
+	}
+}
+
+// killProcessGroup kills the process group of a started process
+func killProcessGroup(process *exec.Cmd) error {
+	return syscall.Kill(-process.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+)
+
+// profileVersion holds the version of the ranges and profiles in effect; set by their setters
+var profileVersion atomic.Pointer[string]
+
+// ProfileVersion identifies the AF ranges and named profiles in effect
+// It changes whenever either does, so outputs encoded with older settings can be told apart
+func ProfileVersion() string {
+	if version := profileVersion.Load(); version != nil {
+		return *version
+	}
+	return computeProfileVersion()
+}
+
+// updateProfileVersion recomputes the version after the ranges or profiles changed
+func updateProfileVersion() {
+	version := computeProfileVersion()
+	profileVersion.Store(&version)
+}
+
+// computeProfileVersion hashes the ranges and profiles in effect
+func computeProfileVersion() string {
+	var named map[string]AFProfile
+	if p := profiles.Load(); p != nil {
+		named = *p
+	}
+	// Maps marshal with sorted keys, so equal settings always hash the same
+	data, _ := json.Marshal(struct {
+		Ranges   AFRanges
+		Profiles map[string]AFProfile
+	}{currentAFRanges(), named})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
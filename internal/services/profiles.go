@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// AFProfile is a named set of AF levels, defined in the config file's profiles section
+// Level applies to every media type unless a per-media level is set
+type AFProfile struct {
+	Level      string   `yaml:"level" json:"level,omitempty"`
+	Audio      string   `yaml:"audio" json:"audio,omitempty"`
+	Image      string   `yaml:"image" json:"image,omitempty"`
+	Video      string   `yaml:"video" json:"video,omitempty"`
+	Techniques []string `yaml:"techniques" json:"techniques,omitempty"` // Extra AF techniques, each applied to the media types it supports
+
+	AudioBitrateKbps *IntRange `yaml:"audio_bitrate_kbps" json:"audio_bitrate_kbps,omitempty"` // Floor-ceiling of the source-scaled audio bitrate (default 32-128)
+}
+
+// Validate checks that every level and technique in the profile is known
+func (p AFProfile) Validate() error {
+	for _, level := range []string{p.Level, p.Audio, p.Image, p.Video} {
+		if level != "" && !IsValidLevel(level) {
+			return fmt.Errorf("invalid AF level %q (none, basic, moderate, paranoid)", level)
+		}
+	}
+	for _, name := range p.Techniques {
+		if _, ok := Technique(name); !ok {
+			return fmt.Errorf("unknown technique %q (registered: %s)", name, strings.Join(TechniqueNames(), ", "))
+		}
+	}
+	if bounds := p.AudioBitrateKbps; bounds != nil {
+		if err := ValidateAudioBitrateBounds(*bounds); err != nil {
+			return fmt.Errorf("audio_bitrate_kbps: %w", err)
+		}
+	}
+	return nil
+}
+
+// AudioBitrateBounds returns the profile's audio bitrate floor and ceiling (zero when it sets none)
+func (p AFProfile) AudioBitrateBounds() IntRange {
+	if p.AudioBitrateKbps == nil {
+		return IntRange{}
+	}
+	return *p.AudioBitrateKbps
+}
+
+// TechniquesFor returns the profile's techniques that apply to mediaType, in order
+func (p AFProfile) TechniquesFor(mediaType string) []string {
+	var names []string
+	for _, name := range p.Techniques {
+		if t, ok := Technique(name); ok && slices.Contains(t.MediaTypes(), mediaType) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LevelFor returns the profile's level for mediaType ("" when the profile doesn't set one)
+func (p AFProfile) LevelFor(mediaType string) string {
+	var level string
+	switch mediaType {
+	case "audio":
+		level = p.Audio
+	case "image":
+		level = p.Image
+	case "video":
+		level = p.Video
+	}
+	if level == "" {
+		level = p.Level
+	}
+	return level
+}
+
+// profiles holds the named AF profiles; swapped as a whole on reload
+var profiles atomic.Pointer[map[string]AFProfile]
+
+// SetProfiles replaces the named AF profiles (safe to call at runtime)
+func SetProfiles(named map[string]AFProfile) error {
+	for name, profile := range named {
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	profiles.Store(&named)
+	updateProfileVersion()
+	return nil
+}
+
+// Profile returns the named AF profile
+func Profile(name string) (AFProfile, bool) {
+	named := profiles.Load()
+	if named == nil {
+		return AFProfile{}, false
+	}
+	profile, ok := (*named)[name]
+	return profile, ok
+}
+
+// ProfileNames returns the defined profile names, sorted
+func ProfileNames() []string {
+	named := profiles.Load()
+	if named == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*named))
+	for name := range *named {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QualityCheck configures the comparison of outputs against their source (zero = only on request)
+type QualityCheck struct {
+	Always  bool    // Measure every image/video conversion, not only requests asking for it
+	VMAF    bool    // Also compute VMAF when ffmpeg is built with libvmaf
+	MinSSIM float64 // Minimum scores; conversions below any of them fail (0 = no minimum)
+	MinPSNR float64
+	MinVMAF float64
+}
+
+// QualityScores compares an output with its source; higher is closer
+type QualityScores struct {
+	SSIM float64  // 0-1
+	PSNR float64  // dB, capped at maxPSNR for identical frames
+	VMAF *float64 // 0-100, nil when libvmaf is unavailable or disabled
+}
+
+// QualityError reports an output whose score is below the configured minimum
+type QualityError struct {
+	Metric string
+	Score  float64
+	Min    float64
+}
+
+func (e *QualityError) Error() string {
+	return fmt.Sprintf("%s %.4g is below the minimum of %.4g", e.Metric, e.Score, e.Min)
+}
+
+// maxPSNR stands in for the infinite PSNR ffmpeg reports when frames are identical
+const maxPSNR = 100
+
+// Enforced reports whether any minimum is set, which makes measuring mandatory
+func (q QualityCheck) Enforced() bool {
+	return q.MinSSIM > 0 || q.MinPSNR > 0 || q.MinVMAF > 0
+}
+
+// Wanted reports whether a conversion of mediaType should be measured
+func (q QualityCheck) Wanted(mediaType string, requested bool) bool {
+	if mediaType != "image" && mediaType != "video" {
+		return false
+	}
+	return requested || q.Always || q.Enforced()
+}
+
+// Check compares scores with the minimums; VMAF is only checked when it was measured
+func (q QualityCheck) Check(scores *QualityScores) error {
+	switch {
+	case q.MinSSIM > 0 && scores.SSIM < q.MinSSIM:
+		return &QualityError{Metric: "SSIM", Score: scores.SSIM, Min: q.MinSSIM}
+	case q.MinPSNR > 0 && scores.PSNR < q.MinPSNR:
+		return &QualityError{Metric: "PSNR", Score: scores.PSNR, Min: q.MinPSNR}
+	case q.MinVMAF > 0 && scores.VMAF != nil && *scores.VMAF < q.MinVMAF:
+		return &QualityError{Metric: "VMAF", Score: *scores.VMAF, Min: q.MinVMAF}
+	}
+	return nil
+}
+
+var (
+	ssimPattern = regexp.MustCompile(`SSIM .*All:([0-9.]+)`)
+	psnrPattern = regexp.MustCompile(`PSNR .*average:([0-9.]+|inf)`)
+	vmafPattern = regexp.MustCompile(`VMAF score: ([0-9.]+)`)
+
+	vmafOnce      sync.Once
+	vmafSupported bool
+)
+
+// vmafAvailable reports whether the local ffmpeg has the libvmaf filter (checked once)
+func vmafAvailable() bool {
+	vmafOnce.Do(func() {
+		var output bytes.Buffer
+		cmd := FFmpeg("-hide_banner", "-filters")
+		cmd.Stdout = &output
+		cmd.Timeout = 10 * time.Second
+		_, err := RunCommand(context.Background(), cmd)
+		vmafSupported = err == nil && bytes.Contains(output.Bytes(), []byte(" libvmaf "))
+		if !vmafSupported {
+			log.Printf("⚠️  ffmpeg has no libvmaf filter, VMAF scores are skipped")
+		}
+	})
+	return vmafSupported
+}
+
+// Measure scores the output at outputPath against source with ffmpeg's ssim, psnr and libvmaf filters
+// The source is scaled to the output's size first, so downscaled outputs are compared fairly
+func (q QualityCheck) Measure(ctx context.Context, source []byte, outputPath string) (*QualityScores, error) {
+	// Staged like ProbeMedia: MP4 sources with the moov atom at the end can't be read from a pipe
+	tmp, err := os.CreateTemp("", "quality-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quality source file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(source)
+	tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write quality source file: %w", err)
+	}
+
+	metrics := []string{"ssim", "psnr"}
+	if q.VMAF && vmafAvailable() {
+		metrics = append(metrics, "libvmaf")
+	}
+
+	// Each metric filter consumes its inputs, so both streams are split once per metric
+	n := len(metrics)
+	graph := []string{
+		"[0:v]format=yuv420p[out]",
+		"[1:v]format=yuv420p[src]",
+		"[src][out]scale2ref=flags=bicubic[ref][dist]",
+		fmt.Sprintf("[dist]split=%d%s", n, labels("d", n)),
+		fmt.Sprintf("[ref]split=%d%s", n, labels("r", n)),
+	}
+	for i, metric := range metrics {
+		graph = append(graph, fmt.Sprintf("[d%d][r%d]%s", i, i, metric))
+	}
+
+	cmd := FFmpeg(
+		"-hide_banner",
+		"-nostats",
+		"-loglevel", "info", // Scores are only printed at info level
+		"-i", outputPath,
+		"-i", tmp.Name(),
+		"-filter_complex", strings.Join(graph, ";"),
+		"-f", "null", "-",
+	)
+	stderr, err := RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, ffmpegError(err, stderr)
+	}
+	return parseQualityScores(stderr)
+}
+
+// labels builds split outputs like [d0][d1]
+func labels(prefix string, n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "[%s%d]", prefix, i)
+	}
+	return b.String()
+}
+
+// parseQualityScores reads the summary lines the metric filters log when they finish
+func parseQualityScores(stderr string) (*QualityScores, error) {
+	scores := &QualityScores{}
+
+	match := ssimPattern.FindStringSubmatch(stderr)
+	if match == nil {
+		return nil, fmt.Errorf("ffmpeg reported no SSIM score")
+	}
+	scores.SSIM, _ = strconv.ParseFloat(match[1], 64)
+
+	match = psnrPattern.FindStringSubmatch(stderr)
+	if match == nil {
+		return nil, fmt.Errorf("ffmpeg reported no PSNR score")
+	}
+	scores.PSNR = maxPSNR
+	if match[1] != "inf" {
+		psnr, _ := strconv.ParseFloat(match[1], 64)
+		scores.PSNR = min(psnr, maxPSNR)
+	}
+
+	if match = vmafPattern.FindStringSubmatch(stderr); match != nil {
+		vmaf, _ := strconv.ParseFloat(match[1], 64)
+		scores.VMAF = &vmaf
+	}
+	return scores, nil
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// IntRange is an inclusive range a randomized parameter is drawn from
+// Written as "min-max", or a single number for a fixed value
+type IntRange struct {
+	Min int
+	Max int
+}
+
+// Pick draws a value from the range
+func (r IntRange) Pick(rng RNG) int {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rng.IntN(r.Max-r.Min+1)
+}
+
+// Enabled reports whether the range can produce a non-zero value
+func (r IntRange) Enabled() bool {
+	return r.Max > 0
+}
+
+// MarshalText formats the range as "min-max"
+func (r IntRange) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d-%d", r.Min, r.Max)), nil
+}
+
+// UnmarshalText parses "min-max" or a single number
+func (r *IntRange) UnmarshalText(text []byte) error {
+	low, high, err := splitRange(string(text))
+	if err != nil {
+		return err
+	}
+	if r.Min, err = strconv.Atoi(low); err != nil {
+		return fmt.Errorf("invalid range %q: %w", text, err)
+	}
+	if r.Max, err = strconv.Atoi(high); err != nil {
+		return fmt.Errorf("invalid range %q: %w", text, err)
+	}
+	return r.validate()
+}
+
+// CheckBounds fails unless the range lies within bounds ("low-high", from a field's bounds tag)
+func (r IntRange) CheckBounds(bounds string) error {
+	low, high, err := parseBounds(bounds)
+	if err != nil {
+		return err
+	}
+	if float64(r.Min) < low || float64(r.Max) > high {
+		return fmt.Errorf("range %d-%d must lie within %s", r.Min, r.Max, bounds)
+	}
+	return nil
+}
+
+func (r IntRange) validate() error {
+	if r.Min < 0 || r.Max < r.Min {
+		return fmt.Errorf("range %d-%d must satisfy 0 <= min <= max", r.Min, r.Max)
+	}
+	return nil
+}
+
+// FloatRange is a range a randomized parameter is drawn from uniformly
+// Written as "min-max", or a single number for a fixed value
+type FloatRange struct {
+	Min float64
+	Max float64
+}
+
+// Pick draws a value from the range
+func (r FloatRange) Pick(rng RNG) float64 {
+	return r.Min + rng.Float64()*(r.Max-r.Min)
+}
+
+// Enabled reports whether the range can produce a non-zero value
+func (r FloatRange) Enabled() bool {
+	return r.Max > 0
+}
+
+// MarshalText formats the range as "min-max"
+func (r FloatRange) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%g-%g", r.Min, r.Max)), nil
+}
+
+// UnmarshalText parses "min-max" or a single number
+func (r *FloatRange) UnmarshalText(text []byte) error {
+	low, high, err := splitRange(string(text))
+	if err != nil {
+		return err
+	}
+	if r.Min, err = strconv.ParseFloat(low, 64); err != nil {
+		return fmt.Errorf("invalid range %q: %w", text, err)
+	}
+	if r.Max, err = strconv.ParseFloat(high, 64); err != nil {
+		return fmt.Errorf("invalid range %q: %w", text, err)
+	}
+	return r.validate()
+}
+
+// CheckBounds fails unless the range lies within bounds ("low-high", from a field's bounds tag)
+func (r FloatRange) CheckBounds(bounds string) error {
+	low, high, err := parseBounds(bounds)
+	if err != nil {
+		return err
+	}
+	if r.Min < low || r.Max > high {
+		return fmt.Errorf("range %g-%g must lie within %s", r.Min, r.Max, bounds)
+	}
+	return nil
+}
+
+func (r FloatRange) validate() error {
+	if r.Min < 0 || r.Max < r.Min {
+		return fmt.Errorf("range %g-%g must satisfy 0 <= min <= max", r.Min, r.Max)
+	}
+	return nil
+}
+
+// Deviation is the maximum distance from a neutral value (e.g. ±0.002 around 1.0)
+// 0 disables the adjustment
+type Deviation float64
+
+// Pick draws an offset in [-d, d]
+func (d Deviation) Pick(rng RNG) float64 {
+	return (rng.Float64()*2 - 1) * float64(d)
+}
+
+// UnmarshalText parses a non-negative number
+func (d *Deviation) UnmarshalText(text []byte) error {
+	value, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(string(text)), "±"), 64)
+	if err != nil || value < 0 {
+		return fmt.Errorf("invalid deviation %q (expected a non-negative number)", text)
+	}
+	*d = Deviation(value)
+	return nil
+}
+
+// CheckBounds fails unless the deviation lies within bounds ("low-high", from a field's bounds tag)
+func (d Deviation) CheckBounds(bounds string) error {
+	low, high, err := parseBounds(bounds)
+	if err != nil {
+		return err
+	}
+	if float64(d) < low || float64(d) > high {
+		return fmt.Errorf("deviation %g must lie within %s", float64(d), bounds)
+	}
+	return nil
+}
+
+// parseBounds parses a bounds tag such as "0-51"
+func parseBounds(bounds string) (float64, float64, error) {
+	var r FloatRange
+	if err := r.UnmarshalText([]byte(bounds)); err != nil {
+		return 0, 0, fmt.Errorf("invalid bounds %q: %w", bounds, err)
+	}
+	return r.Min, r.Max, nil
+}
+
+// splitRange splits "min-max" (or "n", meaning n-n) into its bounds
+func splitRange(text string) (string, string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", "", fmt.Errorf("empty range")
+	}
+	low, high, found := strings.Cut(text, "-")
+	if !found {
+		return text, text, nil
+	}
+	return strings.TrimSpace(low), strings.TrimSpace(high), nil
+}
+
+// AudioRanges are the randomization ranges of one AF level for audio
+// bounds tags hold what the encoders and filters accept; overrides outside them are rejected
+type AudioRanges struct {
+	BitrateKbps    IntRange   `af:"BITRATE_KBPS" bounds:"8-320" json:"bitrate_kbps"`   // Used when the source bitrate is unknown
+	BitrateJitter  FloatRange `af:"BITRATE_JITTER" bounds:"0-1" json:"bitrate_jitter"` // Fraction of the source-scaled bitrate
+	Compression    IntRange   `af:"COMPRESSION" bounds:"0-10" json:"compression"`      // Opus compression level (mp3 maps 7-10 to 0-3)
+	SilencePadding IntRange   `af:"SILENCE_PADDING_MS" json:"silence_padding_ms"`      // Leading silence in milliseconds
+	PitchShift     Deviation  `af:"PITCH_SHIFT" bounds:"0-0.5" json:"pitch_shift"`     // Around 1.0; 0 = off
+	NoiseLevel     FloatRange `af:"NOISE_LEVEL" bounds:"0-1" json:"noise_level"`       // 0 = off
+}
+
+// ImageRanges are the randomization ranges of one AF level for images
+type ImageRanges struct {
+	Quality          IntRange   `af:"QUALITY" bounds:"0-100" json:"quality"`                   // WebP quality
+	CompressionLevel IntRange   `af:"COMPRESSION_LEVEL" bounds:"0-9" json:"compression_level"` // PNG compression
+	JPEGQScale       IntRange   `af:"JPEG_QSCALE" bounds:"1-31" json:"jpeg_qscale"`
+	Noise            IntRange   `af:"NOISE" bounds:"0-100" json:"noise"`         // Noise strength for JPEG/WebP; 0 = off
+	NoisePNG         IntRange   `af:"NOISE_PNG" bounds:"0-100" json:"noise_png"` // PNG is more sensitive to noise
+	Brightness       Deviation  `af:"BRIGHTNESS" bounds:"0-1" json:"brightness"`
+	Contrast         Deviation  `af:"CONTRAST" bounds:"0-1" json:"contrast"`
+	Blur             FloatRange `af:"BLUR" bounds:"0-5" json:"blur"`                  // unsharp amount; 0 = off
+	AVIFCRF          IntRange   `af:"AVIF_CRF" bounds:"0-63" json:"avif_crf"`         // AVIF quality (0-63, lower is better)
+	JXLDistance      FloatRange `af:"JXL_DISTANCE" bounds:"0-25" json:"jxl_distance"` // JPEG XL Butteraugli distance (1.0 = visually lossless)
+}
+
+// VideoRanges are the randomization ranges of one AF level for video
+type VideoRanges struct {
+	BitrateJitter    FloatRange `af:"BITRATE_JITTER" bounds:"0-1" json:"bitrate_jitter"` // Fraction of the source bitrate
+	CRF              IntRange   `af:"CRF" bounds:"0-51" json:"crf"`
+	KeyframeInterval IntRange   `af:"KEYFRAME_INTERVAL" bounds:"1-600" json:"keyframe_interval"`
+	Noise            IntRange   `af:"NOISE" bounds:"0-100" json:"noise"` // 0 = off
+	Brightness       Deviation  `af:"BRIGHTNESS" bounds:"0-1" json:"brightness"`
+	Contrast         Deviation  `af:"CONTRAST" bounds:"0-1" json:"contrast"`
+	Saturation       Deviation  `af:"SATURATION" bounds:"0-1" json:"saturation"`
+}
+
+// LevelRanges holds ranges for each randomizing AF level ("none" is never randomized)
+type LevelRanges[T any] struct {
+	Basic    T `af:"BASIC" json:"basic"`
+	Moderate T `af:"MODERATE" json:"moderate"`
+	Paranoid T `af:"PARANOID" json:"paranoid"`
+}
+
+// For returns the ranges of level; ok is false for "none" and unknown levels
+func (l LevelRanges[T]) For(level string) (ranges T, ok bool) {
+	switch level {
+	case "basic":
+		return l.Basic, true
+	case "moderate":
+		return l.Moderate, true
+	case "paranoid":
+		return l.Paranoid, true
+	}
+	return ranges, false
+}
+
+// AFRanges holds every tunable randomization range
+// Each one is set with AF_<MEDIA>_<LEVEL>_<PARAM>, e.g. AF_VIDEO_PARANOID_CRF=21-25
+type AFRanges struct {
+	Audio LevelRanges[AudioRanges] `af:"AUDIO" json:"audio"`
+	Image LevelRanges[ImageRanges] `af:"IMAGE" json:"image"`
+	Video LevelRanges[VideoRanges] `af:"VIDEO" json:"video"`
+}
+
+// DefaultAFRanges returns the built-in ranges
+func DefaultAFRanges() AFRanges {
+	return AFRanges{
+		Audio: LevelRanges[AudioRanges]{
+			Basic: AudioRanges{
+				BitrateKbps:    IntRange{70, 74},
+				BitrateJitter:  FloatRange{0.02, 0.04},
+				Compression:    IntRange{8, 10},
+				SilencePadding: IntRange{1, 3},
+			},
+			Moderate: AudioRanges{
+				BitrateKbps:    IntRange{70, 74},
+				BitrateJitter:  FloatRange{0.02, 0.05},
+				Compression:    IntRange{8, 10},
+				SilencePadding: IntRange{1, 3},
+				PitchShift:     0.001,
+			},
+			Paranoid: AudioRanges{
+				BitrateKbps:    IntRange{68, 76},
+				BitrateJitter:  FloatRange{0.04, 0.08},
+				Compression:    IntRange{7, 10},
+				SilencePadding: IntRange{1, 5},
+				PitchShift:     0.002,
+				NoiseLevel:     FloatRange{0.0005, 0.0006},
+			},
+		},
+		Image: LevelRanges[ImageRanges]{
+			Basic: ImageRanges{
+				Quality:          IntRange{88, 92},
+				CompressionLevel: IntRange{5, 7},
+				JPEGQScale:       IntRange{3, 4},
+				AVIFCRF:          IntRange{28, 30},
+				JXLDistance:      FloatRange{0.9, 1.1},
+			},
+			Moderate: ImageRanges{
+				Quality:          IntRange{88, 92},
+				CompressionLevel: IntRange{5, 7},
+				JPEGQScale:       IntRange{3, 4},
+				Noise:            IntRange{2, 4},
+				NoisePNG:         IntRange{1, 2},
+				Brightness:       0.001,
+				Contrast:         0.001,
+				AVIFCRF:          IntRange{27, 31},
+				JXLDistance:      FloatRange{0.8, 1.2},
+			},
+			Paranoid: ImageRanges{
+				Quality:          IntRange{85, 92},
+				CompressionLevel: IntRange{4, 7},
+				JPEGQScale:       IntRange{2, 4},
+				Noise:            IntRange{3, 7},
+				NoisePNG:         IntRange{1, 3},
+				Brightness:       0.002,
+				Contrast:         0.002,
+				Blur:             FloatRange{0.1, 0.14},
+				AVIFCRF:          IntRange{26, 32},
+				JXLDistance:      FloatRange{0.7, 1.4},
+			},
+		},
+		Video: LevelRanges[VideoRanges]{
+			Basic: VideoRanges{
+				BitrateJitter:    FloatRange{0.05, 0.10},
+				CRF:              IntRange{22, 24},
+				KeyframeInterval: IntRange{240, 260},
+			},
+			Moderate: VideoRanges{
+				BitrateJitter:    FloatRange{0.08, 0.12},
+				CRF:              IntRange{22, 25},
+				KeyframeInterval: IntRange{230, 270},
+				Noise:            IntRange{1, 2},
+				Brightness:       0.001,
+				Contrast:         0.001,
+				Saturation:       0.001,
+			},
+			Paranoid: VideoRanges{
+				BitrateJitter:    FloatRange{0.10, 0.15},
+				CRF:              IntRange{21, 25},
+				KeyframeInterval: IntRange{220, 280},
+				Noise:            IntRange{2, 5},
+				Brightness:       0.002,
+				Contrast:         0.002,
+				Saturation:       0.002,
+			},
+		},
+	}
+}
+
+// afRanges holds the ranges in effect; nil means DefaultAFRanges
+var afRanges atomic.Pointer[AFRanges]
+
+// SetAFRanges replaces the randomization ranges (safe to call at runtime)
+func SetAFRanges(ranges AFRanges) {
+	afRanges.Store(&ranges)
+	updateProfileVersion()
+}
+
+// currentAFRanges returns the ranges in effect
+func currentAFRanges() AFRanges {
+	if ranges := afRanges.Load(); ranges != nil {
+		return *ranges
+	}
+	return DefaultAFRanges()
+}
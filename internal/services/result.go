@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// ConversionResult describes a finished conversion
+type ConversionResult struct {
+	Path       string        // Where the output was written
+	Format     string        // opus, mp3, jpeg, png, webp, avif, jxl or mp4
+	Duration   time.Duration // The whole Convert call, input probing included
+	FFmpegTime time.Duration // Spent running ffmpeg
+	Width      int           // Image/video output, as coded (0 = unknown)
+	Height     int
+	Params     map[string]string // Encoder settings drawn for the AF level, e.g. "crf": "23"
+}
+
+// measure fills in the output dimensions by probing the written file; left 0 when that fails
+func (r *ConversionResult) measure(ctx context.Context) {
+	if info, err := ProbeFile(ctx, r.Path); err == nil {
+		r.Width, r.Height = info.Width, info.Height
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	crand "crypto/rand"
+	"math/rand/v2"
+	"sync"
+)
+
+// RNG is the random source behind every AF parameter
+// Implementations must be safe for concurrent use
+type RNG interface {
+	IntN(n int) int
+	Float64() float64
+}
+
+// lockedRNG serializes access to a *rand.Rand, which is not goroutine-safe
+type lockedRNG struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewRNG returns a ChaCha8 source seeded from crypto/rand, or a deterministic
+// PCG source when seed is non-zero (reproducible output for debugging and tests)
+func NewRNG(seed uint64) RNG {
+	if seed != 0 {
+		return &lockedRNG{rnd: rand.New(rand.NewPCG(seed, seed))}
+	}
+
+	var key [32]byte
+	crand.Read(key[:]) // Never fails on supported platforms
+	return &lockedRNG{rnd: rand.New(rand.NewChaCha8(key))}
+}
+
+// IntN returns a value in [0, n); n must be positive
+func (r *lockedRNG) IntN(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.IntN(n)
+}
+
+// Float64 returns a value in [0, 1)
+func (r *lockedRNG) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Float64()
+}
+
+// orDefaultRNG substitutes a crypto-seeded source for a nil RNG
+func orDefaultRNG(rng RNG) RNG {
+	if rng == nil {
+		return NewRNG(0)
+	}
+	return rng
+}
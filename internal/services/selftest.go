@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// selfTestArgs encode a generated clip with each converter's codec, so a broken ffmpeg
+// build (missing encoder, unloadable library) fails the same way real conversions do
+var selfTestArgs = map[string][]string{
+	"audio": {"-f", "lavfi", "-i", "sine=duration=0.2", "-c:a", "libopus", "-ar", "48000"},
+	"image": {"-f", "lavfi", "-i", "testsrc=size=64x64:duration=0.04", "-frames:v", "1", "-c:v", "mjpeg"},
+	"video": {"-f", "lavfi", "-i", "testsrc=size=64x64:duration=0.2", "-c:v", "libx264", "-pix_fmt", "yuv420p"},
+}
+
+// SelfTest runs a tiny synthetic encode for mediaType without touching client input
+func SelfTest(ctx context.Context, mediaType string) error {
+	args, ok := selfTestArgs[mediaType]
+	if !ok {
+		return fmt.Errorf("unsupported media_type: %s", mediaType)
+	}
+
+	cmd := FFmpeg("-hide_banner", "-loglevel", "error")
+	cmd.Args = append(cmd.Args, args...)
+	cmd.Args = append(cmd.Args, "-f", "null", "-")
+
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		return ffmpegError(err, stderr)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fingerprint-converter/internal/pool"
+)
+
+// SlideshowBuilder assembles images (plus optional audio) into an MP4 slideshow
+type SlideshowBuilder struct {
+	workerPool *pool.WorkerPool
+	bufferPool *pool.BufferPool
+	rng        RNG
+	mu         sync.RWMutex
+	stats      SlideshowStats
+}
+
+// SlideshowStats tracks build metrics
+type SlideshowStats struct {
+	TotalBuilds  int64
+	FailedBuilds int64
+	AvgBuildTime time.Duration
+}
+
+// SlideshowSpec describes the slideshow to build
+type SlideshowSpec struct {
+	Images        [][]byte // Image data in display order
+	Audio         []byte   // Optional soundtrack (trimmed/padded to slideshow length)
+	FrameDuration float64  // Seconds each image stays on screen
+	Width         int      // Output width
+	Height        int      // Output height
+	Level         string   // Anti-fingerprint level
+}
+
+// NewSlideshowBuilder creates a new slideshow builder
+func NewSlideshowBuilder(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool, rng RNG) *SlideshowBuilder {
+	return &SlideshowBuilder{
+		workerPool: workerPool,
+		bufferPool: bufferPool,
+		rng:        orDefaultRNG(rng),
+	}
+}
+
+// ParseResolution parses an exact WxH output size (orientation is preserved)
+func ParseResolution(value string) (int, int, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(value)), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid resolution %q: use WxH (e.g. 720x1280)", value)
+	}
+
+	width, errW := strconv.Atoi(parts[0])
+	height, errH := strconv.Atoi(parts[1])
+	if errW != nil || errH != nil || width < 16 || height < 16 || width > 3840 || height > 3840 {
+		return 0, 0, fmt.Errorf("invalid resolution %q: dimensions must be between 16 and 3840", value)
+	}
+
+	// H.264 with yuv420p needs even dimensions
+	return width &^ 1, height &^ 1, nil
+}
+
+// Build renders the slideshow to outputPath
+func (sb *SlideshowBuilder) Build(ctx context.Context, spec SlideshowSpec, outputPath string) error {
+	start := time.Now()
+
+	if len(spec.Images) == 0 {
+		return fmt.Errorf("no images provided")
+	}
+	if spec.FrameDuration <= 0 {
+		spec.FrameDuration = 3
+	}
+
+	// FFmpeg needs seekable inputs for looped images, so stage them in a temp dir
+	workDir, err := os.MkdirTemp("", "slideshow-*")
+	if err != nil {
+		sb.recordFailure()
+		return fmt.Errorf("failed to create work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	cmd := FFmpeg(
+		"-hide_banner",
+		"-loglevel", "error",
+	)
+
+	duration := strconv.FormatFloat(spec.FrameDuration, 'f', 3, 64)
+	for i, img := range spec.Images {
+		imgPath := filepath.Join(workDir, fmt.Sprintf("frame_%03d", i))
+		if err := os.WriteFile(imgPath, img, 0644); err != nil {
+			sb.recordFailure()
+			return fmt.Errorf("failed to stage image %d: %w", i, err)
+		}
+		cmd.Args = append(cmd.Args, "-loop", "1", "-t", duration, "-i", imgPath)
+	}
+
+	hasAudio := len(spec.Audio) > 0
+	if hasAudio {
+		audioPath := filepath.Join(workDir, "audio")
+		if err := os.WriteFile(audioPath, spec.Audio, 0644); err != nil {
+			sb.recordFailure()
+			return fmt.Errorf("failed to stage audio: %w", err)
+		}
+		cmd.Args = append(cmd.Args, "-i", audioPath)
+	}
+
+	// Normalize every image to the same canvas, then add per-frame AF noise
+	graph := []string{}
+	labels := ""
+	for i := range spec.Images {
+		chain := fmt.Sprintf(
+			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p",
+			i, spec.Width, spec.Height, spec.Width, spec.Height)
+		if frameFilter := sb.frameFilter(spec.Level); frameFilter != "" {
+			chain += "," + frameFilter
+		}
+		graph = append(graph, fmt.Sprintf("%s[v%d]", chain, i))
+		labels += fmt.Sprintf("[v%d]", i)
+	}
+	graph = append(graph, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[vout]", labels, len(spec.Images)))
+
+	cmd.Args = append(cmd.Args,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-map", "[vout]",
+	)
+
+	if hasAudio {
+		// Pad short soundtracks with silence and cut long ones at the last frame
+		cmd.Args = append(cmd.Args,
+			"-map", fmt.Sprintf("%d:a:0", len(spec.Images)),
+			"-af", "apad",
+			"-c:a", "aac",
+			"-b:a", fmt.Sprintf("%dk", 128+sb.rng.IntN(16)), // 128-143k
+			"-ar", "48000",
+			"-shortest",
+		)
+	}
+
+	cmd.Args = append(cmd.Args,
+		"-c:v", "libx264",
+		"-crf", strconv.Itoa(22+sb.rng.IntN(3)), // 22-24
+		"-preset", "medium",
+		"-tune", "stillimage",
+		"-pix_fmt", "yuv420p",
+		"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+		"-f", "mp4",
+		"-threads", threadsArg(),
+		"pipe:1",
+	)
+
+	var outputBuffer bytes.Buffer
+	cmd.Stdout = &outputBuffer
+
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		sb.recordFailure()
+		return ffmpegError(err, stderr)
+	}
+
+	output := outputBuffer.Bytes()
+	if len(output) == 0 {
+		sb.recordFailure()
+		return fmt.Errorf("ffmpeg produced no output")
+	}
+
+	if err := os.WriteFile(outputPath, output, 0644); err != nil {
+		sb.recordFailure()
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	sb.recordSuccess(time.Since(start))
+	return nil
+}
+
+// frameFilter returns a per-image randomized AF filter so no two frames share a noise pattern
+func (sb *SlideshowBuilder) frameFilter(level string) string {
+	switch level {
+	case "basic":
+		return fmt.Sprintf("noise=alls=%d:allf=t+u", 1+sb.rng.IntN(2)) // 1-2
+	case "moderate":
+		return fmt.Sprintf("noise=alls=%d:allf=t+u,eq=brightness=%.6f:contrast=%.6f",
+			2+sb.rng.IntN(2),                     // 2-3
+			float64(sb.rng.IntN(3)-1)/1000.0,     // ±0.001
+			1.0+float64(sb.rng.IntN(3)-1)/1000.0) // ±0.001
+	case "paranoid":
+		return fmt.Sprintf("noise=alls=%d:allf=t+u,eq=brightness=%.6f:contrast=%.6f:saturation=%.6f",
+			3+sb.rng.IntN(3),                     // 3-5
+			float64(sb.rng.IntN(5)-2)/1000.0,     // ±0.002
+			1.0+float64(sb.rng.IntN(5)-2)/1000.0, // ±0.002
+			1.0+float64(sb.rng.IntN(5)-2)/1000.0) // ±0.002
+	default: // "none"
+		return ""
+	}
+}
+
+func (sb *SlideshowBuilder) recordSuccess(duration time.Duration) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.stats.TotalBuilds++
+	sb.stats.AvgBuildTime = (sb.stats.AvgBuildTime*time.Duration(sb.stats.TotalBuilds-1) + duration) / time.Duration(sb.stats.TotalBuilds)
+}
+
+func (sb *SlideshowBuilder) recordFailure() {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.stats.FailedBuilds++
+}
+
+// GetStats returns current statistics
+func (sb *SlideshowBuilder) GetStats() SlideshowStats {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+	return sb.stats
+}
+
+// GenerateOutputPath creates a unique output path
+func (sb *SlideshowBuilder) GenerateOutputPath(cacheDir, deviceID, keyHash string) string {
+	return filepath.Join(cacheDir, OutputName(deviceID, keyHash, "_slideshow.mp4"))
+}
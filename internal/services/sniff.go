@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Content kinds detected from file signatures
+const (
+	KindImage      = "image"
+	KindAudio      = "audio"
+	KindVideo      = "video"
+	KindAV         = "audio/video" // Containers that may hold either (MP4, Matroska, Ogg)
+	KindArchive    = "archive"
+	KindExecutable = "executable"
+	KindDocument   = "document"
+)
+
+// Format is a file type identified by its leading bytes
+type Format struct {
+	Name string
+	Kind string
+}
+
+// signature matches magic bytes at an offset
+type signature struct {
+	offset int
+	magic  []byte
+	format Format
+}
+
+var signatures = []signature{
+	// Images
+	{0, []byte{0xFF, 0xD8, 0xFF}, Format{"jpeg", KindImage}},
+	{0, []byte("\x89PNG\r\n\x1a\n"), Format{"png", KindImage}},
+	{0, []byte("GIF87a"), Format{"gif", KindImage}},
+	{0, []byte("GIF89a"), Format{"gif", KindImage}},
+	{8, []byte("WEBP"), Format{"webp", KindImage}},
+	{0, []byte("BM"), Format{"bmp", KindImage}},
+	{0, []byte("II*\x00"), Format{"tiff", KindImage}},
+	{0, []byte("MM\x00*"), Format{"tiff", KindImage}},
+
+	// Audio
+	{0, []byte("ID3"), Format{"mp3", KindAudio}},
+	{0, []byte("fLaC"), Format{"flac", KindAudio}},
+	{8, []byte("WAVE"), Format{"wav", KindAudio}},
+	{0, []byte("#!AMR"), Format{"amr", KindAudio}},
+
+	// Video and audio/video containers
+	{4, []byte("ftyp"), Format{"mp4", KindAV}},
+	{0, []byte{0x1A, 0x45, 0xDF, 0xA3}, Format{"matroska", KindAV}},
+	{0, []byte("OggS"), Format{"ogg", KindAV}},
+	{8, []byte("AVI "), Format{"avi", KindVideo}},
+	{0, []byte("FLV"), Format{"flv", KindVideo}},
+
+	// Never media
+	{0, []byte("PK\x03\x04"), Format{"zip", KindArchive}},
+	{0, []byte("Rar!\x1a\x07"), Format{"rar", KindArchive}},
+	{0, []byte("7z\xBC\xAF\x27\x1C"), Format{"7z", KindArchive}},
+	{0, []byte{0x1F, 0x8B}, Format{"gzip", KindArchive}},
+	{0, []byte("MZ"), Format{"pe", KindExecutable}},
+	{0, []byte("\x7fELF"), Format{"elf", KindExecutable}},
+	{0, []byte("%PDF"), Format{"pdf", KindDocument}},
+	{0, []byte("<?xml"), Format{"xml", KindDocument}},
+	{0, []byte("<!DOCTYPE"), Format{"html", KindDocument}},
+	{0, []byte("<html"), Format{"html", KindDocument}},
+}
+
+// MismatchError reports content that doesn't match the declared media type
+type MismatchError struct {
+	Declared string
+	Detected string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("declared %s, but content is %s", e.Declared, e.Detected)
+}
+
+// SniffFormat identifies data by its leading bytes; ok is false for unknown formats
+func SniffFormat(data []byte) (Format, bool) {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(data) >= end && bytes.Equal(data[sig.offset:end], sig.magic) {
+			return sig.format, true
+		}
+	}
+	// MPEG audio frame sync without an ID3 tag (MP3 or ADTS AAC)
+	if len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		return Format{"mpeg-audio", KindAudio}, true
+	}
+	return Format{}, false
+}
+
+// allowedKinds lists the content kinds each declared media type may contain
+// Audio accepts video containers because audio can be extracted from them
+var allowedKinds = map[string][]string{
+	"image": {KindImage},
+	"video": {KindVideo, KindAV},
+	"audio": {KindAudio, KindAV, KindVideo},
+}
+
+// CheckSignature verifies that a file starting with head can be mediaType
+// Only the leading bytes are needed, so downloads are checked before they are transferred
+// Unknown signatures pass; the stream check after probing catches the rest
+func CheckSignature(mediaType string, head []byte) error {
+	format, known := SniffFormat(head)
+	if !known {
+		return nil
+	}
+	allowed := format.Name == "gif" && mediaType == "video" // Animated GIF → MP4
+	for _, kind := range allowedKinds[mediaType] {
+		allowed = allowed || kind == format.Kind
+	}
+	if !allowed {
+		return &MismatchError{Declared: mediaType, Detected: fmt.Sprintf("%s (%s)", format.Name, format.Kind)}
+	}
+	return nil
+}
+
+// CheckContent verifies that data matches mediaType and isn't a polyglot
+func CheckContent(mediaType string, data []byte) error {
+	if err := CheckSignature(mediaType, data); err != nil {
+		return err
+	}
+
+	// A trailing ZIP directory makes a file valid both as media and as an archive (GIFAR-style polyglot)
+	if hasZipTrailer(data) {
+		detected := "zip archive appended to media"
+		if format, known := SniffFormat(data); known {
+			detected = fmt.Sprintf("zip archive appended to %s", format.Name)
+		}
+		return &MismatchError{Declared: mediaType, Detected: detected}
+	}
+	return nil
+}
+
+// CheckStreams verifies the probed streams match mediaType
+func CheckStreams(mediaType string, info *MediaInfo) error {
+	switch {
+	case mediaType == "image" && (!info.HasVideo || info.HasAudio):
+		return &MismatchError{Declared: mediaType, Detected: describeStreams(info)}
+	case mediaType == "video" && !info.HasVideo:
+		return &MismatchError{Declared: mediaType, Detected: describeStreams(info)}
+	case mediaType == "audio" && !info.HasAudio:
+		return &MismatchError{Declared: mediaType, Detected: describeStreams(info)}
+	}
+	return nil
+}
+
+func describeStreams(info *MediaInfo) string {
+	switch {
+	case info.HasVideo && info.HasAudio:
+		return "media with video and audio streams"
+	case info.HasVideo:
+		return "media with only a video stream"
+	case info.HasAudio:
+		return "media with only an audio stream"
+	default:
+		return "media without audio or video streams"
+	}
+}
+
+// hasZipTrailer looks for a ZIP end-of-central-directory record that ends exactly at EOF
+// Checking the comment length against the position avoids false hits inside compressed data
+func hasZipTrailer(data []byte) bool {
+	const eocdSize = 22
+	eocd := []byte("PK\x05\x06")
+
+	searchFrom := max(0, len(data)-eocdSize-0xFFFF)
+	for i := len(data) - eocdSize; i >= searchFrom; i-- {
+		if !bytes.Equal(data[i:i+4], eocd) {
+			continue
+		}
+		commentLen := int(binary.LittleEndian.Uint16(data[i+20 : i+22]))
+		if i+eocdSize+commentLen == len(data) {
+			return true
+		}
+	}
+	return false
+}
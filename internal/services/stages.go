@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// stagesKey carries the Stages of a conversion through the pipeline
+type stagesKey struct{}
+
+// Stages accumulates how long each stage of one conversion took (download, probe, encode, write, ...)
+// A stage run more than once, such as a safe-mode re-encode, adds up. Safe for concurrent use.
+type Stages struct {
+	mu    sync.Mutex
+	spent map[string]time.Duration
+}
+
+// WithStages returns ctx with a new Stages that the converters under it record into
+func WithStages(ctx context.Context) (context.Context, *Stages) {
+	s := &Stages{spent: make(map[string]time.Duration)}
+	return context.WithValue(ctx, stagesKey{}, s), s
+}
+
+// StagesFrom returns the Stages of ctx, nil when it has none
+func StagesFrom(ctx context.Context) *Stages {
+	s, _ := ctx.Value(stagesKey{}).(*Stages)
+	return s
+}
+
+// Since adds the time elapsed since start to stage; a nil Stages records nothing
+func (s *Stages) Since(stage string, start time.Time) {
+	if s == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	s.mu.Lock()
+	s.spent[stage] += elapsed
+	s.mu.Unlock()
+}
+
+// Milliseconds returns the time spent in each recorded stage
+func (s *Stages) Milliseconds() map[string]int64 {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := make(map[string]int64, len(s.spent))
+	for stage, d := range s.spent {
+		ms[stage] = d.Milliseconds()
+	}
+	return ms
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// streamProtocols are the only protocols ffmpeg may open while pulling a stream
+// Playlists are untrusted, so segments pointing at file: or other local protocols are refused
+const streamProtocols = "http,https,tcp,tls,crypto"
+
+// IsStreamManifest reports whether data is an HLS playlist or a DASH manifest
+func IsStreamManifest(data []byte) bool {
+	head := bytes.TrimLeft(data[:min(len(data), 1024)], "\xef\xbb\xbf \t\r\n")
+	if bytes.HasPrefix(head, []byte("#EXTM3U")) {
+		return true
+	}
+	if bytes.HasPrefix(head, []byte("<?xml")) || bytes.HasPrefix(head, []byte("<MPD")) {
+		return bytes.Contains(head, []byte("<MPD"))
+	}
+	return false
+}
+
+// pullStream lets ffmpeg fetch the stream at url and remuxes up to maxDuration of it into one Matroska file
+// Streams are copied without re-encoding; the converter re-encodes the result like any other input
+func (d *Downloader) pullStream(ctx context.Context, url string) ([]byte, error) {
+	cmd := FFmpeg(
+		"-hide_banner",
+		"-loglevel", "error",
+		"-protocol_whitelist", streamProtocols,
+		"-rw_timeout", strconv.FormatInt(d.client.Timeout.Microseconds(), 10),
+		"-i", url,
+		"-map", "0:v?", "-map", "0:a?",
+		"-c", "copy",
+		"-t", formatSeconds(d.streamMaxDuration),
+		"-fs", strconv.FormatInt(d.maxSize, 10),
+		"-f", "matroska",
+		"pipe:1",
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	if stderr, err := RunCommand(ctx, cmd); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Segments that can't be fetched are as likely to be a network hiccup as a broken stream
+		return nil, transient(fmt.Errorf("failed to pull stream: %w", ffmpegError(err, stderr)))
+	}
+	if output.Len() == 0 {
+		return nil, fmt.Errorf("stream has no audio or video")
+	}
+	if int64(output.Len()) >= d.maxSize {
+		return nil, fmt.Errorf("%w: stream reached %d bytes before the duration cap", ErrFileTooLarge, d.maxSize)
+	}
+
+	log.Printf("📡 Pulled stream: url=%s, size=%d, cap=%v", truncateStreamURL(url), output.Len(), d.streamMaxDuration)
+	return output.Bytes(), nil
+}
+
+// truncateStreamURL shortens url for logs and drops its query string, which often carries tokens
+func truncateStreamURL(url string) string {
+	url, _, _ = strings.Cut(url, "?")
+	if len(url) > 80 {
+		return url[:80] + "..."
+	}
+	return url
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AFTechnique is a pluggable randomization step added to a conversion's ffmpeg run
+// Profiles list techniques by name (techniques: [...]); they run after the built-in AF filters
+// and before the watermark. Register new ones with RegisterTechnique.
+type AFTechnique interface {
+	// Name identifies the technique in profiles
+	Name() string
+	// MediaTypes lists the media types the technique applies to (audio, image, video)
+	MediaTypes() []string
+	// Apply draws this run's parameters and returns what to add to the ffmpeg command
+	Apply(params TechniqueParams) TechniqueResult
+}
+
+// TechniqueParams describes the conversion a technique is applied to
+type TechniqueParams struct {
+	MediaType string // audio/image/video
+	Level     string // AF level of the conversion; techniques usually do nothing for "none"
+	RNG       RNG    // Draw every random value from here so AF_SEED keeps runs reproducible
+}
+
+// TechniqueResult is what a technique adds to one ffmpeg run
+type TechniqueResult struct {
+	Filter string   // Appended to the filter chain (-vf/-af); "" = none
+	Args   []string // Output options placed before the output (e.g. -metadata key=value)
+}
+
+// techniques holds the registered AF techniques by name
+var techniques = struct {
+	sync.RWMutex
+	byName map[string]AFTechnique
+}{byName: make(map[string]AFTechnique)}
+
+// RegisterTechnique adds an AF technique
+// Call it from an init function so profiles in the config file can name the technique
+func RegisterTechnique(t AFTechnique) error {
+	name := t.Name()
+	if name == "" || strings.ContainsAny(name, ",; ") {
+		return fmt.Errorf("invalid technique name %q", name)
+	}
+	for _, mediaType := range t.MediaTypes() {
+		if mediaType != "audio" && mediaType != "image" && mediaType != "video" {
+			return fmt.Errorf("technique %s: unknown media type %q", name, mediaType)
+		}
+	}
+
+	techniques.Lock()
+	defer techniques.Unlock()
+	if _, exists := techniques.byName[name]; exists {
+		return fmt.Errorf("duplicate technique %q", name)
+	}
+	techniques.byName[name] = t
+	return nil
+}
+
+// mustRegisterTechnique registers a built-in technique
+func mustRegisterTechnique(t AFTechnique) {
+	if err := RegisterTechnique(t); err != nil {
+		panic(err)
+	}
+}
+
+// Technique returns the registered technique with the given name
+func Technique(name string) (AFTechnique, bool) {
+	techniques.RLock()
+	defer techniques.RUnlock()
+	t, ok := techniques.byName[name]
+	return t, ok
+}
+
+// TechniqueNames returns the registered technique names, sorted
+func TechniqueNames() []string {
+	techniques.RLock()
+	defer techniques.RUnlock()
+	names := make([]string, 0, len(techniques.byName))
+	for name := range techniques.byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyTechniques runs the options' techniques for one conversion
+// Returns the filters to append to the chain and the output options to add
+func (o ConvertOptions) applyTechniques(mediaType, level string, rng RNG) ([]string, []string) {
+	var filters, args []string
+	for _, name := range o.Techniques {
+		t, ok := Technique(name)
+		if !ok || !slices.Contains(t.MediaTypes(), mediaType) {
+			continue
+		}
+		result := t.Apply(TechniqueParams{MediaType: mediaType, Level: level, RNG: rng})
+		if result.Filter != "" {
+			filters = append(filters, result.Filter)
+		}
+		args = append(args, result.Args...)
+	}
+	return filters, args
+}
+
+func init() {
+	mustRegisterTechnique(edgeCrop{})
+	mustRegisterTechnique(tempoJitter{})
+	mustRegisterTechnique(metadataTag{})
+}
+
+// techniqueStrength scales built-in techniques with the AF level (0 = off)
+func techniqueStrength(level string) int {
+	switch level {
+	case "basic":
+		return 1
+	case "moderate":
+		return 2
+	case "paranoid":
+		return 4
+	}
+	return 0
+}
+
+// edgeCrop trims a few pixels off the frame edges, shifting every pixel position
+type edgeCrop struct{}
+
+func (edgeCrop) Name() string         { return "edge_crop" }
+func (edgeCrop) MediaTypes() []string { return []string{"image", "video"} }
+
+func (edgeCrop) Apply(p TechniqueParams) TechniqueResult {
+	strength := techniqueStrength(p.Level)
+	if strength == 0 {
+		return TechniqueResult{}
+	}
+	// Even totals keep 4:2:0 dimensions valid; the split between the two edges is random
+	cropX := 2 * (1 + p.RNG.IntN(strength))
+	cropY := 2 * (1 + p.RNG.IntN(strength))
+	return TechniqueResult{Filter: fmt.Sprintf("crop=iw-%d:ih-%d:%d:%d",
+		cropX, cropY, p.RNG.IntN(cropX+1), p.RNG.IntN(cropY+1))}
+}
+
+// tempoJitter speeds audio up or down by a fraction of a percent without changing its pitch
+type tempoJitter struct{}
+
+func (tempoJitter) Name() string         { return "tempo_jitter" }
+func (tempoJitter) MediaTypes() []string { return []string{"audio"} }
+
+func (tempoJitter) Apply(p TechniqueParams) TechniqueResult {
+	strength := techniqueStrength(p.Level)
+	if strength == 0 {
+		return TechniqueResult{}
+	}
+	maxShift := 0.0025 * float64(strength) // ±0.25% (basic) to ±1% (paranoid)
+	tempo := 1 + (p.RNG.Float64()*2-1)*maxShift
+	return TechniqueResult{Filter: fmt.Sprintf("atempo=%.6f", tempo)}
+}
+
+// metadataTag writes a random comment tag, so container metadata differs between copies
+type metadataTag struct{}
+
+func (metadataTag) Name() string         { return "metadata_tag" }
+func (metadataTag) MediaTypes() []string { return []string{"audio", "video"} }
+
+func (metadataTag) Apply(p TechniqueParams) TechniqueResult {
+	if techniqueStrength(p.Level) == 0 {
+		return TechniqueResult{}
+	}
+	const hexDigits = "0123456789abcdef"
+	comment := make([]byte, 16)
+	for i := range comment {
+		comment[i] = hexDigits[p.RNG.IntN(len(hexDigits))]
+	}
+	return TechniqueResult{Args: []string{"-metadata", "comment=" + string(comment)}}
+}
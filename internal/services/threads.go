@@ -0,0 +1,34 @@
+package services
+
+import (
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// ffmpegThreads is the -threads value of every encode; 0 = ffmpeg picks (all cores)
+var ffmpegThreads atomic.Int64
+
+// SetFFmpegThreads sets how many threads each ffmpeg run may use (safe to call at runtime)
+func SetFFmpegThreads(threads int) {
+	ffmpegThreads.Store(int64(threads))
+}
+
+// FFmpegThreads returns the threads each ffmpeg run may use (0 = all cores)
+func FFmpegThreads() int {
+	return int(ffmpegThreads.Load())
+}
+
+// ThreadsPerJob shares the CPU cores among jobs running at once, at least one thread each
+// With more jobs than cores every job gets one thread, so concurrent encodes don't thrash each other
+func ThreadsPerJob(jobs int) int {
+	if jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return max(runtime.NumCPU()/jobs, 1)
+}
+
+// threadsArg renders the -threads value
+func threadsArg() string {
+	return strconv.FormatInt(ffmpegThreads.Load(), 10)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+
+	"fingerprint-converter/internal/reqid"
+)
+
+// uploadClient sends outputs to destination URLs; the caller's context bounds each upload
+var uploadClient = &http.Client{}
+
+// UploadFile streams the file at path to url with an HTTP PUT, as presigned S3, GCS and R2 URLs expect
+// Connection failures, 5xx and 429 are transient; other statuses (an expired signature) are not.
+// Errors never include url, since its query string is the signature.
+func UploadFile(ctx context.Context, url, path, contentType string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, file)
+	if err != nil {
+		return fmt.Errorf("invalid destination URL")
+	}
+	req.ContentLength = stat.Size()
+	req.Header.Set("Content-Type", contentType)
+	reqid.Apply(ctx, req)
+
+	resp, err := uploadClient.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return transient(fmt.Errorf("upload failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("upload failed: HTTP %d", resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return transient(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// StripQuery returns rawURL without its login, query string and fragment
+// Presigned URLs are reported this way, so responses and logs don't carry the signature
+func StripQuery(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
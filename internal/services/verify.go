@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutputCheck verifies that encoded outputs play before they are cached or returned
+type OutputCheck struct {
+	Enabled           bool
+	DurationTolerance time.Duration // Allowed difference between source and output duration (0 = not checked)
+}
+
+// OutputError reports an output that failed verification
+type OutputError struct {
+	Reason string
+}
+
+func (e *OutputError) Error() string {
+	return "output failed verification: " + e.Reason
+}
+
+// Verify probes the output at path and decodes its first and last second
+// source is the probed input; the duration check is skipped when it is nil or has no duration
+func (c OutputCheck) Verify(ctx context.Context, mediaType, path string, source *MediaInfo) error {
+	info, err := ProbeFile(ctx, path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return &OutputError{Reason: "container can't be read: " + err.Error()}
+	}
+
+	switch {
+	case mediaType == "audio" && !info.HasAudio:
+		return &OutputError{Reason: "no audio stream"}
+	case mediaType != "audio" && !info.HasVideo:
+		return &OutputError{Reason: "no video stream"}
+	}
+
+	if mediaType != "image" && c.DurationTolerance > 0 && source != nil && source.Duration > 0 {
+		if info.Duration <= 0 {
+			return &OutputError{Reason: "container reports no duration"}
+		}
+		if diff := (info.Duration - source.Duration).Abs(); diff > c.DurationTolerance {
+			return &OutputError{Reason: fmt.Sprintf("duration %s differs from the source's %s by more than %s",
+				info.Duration.Round(time.Millisecond), source.Duration.Round(time.Millisecond), c.DurationTolerance)}
+		}
+	}
+
+	// Images are a single frame; audio and video are decoded at both ends, where truncation shows
+	if mediaType == "image" {
+		return decodeCheck(ctx, "frame", path)
+	}
+	if err := decodeCheck(ctx, "first second", path, "-t", "1"); err != nil {
+		return err
+	}
+	return decodeCheck(ctx, "last second", path, "-sseof", "-1")
+}
+
+// decodeCheck decodes path to nothing and fails on the first decode error
+// inputArgs go before -i, so seeking happens in the demuxer without decoding the middle
+func decodeCheck(ctx context.Context, part, path string, inputArgs ...string) error {
+	cmd := FFmpeg("-hide_banner", "-nostats", "-v", "error", "-xerror")
+	cmd.Args = append(cmd.Args, inputArgs...)
+	cmd.Args = append(cmd.Args, "-i", path, "-f", "null", "-")
+
+	stderr, err := RunCommand(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &OutputError{Reason: fmt.Sprintf("%s doesn't decode: %s", part, DescribeFFmpegFailure(stderr))}
+	}
+	if stderr := strings.TrimSpace(stderr); stderr != "" {
+		return &OutputError{Reason: fmt.Sprintf("%s decodes with errors: %s", part, lastLine(stderr))}
+	}
+	return nil
+}
@@ -4,12 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"math/rand"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +18,7 @@ import (
 type VideoConverter struct {
 	workerPool *pool.WorkerPool
 	bufferPool *pool.BufferPool
+	rng        RNG
 	mu         sync.RWMutex
 	stats      VideoStats
 }
@@ -29,28 +28,33 @@ type VideoStats struct {
 	TotalConversions  int64
 	FailedConversions int64
 	AvgConversionTime time.Duration
+	FailureReasons    map[string]int64 // Failed conversions by category (FailureDecode, ...)
 }
 
 // NewVideoConverter creates a new video converter
-func NewVideoConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool) *VideoConverter {
+func NewVideoConverter(workerPool *pool.WorkerPool, bufferPool *pool.BufferPool, rng RNG) *VideoConverter {
 	return &VideoConverter{
 		workerPool: workerPool,
 		bufferPool: bufferPool,
+		rng:        orDefaultRNG(rng),
 	}
 }
 
 // Convert processes video with anti-fingerprinting
-func (vc *VideoConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string) error {
+func (vc *VideoConverter) Convert(ctx context.Context, inputData []byte, level string, outputPath string, opts ConvertOptions) (*ConversionResult, error) {
 	start := time.Now()
 
 	// Validate input
 	if len(inputData) == 0 {
-		return fmt.Errorf("empty input data")
+		return nil, fmt.Errorf("empty input data")
 	}
 
 	// Get original video bitrate
-	originalBitrate, err := vc.getVideoBitrate(ctx, inputData)
-	if err != nil {
+	originalBitrate := 0
+	if info, err := ProbeMedia(ctx, inputData); err == nil {
+		originalBitrate = info.VideoBitrate()
+	}
+	if originalBitrate <= 0 {
 		// If we can't get bitrate, use a default
 		originalBitrate = 2000
 	}
@@ -58,16 +62,37 @@ func (vc *VideoConverter) Convert(ctx context.Context, inputData []byte, level s
 	// Get randomized parameters based on level
 	params := vc.getRandomizedParams(level, originalBitrate)
 
+	// Stage watermark assets before building the command
+	if opts.Watermark != nil {
+		cleanup, err := opts.Watermark.stage()
+		defer cleanup()
+		if err != nil {
+			vc.recordFailure(FailureOther)
+			return nil, err
+		}
+	}
+
 	// Build FFmpeg command with anti-fingerprinting
-	cmd := exec.CommandContext(ctx, "ffmpeg",
+	cmd := FFmpeg(
 		"-hide_banner",
 		"-loglevel", "error",
 		"-i", "pipe:0", // Input from stdin
 	)
+	cmd.Args = append(cmd.Args, opts.Watermark.inputArgs()...)
 
 	// Video filters for anti-fingerprinting
 	videoFilters := []string{}
 
+	// Downscale oversized inputs first so the remaining filters work on fewer pixels
+	if scale := opts.scaleFilter(); scale != "" {
+		videoFilters = append(videoFilters, scale)
+	}
+
+	// Normalize frame rate (also turns VFR phone footage into CFR)
+	if opts.FrameRate != "" {
+		videoFilters = append(videoFilters, "fps="+opts.FrameRate)
+	}
+
 	// Add subtle noise (basic, moderate, paranoid)
 	if params.addNoise {
 		videoFilters = append(videoFilters, fmt.Sprintf("noise=alls=%d:allf=t+u", params.noiseStrength))
@@ -84,8 +109,15 @@ func (vc *VideoConverter) Convert(ctx context.Context, inputData []byte, level s
 		videoFilters = append(videoFilters, fmt.Sprintf("drawtext=text='':x=0:y=0:fontsize=1:fontcolor=black@0.01"))
 	}
 
-	if len(videoFilters) > 0 {
-		cmd.Args = append(cmd.Args, "-vf", strings.Join(videoFilters, ","))
+	// Extra techniques from the profile
+	techniqueFilters, techniqueArgs := opts.applyTechniques("video", level, vc.rng)
+	videoFilters = append(videoFilters, techniqueFilters...)
+
+	// Watermark is drawn last so AF noise doesn't smear it
+	filterArgs, mapped := buildVideoFilterArgs(videoFilters, opts.Watermark)
+	cmd.Args = append(cmd.Args, filterArgs...)
+	if mapped && !opts.DropAudio {
+		cmd.Args = append(cmd.Args, "-map", "0:a:0?") // Keep audio when the graph maps video explicitly
 	}
 
 	// Video codec settings
@@ -99,52 +131,83 @@ func (vc *VideoConverter) Convert(ctx context.Context, inputData []byte, level s
 		"-movflags", "frag_keyframe+empty_moov+default_base_moof", // Enable streaming for pipe output
 	)
 
-	// Audio settings (copy or re-encode depending on level)
-	if level == "none" || level == "basic" {
+	if opts.FrameRate != "" {
+		cmd.Args = append(cmd.Args, "-fps_mode", "cfr")
+	}
+
+	// 8-bit 4:2:0 is the one pixel format every H.264 encoder and player handles
+	if opts.SafeMode {
+		cmd.Args = append(cmd.Args, "-pix_fmt", "yuv420p")
+	}
+
+	// Audio settings (drop, copy or re-encode depending on options and level)
+	// Frame rate normalization always re-encodes so audio can be resynced to the new timing
+	if opts.DropAudio {
+		cmd.Args = append(cmd.Args, "-an") // Silent output, skip audio work entirely
+	} else if (level == "none" || level == "basic") && opts.FrameRate == "" && !opts.SafeMode {
 		cmd.Args = append(cmd.Args, "-c:a", "copy") // Copy audio stream
 	} else {
-		// Re-encode audio with slight variations
+		// Re-encode audio with slight variations (safe mode too: copying fails for codecs MP4 can't hold)
 		cmd.Args = append(cmd.Args,
 			"-c:a", "aac",
-			"-b:a", fmt.Sprintf("%dk", 128+rand.Intn(16)), // 128-143k
+			"-b:a", fmt.Sprintf("%dk", 128+vc.rng.IntN(16)), // 128-143k
 			"-ar", "48000",
 		)
+		if opts.FrameRate != "" {
+			// Stretch/squeeze audio to match video timestamps (prevents VFR drift)
+			cmd.Args = append(cmd.Args, "-af", "aresample=async=1:first_pts=0")
+		}
 	}
 
 	// Output settings
+	cmd.Args = append(cmd.Args, techniqueArgs...)
+	cmd.Args = append(cmd.Args, CurrentOutputMarker().args(vc.rng)...)
 	cmd.Args = append(cmd.Args,
 		"-f", "mp4",
-		"-threads", "0",
+		"-threads", threadsArg(),
 		"pipe:1", // Output to stdout
 	)
 
 	// Set up pipes
 	cmd.Stdin = bytes.NewReader(inputData)
 	var outputBuffer bytes.Buffer
-	var errorBuffer bytes.Buffer
-	cmd.Stdout = &outputBuffer
-	cmd.Stderr = &errorBuffer
+	cmd.Stdout = outputSink(ctx, &outputBuffer)
 
 	// Execute conversion
-	if err := cmd.Run(); err != nil {
-		vc.recordFailure()
-		return fmt.Errorf("ffmpeg error: %v, stderr: %s", err, errorBuffer.String())
+	encodeStart := time.Now()
+	stderr, err := RunCommand(ctx, cmd)
+	ffmpegTime := time.Since(encodeStart)
+	StagesFrom(ctx).Since("encode", encodeStart)
+	if err != nil {
+		err = ffmpegError(err, stderr)
+		vc.recordFailure(failureCategory(ctx, err))
+		return nil, err
 	}
 
 	output := outputBuffer.Bytes()
 	if len(output) == 0 {
-		vc.recordFailure()
-		return fmt.Errorf("ffmpeg produced no output")
+		vc.recordFailure(FailureDecode)
+		return nil, fmt.Errorf("ffmpeg produced no output")
 	}
 
 	// Write to file
+	writeStart := time.Now()
 	if err := os.WriteFile(outputPath, output, 0644); err != nil {
-		vc.recordFailure()
-		return fmt.Errorf("failed to write output file: %w", err)
+		vc.recordFailure(FailureWrite)
+		return nil, fmt.Errorf("failed to write output file: %w", err)
 	}
+	StagesFrom(ctx).Since("write", writeStart)
 
 	vc.recordSuccess(time.Since(start))
-	return nil
+	result := &ConversionResult{
+		Path:       outputPath,
+		Format:     "mp4",
+		Duration:   time.Since(start),
+		FFmpegTime: ffmpegTime,
+		Params:     params.describe(),
+	}
+	result.measure(ctx)
+	return result, nil
 }
 
 type videoParams struct {
@@ -161,6 +224,25 @@ type videoParams struct {
 	addTimestamp     bool
 }
 
+// describe lists the parameters for ConversionResult.Params
+func (p videoParams) describe() map[string]string {
+	params := map[string]string{
+		"bitrate":           fmt.Sprintf("%dk", p.bitrate),
+		"crf":               strconv.Itoa(p.crf),
+		"preset":            p.preset,
+		"keyframe_interval": strconv.Itoa(p.keyframeInterval),
+	}
+	if p.addNoise {
+		params["noise"] = strconv.Itoa(p.noiseStrength)
+	}
+	if p.colorAdjust {
+		params["brightness"] = fmt.Sprintf("%.6f", p.brightness)
+		params["contrast"] = fmt.Sprintf("%.6f", p.contrast)
+		params["saturation"] = fmt.Sprintf("%.6f", p.saturation)
+	}
+	return params
+}
+
 func (vc *VideoConverter) getRandomizedParams(level string, originalBitrate int) videoParams {
 	params := videoParams{
 		bitrate:          originalBitrate,
@@ -169,75 +251,37 @@ func (vc *VideoConverter) getRandomizedParams(level string, originalBitrate int)
 		keyframeInterval: 250,
 	}
 
-	switch level {
-	case "basic":
-		// Minimal randomization (recommended for video)
-		bitrateVariation := int(float64(originalBitrate) * (0.05 + float64(rand.Intn(6))/100.0)) // 5-10%
-		params.bitrate = originalBitrate + bitrateVariation - rand.Intn(bitrateVariation*2)
-		params.crf = 22 + rand.Intn(3)                // 22-24
-		params.keyframeInterval = 240 + rand.Intn(21) // 240-260
-
-	case "moderate":
-		// Moderate randomization
-		bitrateVariation := int(float64(originalBitrate) * (0.08 + float64(rand.Intn(5))/100.0)) // 8-12%
-		params.bitrate = originalBitrate + bitrateVariation - rand.Intn(bitrateVariation*2)
-		params.crf = 22 + rand.Intn(4)                // 22-25
-		params.keyframeInterval = 230 + rand.Intn(41) // 230-270
-		params.addNoise = true
-		params.noiseStrength = 1 + rand.Intn(2) // 1-2
-		params.colorAdjust = true
-		params.brightness = float64(rand.Intn(3)-1) / 1000.0     // ±0.001
-		params.contrast = 1.0 + float64(rand.Intn(3)-1)/1000.0   // ±0.001
-		params.saturation = 1.0 + float64(rand.Intn(3)-1)/1000.0 // ±0.001
-
-	case "paranoid":
-		// Maximum randomization
-		bitrateVariation := int(float64(originalBitrate) * (0.10 + float64(rand.Intn(6))/100.0)) // 10-15%
-		params.bitrate = originalBitrate + bitrateVariation - rand.Intn(bitrateVariation*2)
-		params.crf = 21 + rand.Intn(5)                                     // 21-25
-		params.keyframeInterval = 220 + rand.Intn(61)                      // 220-280
-		params.preset = []string{"fast", "medium", "medium"}[rand.Intn(3)] // Vary preset
-		params.addNoise = true
-		params.noiseStrength = 2 + rand.Intn(4) // 2-5
-		params.colorAdjust = true
-		params.brightness = float64(rand.Intn(5)-2) / 1000.0     // ±0.002
-		params.contrast = 1.0 + float64(rand.Intn(5)-2)/1000.0   // ±0.002
-		params.saturation = 1.0 + float64(rand.Intn(5)-2)/1000.0 // ±0.002
-		params.addTimestamp = true
-
-	default: // "none"
-		params.bitrate = originalBitrate
-		params.crf = 23
-		params.keyframeInterval = 250
+	// "none" keeps the fixed defaults; ranges are tunable per level (AF_VIDEO_*)
+	ranges, ok := currentAFRanges().Video.For(level)
+	if !ok {
+		return params
 	}
 
-	return params
-}
+	// Move the bitrate up or down by a random share of the jitter
+	if variation := int(float64(originalBitrate) * ranges.BitrateJitter.Pick(vc.rng)); variation > 0 {
+		params.bitrate = originalBitrate + variation - vc.rng.IntN(variation*2)
+	}
+	params.crf = ranges.CRF.Pick(vc.rng)
+	params.keyframeInterval = ranges.KeyframeInterval.Pick(vc.rng)
 
-// getVideoBitrate probes the video to get its bitrate
-func (vc *VideoConverter) getVideoBitrate(ctx context.Context, inputData []byte) (int, error) {
-	cmd := exec.CommandContext(ctx, "ffprobe",
-		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "stream=bit_rate",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		"-i", "pipe:0",
-	)
+	if ranges.Noise.Enabled() {
+		params.addNoise = true
+		params.noiseStrength = ranges.Noise.Pick(vc.rng)
+	}
 
-	cmd.Stdin = bytes.NewReader(inputData)
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
+	if ranges.Brightness > 0 || ranges.Contrast > 0 || ranges.Saturation > 0 {
+		params.colorAdjust = true
+		params.brightness = ranges.Brightness.Pick(vc.rng)
+		params.contrast = 1.0 + ranges.Contrast.Pick(vc.rng)
+		params.saturation = 1.0 + ranges.Saturation.Pick(vc.rng)
 	}
 
-	bitrateStr := strings.TrimSpace(string(output))
-	bitrate, err := strconv.Atoi(bitrateStr)
-	if err != nil {
-		return 0, err
+	if level == "paranoid" {
+		params.preset = []string{"fast", "medium", "medium"}[vc.rng.IntN(3)] // Vary preset
+		params.addTimestamp = true
 	}
 
-	// Convert to kbps
-	return bitrate / 1000, nil
+	return params
 }
 
 func (vc *VideoConverter) recordSuccess(duration time.Duration) {
@@ -247,17 +291,23 @@ func (vc *VideoConverter) recordSuccess(duration time.Duration) {
 	vc.stats.AvgConversionTime = (vc.stats.AvgConversionTime*time.Duration(vc.stats.TotalConversions-1) + duration) / time.Duration(vc.stats.TotalConversions)
 }
 
-func (vc *VideoConverter) recordFailure() {
+func (vc *VideoConverter) recordFailure(category string) {
 	vc.mu.Lock()
 	defer vc.mu.Unlock()
 	vc.stats.FailedConversions++
+	if vc.stats.FailureReasons == nil {
+		vc.stats.FailureReasons = make(map[string]int64)
+	}
+	vc.stats.FailureReasons[category]++
 }
 
 // GetStats returns current statistics
 func (vc *VideoConverter) GetStats() VideoStats {
 	vc.mu.RLock()
 	defer vc.mu.RUnlock()
-	return vc.stats
+	stats := vc.stats
+	stats.FailureReasons = maps.Clone(vc.stats.FailureReasons)
+	return stats
 }
 
 // GetOutputExtension returns the file extension for this converter
@@ -267,7 +317,5 @@ func (vc *VideoConverter) GetOutputExtension() string {
 
 // GenerateOutputPath creates a unique output path
 func (vc *VideoConverter) GenerateOutputPath(cacheDir, deviceID, urlHash string) string {
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%s_%s_%d%s", deviceID, urlHash[:8], timestamp, vc.GetOutputExtension())
-	return filepath.Join(cacheDir, filename)
+	return filepath.Join(cacheDir, OutputName(deviceID, urlHash, vc.GetOutputExtension()))
 }
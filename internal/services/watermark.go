@@ -0,0 +1,189 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Watermark describes a visible overlay applied in the same ffmpeg pass as the AF filters
+type Watermark struct {
+	Text      string  // Text to draw (ignored when a logo is set)
+	LogoURL   string  // Source of the PNG logo (used for cache keys)
+	Logo      []byte  // PNG logo data, downloaded by the caller
+	Position  string  // top-left/top-right/bottom-left/bottom-right/center
+	Opacity   float64 // 0-1
+	FontSize  int     // Text size in pixels (0 = relative to frame height)
+	FontColor string  // Text color name or hex
+	Scale     float64 // Logo width as a fraction of the frame width
+
+	// Staged asset paths (set by stage)
+	textPath string
+	logoPath string
+}
+
+var watermarkPositions = map[string]bool{
+	"top-left":     true,
+	"top-right":    true,
+	"bottom-left":  true,
+	"bottom-right": true,
+	"center":       true,
+}
+
+// Normalize fills defaults and validates the watermark settings
+func (w *Watermark) Normalize() error {
+	if w.Text == "" && w.LogoURL == "" {
+		return fmt.Errorf("watermark requires text or image_url")
+	}
+	if w.Position == "" {
+		w.Position = "bottom-right"
+	}
+	if !watermarkPositions[w.Position] {
+		return fmt.Errorf("invalid watermark position %q (supported: top-left, top-right, bottom-left, bottom-right, center)", w.Position)
+	}
+	if w.Opacity == 0 {
+		w.Opacity = 0.5
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("watermark opacity must be between 0 and 1")
+	}
+	if w.FontSize < 0 || w.FontSize > 500 {
+		return fmt.Errorf("watermark font_size must be between 0 and 500 (0 = relative to frame height)")
+	}
+	if w.FontColor == "" {
+		w.FontColor = "white"
+	}
+	if strings.ContainsAny(w.FontColor, ":,;'[]") {
+		return fmt.Errorf("invalid watermark font_color %q", w.FontColor)
+	}
+	if w.Scale == 0 {
+		w.Scale = 0.2
+	}
+	if w.Scale < 0.01 || w.Scale > 1 {
+		return fmt.Errorf("watermark scale must be between 0.01 and 1")
+	}
+	return nil
+}
+
+// Signature returns a short stable identifier for cache keys
+func (w *Watermark) Signature() string {
+	raw := fmt.Sprintf("%s|%s|%s|%.3f|%d|%s|%.3f",
+		w.Text, w.LogoURL, w.Position, w.Opacity, w.FontSize, w.FontColor, w.Scale)
+	hash := md5.Sum([]byte(raw))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// hasLogo reports whether the overlay needs a second (image) input
+func (w *Watermark) hasLogo() bool {
+	return len(w.Logo) > 0
+}
+
+// textFilter builds the drawtext filter for text watermarks
+// The text is read from a staged file so no filtergraph escaping is needed
+func (w *Watermark) textFilter() string {
+	fontSize := "h/24"
+	if w.FontSize > 0 {
+		fontSize = fmt.Sprintf("%d", w.FontSize)
+	}
+
+	var x, y string
+	switch w.Position {
+	case "top-left":
+		x, y = "10", "10"
+	case "top-right":
+		x, y = "w-tw-10", "10"
+	case "bottom-left":
+		x, y = "10", "h-th-10"
+	case "center":
+		x, y = "(w-tw)/2", "(h-th)/2"
+	default: // bottom-right
+		x, y = "w-tw-10", "h-th-10"
+	}
+
+	return fmt.Sprintf("drawtext=textfile=%s:expansion=none:fontsize=%s:fontcolor=%s@%.2f:shadowcolor=black@%.2f:shadowx=1:shadowy=1:x=%s:y=%s",
+		w.textPath, fontSize, w.FontColor, w.Opacity, w.Opacity*0.6, x, y)
+}
+
+// overlayPosition returns overlay x/y expressions for logo watermarks
+func (w *Watermark) overlayPosition() string {
+	switch w.Position {
+	case "top-left":
+		return "x=10:y=10"
+	case "top-right":
+		return "x=W-w-10:y=10"
+	case "bottom-left":
+		return "x=10:y=H-h-10"
+	case "center":
+		return "x=(W-w)/2:y=(H-h)/2"
+	default: // bottom-right
+		return "x=W-w-10:y=H-h-10"
+	}
+}
+
+// stage writes the logo or text to a temp file so ffmpeg can read it
+// The returned cleanup func must always be called
+func (w *Watermark) stage() (func(), error) {
+	data, pattern := []byte(w.Text), "watermark-*.txt"
+	if w.hasLogo() {
+		data, pattern = w.Logo, "watermark-*.png"
+	}
+
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return func() {}, fmt.Errorf("failed to stage watermark: %w", err)
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		cleanup()
+		return func() {}, fmt.Errorf("failed to stage watermark: %w", err)
+	}
+	file.Close()
+
+	if w.hasLogo() {
+		w.logoPath = file.Name()
+	} else {
+		w.textPath = file.Name()
+	}
+	return cleanup, nil
+}
+
+// inputArgs returns the extra ffmpeg input needed by logo overlays
+func (w *Watermark) inputArgs() []string {
+	if w == nil || !w.hasLogo() {
+		return nil
+	}
+	return []string{"-i", w.logoPath}
+}
+
+// buildVideoFilterArgs turns a filter chain plus optional watermark into ffmpeg args
+// Logo overlays need a filter graph with a second input (index 1) and an explicit output map
+func buildVideoFilterArgs(filters []string, wm *Watermark) (args []string, mapped bool) {
+	if wm != nil && !wm.hasLogo() {
+		filters = append(filters, wm.textFilter())
+	}
+
+	if wm == nil || !wm.hasLogo() {
+		if len(filters) == 0 {
+			return nil, false
+		}
+		return []string{"-vf", strings.Join(filters, ",")}, false
+	}
+
+	base := "[0:v]null[base]"
+	if len(filters) > 0 {
+		base = "[0:v]" + strings.Join(filters, ",") + "[base]"
+	}
+
+	graph := strings.Join([]string{
+		base,
+		fmt.Sprintf("[1:v][base]scale2ref=w='main_w*%.3f':h='ow*ih/iw'[logo][ref]", wm.Scale),
+		fmt.Sprintf("[logo]format=rgba,colorchannelmixer=aa=%.2f[wm]", wm.Opacity),
+		fmt.Sprintf("[ref][wm]overlay=%s[vout]", wm.overlayPosition()),
+	}, ";")
+
+	return []string{"-filter_complex", graph, "-map", "[vout]"}, true
+}
@@ -0,0 +1,190 @@
+// Package slowlog reports conversions that take longer than a threshold for their media type,
+// with the time spent in each stage, so regressions are noticed before users do
+package slowlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/reqid"
+)
+
+const alertBuffer = 256
+
+// Thresholds is how long a conversion of each media type may take before it is reported; 0 = never
+type Thresholds struct {
+	Audio time.Duration
+	Image time.Duration
+	Video time.Duration
+}
+
+// Reporter logs slow conversions and, with a webhook URL, POSTs each one as JSON
+// Alerts are sent in the background; when the receiver falls behind they are dropped and counted
+// A nil Reporter reports nothing
+type Reporter struct {
+	thresholds atomic.Pointer[Thresholds]
+	url        string // "" = log only
+	client     *http.Client
+	alerts     chan *models.SlowConversion
+	wg         sync.WaitGroup
+	reported   atomic.Int64
+	dropped    atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates a reporter and, when webhookURL is set, starts its sender
+func New(thresholds Thresholds, webhookURL string, timeout time.Duration) *Reporter {
+	r := &Reporter{url: webhookURL}
+	r.thresholds.Store(&thresholds)
+	if webhookURL != "" {
+		r.client = &http.Client{Timeout: timeout}
+		r.alerts = make(chan *models.SlowConversion, alertBuffer)
+		r.wg.Add(1)
+		go r.send()
+	}
+	return r
+}
+
+// SetThresholds replaces the thresholds; conversions already running are judged by the new ones
+func (r *Reporter) SetThresholds(thresholds Thresholds) {
+	r.thresholds.Store(&thresholds)
+}
+
+// Threshold returns the threshold for mediaType (0 = not reported)
+func (r *Reporter) Threshold(mediaType string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	t := r.thresholds.Load()
+	switch mediaType {
+	case "audio":
+		return t.Audio
+	case "image":
+		return t.Image
+	case "video":
+		return t.Video
+	}
+	return 0
+}
+
+// Observe reports conv when it took longer than the threshold for its media type
+// ThresholdMs is filled in; conversions within the threshold are ignored
+func (r *Reporter) Observe(conv *models.SlowConversion) {
+	threshold := r.Threshold(conv.MediaType)
+	if threshold <= 0 || conv.DurationMs <= threshold.Milliseconds() {
+		return
+	}
+	conv.ThresholdMs = threshold.Milliseconds()
+	r.reported.Add(1)
+
+	outcome := "ok"
+	if !conv.Success {
+		outcome = "failed: " + conv.Error
+	}
+	log.Printf("🐢 SLOW CONVERSION: device=%s, type=%s, level=%s, time=%dms (threshold %dms), stages=[%s], %s",
+		conv.DeviceID, conv.MediaType, conv.Level, conv.DurationMs, conv.ThresholdMs, formatStages(conv.StagesMs), outcome)
+
+	if r.url == "" {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.alerts <- conv:
+	default:
+		if dropped := r.dropped.Add(1); dropped%100 == 1 {
+			log.Printf("⚠️  Slow-conversion webhook buffer full, %d alerts dropped", dropped)
+		}
+	}
+}
+
+// Stats returns how many conversions were reported and how many webhook alerts were dropped
+func (r *Reporter) Stats() (reported, dropped int64) {
+	if r == nil {
+		return 0, 0
+	}
+	return r.reported.Load(), r.dropped.Load()
+}
+
+func (r *Reporter) send() {
+	defer r.wg.Done()
+
+	for conv := range r.alerts {
+		if err := r.post(conv); err != nil {
+			log.Printf("⚠️  Slow-conversion webhook delivery failed: %v", err)
+		}
+	}
+}
+
+func (r *Reporter) post(conv *models.SlowConversion) error {
+	body, err := json.Marshal(conv)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if conv.RequestID != "" {
+		req.Header.Set(reqid.Header, conv.RequestID)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close delivers queued alerts and stops the sender
+func (r *Reporter) Close() {
+	if r == nil || r.url == "" {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.alerts)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// formatStages renders the stage breakdown as "download=120ms, encode=8400ms", in name order
+func formatStages(stages map[string]int64) string {
+	names := make([]string, 0, len(stages))
+	for name := range stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%dms", name, stages[name])
+	}
+	return strings.Join(parts, ", ")
+}
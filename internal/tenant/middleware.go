@@ -0,0 +1,53 @@
+package tenant
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"fingerprint-converter/internal/apierr"
+)
+
+const localsKey = "tenant_id"
+
+// Middleware authenticates requests by API key (X-API-Key or Authorization: Bearer)
+// and enforces the tenant's request rate. Paths in skip are left open.
+func (r *Registry) Middleware(skip ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if r == nil {
+			return c.Next()
+		}
+		for _, path := range skip {
+			if c.Path() == path {
+				return c.Next()
+			}
+		}
+
+		apiKey := c.Get("X-API-Key")
+		if apiKey == "" {
+			apiKey = strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		}
+
+		t := r.Authenticate(apiKey)
+		if t == nil {
+			return apierr.Write(c, fiber.StatusUnauthorized, apierr.Unauthorized,
+				"Invalid or missing API key", "")
+		}
+
+		if ok, retryAfter := t.allowRequest(); !ok {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			return apierr.Write(c, fiber.StatusTooManyRequests, apierr.RateLimited,
+				"Tenant request quota exceeded", fmt.Sprintf("limit: %d requests per minute", t.Quota.RequestsPerMinute))
+		}
+
+		c.Locals(localsKey, t.ID)
+		return c.Next()
+	}
+}
+
+// IDFromFiber returns the tenant ID set by Middleware ("" without tenancy)
+func IDFromFiber(c fiber.Ctx) string {
+	id, _ := c.Locals(localsKey).(string)
+	return id
+}
@@ -0,0 +1,299 @@
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota limits what a tenant may use (zero = unlimited)
+type Quota struct {
+	RequestsPerMinute int   `json:"requests_per_minute"`
+	MaxConcurrent     int   `json:"max_concurrent"` // Conversions running at the same time
+	MaxFileSize       int64 `json:"max_file_size"`  // Bytes per input file
+}
+
+// Tenant is a team using the service, identified by its API keys
+type Tenant struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	APIKeys           []string `json:"api_keys"`
+	DefaultAFLevel    string   `json:"default_af_level"`    // Overrides the per-media default
+	AllowedMediaTypes []string `json:"allowed_media_types"` // Empty = all
+	StoragePrefix     string   `json:"storage_prefix"`      // Output subdirectory (defaults to ID)
+	PostProcessors    []string `json:"post_processors"`     // Post-processor chain; null = POST_PROCESSORS, [] = none
+	Quota             Quota    `json:"quota"`
+
+	slots   chan struct{}
+	mu      sync.Mutex
+	window  time.Time
+	counter int
+}
+
+// AllowsMediaType reports whether the tenant may convert mediaType
+func (t *Tenant) AllowsMediaType(mediaType string) bool {
+	if t == nil || len(t.AllowedMediaTypes) == 0 {
+		return true
+	}
+	for _, allowed := range t.AllowedMediaTypes {
+		if allowed == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLevel returns the tenant's default AF level ("" = use the media default)
+func (t *Tenant) DefaultLevel() string {
+	if t == nil {
+		return ""
+	}
+	return t.DefaultAFLevel
+}
+
+// DeviceKey namespaces a device ID so tenants never share cache entries
+func (t *Tenant) DeviceKey(deviceID string) string {
+	if t == nil {
+		return deviceID
+	}
+	return t.ID + "/" + deviceID
+}
+
+// StorageDir returns the output subdirectory for the tenant ("" without tenancy)
+func (t *Tenant) StorageDir() string {
+	if t == nil {
+		return ""
+	}
+	return t.StoragePrefix
+}
+
+// FileTooLarge reports whether size exceeds the tenant's per-file quota
+func (t *Tenant) FileTooLarge(size int64) bool {
+	return t != nil && t.Quota.MaxFileSize > 0 && size > t.Quota.MaxFileSize
+}
+
+// Acquire takes a conversion slot; returns false when the tenant is at its concurrency limit
+func (t *Tenant) Acquire() bool {
+	if t == nil || t.slots == nil {
+		return true
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a slot taken by Acquire
+func (t *Tenant) Release() {
+	if t == nil || t.slots == nil {
+		return
+	}
+	<-t.slots
+}
+
+// allowRequest counts a request against the per-minute quota
+// Returns false and the time until the window resets when the quota is used up
+func (t *Tenant) allowRequest() (bool, time.Duration) {
+	if t.Quota.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.window) >= time.Minute {
+		t.window = now
+		t.counter = 0
+	}
+	if t.counter >= t.Quota.RequestsPerMinute {
+		return false, time.Minute - now.Sub(t.window)
+	}
+	t.counter++
+	return true, 0
+}
+
+// Registry holds the configured tenants
+// A nil Registry means tenancy is disabled and every request is anonymous
+type Registry struct {
+	mu    sync.RWMutex
+	byID  map[string]*Tenant
+	byKey map[[32]byte]*Tenant
+}
+
+// tenantsFile is the on-disk format of the tenants file
+type tenantsFile struct {
+	Tenants []*Tenant `json:"tenants"`
+}
+
+// LoadRegistry reads tenants from a JSON file; an empty path disables tenancy
+func LoadRegistry(path string) (*Registry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return readRegistry(path)
+}
+
+// readRegistry parses and validates a tenants file
+func readRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var file tenantsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+	if len(file.Tenants) == 0 {
+		return nil, fmt.Errorf("tenants file %s defines no tenants", path)
+	}
+
+	r := &Registry{
+		byID:  make(map[string]*Tenant),
+		byKey: make(map[[32]byte]*Tenant),
+	}
+	for _, t := range file.Tenants {
+		if err := r.add(t); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) add(t *Tenant) error {
+	if t.ID == "" || strings.ContainsAny(t.ID, "/\\") {
+		return fmt.Errorf("invalid tenant id %q", t.ID)
+	}
+	if _, exists := r.byID[t.ID]; exists {
+		return fmt.Errorf("duplicate tenant id %q", t.ID)
+	}
+	if len(t.APIKeys) == 0 {
+		return fmt.Errorf("tenant %s has no api_keys", t.ID)
+	}
+
+	if t.StoragePrefix == "" {
+		t.StoragePrefix = t.ID
+	}
+	clean := filepath.Clean(t.StoragePrefix)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("tenant %s: storage_prefix must be a relative path inside the cache dir", t.ID)
+	}
+	t.StoragePrefix = clean
+
+	if t.Quota.MaxConcurrent > 0 {
+		t.slots = make(chan struct{}, t.Quota.MaxConcurrent)
+	}
+
+	for _, key := range t.APIKeys {
+		hash := sha256.Sum256([]byte(key))
+		if _, exists := r.byKey[hash]; exists {
+			return fmt.Errorf("tenant %s: api key is already used by another tenant", t.ID)
+		}
+		r.byKey[hash] = t
+	}
+
+	r.byID[t.ID] = t
+	return nil
+}
+
+// Reload replaces the tenants with the contents of path (keys, quotas, AF defaults)
+// Tenants that keep their ID carry over their rate window and, when max_concurrent is
+// unchanged, their running conversions. Tenancy can't be turned on or off at runtime.
+func (r *Registry) Reload(path string) error {
+	if r == nil {
+		if path != "" {
+			return fmt.Errorf("tenancy was disabled at startup; restart to enable TENANTS_FILE")
+		}
+		return nil
+	}
+	if path == "" {
+		return fmt.Errorf("tenancy can't be disabled at runtime; restart without TENANTS_FILE")
+	}
+
+	next, err := readRegistry(path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, t := range next.byID {
+		old, exists := r.byID[id]
+		if !exists {
+			continue
+		}
+		old.mu.Lock()
+		t.window, t.counter = old.window, old.counter
+		old.mu.Unlock()
+		if old.slots != nil && t.slots != nil && cap(old.slots) == cap(t.slots) {
+			t.slots = old.slots
+		}
+	}
+	r.byID, r.byKey = next.byID, next.byKey
+	return nil
+}
+
+// Enabled reports whether tenancy is configured
+func (r *Registry) Enabled() bool {
+	return r != nil
+}
+
+// Get returns the tenant with the given ID, or nil
+func (r *Registry) Get(id string) *Tenant {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.byID[id]
+}
+
+// Authenticate returns the tenant owning apiKey, or nil
+func (r *Registry) Authenticate(apiKey string) *Tenant {
+	if r == nil || apiKey == "" {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.byKey[sha256.Sum256([]byte(apiKey))]
+}
+
+// Tenants returns all configured tenants
+func (r *Registry) Tenants() []*Tenant {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenants := make([]*Tenant, 0, len(r.byID))
+	for _, t := range r.byID {
+		tenants = append(tenants, t)
+	}
+	return tenants
+}
+
+type contextKey struct{}
+
+// WithID returns a context carrying the tenant ID
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// IDFromContext returns the tenant ID carried by ctx ("" if none)
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,152 @@
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"fingerprint-converter/internal/models"
+)
+
+// DateLayout is the format of rollup dates
+const DateLayout = "2006-01-02"
+
+var usageBucket = []byte("usage")
+
+// Event is the usage of a single request
+type Event struct {
+	TenantID string
+	DeviceID string
+	CacheHit bool
+	BytesIn  int64
+	BytesOut int64
+	Runtime  time.Duration // Wall time of the ffmpeg run
+}
+
+// Filter narrows down usage queries (empty fields match everything)
+type Filter struct {
+	From     time.Time // Inclusive day
+	To       time.Time // Inclusive day
+	TenantID string
+	DeviceID string
+}
+
+// Store keeps daily usage rollups per tenant and device in an embedded bbolt database
+// A nil Store records nothing
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens (or creates) the usage database at path
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create usage store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(usageBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize usage store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// rollupKey orders records by day so date ranges are a key range scan
+func rollupKey(date, tenantID, deviceID string) []byte {
+	return []byte(date + "\x00" + tenantID + "\x00" + deviceID)
+}
+
+// Record adds an event to today's rollup for its tenant and device
+func (s *Store) Record(event Event) error {
+	if s == nil {
+		return nil
+	}
+
+	key := rollupKey(time.Now().UTC().Format(DateLayout), event.TenantID, event.DeviceID)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usageBucket)
+
+		var counters models.UsageCounters
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &counters); err != nil {
+				return err
+			}
+		}
+
+		if event.CacheHit {
+			counters.CacheHits++
+		} else {
+			counters.Conversions++
+			counters.BytesIn += event.BytesIn
+			counters.CPUSeconds += event.Runtime.Seconds()
+		}
+		counters.BytesOut += event.BytesOut
+
+		data, err := json.Marshal(counters)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// Query returns the daily rollups in the filter's range, oldest first
+func (s *Store) Query(filter Filter) ([]models.UsageRecord, error) {
+	records := []models.UsageRecord{}
+	if s == nil {
+		return records, nil
+	}
+
+	from := []byte(filter.From.UTC().Format(DateLayout))
+	to := []byte(filter.To.UTC().Format(DateLayout))
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(usageBucket).Cursor()
+		for key, data := cursor.Seek(from); key != nil; key, data = cursor.Next() {
+			parts := strings.SplitN(string(key), "\x00", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			if bytes.Compare([]byte(parts[0]), to) > 0 {
+				break
+			}
+			if filter.TenantID != "" && parts[1] != filter.TenantID {
+				continue
+			}
+			if filter.DeviceID != "" && parts[2] != filter.DeviceID {
+				continue
+			}
+
+			record := models.UsageRecord{Date: parts[0], TenantID: parts[1], DeviceID: parts[2]}
+			if err := json.Unmarshal(data, &record.UsageCounters); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
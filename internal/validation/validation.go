@@ -0,0 +1,147 @@
+// Package validation enforces the `validate` struct tags on request models
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"fingerprint-converter/internal/models"
+)
+
+// Error lists every field that failed validation
+type Error struct {
+	Fields []models.InvalidParam
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Name + " " + f.Reason
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validator implements fiber.StructValidator using go-playground/validator
+type Validator struct {
+	validate *validator.Validate
+}
+
+// New creates a validator that reports fields by their JSON names
+func New() *Validator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("deviceid", deviceID)
+	return &Validator{validate: v}
+}
+
+// deviceID allows letters, digits and "._-@:+", so IDs like phone numbers and JIDs pass
+// but path separators, "..", control characters and spaces don't
+func deviceID(fl validator.FieldLevel) bool {
+	id := fl.Field().String()
+	if id == "." || id == ".." || strings.Contains(id, "..") {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("._-@:+", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+var std = New()
+
+// Struct validates out with the shared validator
+func Struct(out any) error {
+	return std.Validate(out)
+}
+
+// Validate checks out against its struct tags; failures are returned as *Error
+func (v *Validator) Validate(out any) error {
+	err := v.validate.Struct(out)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	result := &Error{Fields: make([]models.InvalidParam, 0, len(fieldErrs))}
+	for _, fe := range fieldErrs {
+		result.Fields = append(result.Fields, models.InvalidParam{
+			Name:   fieldPath(fe.Namespace()),
+			Rule:   fe.Tag(),
+			Reason: reason(fe),
+		})
+	}
+	return result
+}
+
+// fieldPath turns "JobSubmitRequest.ConvertRequest.watermark.opacity" into "watermark.opacity"
+// Go type names (the root and embedded structs) are dropped; JSON names are lowercase
+func fieldPath(namespace string) string {
+	var parts []string
+	for _, part := range strings.Split(namespace, ".") {
+		if part != "" && part[0] >= 'A' && part[0] <= 'Z' {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// reason describes a failed rule in plain words
+func reason(fe validator.FieldError) string {
+	collection := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required when " + strings.ToLower(fe.Param()) + " is not set"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		if collection {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if collection {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "deviceid":
+		return "may only contain letters, digits and . _ - @ : + (no \"..\")"
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
@@ -0,0 +1,107 @@
+package warmup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"fingerprint-converter/internal/models"
+)
+
+// maxManifestSize bounds a manifest read from disk or fetched over HTTP
+const maxManifestSize = 10 * 1024 * 1024
+
+// manifestTimeout bounds fetching a manifest URL
+const manifestTimeout = 30 * time.Second
+
+// Manifest lists assets to convert for a set of devices at startup
+type Manifest struct {
+	TenantID string          `json:"tenant_id,omitempty"` // Tenant the conversions run for (multi-tenancy only)
+	Devices  []string        `json:"devices"`             // Devices every asset is converted for
+	Assets   []ManifestAsset `json:"assets"`
+}
+
+// ManifestAsset is a convert request without a device; it is expanded per device
+type ManifestAsset struct {
+	models.ConvertRequest
+	Devices []string `json:"devices,omitempty"` // Replaces the manifest's device list for this asset
+}
+
+// LoadManifest reads a manifest from a file path or an http(s) URL
+func LoadManifest(ctx context.Context, source string) (*Manifest, error) {
+	data, err := readManifest(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse warm-up manifest %s: %w", source, err)
+	}
+	if len(manifest.Assets) == 0 {
+		return nil, fmt.Errorf("warm-up manifest %s lists no assets", source)
+	}
+	return &manifest, nil
+}
+
+func readManifest(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read warm-up manifest: %w", err)
+		}
+		defer file.Close()
+		return readLimited(file, source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manifestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid warm-up manifest URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch warm-up manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch warm-up manifest: HTTP %d", resp.StatusCode)
+	}
+	return readLimited(resp.Body, source)
+}
+
+func readLimited(r io.Reader, source string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read warm-up manifest: %w", err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("warm-up manifest %s is larger than %d bytes", source, maxManifestSize)
+	}
+	return data, nil
+}
+
+// Requests expands the assets into one convert request per device
+func (m *Manifest) Requests() []models.ConvertRequest {
+	var reqs []models.ConvertRequest
+	for _, asset := range m.Assets {
+		devices := asset.Devices
+		if len(devices) == 0 {
+			devices = m.Devices
+		}
+		for _, device := range devices {
+			req := asset.ConvertRequest
+			req.DeviceID = device
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
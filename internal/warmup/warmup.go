@@ -0,0 +1,192 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/audit"
+	"fingerprint-converter/internal/models"
+	"fingerprint-converter/internal/remote"
+	"fingerprint-converter/internal/tenant"
+)
+
+// ErrQueueFull is returned by Enqueue when the warm-up queue can't take every item
+var ErrQueueFull = errors.New("warm-up queue is full")
+
+// busyPoll is how often a waiting worker re-checks the live load
+const busyPoll = time.Second
+
+// Processor runs a single conversion; results land in the device cache
+type Processor func(ctx context.Context, req *models.ConvertRequest) (*models.ConvertResponse, error)
+
+// item is a queued conversion and the tenant it runs for
+type item struct {
+	tenantID string
+	req      models.ConvertRequest
+}
+
+// Warmer converts media ahead of time at low priority so later requests are cache hits
+// Workers wait while busy reports live traffic, so warm-ups only use spare capacity
+type Warmer struct {
+	process     Processor
+	busy        func() bool
+	queue       chan item
+	concurrency int
+	timeout     time.Duration
+	mu          sync.Mutex
+	pending     map[string]bool // Queued or running items, so repeated submissions aren't converted twice
+	warmed      int64
+	cached      int64
+	failed      int64
+}
+
+// Stats reports warm-up activity
+type Stats struct {
+	Queued int   `json:"queued"`
+	Warmed int64 `json:"warmed"`         // Converted and cached
+	Cached int64 `json:"already_cached"` // Already in the cache when their turn came
+	Failed int64 `json:"failed"`
+}
+
+// New creates a warmer; busy may be nil. Call Run to start processing
+func New(process Processor, busy func() bool, concurrency, maxQueued int, timeout time.Duration) *Warmer {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if maxQueued <= 0 {
+		maxQueued = 1000
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	if busy == nil {
+		busy = func() bool { return false }
+	}
+
+	return &Warmer{
+		process:     process,
+		busy:        busy,
+		queue:       make(chan item, maxQueued),
+		concurrency: concurrency,
+		timeout:     timeout,
+		pending:     make(map[string]bool),
+	}
+}
+
+// Enqueue queues reqs for tenantID; items already queued are skipped
+// Nothing is queued when they don't all fit
+func (w *Warmer) Enqueue(tenantID string, reqs []models.ConvertRequest) (queued int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fresh := make([]item, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		key := itemKey(tenantID, &req)
+		if w.pending[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		fresh = append(fresh, item{tenantID: tenantID, req: req})
+	}
+	if len(w.queue)+len(fresh) > cap(w.queue) {
+		return 0, ErrQueueFull
+	}
+
+	for _, it := range fresh {
+		w.pending[itemKey(it.tenantID, &it.req)] = true
+		w.queue <- it
+	}
+	if len(fresh) > 0 {
+		log.Printf("🔥 Warm-up queued: tenant=%s, items=%d, skipped=%d", tenantID, len(fresh), len(reqs)-len(fresh))
+	}
+	return len(fresh), nil
+}
+
+// itemKey identifies an item by tenant, device, URL and level
+func itemKey(tenantID string, req *models.ConvertRequest) string {
+	return tenantID + "|" + req.DeviceID + "|" + req.URL + "|" + req.AntiFingerprintLevel
+}
+
+// Run processes queued items until ctx is cancelled; items still queued are dropped
+func (w *Warmer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Println("🛑 Warm-up stopped")
+}
+
+func (w *Warmer) loop(ctx context.Context) {
+	for {
+		select {
+		case it := <-w.queue:
+			if !w.waitIdle(ctx) {
+				return
+			}
+			w.handle(ctx, it)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitIdle blocks while live traffic keeps the converters busy; false when ctx is done
+func (w *Warmer) waitIdle(ctx context.Context) bool {
+	for w.busy() {
+		select {
+		case <-time.After(busyPoll):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// handle converts one item; a cache hit means it was already warm
+func (w *Warmer) handle(ctx context.Context, it item) {
+	defer func() {
+		w.mu.Lock()
+		delete(w.pending, itemKey(it.tenantID, &it.req))
+		w.mu.Unlock()
+	}()
+
+	procCtx := audit.WithCaller(tenant.WithID(ctx, it.tenantID), "warmup")
+	procCtx, cancel := context.WithTimeout(procCtx, w.timeout)
+	defer cancel()
+
+	req := it.req
+	resp, err := w.process(procCtx, &req)
+	switch {
+	case err != nil:
+		atomic.AddInt64(&w.failed, 1)
+		log.Printf("❌ Warm-up failed: device=%s, url=%s, error=%v", it.req.DeviceID, remote.Redact(it.req.URL), err)
+	case resp.CacheHit:
+		atomic.AddInt64(&w.cached, 1)
+	default:
+		atomic.AddInt64(&w.warmed, 1)
+		log.Printf("🔥 Warmed: device=%s, url=%s", it.req.DeviceID, remote.Redact(it.req.URL))
+	}
+}
+
+// GetStats returns warm-up counters (nil-safe)
+func (w *Warmer) GetStats() Stats {
+	if w == nil {
+		return Stats{}
+	}
+	return Stats{
+		Queued: len(w.queue),
+		Warmed: atomic.LoadInt64(&w.warmed),
+		Cached: atomic.LoadInt64(&w.cached),
+		Failed: atomic.LoadInt64(&w.failed),
+	}
+}
@@ -0,0 +1,261 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fingerprint-converter/internal/services"
+)
+
+// Config holds watch-folder settings
+type Config struct {
+	InputDir    string
+	OutputDir   string
+	ArchiveDir  string // Originals are moved here after a successful conversion
+	FailedDir   string // Originals that failed to convert, with a .error.txt next to them
+	Interval    time.Duration
+	Level       string // AF level ("" = default per media type)
+	Concurrency int
+	Timeout     time.Duration
+}
+
+// Watcher converts files dropped into a directory
+//
+// It polls instead of using inotify so it also works on network shares (SMB/NFS),
+// and only picks up a file once its size and mtime are stable across two scans.
+type Watcher struct {
+	cfg            Config
+	audioConverter *services.AudioConverter
+	imageConverter *services.ImageConverter
+	videoConverter *services.VideoConverter
+	seen           map[string]fileState
+	inFlight       sync.Map
+	sem            chan struct{}
+	wg             sync.WaitGroup
+	converted      int64
+	failed         int64
+}
+
+// fileState is what a file looked like on the previous scan
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Stats reports watcher activity
+type Stats struct {
+	Converted int64
+	Failed    int64
+}
+
+// New creates a watcher and makes sure its directories exist
+func New(cfg Config, audioConverter *services.AudioConverter, imageConverter *services.ImageConverter, videoConverter *services.VideoConverter) (*Watcher, error) {
+	if cfg.InputDir == "" || cfg.OutputDir == "" {
+		return nil, fmt.Errorf("watch input and output directories are required")
+	}
+	if cfg.ArchiveDir == "" {
+		cfg.ArchiveDir = filepath.Join(cfg.InputDir, "archive")
+	}
+	if cfg.FailedDir == "" {
+		cfg.FailedDir = filepath.Join(cfg.InputDir, "failed")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+
+	for _, dir := range []string{cfg.InputDir, cfg.OutputDir, cfg.ArchiveDir, cfg.FailedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	return &Watcher{
+		cfg:            cfg,
+		audioConverter: audioConverter,
+		imageConverter: imageConverter,
+		videoConverter: videoConverter,
+		seen:           make(map[string]fileState),
+		sem:            make(chan struct{}, cfg.Concurrency),
+	}, nil
+}
+
+// Run scans the input directory until ctx is cancelled, then waits for running conversions
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.scan(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			w.wg.Wait()
+			log.Println("🛑 Watcher stopped")
+			return
+		}
+	}
+}
+
+// scan lists the input directory and starts conversions for files that stopped changing
+func (w *Watcher) scan(ctx context.Context) {
+	entries, err := os.ReadDir(w.cfg.InputDir)
+	if err != nil {
+		log.Printf("⚠️  Watcher scan failed: %v", err)
+		return
+	}
+
+	current := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || isTemporary(name) || services.DetectMediaType(name) == "" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed between listing and stat
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		current[name] = state
+
+		// Still being copied in: wait for the next scan
+		if prev, ok := w.seen[name]; !ok || prev != state {
+			continue
+		}
+		if _, busy := w.inFlight.LoadOrStore(name, true); busy {
+			continue
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+		case <-ctx.Done():
+			w.inFlight.Delete(name)
+			return
+		}
+
+		w.wg.Add(1)
+		go func(name string) {
+			defer w.wg.Done()
+			defer func() { <-w.sem }()
+			defer w.inFlight.Delete(name)
+			w.process(ctx, name)
+		}(name)
+	}
+	w.seen = current
+}
+
+// process converts one file and moves the original to the archive or failed folder
+func (w *Watcher) process(ctx context.Context, name string) {
+	start := time.Now()
+	inputPath := filepath.Join(w.cfg.InputDir, name)
+	mediaType := services.DetectMediaType(name)
+
+	level := w.cfg.Level
+	if level == "" {
+		level = services.DefaultAFLevel(mediaType)
+	}
+
+	outputName := strings.TrimSuffix(name, filepath.Ext(name)) + w.outputExtension(mediaType, inputPath)
+	outputPath := uniquePath(filepath.Join(w.cfg.OutputDir, outputName))
+
+	if err := w.convert(ctx, inputPath, outputPath, mediaType, level); err != nil {
+		atomic.AddInt64(&w.failed, 1)
+		log.Printf("❌ Watch: %s failed: %v", name, err)
+
+		failedPath := uniquePath(filepath.Join(w.cfg.FailedDir, name))
+		if err := os.Rename(inputPath, failedPath); err != nil {
+			log.Printf("⚠️  Watch: failed to move %s to failed folder: %v", name, err)
+			return
+		}
+		os.WriteFile(failedPath+".error.txt", []byte(err.Error()+"\n"), 0644)
+		return
+	}
+
+	if err := os.Rename(inputPath, uniquePath(filepath.Join(w.cfg.ArchiveDir, name))); err != nil {
+		log.Printf("⚠️  Watch: failed to archive %s: %v", name, err)
+	}
+
+	atomic.AddInt64(&w.converted, 1)
+	log.Printf("✅ Watch: %s → %s (%s, %dms)", name, filepath.Base(outputPath), level, time.Since(start).Milliseconds())
+}
+
+// convert writes the output to a hidden temp file first so readers of the
+// output folder never see a partial file
+func (w *Watcher) convert(ctx context.Context, inputPath, outputPath, mediaType, level string) error {
+	inputData, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	tempPath := filepath.Join(filepath.Dir(outputPath), ".tmp-"+filepath.Base(outputPath))
+	defer os.Remove(tempPath)
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	switch mediaType {
+	case "audio":
+		_, err = w.audioConverter.Convert(ctx, inputData, level, tempPath, services.ConvertOptions{})
+	case "image":
+		_, err = w.imageConverter.Convert(ctx, inputData, level, tempPath, services.ConvertOptions{})
+	default:
+		_, err = w.videoConverter.Convert(ctx, inputData, level, tempPath, services.ConvertOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, outputPath)
+}
+
+// outputExtension returns the extension the converter produces for a file
+// Images keep PNG/WebP content (whatever the file is called), everything else becomes JPEG
+func (w *Watcher) outputExtension(mediaType, inputPath string) string {
+	switch mediaType {
+	case "audio":
+		return w.audioConverter.GetOutputExtension("")
+	case "image":
+		return w.imageConverter.OutputExtensionOf(inputPath, services.ConvertOptions{})
+	default:
+		return w.videoConverter.GetOutputExtension()
+	}
+}
+
+// GetStats returns current statistics
+func (w *Watcher) GetStats() Stats {
+	return Stats{
+		Converted: atomic.LoadInt64(&w.converted),
+		Failed:    atomic.LoadInt64(&w.failed),
+	}
+}
+
+// isTemporary skips hidden files and partial uploads
+func isTemporary(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") ||
+		strings.HasSuffix(lower, ".tmp") || strings.HasSuffix(lower, ".part") ||
+		strings.HasSuffix(lower, ".crdownload")
+}
+
+// uniquePath appends a timestamp when path already exists
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(path, ext), time.Now().UnixNano(), ext)
+}